@@ -0,0 +1,104 @@
+package segb
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChangeKind says how an entry differs between two acquisitions of a stream.
+type ChangeKind int
+
+const (
+	ChangeAdded    ChangeKind = iota // Only in the later acquisition
+	ChangeDeleted                    // Written in the earlier acquisition, deleted in the later one
+	ChangeVanished                   // Only in the earlier acquisition
+)
+
+// String returns the name of the change, e.g. "added".
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeDeleted:
+		return "deleted"
+	case ChangeVanished:
+		return "vanished"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// EntryChange is an entry that differs between two acquisitions.
+type EntryChange struct {
+	Kind   ChangeKind
+	Hash   PayloadHash    // SHA-256 of the payload, which identifies the entry
+	Before *PayloadSource // The entry in the earlier acquisition, nil if added
+	After  *PayloadSource // The entry in the later acquisition, nil if vanished
+}
+
+// DiffAcquisitions compares two acquisitions of the same stream, e.g. from
+// backups a week apart, each given as its decoded segments keyed by path.
+// Entries are told apart by the SHA-256 of their payload, as their indexes
+// and offsets shift when segments are compacted or rotated; entries with the
+// same payload are paired in order. It returns the entries that vanished or
+// were deleted, in the order of the earlier acquisition, then those added, in
+// the order of the later one. Entries present in both with the same state,
+// or deleted ones written again, are left out.
+func DiffAcquisitions(before, after map[string]Segb) []EntryChange {
+	earlier, later := acquisitionSources(before), acquisitionSources(after)
+
+	// Entries of the later acquisition by payload, consumed as they pair up
+	pending := make(map[PayloadHash][]*PayloadSource)
+	hashes := make([]PayloadHash, len(later))
+	for i := range later {
+		hashes[i] = HashPayload(later[i].Entry.Data)
+		pending[hashes[i]] = append(pending[hashes[i]], &later[i])
+	}
+
+	var changes []EntryChange
+	for i := range earlier {
+		source := &earlier[i]
+		hash := HashPayload(source.Entry.Data)
+		matches := pending[hash]
+		if len(matches) == 0 {
+			changes = append(changes, EntryChange{Kind: ChangeVanished, Hash: hash, Before: source})
+			continue
+		}
+		match := matches[0]
+		pending[hash] = matches[1:]
+		if source.Entry.State != EntryStateDeleted && match.Entry.State == EntryStateDeleted {
+			changes = append(changes, EntryChange{Kind: ChangeDeleted, Hash: hash, Before: source, After: match})
+		}
+	}
+
+	// Whatever did not pair up is new
+	unpaired := make(map[*PayloadSource]bool)
+	for _, sources := range pending {
+		for _, source := range sources {
+			unpaired[source] = true
+		}
+	}
+	for i := range later {
+		if unpaired[&later[i]] {
+			changes = append(changes, EntryChange{Kind: ChangeAdded, Hash: hashes[i], After: &later[i]})
+		}
+	}
+	return changes
+}
+
+// acquisitionSources lists the entries of an acquisition in sorted path
+// order, then entry order.
+func acquisitionSources(files map[string]Segb) []PayloadSource {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sources []PayloadSource
+	for _, path := range paths {
+		for i, entry := range files[path].Entries {
+			sources = append(sources, PayloadSource{Path: path, Index: i, Entry: entry})
+		}
+	}
+	return sources
+}
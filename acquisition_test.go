@@ -0,0 +1,55 @@
+package segb
+
+import "testing"
+
+func TestDiffAcquisitions(t *testing.T) {
+	entry := func(state EntryState, data string) Entry {
+		return Entry{State: state, Data: []byte(data)}
+	}
+	before := map[string]Segb{
+		"week1/b": {Entries: []Entry{entry(EntryStateWritten, "kept"), entry(EntryStateWritten, "removed")}},
+		"week1/a": {Entries: []Entry{entry(EntryStateWritten, "dup"), entry(EntryStateWritten, "dup"), entry(EntryStateWritten, "gone")}},
+	}
+	after := map[string]Segb{
+		"week2/a": {Entries: []Entry{
+			entry(EntryStateWritten, "new"), entry(EntryStateWritten, "dup"), entry(EntryStateDeleted, "removed"),
+			entry(EntryStateWritten, "kept"), entry(EntryStateDeleted, "dup"), entry(EntryStateWritten, "dup"),
+		}},
+	}
+
+	type change struct {
+		kind        ChangeKind
+		data        string
+		before      string
+		beforeIndex int
+		afterIndex  int
+		hasBefore   bool
+		hasAfter    bool
+	}
+	want := []change{
+		// The second "dup" pairs with the deleted copy, the third one is new
+		{kind: ChangeDeleted, data: "dup", before: "week1/a", beforeIndex: 1, afterIndex: 4, hasBefore: true, hasAfter: true},
+		{kind: ChangeVanished, data: "gone", before: "week1/a", beforeIndex: 2, hasBefore: true},
+		{kind: ChangeDeleted, data: "removed", before: "week1/b", beforeIndex: 1, afterIndex: 2, hasBefore: true, hasAfter: true},
+		{kind: ChangeAdded, data: "new", afterIndex: 0, hasAfter: true},
+		{kind: ChangeAdded, data: "dup", afterIndex: 5, hasAfter: true},
+	}
+
+	changes := DiffAcquisitions(before, after)
+	if len(changes) != len(want) {
+		t.Fatalf("DiffAcquisitions() = %d changes; want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, c := range changes {
+		w := want[i]
+		if c.Kind != w.kind || c.Hash != HashPayload([]byte(w.data)) || (c.Before != nil) != w.hasBefore || (c.After != nil) != w.hasAfter {
+			t.Errorf("change %d = %v %+v; want %v of %q", i, c.Kind, c, w.kind, w.data)
+			continue
+		}
+		if c.Before != nil && (c.Before.Path != w.before || c.Before.Index != w.beforeIndex) {
+			t.Errorf("change %d before = %s[%d]; want %s[%d]", i, c.Before.Path, c.Before.Index, w.before, w.beforeIndex)
+		}
+		if c.After != nil && (c.After.Path != "week2/a" || c.After.Index != w.afterIndex) {
+			t.Errorf("change %d after = %s[%d]; want week2/a[%d]", i, c.After.Path, c.After.Index, w.afterIndex)
+		}
+	}
+}
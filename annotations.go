@@ -0,0 +1,127 @@
+package segb
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// AnnotationsSuffix is appended to the path of a SEGB file to name the
+// sidecar holding its annotations, e.g. "file.segb.tags.json".
+const AnnotationsSuffix = ".tags.json"
+
+// Annotation is what an analyst noted about an entry.
+type Annotation struct {
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
+}
+
+// IsZero reports whether the annotation holds no tags and no note.
+func (a Annotation) IsZero() bool {
+	return len(a.Tags) == 0 && a.Note == ""
+}
+
+// AddTags adds the tags the annotation does not have yet.
+func (a *Annotation) AddTags(tags ...string) {
+	for _, tag := range tags {
+		if !slices.Contains(a.Tags, tag) {
+			a.Tags = append(a.Tags, tag)
+		}
+	}
+}
+
+// RemoveTags removes the given tags from the annotation.
+func (a *Annotation) RemoveTags(tags ...string) {
+	a.Tags = slices.DeleteFunc(a.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+}
+
+// Annotations are the tags and notes of the entries of a SEGB file, stored
+// as JSON in a sidecar next to it. Entries are keyed by the hex SHA-256 of
+// their payload (PayloadHash, as in export.Record.SHA256) rather than by
+// index or offset, so annotations survive re-parsing with other options, and
+// entries with identical payloads share them.
+type Annotations map[string]Annotation
+
+// AnnotationsPath returns the path of the sidecar of the SEGB file at path.
+func AnnotationsPath(path string) string {
+	return path + AnnotationsSuffix
+}
+
+// LoadAnnotations reads the sidecar of the SEGB file at path. A file without
+// a sidecar has no annotations, which is not an error.
+func LoadAnnotations(path string) (Annotations, error) {
+	data, err := os.ReadFile(AnnotationsPath(path))
+	return parseAnnotations(AnnotationsPath(path), data, err)
+}
+
+// LoadAnnotationsFS is LoadAnnotations for the SEGB file at path in fsys,
+// e.g. a bucket opened with the remote package.
+func LoadAnnotationsFS(fsys fs.FS, path string) (Annotations, error) {
+	data, err := fs.ReadFile(fsys, AnnotationsPath(path))
+	return parseAnnotations(AnnotationsPath(path), data, err)
+}
+
+// parseAnnotations parses the sidecar read from path, or returns the error
+// reading it failed with.
+func parseAnnotations(path string, data []byte, err error) (Annotations, error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		return Annotations{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	annotations := Annotations{}
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, &fs.PathError{Op: "parse", Path: path, Err: err}
+	}
+	return annotations, nil
+}
+
+// Save writes the annotations to the sidecar of the SEGB file at path,
+// replacing it in one step, or removes the sidecar if there are none.
+func (a Annotations) Save(path string) error {
+	sidecar := AnnotationsPath(path)
+	if len(a) == 0 {
+		if err := os.Remove(sidecar); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(sidecar), filepath.Base(sidecar)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), sidecar)
+}
+
+// For returns the annotation of an entry, the zero Annotation if it has
+// none.
+func (a Annotations) For(entry Entry) Annotation {
+	return a[HashPayload(entry.Data).String()]
+}
+
+// Set replaces the annotation of an entry, removing it if it is zero.
+func (a Annotations) Set(entry Entry, annotation Annotation) {
+	key := HashPayload(entry.Data).String()
+	if annotation.IsZero() {
+		delete(a, key)
+		return
+	}
+	a[key] = annotation
+}
@@ -0,0 +1,71 @@
+package segb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAnnotations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.segb")
+
+	annotations, err := LoadAnnotations(path)
+	if err != nil || len(annotations) != 0 {
+		t.Fatalf("LoadAnnotations(no sidecar) = %v, %v; want empty, nil", annotations, err)
+	}
+
+	launch := Entry{Data: []byte("com.apple.mobilesafari")}
+	var annotation Annotation
+	annotation.AddTags("launch", "safari", "launch")
+	annotation.Note = "first launch after restore"
+	annotations.Set(launch, annotation)
+	if err := annotations.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries are matched by payload, wherever they are in the file
+	loaded, err := LoadAnnotations(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Annotation{Tags: []string{"launch", "safari"}, Note: "first launch after restore"}
+	if got := loaded.For(Entry{ID: 7, Offset: 0x40, Data: launch.Data}); !reflect.DeepEqual(got, want) {
+		t.Errorf("For() = %+v; want %+v", got, want)
+	}
+	if got := loaded.For(Entry{Data: []byte("other")}); !got.IsZero() {
+		t.Errorf("For(unannotated) = %+v; want zero", got)
+	}
+
+	// The sidecar reads the same from a file system
+	data, err := os.ReadFile(AnnotationsPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"streams/file.segb" + AnnotationsSuffix: &fstest.MapFile{Data: data}}
+	if got, err := LoadAnnotationsFS(fsys, "streams/file.segb"); err != nil || !reflect.DeepEqual(got, loaded) {
+		t.Errorf("LoadAnnotationsFS() = %+v, %v; want %+v", got, err, loaded)
+	}
+	if got, err := LoadAnnotationsFS(fsys, "other.segb"); err != nil || len(got) != 0 {
+		t.Errorf("LoadAnnotationsFS(no sidecar) = %v, %v; want empty, nil", got, err)
+	}
+
+	// Removing the last annotation removes the sidecar
+	annotation.RemoveTags("launch", "safari")
+	annotation.Note = ""
+	loaded.Set(launch, annotation)
+	if err := loaded.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(AnnotationsPath(path)); !os.IsNotExist(err) {
+		t.Errorf("Stat(sidecar) error = %v; want not exist", err)
+	}
+
+	if err := os.WriteFile(AnnotationsPath(path), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAnnotations(path); err == nil {
+		t.Error("LoadAnnotations(malformed) error = nil; want an error")
+	}
+}
@@ -0,0 +1,381 @@
+// Package archive decodes SEGB files inside zip and tar archives without
+// extracting them, since extractions are commonly delivered as archives and
+// unpacking all of one to parse a few Biome files is wasteful.
+//
+// Members of zip archives and of uncompressed tar archives are read in
+// place. Tar archives compressed as a whole (gzip, bzip2 or zstd) are read
+// in a single pass; only the members that are read are kept in memory, and
+// only as far as they are read.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is the format of an archive.
+type Format int
+
+const (
+	FormatNone Format = iota
+	FormatZip
+	FormatTar
+	FormatTarGzip
+	FormatTarBzip2
+	FormatTarZstd
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatZip:
+		return "zip"
+	case FormatTar:
+		return "tar"
+	case FormatTarGzip:
+		return "tar.gz"
+	case FormatTarBzip2:
+		return "tar.bz2"
+	case FormatTarZstd:
+		return "tar.zst"
+	default:
+		return "none"
+	}
+}
+
+// ErrNotArchive is returned for files that are neither zip nor tar.
+var ErrNotArchive = errors.New("not a zip or tar archive")
+
+// errStop ends a Walk early.
+var errStop = errors.New("stop walking")
+
+// tarMagicOffset is where the "ustar" magic of a tar header sits.
+const tarMagicOffset = 257
+
+// Detect tells the format of the archive at path by its contents.
+func Detect(path string) (Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FormatNone, err
+	}
+	defer file.Close()
+	return detect(file)
+}
+
+// detect tells the format of the archive read from r.
+func detect(r io.Reader) (Format, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return FormatNone, nil
+	}
+
+	var zr io.Reader
+	format := FormatTar
+	switch {
+	case bytes.Equal(magic, []byte("PK\x03\x04")), bytes.Equal(magic, []byte("PK\x05\x06")):
+		return FormatZip, nil
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return FormatNone, nil
+		}
+		zr, format = gz, FormatTarGzip
+	case bytes.Equal(magic[:3], []byte("BZh")):
+		zr, format = bzip2.NewReader(br), FormatTarBzip2
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zs, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return FormatNone, nil
+		}
+		defer zs.Close()
+		zr, format = zs, FormatTarZstd
+	default:
+		zr = br
+	}
+
+	header := make([]byte, tarMagicOffset+5)
+	if _, err := io.ReadFull(zr, header); err != nil || !bytes.Equal(header[tarMagicOffset:], []byte("ustar")) {
+		return FormatNone, nil
+	}
+	return format, nil
+}
+
+// Member is a regular file in an archive.
+type Member struct {
+	Name    string // Slash-separated path in the archive, without a leading "/" or "./"
+	Size    int64
+	ModTime time.Time
+	open    func() (io.ReadSeeker, error)
+}
+
+// Open returns the contents of the member. It may only be called, and the
+// contents read, until the function passed to Walk returns.
+func (m Member) Open() (io.ReadSeeker, error) {
+	return m.open()
+}
+
+// Walk calls fn with each regular file of the zip or tar archive at path,
+// in archive order, until fn returns an error, which Walk returns.
+func Walk(path string, fn func(Member) error) error {
+	format, err := Detect(path)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatNone:
+		return fmt.Errorf("%s: %w", path, ErrNotArchive)
+	case FormatZip:
+		return walkZip(path, fn)
+	default:
+		return walkTar(path, format, fn)
+	}
+}
+
+func walkZip(path string, fn func(Member) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	r, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		name, ok := memberName(f.Name)
+		if !ok || !f.Mode().IsRegular() {
+			continue
+		}
+		member := Member{Name: name, Size: int64(f.UncompressedSize64), ModTime: f.Modified}
+		var rc io.ReadCloser
+		member.open = func() (io.ReadSeeker, error) {
+			// Stored members are read in place, others as far as read
+			if f.Method == zip.Store {
+				offset, err := f.DataOffset()
+				if err != nil {
+					return nil, err
+				}
+				return io.NewSectionReader(file, offset, member.Size), nil
+			}
+			if rc != nil {
+				rc.Close()
+			}
+			var err error
+			if rc, err = f.Open(); err != nil {
+				return nil, err
+			}
+			return newSpoolReader(rc, member.Size), nil
+		}
+		err := fn(member)
+		if rc != nil {
+			rc.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(path string, format Format, fn func(Member) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var source io.Reader = file
+	switch format {
+	case FormatTarGzip:
+		gz, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			return err
+		}
+		source = gz
+	case FormatTarBzip2:
+		source = bzip2.NewReader(bufio.NewReader(file))
+	case FormatTarZstd:
+		zs, err := zstd.NewReader(bufio.NewReader(file), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		defer zs.Close()
+		source = zs
+	}
+
+	tr := tar.NewReader(source)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := memberName(header.Name)
+		if !ok || !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		member := Member{Name: name, Size: header.Size, ModTime: header.ModTime}
+		// Without compression the data follows the header in the file, and
+		// is read in place unless the member is sparse
+		offset := int64(-1)
+		if format == FormatTar && !sparse(header) {
+			if offset, err = file.Seek(0, io.SeekCurrent); err != nil {
+				return err
+			}
+		}
+		opened := false
+		member.open = func() (io.ReadSeeker, error) {
+			if offset >= 0 {
+				return io.NewSectionReader(file, offset, member.Size), nil
+			}
+			// The stream cannot be rewound, so it is read once
+			if opened {
+				return nil, fmt.Errorf("%s: %s: member already opened", path, name)
+			}
+			opened = true
+			return newSpoolReader(tr, member.Size), nil
+		}
+		if err := fn(member); err != nil {
+			return err
+		}
+	}
+}
+
+// sparse reports whether a tar member is stored sparse, with holes that
+// only the tar reader fills in.
+func sparse(header *tar.Header) bool {
+	if header.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// memberName cleans the name of a member, or returns false for names that
+// leave the archive.
+func memberName(name string) (string, bool) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	return name, fs.ValidPath(name) && name != "."
+}
+
+// Decode decodes the member with the given name of the archive at path.
+func Decode(path, name string, opts ...segb.DecodeOption) (segb.Segb, error) {
+	var s segb.Segb
+	err := withMember(path, name, func(r io.ReadSeeker) (err error) {
+		s, err = segb.Decode(r, opts...)
+		return err
+	})
+	return s, err
+}
+
+// ReadFile returns the contents of the member with the given name of the
+// archive at path.
+func ReadFile(path, name string) ([]byte, error) {
+	var data []byte
+	err := withMember(path, name, func(r io.ReadSeeker) (err error) {
+		data, err = io.ReadAll(r)
+		return err
+	})
+	return data, err
+}
+
+// withMember calls fn with the contents of the member with the given name
+// of the archive at path.
+func withMember(path, name string, fn func(io.ReadSeeker) error) error {
+	want, _ := memberName(name)
+	found := false
+	err := Walk(path, func(m Member) error {
+		if m.Name != want {
+			return nil
+		}
+		found = true
+		r, err := m.Open()
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+		return errStop
+	})
+	if err != nil && err != errStop {
+		return err
+	}
+	if !found {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// Load decodes every SEGB member of the archive at path, told by its
+// contents as segb.LoadDir does. Results are keyed by the path of the
+// archive joined with the name of the member, the corpus root being the
+// archive. Of the options, only segb.WithLoadRetry applies.
+func Load(path string, opts ...segb.LoadOption) (*segb.Corpus, error) {
+	corpus := &segb.Corpus{Root: path}
+	err := Walk(path, func(m Member) error {
+		r, err := m.Open()
+		if err != nil {
+			return err
+		}
+		corpus.Add(segb.LoadStream(filepath.Join(path, filepath.FromSlash(m.Name)), r, m.Size, opts...))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+// SplitPath splits a path into an archive and the name of a member in it,
+// for paths that go on into an archive as into a directory, such as
+// "extraction.zip/private/var/mobile/Library/Biome/streams/...". It
+// returns false if no leading part of the path is an archive file.
+func SplitPath(name string) (archive, member string, ok bool) {
+	slashed := filepath.ToSlash(name)
+	for i := 0; i < len(slashed); i++ {
+		if slashed[i] != '/' || i == 0 {
+			continue
+		}
+		prefix := filepath.FromSlash(slashed[:i])
+		info, err := os.Stat(prefix)
+		if err != nil {
+			return "", "", false
+		}
+		if info.IsDir() {
+			continue
+		}
+		if format, err := Detect(prefix); err != nil || format == FormatNone {
+			return "", "", false
+		}
+		return prefix, slashed[i+1:], true
+	}
+	return "", "", false
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/segbtest"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const segmentName = "private/var/mobile/Library/Biome/streams/restricted/App.InFocus/local/1"
+
+// testMembers returns the members of the test archives: a SEGB file, and
+// a larger file that is not one.
+func testMembers(t *testing.T) map[string][]byte {
+	data, err := segbtest.Bytes(segb.SEGB_VERSION_2, []segbtest.Entry{
+		{Data: []byte("com.apple.mobilesafari"), Created: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Data: []byte("com.apple.MobileSMS"), Created: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{
+		segmentName:                    data,
+		"private/var/mobile/video.mov": bytes.Repeat([]byte("not a segment "), 100000),
+	}
+}
+
+// writeArchive writes members to a new archive of the given format.
+func writeArchive(t *testing.T, format Format, members map[string][]byte) string {
+	var buf bytes.Buffer
+	switch format {
+	case FormatZip:
+		zw := zip.NewWriter(&buf)
+		for _, name := range []string{segmentName, "private/var/mobile/video.mov"} {
+			// The segment is deflated, the video stored
+			method := zip.Deflate
+			if name != segmentName {
+				method = zip.Store
+			}
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(members[name])
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		var w io.WriteCloser = nopCloser{&buf}
+		switch format {
+		case FormatTarGzip:
+			w = gzip.NewWriter(&buf)
+		case FormatTarZstd:
+			zw, err := zstd.NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w = zw
+		}
+		tw := tar.NewWriter(w)
+		tw.WriteHeader(&tar.Header{Name: "./private/", Typeflag: tar.TypeDir, Mode: 0o755})
+		for _, name := range []string{"private/var/mobile/video.mov", segmentName} {
+			tw.WriteHeader(&tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(members[name]))})
+			tw.Write(members[name])
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "extraction."+format.String())
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestArchives(t *testing.T) {
+	members := testMembers(t)
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGzip, FormatTarZstd} {
+		t.Run(format.String(), func(t *testing.T) {
+			path := writeArchive(t, format, members)
+			if got, err := Detect(path); got != format || err != nil {
+				t.Fatalf("Detect() = %v, %v; want %v", got, err, format)
+			}
+
+			corpus, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if corpus.Stats.FilesScanned != 2 || corpus.Stats.Decoded != 1 || corpus.Stats.Entries != 2 {
+				t.Errorf("scanned %d files, decoded %d with %d entries; want 2, 1 and 2",
+					corpus.Stats.FilesScanned, corpus.Stats.Decoded, corpus.Stats.Entries)
+			}
+			key := filepath.Join(path, filepath.FromSlash(segmentName))
+			if result := corpus.Files[key]; result == nil || result.Size != int64(len(members[segmentName])) {
+				t.Errorf("Files[%s] = %+v", key, result)
+			}
+
+			s, err := Decode(path, "/"+segmentName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(s.Entries) != 2 || string(s.Entries[1].Data) != "com.apple.MobileSMS" {
+				t.Errorf("Decode() = %d entries", len(s.Entries))
+			}
+			if _, err := Decode(path, "private/missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Decode(missing) error = %v; want fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	path := writeArchive(t, FormatZip, testMembers(t))
+	archive, member, ok := SplitPath(filepath.Join(path, filepath.FromSlash(segmentName)))
+	if !ok || archive != path || member != segmentName {
+		t.Errorf("SplitPath() = %q, %q, %v; want %q, %q", archive, member, ok, path, segmentName)
+	}
+	for _, name := range []string{path, filepath.Join(filepath.Dir(path), "missing", "member")} {
+		if _, _, ok := SplitPath(name); ok {
+			t.Errorf("SplitPath(%q) found an archive", name)
+		}
+	}
+
+	if _, err := Load(filepath.Dir(path)); err == nil {
+		t.Error("Load(directory) error = nil")
+	}
+}
@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// spoolReader makes a stream of known size seekable by keeping what has
+// been read of it in memory. Seeking needs no reading, so sniffing a large
+// member that turns out not to be SEGB reads only its first bytes.
+type spoolReader struct {
+	r    io.Reader
+	size int64
+	buf  []byte // The stream up to where it has been read
+	pos  int64
+	err  error // Error the stream failed with
+}
+
+func newSpoolReader(r io.Reader, size int64) *spoolReader {
+	return &spoolReader{r: r, size: size}
+}
+
+func (s *spoolReader) Read(p []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+	end := min(s.pos+int64(len(p)), s.size)
+	if err := s.fill(end); err != nil {
+		return 0, err
+	}
+	n := copy(p, s.buf[s.pos:end])
+	s.pos += int64(n)
+	return n, nil
+}
+
+// fill reads the stream up to end.
+func (s *spoolReader) fill(end int64) error {
+	if int64(len(s.buf)) >= end {
+		return nil
+	}
+	if s.err != nil {
+		return s.err
+	}
+	start := len(s.buf)
+	s.buf = append(s.buf, make([]byte, end-int64(start))...)
+	n, err := io.ReadFull(s.r, s.buf[start:])
+	s.buf = s.buf[:start+n]
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("member shorter than its %d bytes: %w", s.size, io.ErrUnexpectedEOF)
+		}
+		s.err = err
+		return err
+	}
+	return nil
+}
+
+func (s *spoolReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, fmt.Errorf("archive: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("archive: negative position %d", offset)
+	}
+	s.pos = offset
+	return offset, nil
+}
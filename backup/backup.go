@@ -0,0 +1,177 @@
+// Package backup locates and decodes SEGB files inside iTunes/Finder backups
+// of iOS devices.
+//
+// Backups store every file under a hashed name (the SHA-1 of
+// "domain-relativePath") and record the original location in Manifest.db.
+// This package reads that manifest so Biome streams can be found by their
+// real path and decoded in place.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestName is the name of the manifest database at the root of a backup.
+const ManifestName = "Manifest.db"
+
+// BiomePathPrefix is the relative path prefix under which Biome data lives.
+const BiomePathPrefix = "Library/Biome/"
+
+// ErrEncrypted is returned when the manifest cannot be read because the
+// backup is encrypted.
+var ErrEncrypted = errors.New("manifest is not readable (encrypted backup?)")
+
+// File flags as stored in the manifest.
+const (
+	FlagFile      = 1
+	FlagDirectory = 2
+	FlagSymlink   = 4
+)
+
+// File is a row of the manifest's Files table.
+type File struct {
+	FileID       string // SHA-1 of "Domain-RelativePath", also the on-disk name
+	Domain       string // Backup domain, e.g. "HomeDomain"
+	RelativePath string // Path relative to the domain root
+	Flags        int64  // One of the Flag constants
+}
+
+// IsFile reports whether the entry is a regular file.
+func (f File) IsFile() bool {
+	return f.Flags == FlagFile
+}
+
+// Backup is an opened iOS backup directory.
+type Backup struct {
+	Root  string
+	Files []File
+}
+
+// Open reads the manifest of the backup at root.
+func Open(root string) (*Backup, error) {
+	db, err := openSQLite(filepath.Join(root, ManifestName))
+	if errors.Is(err, errNotSQLite) {
+		return nil, ErrEncrypted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rootPage, columns, err := db.table("Files")
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[strings.ToLower(column)] = i
+	}
+	for _, column := range []string{"fileid", "domain", "relativepath", "flags"} {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("manifest Files table has no %s column", column)
+		}
+	}
+
+	backup := &Backup{Root: root}
+	err = db.walk(rootPage, func(row []any) error {
+		text := func(column string) string {
+			if i := index[column]; i < len(row) {
+				s, _ := row[i].(string)
+				return s
+			}
+			return ""
+		}
+		file := File{
+			FileID:       text("fileid"),
+			Domain:       text("domain"),
+			RelativePath: text("relativepath"),
+		}
+		if i := index["flags"]; i < len(row) {
+			file.Flags, _ = row[i].(int64)
+		}
+		backup.Files = append(backup.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// Path returns the on-disk location of a backed up file. Modern backups
+// shard files into subdirectories named after the first two characters of
+// the file ID, older ones store them flat at the root.
+func (b *Backup) Path(f File) string {
+	if len(f.FileID) > 2 {
+		sharded := filepath.Join(b.Root, f.FileID[:2], f.FileID)
+		if _, err := os.Stat(sharded); err == nil {
+			return sharded
+		}
+	}
+	return filepath.Join(b.Root, f.FileID)
+}
+
+// Find returns the regular files for which match returns true.
+func (b *Backup) Find(match func(File) bool) []File {
+	var files []File
+	for _, f := range b.Files {
+		if f.IsFile() && match(f) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// BiomeFiles returns every regular file under a Biome directory, in any
+// domain.
+func (b *Backup) BiomeFiles() []File {
+	return b.Find(func(f File) bool {
+		return strings.HasPrefix(f.RelativePath, BiomePathPrefix) || strings.Contains(f.RelativePath, "/"+BiomePathPrefix)
+	})
+}
+
+// Result is the outcome of decoding a single backed up file.
+type Result struct {
+	File File
+	Path string // On-disk (hashed) path the file was read from
+	Segb segb.Segb
+	Err  error
+}
+
+// DecodeBiome decodes every SEGB file found under a Biome directory. Files
+// that are not SEGB (tombstones, configuration, ...) are skipped.
+func (b *Backup) DecodeBiome() []Result {
+	var results []Result
+	for _, f := range b.BiomeFiles() {
+		result, ok := b.decode(f)
+		if ok {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func (b *Backup) decode(f File) (Result, bool) {
+	result := Result{File: f, Path: b.Path(f)}
+
+	file, err := os.Open(result.Path)
+	if err != nil {
+		result.Err = err
+		return result, true
+	}
+	defer file.Close()
+
+	version, err := segb.DetectVersion(file)
+	if (err != nil && !errors.Is(err, segb.ErrAmbiguousVersion)) || version == segb.NONE {
+		return result, false
+	}
+
+	result.Segb, result.Err = segb.Decode(file)
+	return result, true
+}
@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bluefalconhd/segb/segbtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// manifestFiles are the rows of the Manifest.db of setupBackup. Enough
+// filler rows and a large blob are added so the Files table spans interior
+// pages and overflow chains.
+func manifestFiles() []segbtest.ManifestFile {
+	files := []segbtest.ManifestFile{
+		{FileID: "aa11", Domain: "HomeDomain", RelativePath: "Library/Biome/streams/restricted/App.InFocus/local/1", Flags: 1, File: bytes.Repeat([]byte("x"), 5000)},
+		{FileID: "bb22", Domain: "HomeDomain", RelativePath: "Library/Biome/streams/restricted/App.InFocus/remote/2", Flags: 1},
+		{FileID: "cc33", Domain: "HomeDomain", RelativePath: "Library/Biome/streams/restricted/App.InFocus/tombstone/3", Flags: 1},
+		{FileID: "dd44", Domain: "HomeDomain", RelativePath: "Library/Biome/streams", Flags: 2},
+	}
+	for i := range 2000 {
+		files = append(files, segbtest.ManifestFile{FileID: fmt.Sprintf("f%05d", i), Domain: "MediaDomain", RelativePath: fmt.Sprintf("Media/DCIM/%05d.JPG", i), Flags: 1})
+	}
+	return files
+}
+
+func setupBackup(t *testing.T) string {
+	root := t.TempDir()
+
+	if err := segbtest.WriteManifest(filepath.Join(root, ManifestName), manifestFiles()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := segbtest.WriteFixtures(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// Place the fixtures under their hashed names (sharded and flat)
+	if err := os.Mkdir(filepath.Join(root, "aa"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	renames := map[string]string{
+		"segb_version1.bin": filepath.Join("aa", "aa11"),
+		"segb_version2.bin": "bb22",
+	}
+	for src, dst := range renames {
+		if err := os.Rename(filepath.Join(root, src), filepath.Join(root, dst)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "cc33"), []byte("not a segb file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+func TestOpen(t *testing.T) {
+	root := setupBackup(t)
+
+	backup, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backup.Files) != 2004 {
+		t.Errorf("len(Files) = %d; want 2004", len(backup.Files))
+	}
+
+	biome := backup.BiomeFiles()
+	if len(biome) != 3 {
+		t.Fatalf("len(BiomeFiles()) = %d; want 3", len(biome))
+	}
+	if got, want := backup.Path(biome[0]), filepath.Join(root, "aa", "aa11"); got != want {
+		t.Errorf("Path() = %s; want %s", got, want)
+	}
+}
+
+func TestDecodeBiome(t *testing.T) {
+	root := setupBackup(t)
+
+	backup, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := backup.DecodeBiome()
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d; want 2", len(results))
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s: %v", result.File.RelativePath, result.Err)
+		}
+		if len(result.Segb.Entries) != 3 {
+			t.Errorf("%s: len(Entries) = %d; want 3", result.File.RelativePath, len(result.Segb.Entries))
+		}
+	}
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ManifestName), make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(root); err != ErrEncrypted {
+		t.Errorf("Open() error = %v; want %v", err, ErrEncrypted)
+	}
+}
+
+func TestDecodeRecordCorrupt(t *testing.T) {
+	// Header of 3 bytes: a 1-byte integer and a 1-byte string
+	row, err := decodeRecord([]byte{3, 1, 15, 42, 'x'})
+	if err != nil || len(row) != 2 || row[0] != int64(42) || row[1] != "x" {
+		t.Fatalf("got %v, %v", row, err)
+	}
+
+	huge := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	for name, payload := range map[string][]byte{
+		"empty":              nil,
+		"zero header size":   {0, 1, 15, 42, 'x'},
+		"huge header size":   append(append([]byte{}, huge...), 1, 42),
+		"header past end":    {9, 1, 42},
+		"huge serial type":   append([]byte{10}, huge...),
+		"truncated body":     {3, 1, 17, 42, 'x'},
+		"reserved type":      {2, 10},
+		"unterminated types": {2, 0x81},
+	} {
+		if _, err := decodeRecord(payload); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	db := &sqliteDB{data: make([]byte, 4096), pageSize: 4096, usableSize: 4096}
+	if _, err := db.leafPayload(append(append([]byte{}, huge...), 1)); err == nil {
+		t.Error("leafPayload accepted a payload larger than the database")
+	}
+}
@@ -0,0 +1,338 @@
+package backup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// This file contains a minimal read-only SQLite reader. It understands just
+// enough of the file format to walk table b-trees and decode records, which
+// is all that is needed to read an iOS backup's Manifest.db without pulling
+// in a full SQLite implementation.
+
+const sqliteMagic = "SQLite format 3\x00"
+
+var errNotSQLite = errors.New("not a SQLite database")
+
+// B-tree page types.
+const (
+	pageInteriorTable = 0x05
+	pageLeafTable     = 0x0d
+)
+
+type sqliteDB struct {
+	data       []byte
+	pageSize   int
+	usableSize int
+}
+
+func openSQLite(path string) (*sqliteDB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 100 || string(data[:16]) != sqliteMagic {
+		return nil, errNotSQLite
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size: %d", pageSize)
+	}
+	if encoding := binary.BigEndian.Uint32(data[56:60]); encoding > 1 {
+		return nil, fmt.Errorf("unsupported text encoding: %d", encoding)
+	}
+
+	return &sqliteDB{
+		data:       data,
+		pageSize:   pageSize,
+		usableSize: pageSize - int(data[20]),
+	}, nil
+}
+
+// page returns the bytes of the given 1-based page number.
+func (db *sqliteDB) page(n uint32) ([]byte, error) {
+	start := int64(n-1) * int64(db.pageSize)
+	if n == 0 || start+int64(db.pageSize) > int64(len(db.data)) {
+		return nil, fmt.Errorf("page %d out of range", n)
+	}
+	return db.data[start : start+int64(db.pageSize)], nil
+}
+
+// table returns the root page and column names of the named table.
+func (db *sqliteDB) table(name string) (uint32, []string, error) {
+	var root uint32
+	var columns []string
+
+	// sqlite_schema lives at page 1: type, name, tbl_name, rootpage, sql
+	err := db.walk(1, func(row []any) error {
+		if len(row) < 5 {
+			return nil
+		}
+		kind, _ := row[0].(string)
+		tableName, _ := row[1].(string)
+		if kind != "table" || !strings.EqualFold(tableName, name) {
+			return nil
+		}
+		rootPage, _ := row[3].(int64)
+		sql, _ := row[4].(string)
+		root = uint32(rootPage)
+		columns = parseColumns(sql)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	if root == 0 {
+		return 0, nil, fmt.Errorf("table %q not found", name)
+	}
+	return root, columns, nil
+}
+
+// walk visits every row of the table b-tree rooted at the given page.
+func (db *sqliteDB) walk(root uint32, fn func(row []any) error) error {
+	return db.walkPage(root, fn, 0)
+}
+
+func (db *sqliteDB) walkPage(n uint32, fn func(row []any) error, depth int) error {
+	if depth > 64 {
+		return errors.New("b-tree too deep")
+	}
+	page, err := db.page(n)
+	if err != nil {
+		return err
+	}
+
+	// Page 1 starts with the database header
+	headerOffset := 0
+	if n == 1 {
+		headerOffset = 100
+	}
+	header := page[headerOffset:]
+
+	pageType := header[0]
+	cellCount := int(binary.BigEndian.Uint16(header[3:5]))
+	cellPointers := header[8:]
+	if pageType == pageInteriorTable {
+		cellPointers = header[12:]
+	}
+	if len(cellPointers) < cellCount*2 {
+		return fmt.Errorf("page %d: cell pointer array out of range", n)
+	}
+
+	for i := 0; i < cellCount; i++ {
+		offset := int(binary.BigEndian.Uint16(cellPointers[i*2:]))
+		if offset >= len(page) {
+			return fmt.Errorf("page %d: cell offset out of range", n)
+		}
+		cell := page[offset:]
+
+		switch pageType {
+		case pageInteriorTable:
+			if len(cell) < 4 {
+				return fmt.Errorf("page %d: truncated cell", n)
+			}
+			if err := db.walkPage(binary.BigEndian.Uint32(cell), fn, depth+1); err != nil {
+				return err
+			}
+		case pageLeafTable:
+			payload, err := db.leafPayload(cell)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", n, err)
+			}
+			row, err := decodeRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", n, err)
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("page %d: unexpected page type 0x%02x", n, pageType)
+		}
+	}
+
+	if pageType == pageInteriorTable {
+		return db.walkPage(binary.BigEndian.Uint32(header[8:12]), fn, depth+1)
+	}
+	return nil
+}
+
+// leafPayload returns the full record payload of a table leaf cell,
+// following overflow pages if needed.
+func (db *sqliteDB) leafPayload(cell []byte) ([]byte, error) {
+	payloadSize, n := readVarint(cell)
+	cell = cell[n:]
+	_, n = readVarint(cell) // rowid
+	cell = cell[n:]
+
+	// No payload is larger than the database holding it
+	if payloadSize > uint64(len(db.data)) {
+		return nil, fmt.Errorf("invalid payload size %d", payloadSize)
+	}
+	size := int(payloadSize)
+	u := db.usableSize
+	maxLocal := u - 35
+	if size <= maxLocal {
+		if len(cell) < size {
+			return nil, errors.New("truncated cell")
+		}
+		return cell[:size], nil
+	}
+
+	minLocal := ((u-12)*32)/255 - 23
+	local := minLocal + (size-minLocal)%(u-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if len(cell) < local+4 {
+		return nil, errors.New("truncated cell")
+	}
+
+	payload := make([]byte, 0, size)
+	payload = append(payload, cell[:local]...)
+	next := binary.BigEndian.Uint32(cell[local:])
+	for len(payload) < size {
+		if next == 0 {
+			return nil, errors.New("overflow chain ended early")
+		}
+		page, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(page)
+		chunk := page[4:u]
+		if remaining := size - len(payload); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+		payload = append(payload, chunk...)
+	}
+	return payload, nil
+}
+
+// decodeRecord decodes a record into a slice of nil, int64, float64, string
+// and []byte values.
+func decodeRecord(payload []byte) ([]any, error) {
+	headerSize, n := readVarint(payload)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(payload)) {
+		return nil, errors.New("invalid record header")
+	}
+
+	header := payload[n:headerSize]
+	body := payload[headerSize:]
+
+	var row []any
+	for len(header) > 0 {
+		serialType, n := readVarint(header)
+		header = header[n:]
+
+		var size uint64
+		switch {
+		case serialType >= 12:
+			size = (serialType - 12) / 2
+		case serialType == 7:
+			size = 8
+		case serialType >= 1 && serialType <= 6:
+			size = []uint64{0, 1, 2, 3, 4, 6, 8}[serialType]
+		}
+		if size > uint64(len(body)) {
+			return nil, errors.New("record body truncated")
+		}
+		value := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			row = append(row, nil)
+		case serialType >= 1 && serialType <= 6:
+			row = append(row, readInt(value))
+		case serialType == 7:
+			row = append(row, math.Float64frombits(binary.BigEndian.Uint64(value)))
+		case serialType == 8:
+			row = append(row, int64(0))
+		case serialType == 9:
+			row = append(row, int64(1))
+		case serialType >= 12 && serialType%2 == 0:
+			row = append(row, append([]byte(nil), value...))
+		case serialType >= 13:
+			row = append(row, string(value))
+		default:
+			return nil, fmt.Errorf("reserved serial type %d", serialType)
+		}
+	}
+	return row, nil
+}
+
+// readInt decodes a big-endian two's complement integer of 1 to 8 bytes.
+func readInt(b []byte) int64 {
+	var v int64
+	if b[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// readVarint decodes a SQLite varint, returning the value and the number of
+// bytes consumed (0 if the input is empty).
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
+
+// parseColumns extracts the column names from a CREATE TABLE statement.
+func parseColumns(sql string) []string {
+	start := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var columns []string
+	depth := 0
+	field := strings.Builder{}
+	flush := func() {
+		words := strings.Fields(field.String())
+		field.Reset()
+		if len(words) == 0 {
+			return
+		}
+		switch strings.ToUpper(words[0]) {
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+			return
+		}
+		columns = append(columns, strings.Trim(words[0], "\"`[]"))
+	}
+	for _, r := range sql[start+1 : end] {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			flush()
+			continue
+		}
+		field.WriteRune(r)
+	}
+	flush()
+	return columns
+}
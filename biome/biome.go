@@ -0,0 +1,274 @@
+// Package biome models the directory layout Apple uses to store Biome
+// streams on disk and decodes the SEGB segment files it contains.
+//
+// A Biome root looks like this:
+//
+//	streams/
+//	  restricted/                 access class ("restricted" or "public")
+//	    App.InFocus/              stream name
+//	      local/                  segments written on this device
+//	        764213212345678
+//	      remote/                 segments synced from other devices
+//	        <device identifier>/
+//	          764213298765432
+//	      tombstone/              deletion bookkeeping
+//	        764213299999999
+//
+// Segment files are named after their creation time, expressed as
+// microseconds since the Cocoa epoch.
+package biome
+
+import (
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamsDir is the name of the directory holding all streams.
+const StreamsDir = "streams"
+
+// Location is where a segment sits within its stream directory.
+type Location int
+
+const (
+	LocationUnknown Location = iota
+	LocationLocal
+	LocationRemote
+	LocationTombstone
+)
+
+// String returns the directory name associated with the location.
+func (l Location) String() string {
+	switch l {
+	case LocationLocal:
+		return "local"
+	case LocationRemote:
+		return "remote"
+	case LocationTombstone:
+		return "tombstone"
+	default:
+		return "unknown"
+	}
+}
+
+func parseLocation(name string) Location {
+	switch name {
+	case "local":
+		return LocationLocal
+	case "remote":
+		return LocationRemote
+	case "tombstone":
+		return LocationTombstone
+	default:
+		return LocationUnknown
+	}
+}
+
+// Segment is a single segment file belonging to a stream.
+type Segment struct {
+	Path     string    // Path of the segment file
+	Stream   string    // Stream name, e.g. "App.InFocus"
+	Access   string    // Access class, e.g. "restricted"
+	Location Location  // Local, remote or tombstone
+	Device   string    // Remote device identifier, empty for local segments
+	Name     string    // File name of the segment
+	Created  time.Time // Creation time derived from the name, zero if the name is not a timestamp
+}
+
+// ParsePath interprets a path as a Biome segment path. It returns false if
+// the path does not contain a streams/<access>/<stream>/<location>/ sequence.
+func ParsePath(path string) (Segment, bool) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+
+	// Search from the end so nested "streams" directories resolve to the
+	// innermost one
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] != StreamsDir {
+			continue
+		}
+		rest := parts[i+1:]
+		if len(rest) < 4 {
+			continue
+		}
+
+		segment := Segment{
+			Path:     path,
+			Access:   rest[0],
+			Stream:   rest[1],
+			Location: parseLocation(rest[2]),
+			Name:     rest[len(rest)-1],
+		}
+		if segment.Location == LocationUnknown {
+			continue
+		}
+		if segment.Location == LocationRemote && len(rest) > 4 {
+			segment.Device = rest[3]
+		}
+		segment.Created = SegmentTime(segment.Name)
+		return segment, true
+	}
+	return Segment{}, false
+}
+
+// StreamName returns the name of the stream a segment path belongs to, as
+// parsed by ParsePath, or "" if the path is not a Biome segment path.
+func StreamName(path string) string {
+	segment, ok := ParsePath(path)
+	if !ok {
+		return ""
+	}
+	return segment.Stream
+}
+
+// SegmentTime converts a segment file name into its creation time. It
+// returns the zero time if the name is not numeric.
+func SegmentTime(name string) time.Time {
+	micros, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return segb.CocoaTimestampToTime(float64(micros) / 1e6)
+}
+
+// Stream groups the segment files of a single stream.
+type Stream struct {
+	Name       string
+	Access     string
+	Segments   []Segment // Local and remote segments, oldest first
+	Tombstones []Segment // Tombstone segments, oldest first
+}
+
+// Local returns the segments written on the device itself.
+func (s *Stream) Local() []Segment {
+	return s.filter(LocationLocal)
+}
+
+// Remote returns the segments synced from other devices.
+func (s *Stream) Remote() []Segment {
+	return s.filter(LocationRemote)
+}
+
+func (s *Stream) filter(location Location) []Segment {
+	var segments []Segment
+	for _, segment := range s.Segments {
+		if segment.Location == location {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// Enumerate walks root (a Biome directory or any ancestor of one, such as
+// an extraction) and returns every stream found, sorted by access class and
+// name. The layout of root is told by DetectProfile.
+func Enumerate(root string) ([]*Stream, error) {
+	return EnumerateProfile(root, DetectProfile(root))
+}
+
+// EnumerateProfile is Enumerate for an extraction with the given layout.
+// Segment paths are the paths of the files on disk.
+func EnumerateProfile(root string, profile Profile) ([]*Stream, error) {
+	streams := make(map[string]*Stream)
+
+	err := profile.Walk(root, func(path, devicePath string) error {
+		segment, ok := ParsePath(devicePath)
+		if !ok {
+			return nil
+		}
+		segment.Path = path
+
+		key := segment.Access + "/" + segment.Stream
+		stream, ok := streams[key]
+		if !ok {
+			stream = &Stream{Name: segment.Stream, Access: segment.Access}
+			streams[key] = stream
+		}
+		if segment.Location == LocationTombstone {
+			stream.Tombstones = append(stream.Tombstones, segment)
+		} else {
+			stream.Segments = append(stream.Segments, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Stream, 0, len(streams))
+	for _, stream := range streams {
+		sortSegments(stream.Segments)
+		sortSegments(stream.Tombstones)
+		result = append(result, stream)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Access != result[j].Access {
+			return result[i].Access < result[j].Access
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func sortSegments(segments []Segment) {
+	sort.SliceStable(segments, func(i, j int) bool {
+		if !segments[i].Created.Equal(segments[j].Created) {
+			return segments[i].Created.Before(segments[j].Created)
+		}
+		return segments[i].Path < segments[j].Path
+	})
+}
+
+// Entry is a decoded SEGB entry tagged with the stream and segment it came
+// from.
+type Entry struct {
+	segb.Entry
+	Stream    string
+	Segment   Segment
+	Tombstone *Tombstone // Tombstone record referencing this entry, nil if none
+}
+
+// DecodeSegment decodes a single segment file and tags its entries.
+func DecodeSegment(segment Segment) ([]Entry, error) {
+	file, err := os.Open(segment.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoded, err := segb.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(decoded.Entries))
+	for i, entry := range decoded.Entries {
+		entries[i] = Entry{Entry: entry, Stream: segment.Stream, Segment: segment}
+	}
+	return entries, nil
+}
+
+// Decode decodes every local and remote segment of the stream, in segment
+// order. Entries referenced by the stream's tombstones are flagged.
+func (s *Stream) Decode() ([]Entry, error) {
+	var entries []Entry
+	for _, segment := range s.Segments {
+		decoded, err := DecodeSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", segment.Path, err)
+		}
+		entries = append(entries, decoded...)
+	}
+
+	tombstones, err := s.ReadTombstones()
+	if err != nil {
+		return nil, err
+	}
+	Correlate(entries, tombstones)
+
+	return entries, nil
+}
@@ -0,0 +1,235 @@
+package biome
+
+import (
+	"encoding/binary"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/segbtest"
+	"google.golang.org/protobuf/encoding/protowire"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupBiome lays out a Biome root containing one stream with a local and a
+// remote segment built from the repository's generated fixtures.
+func setupBiome(t *testing.T) string {
+	root := t.TempDir()
+
+	if err := segbtest.WriteFixtures(root); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := filepath.Join(root, "Biome", "streams", "restricted", "App.InFocus")
+	layout := map[string]string{
+		"segb_version1.bin": filepath.Join(stream, "local", "764213212345678"),
+		"segb_version2.bin": filepath.Join(stream, "remote", "DEVICE-1", "764213298765432"),
+	}
+	for src, dst := range layout {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(root, src), dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestParsePath(t *testing.T) {
+	segment, ok := ParsePath("/x/Biome/streams/restricted/App.InFocus/remote/DEVICE-1/764213298765432")
+	if !ok {
+		t.Fatal("ParsePath() = false; want true")
+	}
+	if segment.Stream != "App.InFocus" || segment.Access != "restricted" {
+		t.Errorf("Stream, Access = %s, %s; want App.InFocus, restricted", segment.Stream, segment.Access)
+	}
+	if segment.Location != LocationRemote || segment.Device != "DEVICE-1" {
+		t.Errorf("Location, Device = %v, %s; want remote, DEVICE-1", segment.Location, segment.Device)
+	}
+	want := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(764213298*time.Second + 765432*time.Microsecond)
+	if !segment.Created.Equal(want) {
+		t.Errorf("Created = %v; want %v", segment.Created, want)
+	}
+
+	if _, ok := ParsePath("/x/Biome/streams/restricted/App.InFocus"); ok {
+		t.Error("ParsePath() on a stream directory = true; want false")
+	}
+
+	if name := StreamName("Biome/streams/public/Safari.History/local/764213212345678"); name != "Safari.History" {
+		t.Errorf("StreamName() = %q; want Safari.History", name)
+	}
+	if name := StreamName("/x/segb_version1.bin"); name != "" {
+		t.Errorf("StreamName() outside a Biome root = %q; want empty", name)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	root := setupBiome(t)
+
+	streams, err := Enumerate(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 1 {
+		t.Fatalf("len(streams) = %d; want 1", len(streams))
+	}
+
+	stream := streams[0]
+	if len(stream.Local()) != 1 || len(stream.Remote()) != 1 {
+		t.Fatalf("len(Local()), len(Remote()) = %d, %d; want 1, 1", len(stream.Local()), len(stream.Remote()))
+	}
+
+	entries, err := stream.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("len(entries) = %d; want 6", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Stream != "App.InFocus" {
+			t.Errorf("entry.Stream = %s; want App.InFocus", entry.Stream)
+		}
+	}
+	if entries[0].Segment.Location != LocationLocal || entries[5].Segment.Location != LocationRemote {
+		t.Errorf("entries are not in segment order")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	root := setupBiome(t)
+	broken := filepath.Join(root, "Biome", "streams", "restricted", "App.InFocus", "local", "764213299999999")
+	if err := os.WriteFile(broken, []byte("not a segment"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	streams, err := Enumerate(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := streams[0].Summarize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Stream != "App.InFocus" || summary.LocalSegments != 2 || summary.RemoteSegments != 1 || summary.Failed != 1 {
+		t.Errorf("Summarize() = %+v; want 2 local and 1 remote segments, 1 failed", summary)
+	}
+	if summary.Entries != 6 || summary.EntriesByState[segb.EntryStateWritten] != 6 || summary.DeletedRatio() != 0 {
+		t.Errorf("Entries = %d (%d written); want 6", summary.Entries, summary.EntriesByState[segb.EntryStateWritten])
+	}
+	first, last := segbtest.Entries[0].Created, segbtest.Entries[2].Created
+	if !summary.First.Equal(first) || !summary.Last.Equal(last) {
+		t.Errorf("First, Last = %v, %v; want %v, %v", summary.First, summary.Last, first, last)
+	}
+	if summary.Bytes == 0 {
+		t.Error("Bytes = 0")
+	}
+}
+
+// writeV2 writes a minimal SEGB v2 file containing the given payloads, all
+// in the written state.
+func writeV2(t *testing.T, path string, payloads [][]byte, timestamps []float64) {
+	var header, body, trailer []byte
+	header = append(header, "SEGB"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(payloads)))
+	header = binary.LittleEndian.AppendUint64(header, math.Float64bits(0))
+	header = append(header, make([]byte, 16)...)
+
+	for i, payload := range payloads {
+		trailer = binary.LittleEndian.AppendUint32(trailer, uint32(len(body)))
+		trailer = binary.LittleEndian.AppendUint32(trailer, 1)
+		trailer = binary.LittleEndian.AppendUint64(trailer, math.Float64bits(timestamps[i]))
+
+		body = binary.LittleEndian.AppendUint32(body, crc32.ChecksumIEEE(payload))
+		body = append(body, make([]byte, 4)...)
+		body = append(body, payload...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := append(append(header, body...), trailer...)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTombstones(t *testing.T) {
+	root := setupBiome(t)
+
+	// "The misfits." was written on 2007-06-29 into the local segment
+	deleted := time.Date(2007, 6, 29, 0, 0, 0, 0, time.UTC).Sub(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)).Seconds()
+
+	var payload []byte
+	payload = protowire.AppendTag(payload, 1, protowire.BytesType)
+	payload = protowire.AppendString(payload, "764213212345678")
+	payload = protowire.AppendTag(payload, 2, protowire.Fixed64Type)
+	payload = protowire.AppendFixed64(payload, math.Float64bits(deleted))
+
+	path := filepath.Join(root, "Biome", "streams", "restricted", "App.InFocus", "tombstone", "764213299999999")
+	writeV2(t, path, [][]byte{payload}, []float64{deleted + 60})
+
+	streams, err := Enumerate(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 1 || len(streams[0].Tombstones) != 1 {
+		t.Fatalf("expected one stream with one tombstone segment")
+	}
+
+	entries, err := streams[0].Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the local copy is referenced, the remote one lives in another segment
+	for _, entry := range entries {
+		want := entry.Segment.Location == LocationLocal && string(entry.Data) == "The misfits."
+		if got := entry.Tombstone != nil; got != want {
+			t.Errorf("%s entry %q: tombstoned = %v; want %v", entry.Segment.Location, entry.Data, got, want)
+		}
+	}
+}
+
+func TestOpenStream(t *testing.T) {
+	root := setupBiome(t)
+
+	r, err := OpenStream(filepath.Join(root, "Biome", "streams", "restricted", "App.InFocus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := r.All()
+	if len(entries) != 6 {
+		t.Fatalf("len(entries) = %d; want 6", len(entries))
+	}
+
+	// Both segments hold the same three entries, so the merge interleaves
+	// them pairwise with the local (older) segment first
+	for i, entry := range entries {
+		if i > 0 && entry.Created.Before(entries[i-1].Created) {
+			t.Errorf("entry %d is older than entry %d", i, i-1)
+		}
+		wantLocation := LocationLocal
+		if i%2 == 1 {
+			wantLocation = LocationRemote
+		}
+		if entry.Segment.Location != wantLocation {
+			t.Errorf("entry %d: Segment.Location = %v; want %v", i, entry.Segment.Location, wantLocation)
+		}
+	}
+
+	if _, err := OpenStream(root); err != nil {
+		t.Errorf("OpenStream() on an ancestor with one stream: %v", err)
+	}
+	if _, err := OpenStream(t.TempDir()); err == nil {
+		t.Error("OpenStream() on an empty directory succeeded")
+	}
+}
@@ -0,0 +1,166 @@
+package biome
+
+import (
+	"fmt"
+	"github.com/bluefalconhd/segb/backup"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Profile is the directory layout an extraction tool writes the files of a
+// device in. Enumerate detects the profile of the directory it is given,
+// EnumerateProfile takes one.
+type Profile struct {
+	Name string
+	// Match reports whether root was laid out by the tool.
+	Match func(root string) bool
+	// Walk calls fn with the on-disk path of each regular file of the
+	// extraction at root and the path the file had on the device, which
+	// ParsePath interprets.
+	Walk func(root string, fn func(path, devicePath string) error) error
+}
+
+var (
+	// ITunesBackup is an iTunes or Finder backup, whose files are stored
+	// under hashed names listed in its Manifest.db (see the backup package).
+	ITunesBackup = Profile{Name: "itunes-backup", Match: matchITunesBackup, Walk: walkITunesBackup}
+	// Cellebrite is a Cellebrite UFED file system extraction, with the file
+	// system of the device in filesystem1 (and filesystem2 and so on for
+	// further partitions).
+	Cellebrite = Profile{Name: "cellebrite", Match: matchCellebrite, Walk: walkCellebrite}
+	// GrayKey is a GrayKey full file system extraction, with the file system
+	// of the device in a directory whose name ends in "_files_full".
+	GrayKey = Profile{Name: "graykey", Match: matchGrayKey, Walk: walkGrayKey}
+	// FileSystem is a copy of the file system of a device, or of any part of
+	// it, with Biome data at any depth.
+	FileSystem = Profile{Name: "filesystem", Match: func(string) bool { return true }, Walk: walkFileSystem}
+)
+
+// Profiles lists the known profiles in the order DetectProfile tries them.
+// FileSystem comes last, it matches any directory.
+var Profiles = []Profile{ITunesBackup, Cellebrite, GrayKey, FileSystem}
+
+// DetectProfile returns the first of Profiles that matches root.
+func DetectProfile(root string) Profile {
+	for _, profile := range Profiles {
+		if profile.Match(root) {
+			return profile
+		}
+	}
+	return FileSystem
+}
+
+// ProfileByName returns the profile of Profiles with the given name.
+func ProfileByName(name string) (Profile, error) {
+	var names []string
+	for _, profile := range Profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+		names = append(names, profile.Name)
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q (want %s)", name, strings.Join(names, ", "))
+}
+
+// walkTree walks the directory tree at root, which holds the root of the
+// file system of a device. Hidden files are skipped.
+func walkTree(root string, fn func(path, devicePath string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, "/"+filepath.ToSlash(rel))
+	})
+}
+
+// walkFileSystem walks the directory tree at root, which may be any part of
+// the file system of a device: a Biome directory or even a stream. Device
+// paths are the paths on disk, in which ParsePath finds the streams
+// directory.
+func walkFileSystem(root string, fn func(path, devicePath string) error) error {
+	return walkTree(root, func(path, _ string) error {
+		return fn(path, path)
+	})
+}
+
+func matchITunesBackup(root string) bool {
+	info, err := os.Stat(filepath.Join(root, backup.ManifestName))
+	return err == nil && info.Mode().IsRegular()
+}
+
+func walkITunesBackup(root string, fn func(path, devicePath string) error) error {
+	b, err := backup.Open(root)
+	if err != nil {
+		return err
+	}
+	for _, file := range b.BiomeFiles() {
+		if err := fn(b.Path(file), file.RelativePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cellebriteFileSystem matches the directories UFED extracts partitions to.
+var cellebriteFileSystem = regexp.MustCompile(`^filesystem[0-9]+$`)
+
+// children returns the directories in root whose names match.
+func children(root string, match func(name string) bool) []string {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && match(entry.Name()) {
+			dirs = append(dirs, filepath.Join(root, entry.Name()))
+		}
+	}
+	return dirs
+}
+
+func matchCellebrite(root string) bool {
+	return len(children(root, cellebriteFileSystem.MatchString)) > 0
+}
+
+func walkCellebrite(root string, fn func(path, devicePath string) error) error {
+	for _, dir := range children(root, cellebriteFileSystem.MatchString) {
+		if err := walkTree(dir, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grayKeyRoots returns the file system directories of a GrayKey
+// extraction: root itself, or its children, named "..._files_full".
+func grayKeyRoots(root string) []string {
+	isFull := func(name string) bool { return strings.HasSuffix(name, "_files_full") }
+	if abs, err := filepath.Abs(root); err == nil && isFull(filepath.Base(abs)) {
+		return []string{root}
+	}
+	return children(root, isFull)
+}
+
+func matchGrayKey(root string) bool {
+	return len(grayKeyRoots(root)) > 0
+}
+
+func walkGrayKey(root string, fn func(path, devicePath string) error) error {
+	for _, dir := range grayKeyRoots(root) {
+		if err := walkTree(dir, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
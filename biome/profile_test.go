@@ -0,0 +1,93 @@
+package biome
+
+import (
+	"github.com/bluefalconhd/segb/segbtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupExtraction moves the Biome root of setupBiome to where a device
+// keeps it, below dir in a new extraction directory.
+func setupExtraction(t *testing.T, dir string) string {
+	root := setupBiome(t)
+	library := filepath.Join(root, filepath.FromSlash(dir), "private", "var", "mobile", "Library")
+	if err := os.MkdirAll(library, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(root, "Biome"), filepath.Join(library, "Biome")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// setupITunesBackup moves the segments of setupBiome to where an iTunes
+// backup keeps them, under the hashes named in its Manifest.db.
+func setupITunesBackup(t *testing.T) string {
+	root := setupBiome(t)
+	files := []segbtest.ManifestFile{
+		{FileID: "aa11", Domain: "HomeDomain", RelativePath: "Library/Biome/streams/restricted/App.InFocus/local/764213212345678", Flags: 1},
+		{FileID: "bb22", Domain: "HomeDomain", RelativePath: "Library/Biome/streams/restricted/App.InFocus/remote/DEVICE-1/764213298765432", Flags: 1},
+	}
+	if err := segbtest.WriteManifest(filepath.Join(root, "Manifest.db"), files); err != nil {
+		t.Fatal(err)
+	}
+	stream := filepath.Join(root, "Biome", "streams", "restricted", "App.InFocus")
+	renames := map[string]string{
+		filepath.Join(stream, "local", "764213212345678"):              filepath.Join(root, "aa", "aa11"),
+		filepath.Join(stream, "remote", "DEVICE-1", "764213298765432"): filepath.Join(root, "bb", "bb22"),
+	}
+	for src, dst := range renames {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestProfiles(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		root  string
+		want  Profile
+		local string // Path of the local segment, relative to root
+	}{
+		{"filesystem", setupBiome(t), FileSystem, "Biome/streams/restricted/App.InFocus/local/764213212345678"},
+		{"cellebrite", setupExtraction(t, "filesystem1"), Cellebrite, "filesystem1/private/var/mobile/Library/Biome/streams/restricted/App.InFocus/local/764213212345678"},
+		{"graykey", setupExtraction(t, "00008030-001A_files_full"), GrayKey, "00008030-001A_files_full/private/var/mobile/Library/Biome/streams/restricted/App.InFocus/local/764213212345678"},
+		{"itunes-backup", setupITunesBackup(t), ITunesBackup, "aa/aa11"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := DetectProfile(tt.root)
+			if profile.Name != tt.want.Name {
+				t.Fatalf("DetectProfile() = %s; want %s", profile.Name, tt.want.Name)
+			}
+			streams, err := Enumerate(tt.root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(streams) != 1 || streams[0].Name != "App.InFocus" || len(streams[0].Segments) != 2 {
+				t.Fatalf("Enumerate() = %+v; want App.InFocus with 2 segments", streams)
+			}
+			segment := streams[0].Segments[0]
+			if want := filepath.Join(tt.root, filepath.FromSlash(tt.local)); segment.Path != want || segment.Location != LocationLocal {
+				t.Errorf("first segment = %s (%v); want %s (local)", segment.Path, segment.Location, want)
+			}
+			if _, err := DecodeSegment(segment); err != nil {
+				t.Errorf("DecodeSegment() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestProfileByName(t *testing.T) {
+	if profile, err := ProfileByName("graykey"); err != nil || profile.Name != "graykey" {
+		t.Errorf("ProfileByName(graykey) = %s, %v", profile.Name, err)
+	}
+	if _, err := ProfileByName("xry"); err == nil {
+		t.Error("ProfileByName(xry) error = nil")
+	}
+}
@@ -0,0 +1,134 @@
+package biome
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// StreamReader iterates over the entries of every segment of a stream as a
+// single chronologically ordered sequence.
+//
+//	r, err := biome.OpenStream(dir)
+//	...
+//	for r.Next() {
+//		entry := r.Entry()
+//		...
+//	}
+type StreamReader struct {
+	stream  *Stream
+	cursors cursorHeap
+	current Entry
+}
+
+// OpenStream opens the stream stored in dir (e.g.
+// ".../streams/restricted/App.InFocus"). All local and remote segments are
+// decoded, tombstoned entries are flagged and the entries are merged by
+// creation time. Entries with equal creation times keep segment order.
+func OpenStream(dir string) (*StreamReader, error) {
+	streams, err := Enumerate(dir)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(streams) == 0:
+		return nil, fmt.Errorf("%s: no stream segments found", dir)
+	case len(streams) > 1:
+		return nil, fmt.Errorf("%s: contains %d streams, expected one", dir, len(streams))
+	}
+	stream := streams[0]
+
+	tombstones, err := stream.ReadTombstones()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &StreamReader{stream: stream}
+	for i, segment := range stream.Segments {
+		entries, err := DecodeSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", segment.Path, err)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		Correlate(entries, tombstones)
+
+		// Segments are append-only so entries are normally already in
+		// order, but nothing guarantees it
+		sort.SliceStable(entries, func(a, b int) bool {
+			return entries[a].Created.Before(entries[b].Created)
+		})
+		r.cursors = append(r.cursors, &cursor{segment: i, entries: entries})
+	}
+	heap.Init(&r.cursors)
+
+	return r, nil
+}
+
+// Stream returns the stream being read.
+func (r *StreamReader) Stream() *Stream {
+	return r.stream
+}
+
+// Next advances to the next entry. It returns false once every segment has
+// been exhausted.
+func (r *StreamReader) Next() bool {
+	if len(r.cursors) == 0 {
+		return false
+	}
+
+	c := r.cursors[0]
+	r.current = c.entries[c.pos]
+	c.pos++
+	if c.pos == len(c.entries) {
+		heap.Pop(&r.cursors)
+	} else {
+		heap.Fix(&r.cursors, 0)
+	}
+	return true
+}
+
+// Entry returns the entry the reader is positioned on.
+func (r *StreamReader) Entry() Entry {
+	return r.current
+}
+
+// All drains the reader and returns the remaining entries.
+func (r *StreamReader) All() []Entry {
+	var entries []Entry
+	for r.Next() {
+		entries = append(entries, r.Entry())
+	}
+	return entries
+}
+
+// cursor tracks the read position within one segment's entries.
+type cursor struct {
+	segment int // Index of the segment in Stream.Segments, used as a tie breaker
+	entries []Entry
+	pos     int
+}
+
+type cursorHeap []*cursor
+
+func (h cursorHeap) Len() int { return len(h) }
+
+func (h cursorHeap) Less(i, j int) bool {
+	a, b := h[i].entries[h[i].pos], h[j].entries[h[j].pos]
+	if !a.Created.Equal(b.Created) {
+		return a.Created.Before(b.Created)
+	}
+	return h[i].segment < h[j].segment
+}
+
+func (h cursorHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *cursorHeap) Push(x any) { *h = append(*h, x.(*cursor)) }
+
+func (h *cursorHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
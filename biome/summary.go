@@ -0,0 +1,77 @@
+package biome
+
+import (
+	"github.com/bluefalconhd/segb"
+	"os"
+	"time"
+)
+
+// Summary aggregates counters over the segments of a stream, as a first
+// overview of an extraction.
+type Summary struct {
+	Stream         string
+	Access         string
+	LocalSegments  int
+	RemoteSegments int
+	Tombstones     int
+	Failed         int                     // Segments that could not be decoded
+	Entries        int                     // Entries across decoded segments
+	EntriesByState map[segb.EntryState]int // Entry counts keyed by state
+	First, Last    time.Time               // Earliest and latest entry creation times, zero without entries
+	Bytes          int64                   // Total on-disk size of the segments and tombstones
+}
+
+// Summarize decodes every segment of the stream and counts its entries.
+// Segments that fail to decode are counted in Failed rather than failing the
+// summary.
+func (s *Stream) Summarize() (Summary, error) {
+	summary := Summary{
+		Stream:         s.Name,
+		Access:         s.Access,
+		LocalSegments:  len(s.Local()),
+		RemoteSegments: len(s.Remote()),
+		Tombstones:     len(s.Tombstones),
+		EntriesByState: make(map[segb.EntryState]int),
+	}
+
+	for _, segment := range s.Tombstones {
+		info, err := os.Stat(segment.Path)
+		if err != nil {
+			return Summary{}, err
+		}
+		summary.Bytes += info.Size()
+	}
+	for _, segment := range s.Segments {
+		info, err := os.Stat(segment.Path)
+		if err != nil {
+			return Summary{}, err
+		}
+		summary.Bytes += info.Size()
+
+		entries, err := DecodeSegment(segment)
+		if err != nil {
+			summary.Failed++
+			continue
+		}
+		summary.Entries += len(entries)
+		for _, entry := range entries {
+			summary.EntriesByState[entry.State]++
+			if summary.First.IsZero() || entry.Created.Before(summary.First) {
+				summary.First = entry.Created
+			}
+			if entry.Created.After(summary.Last) {
+				summary.Last = entry.Created
+			}
+		}
+	}
+	return summary, nil
+}
+
+// DeletedRatio returns the fraction of entries in the deleted state, 0
+// without entries.
+func (s Summary) DeletedRatio() float64 {
+	if s.Entries == 0 {
+		return 0
+	}
+	return float64(s.EntriesByState[segb.EntryStateDeleted]) / float64(s.Entries)
+}
@@ -0,0 +1,164 @@
+package biome
+
+import (
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/timeconv"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"time"
+	"unicode/utf8"
+)
+
+// Tombstone segments are SEGB files whose entries are protobuf messages
+// describing deletions from the stream. The schema is not documented, so
+// rather than relying on fixed field numbers the parser collects the values
+// that can identify a deleted entry: Cocoa timestamps (doubles within a
+// plausible range) and segment file names (numeric strings).
+
+// maxTombstoneDepth bounds how far nested messages are searched.
+const maxTombstoneDepth = 4
+
+// Tombstone is a single record read from a tombstone segment.
+type Tombstone struct {
+	Segment    Segment     // Tombstone segment the record was read from
+	Index      int         // Index of the record within the segment
+	Created    time.Time   // When the tombstone record was written
+	Timestamps []time.Time // Cocoa timestamps referenced by the record
+	Segments   []string    // Segment file names referenced by the record
+	Data       []byte      // Raw protobuf payload
+}
+
+// ParseTombstone extracts the references contained in a tombstone entry.
+func ParseTombstone(entry segb.Entry) Tombstone {
+	tombstone := Tombstone{
+		Index:   entry.ID,
+		Created: entry.Created,
+		Data:    entry.Data,
+	}
+	tombstone.collect(entry.Data, 0)
+	return tombstone
+}
+
+// collect walks a protobuf message and records candidate references. It
+// stops silently at the first malformed field.
+func (t *Tombstone) collect(data []byte, depth int) {
+	for len(data) > 0 {
+		_, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return
+		}
+		data = data[n:]
+
+		switch typ {
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return
+			}
+			data = data[n:]
+			if f := math.Float64frombits(v); f != 0 && timeconv.PlausibleCocoa(f) {
+				t.Timestamps = append(t.Timestamps, segb.CocoaTimestampToTime(f))
+			}
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return
+			}
+			data = data[n:]
+			if isSegmentName(v) {
+				t.Segments = append(t.Segments, string(v))
+			} else if depth < maxTombstoneDepth && !utf8.Valid(v) {
+				t.collect(v, depth+1)
+			}
+		default:
+			n := protowire.ConsumeFieldValue(0, typ, data)
+			if n < 0 {
+				return
+			}
+			data = data[n:]
+		}
+	}
+}
+
+// isSegmentName reports whether b looks like a segment file name.
+func isSegmentName(b []byte) bool {
+	if len(b) < 10 || len(b) > 20 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// References reports whether the tombstone refers to the given entry. An
+// entry matches when its creation time is one of the tombstone's timestamps
+// and, if the tombstone names any segments, the entry's segment is among
+// them.
+func (t *Tombstone) References(entry Entry) bool {
+	if len(t.Segments) > 0 {
+		found := false
+		for _, name := range t.Segments {
+			if name == entry.Segment.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, timestamp := range t.Timestamps {
+		if timestamp.Equal(entry.Created) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadTombstones decodes a tombstone segment.
+func ReadTombstones(segment Segment) ([]Tombstone, error) {
+	entries, err := DecodeSegment(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	tombstones := make([]Tombstone, len(entries))
+	for i, entry := range entries {
+		tombstones[i] = ParseTombstone(entry.Entry)
+		tombstones[i].Segment = segment
+	}
+	return tombstones, nil
+}
+
+// ReadTombstones decodes every tombstone segment of the stream.
+func (s *Stream) ReadTombstones() ([]Tombstone, error) {
+	var tombstones []Tombstone
+	for _, segment := range s.Tombstones {
+		records, err := ReadTombstones(segment)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", segment.Path, err)
+		}
+		tombstones = append(tombstones, records...)
+	}
+	return tombstones, nil
+}
+
+// Correlate flags every entry referenced by one of the tombstones by
+// setting its Tombstone field. It returns the number of flagged entries.
+func Correlate(entries []Entry, tombstones []Tombstone) int {
+	flagged := 0
+	for i := range entries {
+		for j := range tombstones {
+			if tombstones[j].References(entries[i]) {
+				entries[i].Tombstone = &tombstones[j]
+				flagged++
+				break
+			}
+		}
+	}
+	return flagged
+}
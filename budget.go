@@ -0,0 +1,91 @@
+package segb
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBudgetExceeded is returned by Decode when the entry payloads exceed
+	// the budget set with WithMaxTotalBytes.
+	ErrBudgetExceeded = errors.New("entry payloads exceed the memory budget")
+	// ErrEntryTooLarge is wrapped by the error for an entry larger than the
+	// maximum set with WithMaxEntrySize.
+	ErrEntryTooLarge = errors.New("entry too large")
+)
+
+// WithMaxTotalBytes bounds the payload bytes Decode holds in memory, stored
+// and decompressed payloads combined, for services that parse untrusted
+// files. Decoding fails with ErrBudgetExceeded once the budget would be
+// exceeded; payloads are not allocated past that point.
+func WithMaxTotalBytes(n int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxTotalBytes = n
+	}
+}
+
+// WithMaxEntrySize rejects entries whose stored payload is larger than n
+// bytes before allocating for them, instead of trusting whatever length a
+// corrupt header claims. Decoding fails with an error wrapping
+// ErrEntryTooLarge, or skips the entry into Segb.Errors with
+// WithLenientParsing.
+func WithMaxEntrySize(n int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxEntrySize = n
+	}
+}
+
+// entryGate decides which entries the v1 and v2 readers read, applying the
+// WithEntryFilter predicate and the size limits.
+type entryGate struct {
+	cfg     decodeConfig
+	used    int64        // Stored payload bytes admitted so far
+	err     error        // Set once decoding must fail
+	skipped []EntryError // Entries too large, in lenient mode
+}
+
+// newEntryGate returns a gate for one read, or nil if there is nothing to
+// check.
+func (c decodeConfig) newEntryGate() *entryGate {
+	if c.filter == nil && c.maxTotalBytes <= 0 && c.maxEntrySize <= 0 {
+		return nil
+	}
+	return &entryGate{cfg: c}
+}
+
+func (g *entryGate) admit(meta EntryMeta) bool {
+	if g.err != nil || (g.cfg.filter != nil && !g.cfg.filter(meta)) {
+		return false
+	}
+	if g.cfg.maxEntrySize > 0 && meta.Size > g.cfg.maxEntrySize {
+		err := fmt.Errorf("entry %d at offset 0x%x stores %d bytes, more than the maximum of %d: %w",
+			meta.ID, meta.Offset, meta.Size, g.cfg.maxEntrySize, ErrEntryTooLarge)
+		if !g.cfg.lenient {
+			g.err = err
+		} else {
+			g.skipped = append(g.skipped, EntryError{Index: meta.ID, Offset: meta.Offset, Err: err})
+		}
+		return false
+	}
+	if g.cfg.maxTotalBytes > 0 {
+		g.used += meta.Size
+		if g.used > g.cfg.maxTotalBytes {
+			g.err = ErrBudgetExceeded
+			return false
+		}
+	}
+	return true
+}
+
+// remainingBudget returns how much of the budget is left once the stored
+// payloads of s are held, or -1 without a budget.
+func (c decodeConfig) remainingBudget(s *Segb) int64 {
+	if c.maxTotalBytes <= 0 {
+		return -1
+	}
+	remaining := c.maxTotalBytes
+	for i := range s.Entries {
+		remaining -= int64(len(s.Entries[i].Stored()))
+	}
+	return max(remaining, 0)
+}
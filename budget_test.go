@@ -0,0 +1,68 @@
+package segb
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithMaxTotalBytes(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels.")}
+
+	for name, file := range map[string][]byte{
+		"v1": v1File(8, payloads...),
+		"v2": v2File(payloads...),
+	} {
+		if _, err := DecodeBytes(file, WithMaxTotalBytes(64)); err != nil {
+			t.Errorf("%s: within budget: %v", name, err)
+		}
+		if _, err := DecodeBytes(file, WithMaxTotalBytes(30)); err != ErrBudgetExceeded {
+			t.Errorf("%s: err = %v; want ErrBudgetExceeded", name, err)
+		}
+
+		// Only entries kept by a filter count
+		decoded, err := DecodeBytes(file, WithMaxTotalBytes(25), WithEntryFilter(func(m EntryMeta) bool {
+			return m.ID > 0
+		}))
+		if err != nil || len(decoded.Entries) != 2 {
+			t.Errorf("%s: filtered: %d entries, %v", name, len(decoded.Entries), err)
+		}
+	}
+
+	// Decompressed payloads count too
+	big := bytes.Repeat([]byte("crazy "), 1000)
+	file := v2File(zlibPayload(string(big)))
+	if _, err := DecodeBytes(file, WithMaxTotalBytes(4096)); err != ErrBudgetExceeded {
+		t.Errorf("compressed: err = %v; want ErrBudgetExceeded", err)
+	}
+	if decoded, err := DecodeBytes(file, WithMaxTotalBytes(8192)); err != nil || !bytes.Equal(decoded.Entries[0].Data, big) {
+		t.Errorf("compressed within budget: %v", err)
+	}
+}
+
+func TestWithMaxEntrySize(t *testing.T) {
+	payloads := [][]byte{[]byte("The misfits."), []byte("Here's to the crazy ones."), []byte("The rebels.")}
+
+	for name, file := range map[string][]byte{
+		"v1": v1File(8, payloads...),
+		"v2": v2File(payloads...),
+	} {
+		if _, err := DecodeBytes(file, WithMaxEntrySize(32)); err != nil {
+			t.Errorf("%s: within limit: %v", name, err)
+		}
+		_, err := DecodeBytes(file, WithMaxEntrySize(16))
+		if !errors.Is(err, ErrEntryTooLarge) || !strings.Contains(err.Error(), "entry 1 ") {
+			t.Errorf("%s: err = %v; want ErrEntryTooLarge for entry 1", name, err)
+		}
+
+		// Lenient decoding skips the entry
+		decoded, err := DecodeBytes(file, WithMaxEntrySize(16), WithLenientParsing())
+		if err != nil {
+			t.Fatalf("%s: lenient: %v", name, err)
+		}
+		if len(decoded.Entries) != 2 || len(decoded.Errors) != 1 || decoded.Errors[0].Index != 1 {
+			t.Errorf("%s: lenient: %d entries, errors %v", name, len(decoded.Entries), decoded.Errors)
+		}
+	}
+}
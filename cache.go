@@ -0,0 +1,156 @@
+package segb
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache stores decoded files across LoadDir runs, so unchanged files are not
+// parsed again. See WithCache.
+type Cache interface {
+	Get(key string) (Segb, bool)
+	Put(key string, s Segb) error
+}
+
+// CacheKey selects how files are identified in a Cache.
+type CacheKey int
+
+const (
+	// CacheKeyStat identifies a file by path, size and modification time.
+	// It costs a stat, but misses files touched without being changed.
+	CacheKeyStat CacheKey = iota
+	// CacheKeyContent identifies a file by the SHA-256 of its contents. It
+	// reads every file, but survives renames and touches.
+	CacheKeyContent
+)
+
+// WithCache makes LoadDir look decoded files up in cache before parsing
+// them, and store them after. Hits and misses are counted in CorpusStats.
+// Only successfully decoded files are cached, and failures to store are
+// ignored.
+func WithCache(cache Cache, key CacheKey) LoadOption {
+	return func(c *loadConfig) {
+		c.cache = cache
+		c.cacheKey = key
+	}
+}
+
+// cacheKey returns the key of an open file, named by name in stat keys.
+func cacheKey(file seekFile, name string, mode CacheKey) (string, error) {
+	if mode == CacheKeyContent {
+		h := sha256.New()
+		if _, err := io.Copy(h, file); err != nil {
+			return "", err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("stat:%s:%d:%d", name, info.Size(), info.ModTime().UnixNano()), nil
+}
+
+// MemoryCache is a Cache held in memory, for repeated loads within one
+// process.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]Segb
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]Segb)}
+}
+
+func (c *MemoryCache) Get(key string) (Segb, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.entries[key]
+	return s, ok
+}
+
+func (c *MemoryCache) Put(key string, s Segb) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = s
+	return nil
+}
+
+// DirCache is a Cache stored as one gob file per key in a directory, for
+// reuse across runs.
+type DirCache struct {
+	Dir string
+}
+
+// NewDirCache returns a DirCache in dir, creating it if needed.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirCache{Dir: dir}, nil
+}
+
+func (c *DirCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".gob")
+}
+
+func (c *DirCache) Get(key string) (Segb, bool) {
+	file, err := os.Open(c.path(key))
+	if err != nil {
+		return Segb{}, false
+	}
+	defer file.Close()
+
+	var s Segb
+	if err := gob.NewDecoder(file).Decode(&s); err != nil {
+		return Segb{}, false
+	}
+	s.restoreHeaderOffsets()
+	return s, true
+}
+
+// Put writes the entry to a temporary file first, so concurrent readers never
+// see a partial one.
+func (c *DirCache) Put(key string, s Segb) error {
+	file, err := os.CreateTemp(c.Dir, "put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := gob.NewEncoder(file).Encode(s); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), c.path(key))
+}
+
+// restoreHeaderOffsets recomputes the unexported entry header offsets, which
+// do not survive encoding, from the payload offsets.
+func (s *Segb) restoreHeaderOffsets() {
+	headerSize := int64(v2EntryHeaderSize)
+	if s.Version == SEGB_VERSION_1 {
+		headerSize = v1EntryHeaderSize
+	}
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		if entry.Offset != 0 && entry.headerOffset == 0 {
+			entry.headerOffset = entry.Offset - headerSize
+		}
+	}
+}
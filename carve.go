@@ -0,0 +1,355 @@
+package segb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/bluefalconhd/segb/timeconv"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// MaxCarveSpan bounds how many bytes past a header Carve examines when
+// looking for the end of a structure.
+const MaxCarveSpan = 64 << 20
+
+// carveChunkSize is how much of the input is scanned for magic at a time.
+const carveChunkSize = 1 << 20
+
+// carveV2Window is how much past a v2 header is first searched for a
+// trailer. The window doubles, up to MaxCarveSpan, while none is found.
+const carveV2Window = 64 << 10
+
+// orphanStep is the boundary orphaned v1 entry headers are looked for on.
+// Entries are 8-byte aligned in most files, and files start on sector
+// boundaries, but some samples use 4.
+const orphanStep = 4
+
+// Carved is a SEGB structure found inside a larger blob (e.g. a disk image).
+type Carved struct {
+	Offset  int64 // Absolute offset of the start of the structure
+	Length  int64 // Number of bytes the structure spans
+	Version SegbVersion
+	Segb    Segb
+
+	// Orphan is set for a lone v1 entry found outside any structure, see
+	// WithOrphanEntries. Segb holds just that entry.
+	Orphan bool
+}
+
+// CarveOption configures Carve and CarveFunc.
+type CarveOption func(*carveConfig)
+
+type carveConfig struct {
+	start    int64
+	progress func(int64)
+	orphans  bool
+}
+
+// CarveFrom starts scanning at offset instead of the start of the input,
+// e.g. to resume an interrupted scan where WithCarveProgress last left it.
+func CarveFrom(offset int64) CarveOption {
+	return func(c *carveConfig) {
+		c.start = offset
+	}
+}
+
+// WithCarveProgress calls fn as scanning proceeds with the offset up to
+// which the input has been scanned and every result passed on; passing it to
+// CarveFrom resumes the scan without repeating or missing any.
+func WithCarveProgress(fn func(offset int64)) CarveOption {
+	return func(c *carveConfig) {
+		c.progress = fn
+	}
+}
+
+// WithOrphanEntries makes the scan also report v1 entries outside any
+// structure, e.g. left behind by a file whose header was overwritten. They
+// are recognized by a known state, plausible timestamps and a payload that
+// matches the CRC, at every 4 byte boundary, which slows scanning down.
+func WithOrphanEntries() CarveOption {
+	return func(c *carveConfig) {
+		c.orphans = true
+	}
+}
+
+// Carve scans r for SEGB structures and returns every one that decodes.
+func Carve(r io.ReaderAt, size int64, opts ...CarveOption) ([]Carved, error) {
+	var found []Carved
+	err := CarveFunc(r, size, func(c Carved) error {
+		found = append(found, c)
+		return nil
+	}, opts...)
+	return found, err
+}
+
+// CarveFunc scans r for SEGB structures and calls fn for each one that
+// decodes, in order of offset. Scanning stops at the first error returned by
+// fn.
+//
+// Every occurrence of the "SEGB" magic is a candidate: as the start of a v2
+// header, or 0x34 bytes into a v1 header. A v1 structure ends at its
+// EndOfDataOffset. A v2 structure ends with its trailer, which is located by
+// searching for EntryCount consecutive plausible records.
+func CarveFunc(r io.ReaderAt, size int64, fn func(Carved) error, opts ...CarveOption) error {
+	var cfg carveConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	magic := []byte(v2.FileMagic)
+	// Candidates starting in a chunk may extend past it by an entry header
+	chunk := make([]byte, carveChunkSize+v1EntryHeaderSize-1)
+
+	// Hits inside an already carved structure are skipped
+	carvedUntil := cfg.start
+
+	// The window v2 trailers are searched in, reused from hit to hit
+	var window []byte
+
+	for base := cfg.start; base < size; base += carveChunkSize {
+		n, err := r.ReadAt(chunk[:min(int64(len(chunk)), size-base)], base)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		end := min(n, carveChunkSize)
+
+		nextMagic := func(from int) int {
+			if idx := bytes.Index(chunk[from:n], magic); idx >= 0 && from+idx < end {
+				return from + idx
+			}
+			return end
+		}
+		m := nextMagic(0)
+		o := end
+		if cfg.orphans {
+			o = int((orphanStep - base%orphanStep) % orphanStep)
+		}
+
+		for m < end || o < end {
+			var c Carved
+			var ok bool
+			if m <= o {
+				position := base + int64(m)
+				m = nextMagic(m + 1)
+				if position < carvedUntil {
+					continue
+				}
+				c, ok = carveV2(r, size, position, &window)
+				if !ok && position >= 0x34 {
+					c, ok = carveV1(r, size, position-0x34)
+				}
+			} else {
+				i := o
+				o += orphanStep
+				position := base + int64(i)
+				if position < carvedUntil || i+v1EntryHeaderSize > n {
+					continue
+				}
+				c, ok = carveOrphan(r, size, position, chunk[i:n])
+			}
+			if !ok {
+				continue
+			}
+
+			carvedUntil = c.Offset + c.Length
+			if err := fn(c); err != nil {
+				return err
+			}
+		}
+
+		if cfg.progress != nil {
+			cfg.progress(min(max(base+carveChunkSize, carvedUntil), size))
+		}
+	}
+	return nil
+}
+
+// readWindow reads up to MaxCarveSpan bytes starting at offset.
+func readWindow(r io.ReaderAt, size, offset, length int64) ([]byte, bool) {
+	length = min(length, size-offset, MaxCarveSpan)
+	if length <= 0 {
+		return nil, false
+	}
+	window := make([]byte, length)
+	n, err := r.ReadAt(window, offset)
+	if err != nil && err != io.EOF {
+		return nil, false
+	}
+	return window[:n], true
+}
+
+func carveV1(r io.ReaderAt, size, offset int64) (Carved, bool) {
+	headerSize := int64(binary.Size(v1.Header{}))
+	header, ok := readWindow(r, size, offset, headerSize)
+	if !ok || int64(len(header)) < headerSize {
+		return Carved{}, false
+	}
+
+	end := int64(int32(binary.LittleEndian.Uint32(header)))
+	if end < headerSize || end > MaxCarveSpan || offset+end > size {
+		return Carved{}, false
+	}
+
+	window, ok := readWindow(r, size, offset, end)
+	if !ok || int64(len(window)) < end {
+		return Carved{}, false
+	}
+	h, entries, err := v1.ReadSegb(bytes.NewReader(window))
+	if err != nil {
+		return Carved{}, false
+	}
+
+	s := V1ToStandardSegb(h, entries)
+	s.shiftOffsets(offset)
+	s.applyCRCPolicy(CRCMark, false)
+	s.DecompressPayloads()
+	s.classifyPayloads()
+	return Carved{Offset: offset, Length: end, Version: SEGB_VERSION_1, Segb: s}, true
+}
+
+// carveV2 decodes the v2 structure whose header is at offset, searching for
+// its trailer in a window that grows only while no trailer fits in it. buf
+// holds the window and is reused across calls.
+func carveV2(r io.ReaderAt, size, offset int64, buf *[]byte) (Carved, bool) {
+	headerSize := int64(binary.Size(v2.Header{}))
+	header, ok := readWindow(r, size, offset, headerSize)
+	if !ok || int64(len(header)) < headerSize {
+		return Carved{}, false
+	}
+
+	count := int64(int32(binary.LittleEndian.Uint32(header[4:])))
+	created := math.Float64frombits(binary.LittleEndian.Uint64(header[8:]))
+	if count <= 0 || count*v2.TrailerRecordSize > MaxCarveSpan || !timeconv.PlausibleCocoa(created) {
+		return Carved{}, false
+	}
+
+	trailerSize := count * v2.TrailerRecordSize
+	limit := min(size-offset, MaxCarveSpan)
+	trailer := headerSize
+	window := (*buf)[:0]
+	for length := min(max(carveV2Window, headerSize+trailerSize), limit); ; length = min(2*length, limit) {
+		// Read only the part the previous window did not cover
+		if int64(cap(*buf)) < length {
+			grown := make([]byte, length)
+			copy(grown, window)
+			*buf = grown
+		}
+		have := len(window)
+		window = (*buf)[:length]
+		n, err := r.ReadAt(window[have:], offset+int64(have))
+		if err != nil && err != io.EOF {
+			return Carved{}, false
+		}
+		window = window[:have+n]
+
+		for ; trailer+trailerSize <= int64(len(window)); trailer += 4 {
+			if !plausibleTrailer(window[trailer:trailer+trailerSize], trailer-headerSize) {
+				continue
+			}
+
+			structure := window[:trailer+trailerSize]
+			h, _, entries, err := v2.ReadSegb(bytes.NewReader(structure))
+			if err != nil {
+				continue
+			}
+			s := V2ToStandardSegb(h, entries)
+			if s.Records, err = trailerRecords(bytes.NewReader(structure), h); err != nil {
+				continue
+			}
+			s.shiftOffsets(offset)
+			s.applyCRCPolicy(CRCMark, false)
+			s.DecompressPayloads()
+			s.classifyPayloads()
+			return Carved{Offset: offset, Length: int64(len(structure)), Version: SEGB_VERSION_2, Segb: s}, true
+		}
+		if length == limit || int64(len(window)) < length {
+			return Carved{}, false
+		}
+	}
+}
+
+// carveOrphan checks for a lone v1 entry whose header, at offset, starts
+// header.
+func carveOrphan(r io.ReaderAt, size, offset int64, header []byte) (Carved, bool) {
+	length, ok := plausibleV1Entry(header, size-offset-v1EntryHeaderSize)
+	if !ok {
+		return Carved{}, false
+	}
+	data, ok := readWindow(r, size, offset+v1EntryHeaderSize, length)
+	if !ok || int64(len(data)) < length {
+		return Carved{}, false
+	}
+	entry, ok := v1EntryAt(header, data, offset)
+	if !ok {
+		return Carved{}, false
+	}
+
+	s := Segb{Version: SEGB_VERSION_1, Created: entry.Created, Entries: []Entry{entry}, Alignment: v1.DefaultAlignment}
+	s.DecompressPayloads()
+	s.classifyPayloads()
+	return Carved{Offset: offset, Length: v1EntryHeaderSize + length, Version: SEGB_VERSION_1, Segb: s, Orphan: true}, true
+}
+
+// plausibleV1Entry reports whether header, followed by room bytes, looks
+// like the start of a v1 entry found without the file around it: it has a
+// known state, a creation time and plausible timestamps, and a length that
+// fits. It returns the length.
+func plausibleV1Entry(header []byte, room int64) (int64, bool) {
+	if len(header) < v1EntryHeaderSize {
+		return 0, false
+	}
+	state := v1.EntryState(binary.LittleEndian.Uint32(header[4:]))
+	if state != v1.EntryStateWritten && state != v1.EntryStateDeleted && state != v1.EntryStateUnknown {
+		return 0, false
+	}
+	length := int64(int32(binary.LittleEndian.Uint32(header)))
+	created := math.Float64frombits(binary.LittleEndian.Uint64(header[8:]))
+	other := math.Float64frombits(binary.LittleEndian.Uint64(header[16:]))
+	if length <= 0 || length > room || created == 0 || !timeconv.PlausibleCocoa(created) || !timeconv.PlausibleCocoa(other) {
+		return 0, false
+	}
+	return length, true
+}
+
+// v1EntryAt returns the entry with the given header, at offset, and payload
+// if the payload matches the CRC in the header.
+func v1EntryAt(header, data []byte, offset int64) (Entry, bool) {
+	checksum := binary.LittleEndian.Uint32(header[24:])
+	if crc32.ChecksumIEEE(data) != checksum {
+		return Entry{}, false
+	}
+	return Entry{
+		State:        V1EntryStateToStandardState(v1.EntryState(binary.LittleEndian.Uint32(header[4:]))),
+		Created:      CocoaTimestampToTime(math.Float64frombits(binary.LittleEndian.Uint64(header[8:]))),
+		Data:         data,
+		Checksum:     checksum,
+		Offset:       offset + v1EntryHeaderSize,
+		CRCValid:     true,
+		Size:         int64(len(data)),
+		StoredSize:   int64(len(data)),
+		headerOffset: offset,
+	}, true
+}
+
+// plausibleTrailer reports whether data looks like a v2 trailer for an entry
+// region of the given length.
+func plausibleTrailer(data []byte, entriesLength int64) bool {
+	for i := 0; i < len(data); i += v2.TrailerRecordSize {
+		offset := int64(int32(binary.LittleEndian.Uint32(data[i:])))
+		state := v2.EntryState(binary.LittleEndian.Uint32(data[i+4:]))
+		created := math.Float64frombits(binary.LittleEndian.Uint64(data[i+8:]))
+		if offset < 0 || offset >= entriesLength || offset%v2.DefaultAlignment != 0 {
+			return false
+		}
+		if state != v2.EntryStateWritten && state != v2.EntryStateDeleted && state != v2.EntryStateUnknown {
+			return false
+		}
+		if !timeconv.PlausibleCocoa(created) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package segb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestCarve(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	fileV1, err := os.ReadFile("segb_version1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileV2, err := os.ReadFile("segb_version2.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Embed both files in junk, including a stray magic that decodes to nothing
+	junk := bytes.Repeat([]byte{0xAA}, 1000)
+	var image []byte
+	image = append(image, junk...)
+	image = append(image, fileV2...)
+	image = append(image, "SEGB"...)
+	image = append(image, junk...)
+	v1Offset := len(image)
+	image = append(image, fileV1...)
+	image = append(image, junk...)
+
+	carved, err := Carve(bytes.NewReader(image), int64(len(image)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(carved) != 2 {
+		t.Fatalf("len(carved) = %d; want 2", len(carved))
+	}
+
+	if carved[0].Version != SEGB_VERSION_2 || carved[0].Offset != 1000 || carved[0].Length != int64(len(fileV2)) {
+		t.Errorf("carved[0] = %v at %d (+%d); want v2 at 1000 (+%d)", carved[0].Version, carved[0].Offset, carved[0].Length, len(fileV2))
+	}
+	if carved[1].Version != SEGB_VERSION_1 || carved[1].Offset != int64(v1Offset) {
+		t.Errorf("carved[1] = %v at %d; want v1 at %d", carved[1].Version, carved[1].Offset, v1Offset)
+	}
+	for _, c := range carved {
+		CheckForEntries(t, c.Segb.Entries)
+	}
+}
+
+func TestCarveOrphans(t *testing.T) {
+	file := v1File(8, []byte(expectedEntryData[0]), []byte(expectedEntryData[1]), []byte(expectedEntryData[2]))
+	for i := int64(56); i < int64(len(file)); i = alignUp(i+32+int64(binary.LittleEndian.Uint32(file[i:])), 8) {
+		// Give the entries creation times, as real ones have
+		binary.LittleEndian.PutUint64(file[i+8:], math.Float64bits(1e8))
+	}
+	orphaned := append([]byte(nil), file...)
+	clear(orphaned[:56])
+
+	junk := bytes.Repeat([]byte{0xAA}, 1000)
+	var image []byte
+	image = append(image, junk...)
+	image = append(image, file...)
+	image = append(image, junk...)
+	orphanOffset := len(image)
+	image = append(image, orphaned...)
+	image = append(image, junk...)
+
+	if carved, err := Carve(bytes.NewReader(image), int64(len(image))); err != nil || len(carved) != 1 {
+		t.Fatalf("Carve() = %d structures, %v; want 1", len(carved), err)
+	}
+
+	carved, err := Carve(bytes.NewReader(image), int64(len(image)), WithOrphanEntries())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(carved) != 4 || carved[0].Orphan || carved[0].Offset != 1000 {
+		t.Fatalf("Carve() = %d results; want the structure at 1000 and 3 orphans", len(carved))
+	}
+	for i, c := range carved[1:] {
+		// Orphans are the entries of the file with the wiped header
+		entry := c.Segb.Entries[0]
+		if !c.Orphan || string(entry.Data) != expectedEntryData[i] || c.Offset < int64(orphanOffset) || !bytes.Equal(image[entry.Offset:entry.Offset+entry.Size], entry.Data) {
+			t.Errorf("orphan %d = %q at %d, orphan %v", i, entry.Data, c.Offset, c.Orphan)
+		}
+	}
+}
+
+func TestCarveResume(t *testing.T) {
+	file := v2File([]byte(expectedEntryData[0]), []byte(expectedEntryData[1]))
+	image := make([]byte, carveChunkSize+carveChunkSize/2)
+	copy(image[100:], file)
+	copy(image[carveChunkSize+100:], file)
+
+	var progress []int64
+	carved, err := Carve(bytes.NewReader(image), int64(len(image)), WithCarveProgress(func(offset int64) {
+		progress = append(progress, offset)
+	}))
+	if err != nil || len(carved) != 2 {
+		t.Fatalf("Carve() = %d structures, %v; want 2", len(carved), err)
+	}
+	if len(progress) != 2 || progress[0] != carveChunkSize || progress[1] != int64(len(image)) {
+		t.Errorf("progress = %v", progress)
+	}
+
+	carved, err = Carve(bytes.NewReader(image), int64(len(image)), CarveFrom(progress[0]))
+	if err != nil || len(carved) != 1 || carved[0].Offset != carveChunkSize+100 {
+		t.Errorf("Carve() from %d = %d structures, %v", progress[0], len(carved), err)
+	}
+}
+
+// countingReaderAt counts the bytes read through it.
+type countingReaderAt struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestCarveReadsLittle(t *testing.T) {
+	small := v2File([]byte(expectedEntryData[0]), []byte(expectedEntryData[1]))
+	large := v2File(bytes.Repeat([]byte("large"), 100_000))
+
+	// Many structures, one of them with a trailer far past its header
+	var image []byte
+	for i := 0; i < 100; i++ {
+		image = append(image, bytes.Repeat([]byte{0xAA}, 20_000)...)
+		if i == 50 {
+			image = append(image, large...)
+		} else {
+			image = append(image, small...)
+		}
+	}
+
+	r := &countingReaderAt{r: bytes.NewReader(image)}
+	carved, err := Carve(r, int64(len(image)))
+	if err != nil || len(carved) != 100 {
+		t.Fatalf("Carve() = %d structures, %v; want 100", len(carved), err)
+	}
+	if carved[50].Length != int64(len(large)) {
+		t.Errorf("carved[50].Length = %d; want %d", carved[50].Length, len(large))
+	}
+	if r.n > 4*int64(len(image)) {
+		t.Errorf("read %d bytes of a %d byte image", r.n, len(image))
+	}
+}
@@ -0,0 +1,128 @@
+//go:build cgo
+
+// Command cexport builds the decoder as a C shared library so non-Go tools
+// (Python, Swift, ...) can call it without reimplementing the format.
+//
+// Build it with
+//
+//	go build -buildmode=c-shared -o libsegb.so ./cexport
+//
+// which also writes libsegb.h declaring the functions and structs below.
+// Every returned pointer is owned by the caller and must be released with
+// the matching free function.
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct {
+	int32_t id;
+	int32_t state;       // 1 written, 3 deleted, 4 unknown
+	double created;      // Unix time in seconds
+	uint32_t checksum;
+	uint8_t *data;
+	size_t size;
+} segb_entry;
+
+typedef struct {
+	int32_t version;     // 1 or 2
+	double created;      // Unix time in seconds
+	size_t entry_count;
+	segb_entry *entries;
+	char *error;         // NULL on success
+} segb_result;
+*/
+import "C"
+
+import (
+	"bytes"
+	"github.com/bluefalconhd/segb"
+	"math"
+	"unsafe"
+)
+
+// goBytes copies a buffer passed by a C caller. C.GoBytes takes an int,
+// which a size_t of 2 GiB or more would overflow.
+func goBytes(data unsafe.Pointer, size C.size_t) ([]byte, error) {
+	if uint64(size) > math.MaxInt {
+		return nil, errTooLarge
+	}
+	return bytes.Clone(unsafe.Slice((*byte)(data), int(size))), nil
+}
+
+// segb_decode_json decodes a buffer and returns the result as a JSON
+// document. Release it with segb_free.
+//
+//export segb_decode_json
+func segb_decode_json(data unsafe.Pointer, size C.size_t) *C.char {
+	buf, err := goBytes(data, size)
+	if err != nil {
+		return C.CString(errorJSON(err))
+	}
+	return C.CString(decodeJSON(buf))
+}
+
+// segb_decode decodes a buffer into C structs. On failure the error field is
+// set and no entries are returned. Release the result with segb_result_free.
+//
+//export segb_decode
+func segb_decode(data unsafe.Pointer, size C.size_t) *C.segb_result {
+	result := (*C.segb_result)(C.calloc(1, C.size_t(unsafe.Sizeof(C.segb_result{}))))
+
+	buf, err := goBytes(data, size)
+	if err != nil {
+		result.error = C.CString(err.Error())
+		return result
+	}
+	decoded, err := segb.DecodeBytes(buf)
+	if err != nil {
+		result.error = C.CString(err.Error())
+		return result
+	}
+
+	result.version = C.int32_t(decoded.Version)
+	result.created = C.double(unixSeconds(decoded.Created))
+	result.entry_count = C.size_t(len(decoded.Entries))
+	if len(decoded.Entries) == 0 {
+		return result
+	}
+
+	result.entries = (*C.segb_entry)(C.calloc(C.size_t(len(decoded.Entries)), C.size_t(unsafe.Sizeof(C.segb_entry{}))))
+	entries := unsafe.Slice(result.entries, len(decoded.Entries))
+	for i, entry := range decoded.Entries {
+		entries[i] = C.segb_entry{
+			id:       C.int32_t(entry.ID),
+			state:    C.int32_t(entry.State),
+			created:  C.double(unixSeconds(entry.Created)),
+			checksum: C.uint32_t(entry.Checksum),
+			data:     (*C.uint8_t)(C.CBytes(entry.Data)),
+			size:     C.size_t(len(entry.Data)),
+		}
+	}
+	return result
+}
+
+// segb_result_free releases a result returned by segb_decode.
+//
+//export segb_result_free
+func segb_result_free(result *C.segb_result) {
+	if result == nil {
+		return
+	}
+	if result.entries != nil {
+		for _, entry := range unsafe.Slice(result.entries, int(result.entry_count)) {
+			C.free(unsafe.Pointer(entry.data))
+		}
+		C.free(unsafe.Pointer(result.entries))
+	}
+	C.free(unsafe.Pointer(result.error))
+	C.free(unsafe.Pointer(result))
+}
+
+// segb_free releases a string returned by segb_decode_json.
+//
+//export segb_free
+func segb_free(p unsafe.Pointer) {
+	C.free(p)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/export"
+	"time"
+)
+
+// errTooLarge is returned for buffers larger than a Go slice can hold.
+var errTooLarge = errors.New("buffer too large")
+
+// jsonResult is the document returned by segb_decode_json.
+type jsonResult struct {
+	Version string          `json:"version,omitempty"`
+	Created time.Time       `json:"created,omitempty"`
+	Entries []export.Record `json:"entries,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// decodeJSON decodes a buffer into the JSON document handed to C callers.
+func decodeJSON(data []byte) string {
+	var result jsonResult
+
+	decoded, err := segb.DecodeBytes(data)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Version = decoded.Version.String()
+		result.Created = decoded.Created
+		result.Entries = export.Records("", decoded, true)
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return errorJSON(err)
+	}
+	return string(out)
+}
+
+// errorJSON returns the document of a failure to decode.
+func errorJSON(err error) string {
+	out, _ := json.Marshal(jsonResult{Error: err.Error()})
+	return string(out)
+}
+
+// unixSeconds converts a time to fractional Unix seconds.
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
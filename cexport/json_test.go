@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/bluefalconhd/segb/segbtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	root := t.TempDir()
+	if err := segbtest.WriteFixtures(root); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "segb_version1.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result jsonResult
+	if err := json.Unmarshal([]byte(decodeJSON(data)), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" || result.Version != "v1" || len(result.Entries) != 3 {
+		t.Fatalf("decodeJSON() = %+v", result)
+	}
+	if string(result.Entries[1].Data) != "The misfits." {
+		t.Errorf("Entries[1].Data = %q; want %q", result.Entries[1].Data, "The misfits.")
+	}
+
+	if err := json.Unmarshal([]byte(decodeJSON(data[:10])), &result); err != nil || result.Error == "" {
+		t.Errorf("decodeJSON() on truncated input: error = %q", result.Error)
+	}
+
+	result = jsonResult{}
+	if err := json.Unmarshal([]byte(errorJSON(errTooLarge)), &result); err != nil || result.Error != errTooLarge.Error() || result.Entries != nil {
+		t.Errorf("errorJSON() = %+v", result)
+	}
+}
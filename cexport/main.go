@@ -0,0 +1,5 @@
+package main
+
+// main is required by -buildmode=c-shared but never runs. It lives outside
+// the cgo file so the package still builds with CGO_ENABLED=0.
+func main() {}
@@ -0,0 +1,206 @@
+package segb
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/bluefalconhd/segb/plist"
+	"google.golang.org/protobuf/encoding/protowire"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ContentType is the kind of data a payload holds, as told by Classify.
+type ContentType int
+
+const (
+	ContentUnknown  ContentType = iota // Binary data of no recognized type
+	ContentEmpty                       // No payload at all
+	ContentProtobuf                    // Protobuf message
+	ContentBplist                      // Binary property list
+	ContentJSON
+	ContentText // UTF-8 text
+	ContentJPEG
+	ContentPNG
+	ContentHEIC
+	ContentSQLite // SQLite database
+	ContentGIF
+	ContentMP4          // MPEG-4 video
+	ContentQuickTime    // QuickTime movie
+	ContentKeyedArchive // Binary property list written by NSKeyedArchiver
+)
+
+func (c ContentType) String() string {
+	switch c {
+	case ContentEmpty:
+		return "empty"
+	case ContentProtobuf:
+		return "protobuf"
+	case ContentBplist:
+		return "bplist"
+	case ContentJSON:
+		return "json"
+	case ContentText:
+		return "text"
+	case ContentJPEG:
+		return "jpeg"
+	case ContentPNG:
+		return "png"
+	case ContentHEIC:
+		return "heic"
+	case ContentSQLite:
+		return "sqlite"
+	case ContentGIF:
+		return "gif"
+	case ContentMP4:
+		return "mp4"
+	case ContentQuickTime:
+		return "quicktime"
+	case ContentKeyedArchive:
+		return "keyedarchive"
+	default:
+		return "binary"
+	}
+}
+
+// Extension returns the usual file name extension of the content type,
+// with its dot, e.g. ".jpg". Unknown binary data gets ".bin".
+func (c ContentType) Extension() string {
+	switch c {
+	case ContentProtobuf:
+		return ".pb"
+	case ContentBplist, ContentKeyedArchive:
+		return ".plist"
+	case ContentJSON:
+		return ".json"
+	case ContentText:
+		return ".txt"
+	case ContentJPEG:
+		return ".jpg"
+	case ContentPNG:
+		return ".png"
+	case ContentHEIC:
+		return ".heic"
+	case ContentSQLite:
+		return ".sqlite"
+	case ContentGIF:
+		return ".gif"
+	case ContentMP4:
+		return ".mp4"
+	case ContentQuickTime:
+		return ".mov"
+	default:
+		return ".bin"
+	}
+}
+
+// IsMedia reports whether the content type is an image or video format.
+func (c ContentType) IsMedia() bool {
+	switch c {
+	case ContentJPEG, ContentPNG, ContentHEIC, ContentGIF, ContentMP4, ContentQuickTime:
+		return true
+	}
+	return false
+}
+
+// Magic numbers of the formats recognized by their first bytes
+var contentMagic = []struct {
+	magic []byte
+	typ   ContentType
+}{
+	{[]byte("bplist"), ContentBplist},
+	{[]byte{0xFF, 0xD8, 0xFF}, ContentJPEG},
+	{[]byte("\x89PNG\r\n\x1a\n"), ContentPNG},
+	{[]byte("SQLite format 3\x00"), ContentSQLite},
+	{[]byte("GIF87a"), ContentGIF},
+	{[]byte("GIF89a"), ContentGIF},
+}
+
+// isoBrands maps the major brands of ISO base media files (after "ftyp")
+// to their content type.
+var isoBrands = map[string]ContentType{
+	"heic": ContentHEIC, "heix": ContentHEIC, "hevc": ContentHEIC, "hevx": ContentHEIC,
+	"heim": ContentHEIC, "heis": ContentHEIC, "mif1": ContentHEIC, "msf1": ContentHEIC,
+	"isom": ContentMP4, "iso2": ContentMP4, "mp41": ContentMP4, "mp42": ContentMP4,
+	"avc1": ContentMP4, "M4V ": ContentMP4, "dash": ContentMP4,
+	"qt  ": ContentQuickTime,
+}
+
+// Classify tells what kind of data a payload holds, from magic numbers and,
+// for formats without one, structural probes: JSON and UTF-8 text must be
+// valid, and protobuf must parse as a message down to its last byte. Text
+// that also parses as protobuf, as short strings often do, is classified as
+// text. Binary property lists are parsed to tell NSKeyedArchiver archives
+// apart.
+func Classify(data []byte) ContentType {
+	if len(data) == 0 {
+		return ContentEmpty
+	}
+	for _, m := range contentMagic {
+		if bytes.HasPrefix(data, m.magic) {
+			if m.typ == ContentBplist && plist.IsKeyedArchive(data) {
+				return ContentKeyedArchive
+			}
+			return m.typ
+		}
+	}
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		if typ, ok := isoBrands[string(data[8:12])]; ok {
+			return typ
+		}
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return ContentJSON
+	}
+	if isText(data) {
+		return ContentText
+	}
+	if isProtobuf(data) {
+		return ContentProtobuf
+	}
+	return ContentUnknown
+}
+
+// isText reports whether data is valid UTF-8 without control characters
+// other than whitespace.
+func isText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isProtobuf reports whether data parses as a protobuf message, without
+// groups, to its last byte.
+func isProtobuf(data []byte) bool {
+	for len(data) > 0 {
+		_, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return false
+		}
+		data = data[n:]
+		switch typ {
+		case protowire.VarintType, protowire.Fixed32Type, protowire.Fixed64Type, protowire.BytesType:
+			n = protowire.ConsumeFieldValue(0, typ, data)
+		default:
+			return false
+		}
+		if n < 0 {
+			return false
+		}
+		data = data[n:]
+	}
+	return true
+}
+
+// classifyPayloads sets the ContentType of every entry.
+func (s *Segb) classifyPayloads() {
+	for i := range s.Entries {
+		s.Entries[i].ContentType = Classify(s.Entries[i].Data)
+	}
+}
@@ -0,0 +1,41 @@
+package segb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want ContentType
+	}{
+		{"empty", nil, ContentEmpty},
+		{"protobuf", []byte{0x08, 0x96, 0x01, 0x12, 0x03, 0xff, 0xfe, 0x00}, ContentProtobuf},
+		{"bplist", []byte("bplist00\xd1\x01\x02"), ContentBplist},
+		{"json", []byte(` {"bundleID": "com.apple.mobilesafari"}` + "\n"), ContentJSON},
+		{"text", []byte("Here's to the crazy ones."), ContentText},
+		{"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10}, ContentJPEG},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), ContentPNG},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), ContentHEIC},
+		{"sqlite", []byte("SQLite format 3\x00\x10\x00"), ContentSQLite},
+		{"binary", []byte{0x07, 0xff, 0xff, 0x00}, ContentUnknown},
+		{"malformed json", []byte(`{"a": `), ContentText},
+	} {
+		if got := Classify(tc.data); got != tc.want {
+			t.Errorf("Classify(%s) = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeClassifies(t *testing.T) {
+	file := v2File([]byte(expectedEntryData[0]), bytes.Repeat([]byte{0x07, 0xff}, 8))
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Entries[0].ContentType != ContentText || decoded.Entries[1].ContentType != ContentUnknown {
+		t.Errorf("ContentType = %v, %v; want text, binary", decoded.Entries[0].ContentType, decoded.Entries[1].ContentType)
+	}
+}
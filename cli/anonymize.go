@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"math/rand/v2"
+	"os"
+	"time"
+)
+
+// maxAnonymizeShift bounds the random shift of anonymized timestamps. The
+// shift is forward only, as v2 files dated before 2001 are not detected.
+const maxAnonymizeShift = 10 * 365 * 24 * time.Hour
+
+func runAnonymize(args []string) error {
+	flags := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	output := flags.String("output", "", "file to write the anonymized copy to (default: FILE.anonymized)")
+
+	// Flags may follow FILE, as for redact
+	var positional []string
+	for parseFlags(flags, args); flags.NArg() > 0; flags.Parse(args) {
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) != 1 || positional[0] == "-" {
+		usage()
+		os.Exit(exitUsage)
+	}
+	filename := positional[0]
+	if *output == "" {
+		*output = filename + ".anonymized"
+	}
+
+	segbData, err := openAndDecode(filename, segb.WithRoundTrip())
+	if err != nil {
+		return err
+	}
+	// The shift is not shown: it would give the original times back
+	shift := time.Hour + time.Duration(rand.Int64N(int64(maxAnonymizeShift/time.Microsecond)))*time.Microsecond
+	segbData.Anonymize(shift)
+
+	var buf bytes.Buffer
+	if err := segbData.Encode(&buf); err != nil {
+		return &fileError{filename, err}
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		return &fileError{*output, err}
+	}
+	fmt.Printf("anonymized %d entries of %s -> %s\n", len(segbData.Entries), filename, *output)
+	return nil
+}
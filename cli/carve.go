@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files carve keeps in its output directory besides the carved ones
+const (
+	carveReportName     = "report.jsonl"
+	carveCheckpointName = "checkpoint.json"
+)
+
+// carveRecord is a line of the carve report: one carved file or entry and
+// where it was found.
+type carveRecord struct {
+	Offset  int64     `json:"offset"`
+	Length  int64     `json:"length"`
+	Kind    string    `json:"kind"` // v1, v2 or orphan (a lone v1 entry)
+	File    string    `json:"file"` // Name in the output directory
+	Entries int       `json:"entries"`
+	Created time.Time `json:"created"`
+}
+
+// carveCheckpoint is where an interrupted carve resumes: the image offset
+// scanned up to, and the length of the report at that point.
+type carveCheckpoint struct {
+	Offset     int64 `json:"offset"`
+	ReportSize int64 `json:"report_size"`
+}
+
+func runCarve(args []string) error {
+	flags := flag.NewFlagSet("carve", flag.ExitOnError)
+	output := flags.String("output", "", "directory to write carved files and the report to (required)")
+	resume := flags.Bool("resume", false, "continue an interrupted carve into the same output directory")
+	orphans := flags.Bool("orphans", true, "also recover lone v1 entries outside any SEGB file")
+	quiet := flags.Bool("quiet", false, "do not report progress on stderr")
+	parseFlags(flags, args)
+	if *output == "" || flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	path := flags.Arg(0)
+	image, err := openFile(path)
+	if err != nil {
+		return &fileError{path, err}
+	}
+	defer image.Close()
+	// Seeking rather than Stat also sizes block devices
+	size, err := image.Seek(0, io.SeekEnd)
+	if err != nil {
+		return &fileError{path, err}
+	}
+	if err := os.MkdirAll(*output, 0o755); err != nil {
+		return err
+	}
+
+	// Resume from the checkpoint, dropping what was reported past it
+	var checkpoint carveCheckpoint
+	if *resume {
+		data, err := os.ReadFile(filepath.Join(*output, carveCheckpointName))
+		if err != nil {
+			return fmt.Errorf("resuming: %w", err)
+		}
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			return fmt.Errorf("resuming: %w", err)
+		}
+	}
+	report, err := os.OpenFile(filepath.Join(*output, carveReportName), os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer report.Close()
+	if err := report.Truncate(checkpoint.ReportSize); err != nil {
+		return err
+	}
+	if _, err := report.Seek(checkpoint.ReportSize, io.SeekStart); err != nil {
+		return err
+	}
+
+	found := 0
+	started := time.Now()
+	var lastSaved time.Time
+	progress := func(offset int64) {
+		if offset < size && time.Since(lastSaved) < time.Second {
+			return
+		}
+		lastSaved = time.Now()
+		if err := saveCarveCheckpoint(*output, report, offset); err != nil {
+			fmt.Fprintf(os.Stderr, "\nError saving checkpoint: %v\n", err)
+		}
+		if !*quiet {
+			scanned := offset - checkpoint.Offset
+			rate := float64(scanned) / max(time.Since(started).Seconds(), 1e-3)
+			fmt.Fprintf(os.Stderr, "\r%s of %s scanned (%.1f%%, %s/s), %d found ",
+				formatBytes(offset), formatBytes(size), 100*float64(offset)/float64(max(size, 1)), formatBytes(int64(rate)), found)
+		}
+	}
+
+	opts := []segb.CarveOption{segb.CarveFrom(checkpoint.Offset), segb.WithCarveProgress(progress)}
+	if *orphans {
+		opts = append(opts, segb.WithOrphanEntries())
+	}
+	encoder := json.NewEncoder(report)
+	err = segb.CarveFunc(image, size, func(c segb.Carved) error {
+		record := carveRecord{
+			Offset:  c.Offset,
+			Length:  c.Length,
+			Kind:    c.Version.String(),
+			Entries: len(c.Segb.Entries),
+			Created: displayTime(c.Segb.Created),
+		}
+		var err error
+		if c.Orphan {
+			// Orphans are extracted as their payload
+			record.Kind = "orphan"
+			record.File = fmt.Sprintf("entry-%012x.bin", c.Offset)
+			err = os.WriteFile(filepath.Join(*output, record.File), c.Segb.Entries[0].Stored(), 0o644)
+		} else {
+			record.File = fmt.Sprintf("segb-%012x.segb", c.Offset)
+			err = extractRange(image, c.Offset, c.Length, filepath.Join(*output, record.File))
+		}
+		if err != nil {
+			return err
+		}
+		found++
+		return encoder.Encode(record)
+	}, opts...)
+	if !*quiet && size > checkpoint.Offset {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		return &fileError{path, err}
+	}
+	return nil
+}
+
+// extractRange copies length bytes at offset in r to a new file at path.
+func extractRange(r io.ReaderAt, offset, length int64, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, io.NewSectionReader(r, offset, length)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// saveCarveCheckpoint records that the image is carved up to offset, with
+// everything found so far in report.
+func saveCarveCheckpoint(dir string, report *os.File, offset int64) error {
+	if err := report.Sync(); err != nil {
+		return err
+	}
+	reportSize, err := report.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(carveCheckpoint{Offset: offset, ReportSize: reportSize})
+	if err != nil {
+		return err
+	}
+
+	// Replace the checkpoint atomically, an interruption must not lose it
+	tmp := filepath.Join(dir, carveCheckpointName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, carveCheckpointName))
+}
+
+// formatBytes formats n with a binary unit, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/archive"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// changeRow is a line of changes output in JSON form.
+type changeRow struct {
+	Change      string    `json:"change"`
+	SHA256      string    `json:"sha256"`
+	State       string    `json:"state"`
+	Created     time.Time `json:"created"`
+	Size        int       `json:"size"`
+	Before      string    `json:"before,omitempty"`
+	BeforeEntry *int      `json:"before_entry,omitempty"`
+	After       string    `json:"after,omitempty"`
+	AfterEntry  *int      `json:"after_entry,omitempty"`
+}
+
+func runChanges(args []string) error {
+	flags := flag.NewFlagSet("changes", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print one JSON object per change instead of a table")
+	parseFlags(flags, args)
+	if flags.NArg() != 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	before, err := loadAcquisition(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := loadAcquisition(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	changes := segb.DiffAcquisitions(before, after)
+	counts := make(map[segb.ChangeKind]int)
+	rows := make([]changeRow, len(changes))
+	for i, change := range changes {
+		counts[change.Kind]++
+		row := changeRow{Change: change.Kind.String(), SHA256: change.Hash.String()}
+		// The later copy has the current state, a vanished entry only the
+		// earlier one
+		latest := change.After
+		if latest == nil {
+			latest = change.Before
+		}
+		row.State, row.Created, row.Size = latest.Entry.State.String(), displayTime(latest.Entry.Created), len(latest.Entry.Data)
+		if change.Before != nil {
+			row.Before, row.BeforeEntry = change.Before.Path, &change.Before.Index
+		}
+		if change.After != nil {
+			row.After, row.AfterEntry = change.After.Path, &change.After.Index
+		}
+		rows[i] = row
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tSHA256\tSTATE\tCREATED\tSIZE\tBEFORE\tAFTER")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%.16s\t%s\t%s\t%d\t%s\t%s\n", row.Change, row.SHA256, row.State,
+			formatTime(row.Created), row.Size, changeLocation(row.Before, row.BeforeEntry), changeLocation(row.After, row.AfterEntry))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d added, %d deleted, %d vanished\n", counts[segb.ChangeAdded], counts[segb.ChangeDeleted], counts[segb.ChangeVanished])
+	return nil
+}
+
+// loadAcquisition decodes an acquisition of a stream: a single SEGB file, or
+// every SEGB file below a directory or in a zip or tar archive. Files that
+// fail to decode are reported and left out.
+func loadAcquisition(path string) (map[string]segb.Segb, error) {
+	var corpus *segb.Corpus
+	var err error
+	if info, statErr := os.Stat(path); path == "-" || (statErr == nil && !info.IsDir() && !isArchive(path)) {
+		s, err := openAndDecode(path)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]segb.Segb{path: s}, nil
+	}
+
+	if isArchive(path) {
+		if err = guardArchive(path); err == nil {
+			corpus, err = archive.Load(path, loadOptions()...)
+		}
+	} else {
+		corpus, err = segb.LoadDir(path, loadOptions()...)
+	}
+	if err != nil {
+		return nil, &fileError{path, err}
+	}
+	files := make(map[string]segb.Segb)
+	for _, p := range corpus.Paths() {
+		result := corpus.Files[p]
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", p, result.Err)
+			continue
+		}
+		files[p] = result.Segb
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no SEGB files found", path)
+	}
+	return files, nil
+}
+
+// changeLocation formats where an entry was found, e.g. "segment#3", or "-".
+func changeLocation(path string, index *int) string {
+	if index == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s#%d", path, *index)
+}
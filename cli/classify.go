@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"sort"
+	"strings"
+)
+
+// histogramWidth is the length of the bar of the most common content type.
+const histogramWidth = 30
+
+func runClassify(args []string) error {
+	flags := flag.NewFlagSet("classify", flag.ExitOnError)
+	noColor := flags.Bool("no-color", false, "disable colored output (default: color when stdout is a terminal)")
+	summary := flags.Bool("summary", false, "print only the histogram of each file")
+	parseFlags(flags, args)
+	if flags.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	s := newStyle(os.Stdout, *noColor)
+	for i, filename := range flags.Args() {
+		segbData, err := openAndDecode(filename)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(s.bold(filename))
+
+		counts := make(map[segb.ContentType]int)
+		for j, entry := range segbData.Entries {
+			counts[entry.ContentType]++
+			if !*summary {
+				fmt.Printf("  entry %-5d %-12s %s\n", j, entry.ContentType, s.dim(fmt.Sprintf("%d bytes", len(entry.Data))))
+			}
+		}
+		if !*summary && len(segbData.Entries) > 0 {
+			fmt.Println()
+		}
+		printHistogram(s, counts, len(segbData.Entries))
+	}
+	return nil
+}
+
+// printHistogram prints the number of entries of each content type, most
+// common first, with a bar.
+func printHistogram(s style, counts map[segb.ContentType]int, total int) {
+	types := make([]segb.ContentType, 0, len(counts))
+	for contentType := range counts {
+		types = append(types, contentType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	if len(types) == 0 {
+		fmt.Println(s.dim("  no entries"))
+		return
+	}
+
+	most := counts[types[0]]
+	for _, contentType := range types {
+		count := counts[contentType]
+		bar := strings.Repeat("#", max(1, count*histogramWidth/most))
+		fmt.Printf("  %-12s %6d %5.1f%%  %s\n", contentType, count, 100*float64(count)/float64(total), s.dim(bar))
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// cmpMaxBytes is how many bytes of a differing range are shown.
+const cmpMaxBytes = 32
+
+func runCmp(args []string) error {
+	flags := flag.NewFlagSet("cmp", flag.ExitOnError)
+	noColor := flags.Bool("no-color", false, "disable colored output (default: color when stdout is a terminal)")
+	parseFlags(flags, args)
+	if flags.NArg() != 3 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	filename := flags.Arg(0)
+	segbData, err := openAndDecode(filename)
+	if err != nil {
+		return err
+	}
+	var indexes [2]int
+	var payloads [2][]byte
+	for i, arg := range flags.Args()[1:] {
+		index, err := entryIndex(&segbData, filename, arg)
+		if err != nil {
+			return err
+		}
+		indexes[i], payloads[i] = index, segbData.Entries[index].Data
+	}
+
+	s := newStyle(os.Stdout, *noColor)
+	c := segb.ComparePayloads(payloads[0], payloads[1])
+	n, m := indexes[0], indexes[1]
+	fmt.Printf("%s %d bytes, %s %d bytes\n", s.paint(sgrRed, fmt.Sprintf("--- entry %d:", n)), len(payloads[0]),
+		s.paint(sgrGreen, fmt.Sprintf("+++ entry %d:", m)), len(payloads[1]))
+	if c.Equal() {
+		fmt.Println("payloads are identical")
+		return nil
+	}
+	fmt.Printf("common prefix %d bytes, common suffix %d bytes, %d differing ranges\n", c.Prefix, c.Suffix, len(c.Hunks))
+
+	for _, h := range c.Hunks {
+		fmt.Println()
+		fmt.Println(s.bold(fmt.Sprintf("@@ 0x%x,%d 0x%x,%d @@", h.AOffset, h.ALen, h.BOffset, h.BLen)))
+		if h.ALen > 0 {
+			fmt.Println(s.paint(sgrRed, "- "+cmpBytes(payloads[0][h.AOffset:h.AOffset+h.ALen])))
+		}
+		if h.BLen > 0 {
+			fmt.Println(s.paint(sgrGreen, "+ "+cmpBytes(payloads[1][h.BOffset:h.BOffset+h.BLen])))
+		}
+	}
+
+	if c.Protobuf && len(c.Fields) > 0 {
+		fmt.Println()
+		fmt.Println(s.bold("protobuf fields:"))
+		for _, field := range c.Fields {
+			fmt.Printf("  %-10s %-8s %s -> %s\n", field.Path, wireTypeName(field.Type),
+				s.paint(sgrRed, cmpValue(field.Type, field.A)), s.paint(sgrGreen, cmpValue(field.Type, field.B)))
+		}
+	}
+	return nil
+}
+
+// cmpBytes renders a differing range as hex, truncated to cmpMaxBytes.
+func cmpBytes(data []byte) string {
+	if len(data) <= cmpMaxBytes {
+		return hex.EncodeToString(data)
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", hex.EncodeToString(data[:cmpMaxBytes]), len(data)-cmpMaxBytes)
+}
+
+// cmpValue renders an encoded protobuf value, or its absence.
+func cmpValue(typ protowire.Type, value []byte) string {
+	if value == nil {
+		return "(absent)"
+	}
+	switch typ {
+	case protowire.VarintType:
+		v, _ := protowire.ConsumeVarint(value)
+		return strconv.FormatUint(v, 10)
+	case protowire.Fixed32Type:
+		v, _ := protowire.ConsumeFixed32(value)
+		return fmt.Sprintf("0x%08x (float %g)", v, math.Float32frombits(v))
+	case protowire.Fixed64Type:
+		v, _ := protowire.ConsumeFixed64(value)
+		return fmt.Sprintf("0x%016x (double %g)", v, math.Float64frombits(v))
+	}
+	if utf8.Valid(value) && len(value) <= 2*cmpMaxBytes {
+		return strconv.Quote(string(value))
+	}
+	return cmpBytes(value)
+}
+
+// wireTypeName names a protobuf wire type.
+func wireTypeName(typ protowire.Type) string {
+	switch typ {
+	case protowire.VarintType:
+		return "varint"
+	case protowire.Fixed32Type:
+		return "fixed32"
+	case protowire.Fixed64Type:
+		return "fixed64"
+	case protowire.BytesType:
+		return "bytes"
+	}
+	return fmt.Sprintf("type %d", typ)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// config holds the values of the configuration file, flag defaults by flag
+// name: global ones and those of any command at the top level, and those of
+// a single command in a table named after it, as in [dump] (or
+// [manifest.sign] for "manifest sign"). The [decoders] table configures
+// external payload decoders instead, see externalDecoder.
+type config struct {
+	path     string
+	tables   map[string]map[string]string // By command, "" for the top level
+	decoders map[string][]string          // Command lines by stream name
+}
+
+// settings is the configuration file read at start up, set by --config or
+// found in the default location.
+var settings = &config{tables: make(map[string]map[string]string)}
+
+// defaultConfigPath returns where the configuration file is looked for
+// without --config: $XDG_CONFIG_HOME/segb/config.toml, or
+// ~/.config/segb/config.toml.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "segb", "config.toml")
+}
+
+// loadConfig reads the configuration file at path. A missing file is an
+// error only when required, i.e. given with --config.
+func loadConfig(path string, required bool) (*config, error) {
+	c := &config{path: path, tables: make(map[string]map[string]string)}
+	var values map[string]any
+	_, err := toml.DecodeFile(path, &values)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if table, ok := values[decodersTable]; ok {
+		delete(values, decodersTable)
+		if c.decoders, err = decoderCommands(table); err != nil {
+			return nil, fmt.Errorf("%s: [%s]: %w", path, decodersTable, err)
+		}
+	}
+	if err := c.addTable("", values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// addTable adds the values of the table of a command, and those of the
+// tables nested in it as the tables of its subcommands.
+func (c *config) addTable(name string, values map[string]any) error {
+	for key, value := range values {
+		if table, ok := value.(map[string]any); ok {
+			if err := c.addTable(strings.TrimSpace(name+" "+key), table); err != nil {
+				return err
+			}
+			continue
+		}
+		text, err := flagValue(value)
+		if err != nil {
+			return fmt.Errorf("[%s]: %s: %w", strings.ReplaceAll(name, " ", "."), key, err)
+		}
+		if c.tables[name] == nil {
+			c.tables[name] = make(map[string]string)
+		}
+		c.tables[name][key] = text
+	}
+	return nil
+}
+
+// flagValue returns a TOML value flags can take, a string, boolean or
+// number, as a flag value.
+func flagValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported value %v (want a string, boolean or number)", value)
+}
+
+// global returns the value of a global flag in the configuration file.
+func (c *config) global(name string) (string, bool) {
+	value, ok := c.tables[""][name]
+	return value, ok
+}
+
+// parseFlags parses the flags of a command, their defaults first set from
+// the configuration file: from the top level for the flags of the command
+// found there, then from the table of the command. Flags given on the
+// command line take precedence.
+func parseFlags(flags *flag.FlagSet, args []string) {
+	for _, table := range []string{"", flags.Name()} {
+		for key, value := range settings.tables[table] {
+			if flags.Lookup(key) == nil {
+				if table != "" {
+					fmt.Fprintf(os.Stderr, "%s: [%s]: %s has no flag %s\n", settings.path, table, table, key)
+					os.Exit(exitUsage)
+				}
+				continue
+			}
+			if err := flags.Set(key, value); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s: %v\n", settings.path, key, err)
+				os.Exit(exitUsage)
+			}
+		}
+	}
+	flags.Parse(args)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigValues(t *testing.T) {
+	for _, tc := range []struct {
+		name, text string
+		table, key string
+		want       string
+	}{
+		{"string", `tz = "Europe/Paris"`, "", "tz", "Europe/Paris"},
+		{"escapes", `dir = "C:\\Users\\"`, "", "dir", `C:\Users\`},
+		{"escaped quote", `note = "say \"hi\""`, "", "note", `say "hi"`},
+		{"literal string", `dir = 'C:\Users\'`, "", "dir", `C:\Users\`},
+		{"boolean", `utc = true`, "", "utc", "true"},
+		{"integer", `retries = 1_000`, "", "retries", "1000"},
+		{"float", `ratio = 0.25`, "", "ratio", "0.25"},
+		{"comment", "# Defaults\nutc = false # Keep local time", "", "utc", "false"},
+		{"hash in string", `title = "#1"  # Comment`, "", "title", "#1"},
+		{"table", "tz = \"UTC\"\n[dump]\ndata = true", "dump", "data", "true"},
+		{"nested table", "[manifest.sign]\nkey = \"k.pem\"", "manifest sign", "key", "k.pem"},
+		{"dotted table", "[manifest]\nsign.key = \"k.pem\"", "manifest sign", "key", "k.pem"},
+	} {
+		c, err := loadConfig(writeConfig(t, tc.text), true)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got, ok := c.tables[tc.table][tc.key]; !ok || got != tc.want {
+			t.Errorf("%s: [%s] %s = %q, %v; want %q", tc.name, tc.table, tc.key, got, ok, tc.want)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for name, text := range map[string]string{
+		"unterminated string": `tz = "UTC`,
+		"missing value":       `tz =`,
+		"duplicate key":       "utc = true\nutc = false",
+		"array":               `fields = ["id", "state"]`,
+		"date":                `since = 2024-01-01`,
+		"bad table header":    `[dump`,
+	} {
+		if _, err := loadConfig(writeConfig(t, text), true); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.toml")
+	if c, err := loadConfig(missing, false); err != nil || len(c.tables) != 0 {
+		t.Errorf("optional missing file: %v, %v", c, err)
+	}
+	if _, err := loadConfig(missing, true); err == nil {
+		t.Error("required missing file: no error")
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	c, err := loadConfig(writeConfig(t, `
+data = true
+key = "global.pem"
+
+[manifest.sign]
+key = "sign.pem"
+output = "config.sig"
+`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := settings
+	settings = c
+	defer func() { settings = saved }()
+
+	flags := flag.NewFlagSet("manifest sign", flag.ContinueOnError)
+	key := flags.String("key", "", "")
+	output := flags.String("output", "", "")
+	data := flags.Bool("data", false, "")
+	unset := flags.String("unset", "default", "")
+	parseFlags(flags, []string{"--output", "cli.sig", "file"})
+
+	// The command's table beats the top level, and the command line both
+	if *key != "sign.pem" || *output != "cli.sig" || !*data || *unset != "default" {
+		t.Errorf("key %q, output %q, data %v, unset %q", *key, *output, *data, *unset)
+	}
+	if flags.NArg() != 1 || flags.Arg(0) != "file" {
+		t.Errorf("args %q", flags.Args())
+	}
+
+	if value, ok := c.global("key"); !ok || value != "global.pem" {
+		t.Errorf("global key = %q, %v", value, ok)
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/biome"
+	"github.com/bluefalconhd/segb/export"
+	"github.com/bluefalconhd/segb/hexdump"
+	"github.com/bluefalconhd/segb/plist"
+	"github.com/bluefalconhd/segb/protoschema"
+	"github.com/bluefalconhd/segb/query"
+	"github.com/bluefalconhd/segb/script"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// openAndDecode opens and decodes the named SEGB file ("-" for stdin).
+func openAndDecode(filename string, opts ...segb.DecodeOption) (segb.Segb, error) {
+	// Open the file
+	file, closeFile, err := openInput(filename)
+	if err != nil {
+		return segb.Segb{}, &fileError{filename, fmt.Errorf("opening file: %w", err)}
+	}
+	defer func() {
+		err := closeFile()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing file: %v\n", err)
+		}
+	}()
+
+	// Decode the SEGB file
+	opts = append(opts, segb.WithRetry(retries))
+	segbData, err := segb.Decode(file, opts...)
+	if err != nil {
+		return segb.Segb{}, &fileError{filename, fmt.Errorf("decoding SEGB file: %w", err)}
+	}
+	return segbData, nil
+}
+
+// entryIndex resolves an entry given on the command line, by index or by
+// stable ID (see segb.Entry.StableID), to its index in s.Entries.
+func entryIndex(s *segb.Segb, filename, arg string) (int, error) {
+	index, err := strconv.Atoi(arg)
+	if err != nil {
+		if index, ok := s.FindStableID(arg); ok {
+			return index, nil
+		}
+		return 0, fmt.Errorf("invalid entry %q, not an index or a stable ID in %s", arg, filename)
+	}
+	if index < 0 || index >= len(s.Entries) {
+		return 0, fmt.Errorf("entry %d out of range, %s has %d entries", index, filename, len(s.Entries))
+	}
+	return index, nil
+}
+
+func runDump(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	noColor := flags.Bool("no-color", false, "disable colored output (default: color when stdout is a terminal)")
+	raw := flags.Bool("raw", false, "show compressed payloads as stored instead of decompressing them")
+	padded := flags.Bool("padded", false, "show payloads with their alignment padding, byte for byte as stored (implies --raw)")
+	forceHex := flags.Bool("hexdump", false, "show every payload as a hexdump instead of picking a rendering by content type")
+	flagEntropy := flags.Bool("flag-high-entropy", false, "show payload entropy and flag likely encrypted or compressed payloads")
+	headerFields := flags.Bool("header", false, "show candidate interpretations of the unknown v2 header bytes")
+	lenient := flags.Bool("lenient", false, "skip malformed entries instead of failing")
+	residual := flags.Bool("residual", false, "also recover overwritten v1 entries from past the end of data")
+	crcPolicy := flags.String("crc", "mark", "what to do with CRC mismatches: mark, strict (fail) or repair (correct single bit flips)")
+	order := flags.String("sort", "offset", "entry order: offset, created or sequence (trailer order for v2)")
+	alignment := flags.Int("alignment", 0, "entry alignment in bytes (default: detected for v1, 4 for v2)")
+	descriptors := flags.String("descriptors", "", "compiled protobuf FileDescriptorSet to decode payloads with")
+	typeMap := flags.String("types", "", "JSON file mapping stream names to message types in --descriptors")
+	messageType := flags.String("message", "", "message type in --descriptors for streams --types does not map")
+	streamName := flags.String("stream", "", "stream name of the file (default: inferred from a Biome path)")
+	format := flags.String("template", "", "print each entry with a Go template against its export.Record, e.g. '{{.Index}} {{time .Created}} {{.State}}'")
+	expression := flags.String("query", "", "only show entries whose decoded payload matches a jq-like expression, e.g. '.bundle_id == \"com.apple.mobilesafari\"'; $entry is the record of the entry")
+	scriptPath := flags.String("script", "", "Starlark script defining process(entry), to filter entries and compute derived fields with")
+	selection := addColumnFlags(flags)
+
+	// Parse the command line arguments
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	var opts []segb.DecodeOption
+	if *raw {
+		opts = append(opts, segb.WithoutPayloadDecompression())
+	}
+	if *padded {
+		opts = append(opts, segb.WithRawPayloads())
+	}
+	if *lenient {
+		opts = append(opts, segb.WithLenientParsing())
+	}
+	if *residual {
+		opts = append(opts, segb.WithResidualEntries())
+	}
+	switch *crcPolicy {
+	case "mark":
+	case "strict":
+		opts = append(opts, segb.WithCRCPolicy(segb.CRCStrict))
+	case "repair":
+		opts = append(opts, segb.WithCRCPolicy(segb.CRCRepair))
+	default:
+		return fmt.Errorf("unknown CRC policy %q", *crcPolicy)
+	}
+	switch *order {
+	case "offset":
+	case "created":
+		opts = append(opts, segb.WithSortOrder(segb.SortByCreated))
+	case "sequence":
+		opts = append(opts, segb.WithSortOrder(segb.SortBySequence))
+	default:
+		return fmt.Errorf("unknown sort order %q", *order)
+	}
+	if *alignment != 0 {
+		opts = append(opts, segb.WithAlignment(*alignment))
+	}
+	columns, err := selection.columns()
+	if err != nil {
+		return err
+	}
+	var tmpl *entryTemplate
+	if *format != "" {
+		var err error
+		tmpl, err = parseEntryTemplate(*format)
+		if err != nil {
+			return err
+		}
+	}
+
+	var decoder *protoschema.Decoder
+	if *descriptors != "" {
+		var err error
+		decoder, err = loadProtoDecoder(*descriptors, *typeMap, *messageType)
+		if err != nil {
+			return err
+		}
+	}
+	var q *query.Query
+	if *expression != "" {
+		var err error
+		if q, err = query.Parse(*expression); err != nil {
+			return fmt.Errorf("--query: %w", err)
+		}
+	}
+	var program *script.Script
+	if *scriptPath != "" {
+		var err error
+		if program, err = script.Load(*scriptPath); err != nil {
+			return err
+		}
+	}
+
+	segbData, err := openAndDecode(flags.Arg(0), opts...)
+	if err != nil {
+		return err
+	}
+	annotations, err := loadAnnotations(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	stream := *streamName
+	if abs, err := filepath.Abs(flags.Arg(0)); err == nil && stream == "" && flags.Arg(0) != "-" {
+		stream = biome.StreamName(abs)
+	}
+
+	// Records, with their annotations and the changes of the script, are
+	// only built for a template, columns, a script or a query
+	var records map[int]export.Record
+	if tmpl != nil || columns != nil || program != nil || q != nil {
+		list := export.Records(flags.Arg(0), segbData, tmpl != nil)
+		export.Annotate(list, annotations)
+		if program != nil {
+			if list, err = applyScript(program, list, segbData); err != nil {
+				return err
+			}
+		}
+		if q != nil {
+			if list, err = applyQuery(q, list, segbData, decoder, stream); err != nil {
+				return err
+			}
+		}
+		if tmpl != nil {
+			if err := tmpl.execute(os.Stdout, list); err != nil {
+				return err
+			}
+			return checkCRCs(flags.Arg(0), segbData)
+		}
+		if columns != nil {
+			if err := writeColumns(os.Stdout, list, columns, *selection.csv); err != nil {
+				return err
+			}
+			return checkCRCs(flags.Arg(0), segbData)
+		}
+		records = make(map[int]export.Record, len(list))
+		for _, record := range list {
+			records[record.Index] = record
+		}
+	}
+
+	s := newStyle(os.Stdout, *noColor)
+	if stream != "" {
+		fmt.Printf("%s %s\n", s.bold("Stream:"), stream)
+	}
+	fmt.Printf("%s %v\n", s.bold("Version:"), segbData.Version)
+	fmt.Printf("%s %s\n", s.bold("Created:"), s.dim(formatTime(segbData.Created)))
+	fmt.Printf("%s %d\n", s.bold("Entries:"), len(segbData.Entries))
+	fmt.Printf("%s %d bytes\n", s.bold("Alignment:"), segbData.Alignment)
+	if *headerFields && segbData.HeaderUnknown != nil {
+		fields := v2.InterpretUnknown([16]byte(segbData.HeaderUnknown))
+		fmt.Printf("%s %s\n", s.bold("Unknown:"), fields.Hex)
+		fmt.Printf("  as float64: %v (%s, %s)\n", fields.Float64,
+			s.dim(formatTime(segb.CocoaTimestampToTime(fields.Float64[0]))),
+			s.dim(formatTime(segb.CocoaTimestampToTime(fields.Float64[1]))))
+		fmt.Printf("  as int32:   %v\n", fields.Int32)
+	}
+	if len(segbData.Errors) > 0 {
+		fmt.Printf("%s %d\n", s.bold("Skipped:"), len(segbData.Errors))
+		for _, entryErr := range segbData.Errors {
+			fmt.Println("  " + s.paint(sgrRed, entryErr.Error()))
+		}
+	}
+	fmt.Println()
+	for i, entry := range segbData.Entries {
+		record, kept := records[i]
+		if records != nil && !kept {
+			continue
+		}
+		fmt.Printf("%s %s  %s  %d bytes %s at 0x%x  %s",
+			s.bold(fmt.Sprintf("Entry %d", i)),
+			s.stateBadge(entry.State),
+			s.dim(formatTime(entry.Created)),
+			len(entry.Data),
+			entry.ContentType,
+			entry.Offset,
+			s.crcStatus(entry.CRCValid, entry.CRCRepaired),
+		)
+		if entry.Residual {
+			fmt.Print("  " + s.paint(sgrBoldRed, "residual"))
+		}
+		if *flagEntropy {
+			fmt.Print("  " + s.entropy(entry.Data))
+		}
+		if entry.Compression != segb.CompressionNone {
+			fmt.Print(s.dim(fmt.Sprintf("  %v, %d bytes stored", entry.Compression, len(entry.Raw))))
+		}
+		fmt.Println()
+		annotation := annotations.For(entry)
+		if records != nil {
+			annotation = segb.Annotation{Tags: record.Tags, Note: record.Note}
+		}
+		if !annotation.IsZero() {
+			printAnnotation(s, annotation)
+		}
+		printDerived(s, record.Derived)
+		if decoder != nil {
+			// Fall back to the hexdump for payloads that do not decode
+			data, err := decoder.DecodeJSON(stream, entry.Data)
+			if err == nil {
+				fmt.Println(string(data))
+				fmt.Println(s.dim("--------------------"))
+				continue
+			}
+			fmt.Println(s.dim(err.Error()))
+		}
+		if !*forceHex && printDecoded(s, stream, entry, false) {
+			fmt.Println(s.dim("--------------------"))
+			continue
+		}
+		if !*forceHex && textual(entry.ContentType) {
+			fmt.Println(renderText(entry.Data, entry.ContentType))
+		} else {
+			hexdump.Dump(os.Stdout, entry.Data, hexdump.Options{Color: s.color})
+		}
+
+		fmt.Println(s.dim("--------------------"))
+	}
+	return checkCRCs(flags.Arg(0), segbData)
+}
+
+// applyScript runs a --script over the records of the entries of a file,
+// returning those of the entries it keeps.
+func applyScript(program *script.Script, records []export.Record, s segb.Segb) ([]export.Record, error) {
+	kept := records[:0]
+	for _, record := range records {
+		keep, err := program.Apply(&record, s.Entries[record.Index].Data)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", record.Index, err)
+		}
+		if keep {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
+
+// applyQuery returns the records of the entries whose decoded payload, see
+// queryInput, matches a --query, with the record of each as $entry.
+func applyQuery(q *query.Query, records []export.Record, s segb.Segb, decoder *protoschema.Decoder, stream string) ([]export.Record, error) {
+	kept := records[:0]
+	for _, record := range records {
+		input := queryInput(record, s.Entries[record.Index], decoder, stream)
+		ok, err := q.Match(input, map[string]any{"entry": record})
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", record.Index, err)
+		}
+		if ok {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
+
+// queryInput returns the decoded form of a payload a --query runs against:
+// the value of the registered decoder of the entry, the message decoded
+// with --descriptors, or the payload itself for JSON and property lists.
+// It returns nil for payloads that decode none of these ways.
+func queryInput(record export.Record, entry segb.Entry, decoder *protoschema.Decoder, stream string) any {
+	if record.Decoded != nil {
+		return record.Decoded
+	}
+	if decoder != nil {
+		if data, err := decoder.DecodeJSON(stream, entry.Data); err == nil {
+			return json.RawMessage(data)
+		}
+	}
+	switch entry.ContentType {
+	case segb.ContentJSON:
+		if json.Valid(entry.Data) {
+			return json.RawMessage(entry.Data)
+		}
+	case segb.ContentBplist, segb.ContentKeyedArchive:
+		decode := plist.Parse
+		if entry.ContentType == segb.ContentKeyedArchive {
+			decode = plist.Unarchive
+		}
+		if value, err := decode(entry.Data); err == nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// printDerived prints the fields a --script derived for an entry, sorted
+// by name.
+func printDerived(s style, derived map[string]any) {
+	names := make([]string, 0, len(derived))
+	for name := range derived {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := json.Marshal(derived[name])
+		if err != nil {
+			value = []byte(err.Error())
+		}
+		fmt.Printf("%s %s\n", s.bold(name+":"), value)
+	}
+}
+
+// printDecoded prints the payload of an entry as decoded by the registered
+// decoder that matches it, see segb.RegisterDecoder, and reports whether it
+// decoded. A failure is printed dimmed. With spaced, a blank line goes
+// before anything printed.
+func printDecoded(s style, stream string, entry segb.Entry, spaced bool) bool {
+	name, value, err := segb.DecodePayload(stream, entry)
+	if name == "" {
+		return false
+	}
+	if spaced {
+		fmt.Println()
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.MarshalIndent(value, "", "  ")
+	}
+	if err != nil {
+		fmt.Println(s.dim(fmt.Sprintf("%s: %v", name, err)))
+		return false
+	}
+	fmt.Println(s.dim("Decoded by " + name + ":"))
+	fmt.Println(string(data))
+	return true
+}
+
+// printAnnotation prints the analyst tags and note of an entry.
+func printAnnotation(s style, annotation segb.Annotation) {
+	if len(annotation.Tags) > 0 {
+		fmt.Printf("%s %s\n", s.bold("Tags:"), strings.Join(annotation.Tags, ", "))
+	}
+	if annotation.Note != "" {
+		fmt.Printf("%s %s\n", s.bold("Note:"), annotation.Note)
+	}
+}
+
+// loadProtoDecoder loads the descriptor set and type map given to dump.
+// messageType, if set, applies to the streams the map does not cover.
+func loadProtoDecoder(descriptors, typeMap, messageType string) (*protoschema.Decoder, error) {
+	files, err := protoschema.LoadDescriptorSet(descriptors)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string)
+	if typeMap != "" {
+		if types, err = protoschema.LoadTypeMap(typeMap); err != nil {
+			return nil, err
+		}
+	}
+	if messageType != "" {
+		types[protoschema.AnyStream] = messageType
+	}
+	return protoschema.NewDecoder(files, types)
+}
+
+// textual reports whether payloads of a content type read best as text
+// rather than as a hexdump.
+func textual(contentType segb.ContentType) bool {
+	switch contentType {
+	case segb.ContentText, segb.ContentJSON, segb.ContentBplist, segb.ContentKeyedArchive:
+		return true
+	}
+	return false
+}
+
+// renderText renders a textual payload, indenting JSON and decoding
+// property lists, and keyed archives into their object graph, as JSON.
+func renderText(data []byte, contentType segb.ContentType) string {
+	switch contentType {
+	case segb.ContentJSON:
+		var indented bytes.Buffer
+		if json.Indent(&indented, data, "", "  ") == nil {
+			return indented.String()
+		}
+	case segb.ContentBplist, segb.ContentKeyedArchive:
+		decode := plist.Parse
+		if contentType == segb.ContentKeyedArchive {
+			decode = plist.Unarchive
+		}
+		if value, err := decode(data); err == nil {
+			if indented, err := json.MarshalIndent(value, "", "  "); err == nil {
+				return string(indented)
+			}
+		}
+	}
+	return printableText(data)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/bluefalconhd/segb/export"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIndexElastic(t *testing.T) {
+	t.Setenv("ELASTIC_API_KEY", "secret")
+	records := make([]export.Record, elasticBatchSize+500)
+	for i := range records {
+		records[i] = export.Record{File: "f", StableID: fmt.Sprint(i), State: "Written"}
+	}
+
+	var created bool
+	var bulks []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodPut && r.URL.EscapedPath() == "/es/case%231":
+			if created {
+				http.Error(w, `{"error":{"type":"resource_already_exists_exception"}}`, http.StatusBadRequest)
+				return
+			}
+			created = bytes.Contains(body, []byte(`"mappings"`))
+		case r.Method == http.MethodPost && r.URL.Path == "/es/_bulk":
+			if r.Header.Get("Content-Type") != "application/x-ndjson" {
+				http.Error(w, "bad content type", http.StatusBadRequest)
+				return
+			}
+			lines := 0
+			for scanner := bufio.NewScanner(bytes.NewReader(body)); scanner.Scan(); lines++ {
+			}
+			bulks = append(bulks, lines/2)
+			if strings.Contains(string(body), `"stable_id":"1400"`) {
+				fmt.Fprint(w, `{"errors":true,"items":[{"index":{"_id":"a","status":201}},{"index":{"_id":"b","error":{"type":"mapper_parsing_exception"}}}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"errors":false,"items":[]}`)
+		default:
+			http.Error(w, r.Method+" "+r.URL.EscapedPath(), http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	if err := indexElastic(server.URL+"/es/", "case#1", records[:elasticBatchSize]); err != nil {
+		t.Fatal(err)
+	}
+	if !created || len(bulks) != 1 || bulks[0] != elasticBatchSize {
+		t.Fatalf("created %v, bulk requests %v", created, bulks)
+	}
+
+	// The index exists now, and the second batch has a failing document
+	err := indexElastic(server.URL+"/es", "case#1", records)
+	if err == nil || !strings.Contains(err.Error(), "entries 1000 to 1499") || !strings.Contains(err.Error(), "document b") {
+		t.Errorf("got %v", err)
+	}
+	if len(bulks) != 3 || bulks[1] != elasticBatchSize || bulks[2] != 500 {
+		t.Errorf("bulk requests %v", bulks)
+	}
+
+	if err := indexElastic("ftp://localhost", "biome", records); err == nil {
+		t.Error("indexed into an ftp URL")
+	}
+}
+
+func TestBulkError(t *testing.T) {
+	for _, tc := range []struct {
+		body, want string
+	}{
+		{`{"errors":false,"items":[{"index":{"_id":"a","status":201}}]}`, ""},
+		{`{"errors":true,"items":[{"index":{"_id":"a","status":201}},{"create":{"_id":"b","error":{"reason":"bad"}}},{"index":{"_id":"c","error":{}}}]}`,
+			`2 of 3 documents failed, the first document b: {"reason":"bad"}`},
+		{`{"errors":true,"items":[]}`, "bulk response reports errors"},
+		{`<html>`, "bulk response: invalid character '<' looking for beginning of value"},
+	} {
+		err := bulkError([]byte(tc.body))
+		if got := fmt.Sprint(err); tc.want == "" && err != nil || tc.want != "" && got != tc.want {
+			t.Errorf("bulkError(%s) = %v; want %q", tc.body, err, tc.want)
+		}
+	}
+}
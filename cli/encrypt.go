@@ -0,0 +1,25 @@
+package main
+
+import (
+	"filippo.io/age"
+	"fmt"
+	"io"
+	"os"
+)
+
+// encryptTo returns a writer that encrypts what is written to w with age,
+// to the X25519 recipients ("age1...") listed one per line in the file at
+// path, as age -R reads them. Closing it finishes the encryption, but does
+// not close w.
+func encryptTo(w io.Writer, path string) (io.WriteCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	recipients, err := age.ParseRecipients(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return age.Encrypt(w, recipients...)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Exit codes. 2 is also used by the flag package for usage errors.
+const (
+	exitError     = 1 // Any other failure
+	exitUsage     = 2
+	exitBadMagic  = 3 // Not a SEGB file
+	exitCRC       = 4 // Entries failed their CRC check
+	exitTruncated = 5 // File ends before its structures do
+	exitIO        = 6 // File could not be opened or read
+	exitChanged   = 7 // Input changed during the run, under --guard
+)
+
+// fileError ties an error to the input file it occurred on, for
+// --errors-json. Its message is that of the wrapped error.
+type fileError struct {
+	path string
+	err  error
+}
+
+func (e *fileError) Error() string { return e.err.Error() }
+func (e *fileError) Unwrap() error { return e.err }
+
+// crcError reports entries whose payload does not match its checksum.
+type crcError struct {
+	path    string
+	entries []int // Indexes of the failing entries
+}
+
+func (e *crcError) Error() string {
+	return fmt.Sprintf("%s: %d entries failed their CRC check", e.path, len(e.entries))
+}
+
+// checkCRCs returns a crcError if any entry of s fails its CRC check.
+func checkCRCs(path string, s segb.Segb) error {
+	failures := s.VerifyAll()
+	if len(failures) == 0 {
+		return nil
+	}
+	failed := make([]int, len(failures))
+	for i, failure := range failures {
+		failed[i] = failure.Index
+	}
+	return &crcError{path: path, entries: failed}
+}
+
+// classify maps an error to its kind, as reported by --errors-json, and its
+// exit code.
+func classify(err error) (string, int) {
+	var crcErr *crcError
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, segb.ErrSourceChanged):
+		return "source_changed", exitChanged
+	case errors.As(err, &crcErr), errors.Is(err, segb.ErrCRCMismatch):
+		return "crc_mismatch", exitCRC
+	case errors.Is(err, segb.ErrUnsupportedVersion), errors.Is(err, v1.ErrInvalidMagic), errors.Is(err, v2.ErrInvalidMagic):
+		return "bad_magic", exitBadMagic
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return "truncated", exitTruncated
+	case errors.As(err, &pathErr):
+		return "io", exitIO
+	default:
+		return "error", exitError
+	}
+}
+
+// errorJSON is the object written to stderr by --errors-json.
+type errorJSON struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	File     string `json:"file,omitempty"`
+	Entries  []int  `json:"entries,omitempty"` // Failing entries of a CRC mismatch, or the entry that failed to parse
+	Offset   int64  `json:"offset,omitempty"`  // Where parsing failed
+	Context  string `json:"context,omitempty"` // Hex of the bytes around offset, starting at context_start
+	Start    int64  `json:"context_start,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// fail reports err on stderr, as text or JSON, and exits with its code.
+func fail(err error, asJSON bool) {
+	kind, code := classify(err)
+	var parseErr *segb.ParseError
+	isParseErr := errors.As(err, &parseErr)
+	if !asJSON {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if isParseErr && len(parseErr.Context) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%s", parseErr.Hexdump())
+		}
+		os.Exit(code)
+	}
+
+	report := errorJSON{Kind: kind, Message: err.Error(), ExitCode: code}
+	var fileErr *fileError
+	var crcErr *crcError
+	if errors.As(err, &crcErr) {
+		report.File = crcErr.path
+		report.Entries = crcErr.entries
+	} else if errors.As(err, &fileErr) {
+		report.File = fileErr.path
+	}
+	if isParseErr {
+		report.Entries = []int{parseErr.Entry}
+		report.Offset = parseErr.Offset
+		report.Context, report.Start = hex.EncodeToString(parseErr.Context), parseErr.Start
+	}
+	json.NewEncoder(os.Stderr).Encode(report)
+	os.Exit(code)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/segbtest"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// brokenFixtures writes the v1 fixture to dir with entry 1 claiming 1000
+// bytes, and with a flipped bit in the payload of entry 2, returning their
+// paths and the offset of entry 1.
+func brokenFixtures(t *testing.T, dir string) (string, string, int) {
+	t.Helper()
+	data, err := segbtest.Bytes(segb.SEGB_VERSION_1, segbtest.Entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := bytes.Index(data, segbtest.Entries[1].Data) - 32
+	truncated := bytes.Clone(data)
+	binary.LittleEndian.PutUint32(truncated[offset:], 1000)
+	flipped := bytes.Clone(data)
+	flipped[bytes.Index(data, segbtest.Entries[2].Data)] ^= 1
+
+	paths := []string{filepath.Join(dir, "truncated"), filepath.Join(dir, "flipped")}
+	for i, content := range [][]byte{truncated, flipped} {
+		if err := os.WriteFile(paths[i], content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths[0], paths[1], offset
+}
+
+func TestClassify(t *testing.T) {
+	dir := t.TempDir()
+	truncated, flipped, _ := brokenFixtures(t, dir)
+	v1Fixture, _ := fixtures(t)
+	data, err := os.ReadFile(v1Fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := map[string][]byte{
+		"empty":         nil,
+		"text":          []byte("not a SEGB file, just some text that goes on for a while"),
+		"header only":   data[:56],
+		"half a header": data[:60],
+	}
+	for name, content := range inputs {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	decode := func(name string) error {
+		path := name
+		if !filepath.IsAbs(name) {
+			path = filepath.Join(dir, name)
+		}
+		s, err := openAndDecode(path)
+		if err == nil {
+			err = checkCRCs(path, s)
+		}
+		return err
+	}
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		kind string
+		code int
+	}{
+		// An empty file has no SEGB header, rather than a truncated one
+		{"empty file", decode("empty"), "bad_magic", exitBadMagic},
+		{"text file", decode("text"), "bad_magic", exitBadMagic},
+		{"v1 magic", &fileError{"x", fmt.Errorf("decoding SEGB file: %w: SEGX", v1.ErrInvalidMagic)}, "bad_magic", exitBadMagic},
+		{"v2 magic", &fileError{"x", fmt.Errorf("decoding SEGB file: %w: SEGX", v2.ErrInvalidMagic)}, "bad_magic", exitBadMagic},
+		{"CRC check", decode(flipped), "crc_mismatch", exitCRC},
+		{"strict CRC", fmt.Errorf("entry 2: %w", segb.ErrCRCMismatch), "crc_mismatch", exitCRC},
+		// Files ending at or inside an entry header end with io.EOF
+		{"header only", decode("header only"), "truncated", exitTruncated},
+		{"half a header", decode("half a header"), "truncated", exitTruncated},
+		{"entry past the end", decode(truncated), "truncated", exitTruncated},
+		{"missing file", decode("missing"), "io", exitIO},
+		{"changed", fmt.Errorf("%w: x", segb.ErrSourceChanged), "source_changed", exitChanged},
+		// A change makes a CRC mismatch suspect too
+		{"changed and CRC", errors.Join(segb.ErrSourceChanged, &crcError{}), "source_changed", exitChanged},
+		{"other", errors.New("invalid entry"), "error", exitError},
+	} {
+		if kind, code := classify(tc.err); kind != tc.kind || code != tc.code {
+			t.Errorf("%s: classify(%v) = %s, %d; want %s, %d", tc.name, tc.err, kind, code, tc.kind, tc.code)
+		}
+	}
+}
+
+// TestFailProcess is not a test: it is the process TestFail runs, which
+// decodes the file named after "--" and fails with --errors-json.
+func TestFailProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	path := os.Args[len(os.Args)-1]
+	s, err := openAndDecode(path)
+	if err == nil {
+		err = checkCRCs(path, s)
+	}
+	if err == nil {
+		err = errors.New("no error")
+	}
+	fail(err, true)
+}
+
+func TestFail(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	truncated, flipped, offset := brokenFixtures(t, t.TempDir())
+	data, err := os.ReadFile(truncated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want errorJSON
+	}{
+		{truncated, errorJSON{
+			Kind:     "truncated",
+			File:     truncated,
+			Entries:  []int{1},
+			Offset:   int64(offset),
+			Context:  hex.EncodeToString(data[offset-32 : offset+32]),
+			Start:    int64(offset - 32),
+			ExitCode: exitTruncated,
+		}},
+		{flipped, errorJSON{Kind: "crc_mismatch", File: flipped, Entries: []int{2}, ExitCode: exitCRC}},
+	} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestFailProcess$", "--", tc.path)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != tc.want.ExitCode {
+			t.Errorf("%s: exit %v, want status %d", tc.path, err, tc.want.ExitCode)
+		}
+		var got errorJSON
+		if err := json.Unmarshal(stderr.Bytes(), &got); err != nil {
+			t.Errorf("%s: %v in %q", tc.path, err, stderr.String())
+			continue
+		}
+		tc.want.Message = got.Message
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) || got.Message == "" {
+			t.Errorf("%s: got %+v\nwant %+v", tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/export"
+	"github.com/bluefalconhd/segb/script"
+	"io"
+	"os"
+	"strings"
+)
+
+// exportFormats are the names of the output formats of export.
+var exportFormats = []string{"elastic", "jsonl", "plaso", "timesketch"}
+
+// exportWriter returns the writer of an export format; index is the
+// Elasticsearch index of bulk requests.
+func exportWriter(format, index string) (func(w io.Writer, records []export.Record) error, bool) {
+	switch format {
+	case "elastic":
+		return func(w io.Writer, records []export.Record) error {
+			return export.WriteElasticBulk(w, index, records)
+		}, true
+	case "jsonl":
+		return export.WriteJSONL, true
+	case "plaso":
+		return export.WritePlaso, true
+	case "timesketch":
+		return export.WriteTimesketch, true
+	}
+	return nil, false
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "jsonl", "output format: jsonl (export records), timesketch, plaso or elastic (Elasticsearch bulk API requests)")
+	output := flags.String("output", "", "file to write to (default: standard output)")
+	elasticURL := flags.String("elastic", "", "index the records into the Elasticsearch cluster at this URL instead of writing them")
+	index := flags.String("index", "biome", "Elasticsearch index for --elastic and --format elastic")
+	withData := flags.Bool("data", false, "include the payloads")
+	scriptPath := flags.String("script", "", "Starlark script defining process(entry), to filter entries and compute derived fields with")
+	parseFlags(flags, args)
+	if flags.NArg() == 0 || *elasticURL != "" && *output != "" {
+		usage()
+		os.Exit(exitUsage)
+	}
+	write, ok := exportWriter(*format, *index)
+	if !ok {
+		return fmt.Errorf("unknown export format %q (want one of %s)", *format, strings.Join(exportFormats, ", "))
+	}
+	var program *script.Script
+	if *scriptPath != "" {
+		var err error
+		if program, err = script.Load(*scriptPath); err != nil {
+			return err
+		}
+	}
+
+	var records []export.Record
+	err := decodeInputs(flags.Args(), func(path string, s segb.Segb) error {
+		annotations, err := loadAnnotations(path)
+		if err != nil {
+			return err
+		}
+		list := export.Records(path, s, *withData)
+		export.Annotate(list, annotations)
+		if program != nil {
+			if list, err = applyScript(program, list, s); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		records = append(records, list...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].Created = displayTime(records[i].Created)
+	}
+
+	if *elasticURL != "" {
+		if err := indexElastic(*elasticURL, *index, records); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d entries -> %s/%s\n", len(records), strings.TrimSuffix(*elasticURL, "/"), *index)
+		return nil
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+	}
+	w := bufio.NewWriter(out)
+	err = write(w, records)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if *output != "" {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "%d entries -> %s\n", len(records), *output)
+		}
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodersTable is the table of the configuration file mapping stream names
+// to the external programs decoding their payloads, as command lines split
+// like a shell splits words, or arrays of arguments:
+//
+//	[decoders]
+//	"Media.NowPlaying" = "python3 '/cases/My Decoders/now_playing.py'"
+//	"App.InFocus" = ["/cases/decoders/in_focus", "--compact"]
+const decodersTable = "decoders"
+
+// externalDecoderTimeout is how long an external decoder may run on one
+// payload before it is killed and the payload reported as not decoded.
+var externalDecoderTimeout = 30 * time.Second
+
+// externalDecoder decodes payloads with external programs, one per stream.
+// A program gets the payload on stdin and writes its decoding, a JSON
+// value, on stdout; a non-zero exit status is a failure, reported with
+// what it wrote on stderr. The stream, entry ID and content type are also
+// passed in the environment, as SEGB_STREAM, SEGB_ENTRY_ID and
+// SEGB_CONTENT_TYPE.
+type externalDecoder map[string][]string // Command line by stream name
+
+// decoderCommands returns the command lines of the [decoders] table.
+func decoderCommands(table any) (map[string][]string, error) {
+	values, ok := table.(map[string]any)
+	if !ok {
+		return nil, errors.New("not a table")
+	}
+	commands := make(map[string][]string)
+	for stream, value := range values {
+		var args []string
+		switch v := value.(type) {
+		case string:
+			var err error
+			if args, err = shellWords(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", stream, err)
+			}
+		case []any:
+			for _, arg := range v {
+				s, ok := arg.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: argument %v is not a string", stream, arg)
+				}
+				args = append(args, s)
+			}
+		default:
+			return nil, fmt.Errorf("%s: want a command line or an array of arguments", stream)
+		}
+		if len(args) == 0 || args[0] == "" {
+			return nil, fmt.Errorf("no command for %s", stream)
+		}
+		commands[stream] = args
+	}
+	return commands, nil
+}
+
+// shellWords splits a command line into words as a POSIX shell does,
+// honoring single and double quotes and backslashes, without expanding
+// anything.
+func shellWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case c == '\\':
+			if i++; i == len(line) {
+				return nil, errors.New("command line ends with a backslash")
+			}
+			word.WriteByte(line[i])
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			for i++; ; i++ {
+				if i == len(line) {
+					return nil, errors.New("unterminated double quote")
+				}
+				if line[i] == '"' {
+					break
+				}
+				// Only these keep a backslash from being literal
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\\\"$`", line[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// registerExternalDecoders registers the programs of the [decoders] table
+// of the configuration file as payload decoders.
+func registerExternalDecoders(c *config) {
+	if len(c.decoders) > 0 {
+		segb.RegisterDecoder("external", externalDecoder(c.decoders))
+	}
+}
+
+func (d externalDecoder) Match(entry segb.PayloadEntry) bool {
+	_, ok := d[entry.Stream]
+	return ok
+}
+
+func (d externalDecoder) Decode(entry segb.PayloadEntry) (any, error) {
+	args := d[entry.Stream]
+	ctx, cancel := context.WithTimeout(context.Background(), externalDecoderTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"SEGB_STREAM="+entry.Stream,
+		"SEGB_ENTRY_ID="+strconv.Itoa(entry.ID),
+		"SEGB_CONTENT_TYPE="+entry.ContentType.String(),
+	)
+	cmd.Stdin = bytes.NewReader(entry.Data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// Children left holding the output pipes do not hold up the decoding
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s: timed out after %v", args[0], externalDecoderTimeout)
+		}
+		var exitErr *exec.ExitError
+		if message := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && message != "" {
+			return nil, fmt.Errorf("%s: %v: %s", args[0], err, message)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	output := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(output) {
+		return nil, fmt.Errorf("%s: output is not a JSON value", args[0])
+	}
+	return json.RawMessage(output), nil
+}
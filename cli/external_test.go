@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess is not a test: it is the external decoder the other
+// tests run, as this test binary with the arguments after "--".
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	switch args[1] {
+	case "echo":
+		data, _ := io.ReadAll(os.Stdin)
+		json.NewEncoder(os.Stdout).Encode(map[string]string{
+			"payload": string(data),
+			"stream":  os.Getenv("SEGB_STREAM"),
+			"id":      os.Getenv("SEGB_ENTRY_ID"),
+			"type":    os.Getenv("SEGB_CONTENT_TYPE"),
+			"arg":     strings.Join(args[2:], "|"),
+		})
+	case "fail":
+		fmt.Fprintln(os.Stderr, "cannot decode this")
+		os.Exit(3)
+	case "invalid":
+		fmt.Println("not JSON")
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func helperDecoder(t *testing.T) externalDecoder {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	helper := []string{os.Args[0], "-test.run=^TestHelperProcess$", "--"}
+	return externalDecoder{
+		"Echo":    append(slices.Clip(helper), "echo", "two words"),
+		"Fail":    append(slices.Clip(helper), "fail"),
+		"Invalid": append(slices.Clip(helper), "invalid"),
+		"Hang":    append(slices.Clip(helper), "hang"),
+	}
+}
+
+func TestExternalDecoder(t *testing.T) {
+	decoder := helperDecoder(t)
+	entry := segb.PayloadEntry{Stream: "Echo", Entry: segb.Entry{ID: 42, Data: []byte("payload"), ContentType: segb.ContentText}}
+	if !decoder.Match(entry) || decoder.Match(segb.PayloadEntry{Stream: "Other"}) {
+		t.Fatal("Match() does not go by stream")
+	}
+	decoded, err := decoder.Decode(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(decoded.(json.RawMessage), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"payload": "payload", "stream": "Echo", "id": "42", "type": segb.ContentText.String(), "arg": "two words"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	saved := externalDecoderTimeout
+	externalDecoderTimeout = 500 * time.Millisecond
+	defer func() { externalDecoderTimeout = saved }()
+	for stream, want := range map[string]string{
+		"Fail":    "exit status 3: cannot decode this",
+		"Invalid": "output is not a JSON value",
+		"Hang":    "timed out after 500ms",
+	} {
+		entry.Stream = stream
+		if _, err := decoder.Decode(entry); err == nil || !strings.HasSuffix(err.Error(), want) {
+			t.Errorf("%s: got %v; want %q", stream, err, want)
+		}
+	}
+}
+
+func TestDecoderCommands(t *testing.T) {
+	c, err := loadConfig(writeConfig(t, `
+tz = "UTC"
+
+[decoders]
+"Media.NowPlaying" = "python3 '/cases/My Decoders/now_playing.py' --name \"a b\" c\\ d"
+"App.InFocus" = ["/cases/My Decoders/in_focus", "--compact"]
+`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"Media.NowPlaying": {"python3", "/cases/My Decoders/now_playing.py", "--name", "a b", "c d"},
+		"App.InFocus":      {"/cases/My Decoders/in_focus", "--compact"},
+	}
+	if fmt.Sprintf("%q", c.decoders) != fmt.Sprintf("%q", want) {
+		t.Errorf("decoders = %q", c.decoders)
+	}
+	if _, ok := c.tables[decodersTable]; ok {
+		t.Error("the decoders table is read as flags")
+	}
+
+	for name, text := range map[string]string{
+		"empty command":      "[decoders]\nx = ''",
+		"empty array":        "[decoders]\nx = []",
+		"unclosed quote":     "[decoders]\nx = \"decode 'file\"",
+		"unclosed dquote":    "[decoders]\nx = 'decode \"file'",
+		"trailing backslash": "[decoders]\nx = 'decode \\'",
+		"number":             "[decoders]\nx = 1",
+		"number argument":    "[decoders]\nx = ['decode', 1]",
+		"nested array":       "[decoders]\nx = [['decode']]",
+		"stream table":       "[decoders.x]\ny = 'decode'",
+		"not a table":        "decoders = 'decode'",
+	} {
+		if _, err := loadConfig(writeConfig(t, text), true); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestShellWords(t *testing.T) {
+	for line, want := range map[string][]string{
+		"":                  nil,
+		"  a  b\tc ":        {"a", "b", "c"},
+		`a 'b c' "d e"`:     {"a", "b c", "d e"},
+		`a'b'"c" d\ e`:      {"abc", "d e"},
+		`"\"\\ \$ \n" '\n'`: {`"\ $ \n`, `\n`},
+		`'' x`:              {"", "x"},
+	} {
+		got, err := shellWords(line)
+		if err != nil || fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+			t.Errorf("shellWords(%q) = %q, %v; want %q", line, got, err, want)
+		}
+	}
+}
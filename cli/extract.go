@@ -0,0 +1,204 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/export"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// indexName is the file the content-addressed mode of extract maps entries
+// to payloads in.
+const indexName = "index.jsonl"
+
+func runExtract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	output := flags.String("output", "", "directory to write the payloads to")
+	zipPath := flags.String("zip", "", "zip archive to write the payloads to, with the metadata of each entry and its creation time as modification time")
+	dedup := flags.Bool("dedup", false, "write each distinct payload once, named by its SHA-256, and map entries to them in "+indexName)
+	encryptFile := flags.String("encrypt-to", "", "encrypt the --zip archive with age to the recipients in this file, one per line")
+	parseFlags(flags, args)
+	if (*output == "") == (*zipPath == "") || *encryptFile != "" && *zipPath == "" || flags.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	var w payloadWriter
+	if *zipPath != "" {
+		file, err := os.Create(*zipPath)
+		if err != nil {
+			return err
+		}
+		z := &zipWriter{file: file, names: make(map[string]bool)}
+		var archive io.Writer = file
+		if *encryptFile != "" {
+			if z.encrypted, err = encryptTo(file, *encryptFile); err != nil {
+				file.Close()
+				os.Remove(*zipPath)
+				return err
+			}
+			archive = z.encrypted
+		}
+		z.zw = zip.NewWriter(archive)
+		w = z
+		*output = *zipPath
+	} else {
+		if err := os.MkdirAll(*output, 0o755); err != nil {
+			return err
+		}
+		w = dirWriter(*output)
+	}
+
+	var err error
+	if *dedup {
+		err = extractDeduplicated(w, *output, flags.Args())
+	} else {
+		err = extractEntries(w, flags.Args())
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// extractEntries writes the payload of every entry of paths to a file of
+// its own, named after the file and index of the entry. Zip archives also
+// get the export record of each entry, in a JSON file next to its payload.
+func extractEntries(w payloadWriter, paths []string) error {
+	_, withMetadata := w.(*zipWriter)
+	return decodeInputs(paths, func(path string, s segb.Segb) error {
+		base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if path == "-" {
+			base = "stdin"
+		}
+		for i, entry := range s.Entries {
+			name := fmt.Sprintf("%s-entry%d", base, i)
+			if err := w.write(name+entry.ContentType.Extension(), entry.Data, entry.Created); err != nil {
+				return err
+			}
+			if withMetadata {
+				metadata, err := json.MarshalIndent(export.NewRecord(path, i, entry, false), "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := w.write(name+".json", append(metadata, '\n'), entry.Created); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("%s  entry %d  %s, %d bytes  -> %s\n", path, i, entry.ContentType, len(entry.Data), name+entry.ContentType.Extension())
+		}
+		return nil
+	})
+}
+
+// extractDeduplicated writes the payloads of the entries of paths by
+// content: each distinct payload once, in a file named by its SHA-256, and
+// the export record of every entry to the index, its sha256 field naming
+// the file of its payload. Payloads already written, to a directory by an
+// earlier run, are not written again.
+func extractDeduplicated(w payloadWriter, output string, paths []string) error {
+	dedup := segb.NewDeduplicator()
+	var records []export.Record
+	err := decodeInputs(paths, func(path string, s segb.Segb) error {
+		dedup.Add(path, s)
+		records = append(records, export.Records(path, s, false)...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	written := 0
+	for _, payload := range dedup.Payloads() {
+		name := payload.Hash.String()
+		if exists, err := w.exists(name); err != nil {
+			return err
+		} else if exists {
+			continue
+		}
+		if err := w.write(name, payload.Data, payload.Sources[0].Entry.Created); err != nil {
+			return err
+		}
+		written++
+	}
+
+	var index bytes.Buffer
+	if err := export.WriteJSONL(&index, records); err != nil {
+		return err
+	}
+	if err := w.write(indexName, index.Bytes(), time.Now()); err != nil {
+		return err
+	}
+	fmt.Printf("%d entries, %d distinct payloads (%d new) -> %s\n", len(records), dedup.Len(), written, output)
+	return nil
+}
+
+// payloadWriter is where extract writes files: a directory or a zip archive.
+type payloadWriter interface {
+	write(name string, data []byte, modTime time.Time) error
+	exists(name string) (bool, error)
+	Close() error
+}
+
+// dirWriter writes files to a directory. Modification times are left to
+// the time of writing.
+type dirWriter string
+
+func (d dirWriter) write(name string, data []byte, _ time.Time) error {
+	return os.WriteFile(filepath.Join(string(d), name), data, 0o644)
+}
+
+func (d dirWriter) exists(name string) (bool, error) {
+	_, err := os.Stat(filepath.Join(string(d), name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d dirWriter) Close() error { return nil }
+
+// zipWriter writes files to a new zip archive, encrypted or not.
+type zipWriter struct {
+	file      *os.File
+	encrypted io.WriteCloser // Encrypts the archive into file, see encryptTo
+	zw        *zip.Writer
+	names     map[string]bool
+}
+
+func (z *zipWriter) write(name string, data []byte, modTime time.Time) error {
+	w, err := z.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	z.names[name] = true
+	_, err = w.Write(data)
+	return err
+}
+
+func (z *zipWriter) exists(name string) (bool, error) {
+	return z.names[name], nil
+}
+
+func (z *zipWriter) Close() error {
+	err := z.zw.Close()
+	if z.encrypted != nil {
+		if closeErr := z.encrypted.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := z.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"filippo.io/age"
+	"fmt"
+	"github.com/bluefalconhd/segb/export"
+	"github.com/bluefalconhd/segb/segbtest"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixtures writes the segbtest fixtures to a temporary directory, returning
+// their paths.
+func fixtures(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := segbtest.WriteFixtures(dir); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, segbtest.V1Fixture), filepath.Join(dir, segbtest.V2Fixture)
+}
+
+// checkArchive checks that an extract --zip archive holds the payload and
+// metadata of every fixture entry, dated by its creation time.
+func checkArchive(t *testing.T, data []byte) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if len(files) != 2*len(segbtest.Entries) {
+		t.Errorf("%d files in the archive, want %d", len(files), 2*len(segbtest.Entries))
+	}
+	for i, entry := range segbtest.Entries {
+		name := fmt.Sprintf("segb_version1-entry%d", i)
+		for _, suffix := range []string{".txt", ".json"} {
+			f := files[name+suffix]
+			if f == nil {
+				t.Errorf("no %s", name+suffix)
+				continue
+			}
+			if !f.Modified.Equal(entry.Created) {
+				t.Errorf("%s modified %v, want %v", f.Name, f.Modified, entry.Created)
+			}
+			content := readZipFile(t, f)
+			if suffix == ".txt" && !bytes.Equal(content, entry.Data) {
+				t.Errorf("%s = %q, want %q", f.Name, content, entry.Data)
+			}
+			if suffix == ".json" {
+				var record export.Record
+				if err := json.Unmarshal(content, &record); err != nil || record.Index != i || !record.Created.Equal(entry.Created) {
+					t.Errorf("%s: %+v, %v", f.Name, record, err)
+				}
+			}
+		}
+	}
+}
+
+func readZipFile(t *testing.T, f *zip.File) []byte {
+	t.Helper()
+	r, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestExtractZip(t *testing.T) {
+	v1, _ := fixtures(t)
+	path := filepath.Join(t.TempDir(), "entries.zip")
+	if err := runExtract([]string{"--zip", path, v1}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkArchive(t, data)
+}
+
+func TestExtractEncrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	recipients := filepath.Join(dir, "recipients.txt")
+	text := "# Report key\n" + identity.Recipient().String() + "\n"
+	if err := os.WriteFile(recipients, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v1, _ := fixtures(t)
+	path := filepath.Join(dir, "entries.zip.age")
+	if err := runExtract([]string{"--zip", path, "--encrypt-to", recipients, v1}); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, segbtest.Entries[0].Data) {
+		t.Error("payload written in the clear")
+	}
+	r, err := age.Decrypt(bytes.NewReader(encrypted), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkArchive(t, data)
+
+	// Without valid recipients nothing is left behind
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte("ssh-rsa AAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	failed := filepath.Join(dir, "failed.zip.age")
+	if err := runExtract([]string{"--zip", failed, "--encrypt-to", bad, v1}); err == nil {
+		t.Error("invalid recipients: no error")
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Errorf("archive left behind: %v", err)
+	}
+}
+
+func TestExtractDedup(t *testing.T) {
+	// The two fixtures hold the same entries
+	v1, v2 := fixtures(t)
+	output := filepath.Join(t.TempDir(), "payloads")
+	for run := 0; run < 2; run++ {
+		if err := runExtract([]string{"--dedup", "--output", output, v1, v2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := os.ReadDir(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(segbtest.Entries)+1 {
+		t.Errorf("%d files, want %d payloads and the index", len(names), len(segbtest.Entries))
+	}
+	index, err := os.Open(filepath.Join(output, indexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	records := 0
+	for scanner := bufio.NewScanner(index); scanner.Scan(); records++ {
+		var record export.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		payload, err := os.ReadFile(filepath.Join(output, record.SHA256))
+		if err != nil || !bytes.Equal(payload, segbtest.Entries[record.Index].Data) {
+			t.Errorf("%s entry %d: payload %q, %v", record.File, record.Index, payload, err)
+		}
+	}
+	if records != 2*len(segbtest.Entries) {
+		t.Errorf("%d records, want %d", records, 2*len(segbtest.Entries))
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb/biome"
+	"github.com/bluefalconhd/segb/protoschema"
+	"github.com/bluefalconhd/segb/timeconv"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// fieldRow is a line of the field statistics in JSON form.
+type fieldRow struct {
+	Stream   string  `json:"stream"`
+	Field    string  `json:"field"`
+	Type     string  `json:"type"`
+	Presence float64 `json:"presence"`
+	Count    int     `json:"count"`
+	Range    string  `json:"range,omitempty"`
+	Notes    string  `json:"notes,omitempty"`
+}
+
+func runFields(args []string) error {
+	flags := flag.NewFlagSet("fields", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print one JSON object per field instead of a table")
+	profile := flags.String("profile", "", "directory layout of the extraction: itunes-backup, cellebrite, graykey or filesystem (default: detected)")
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	root := flags.Arg(0)
+	streams, err := enumerateStreams(root, *profile)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return fmt.Errorf("%s: no Biome streams found", root)
+	}
+
+	var rows []fieldRow
+	for _, stream := range streams {
+		message := protoschema.NewMessage()
+		for _, segment := range stream.Segments {
+			entries, err := biome.DecodeSegment(segment)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", segment.Path, err)
+				continue
+			}
+			for _, entry := range entries {
+				// Payloads that are not protobuf do not count
+				message.Add(entry.Data)
+			}
+		}
+		for _, stat := range message.Stats() {
+			rows = append(rows, fieldRow{
+				Stream:   stream.Name,
+				Field:    stat.Path,
+				Type:     stat.Field.Type(),
+				Presence: stat.Presence,
+				Count:    stat.Field.Count,
+				Range:    stat.Field.Range(),
+				Notes:    fieldNotes(stat.Field),
+			})
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STREAM\tFIELD\tTYPE\tPRESENCE %\tCOUNT\tRANGE\tNOTES")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.1f\t%d\t%s\t%s\n",
+			row.Stream, row.Field, row.Type, 100*row.Presence, row.Count, row.Range, row.Notes)
+	}
+	return w.Flush()
+}
+
+// fieldNotes lists what stands out about a field, as hints to its meaning.
+func fieldNotes(field *protoschema.Field) string {
+	var notes []string
+	if field.Repeated {
+		notes = append(notes, "repeated")
+	}
+	if len(field.WireTypes) > 1 {
+		notes = append(notes, "mixed wire types")
+	}
+	if kind := field.TimestampKind(); kind != timeconv.None {
+		notes = append(notes, kind.String()+" timestamps")
+	}
+	if field.Type() == "int64" && field.Min == field.Max {
+		notes = append(notes, "constant")
+	}
+	return strings.Join(notes, ", ")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// infoRow is a line of info output in JSON form.
+type infoRow struct {
+	File        string    `json:"file"`
+	Version     string    `json:"version"`
+	Compression string    `json:"compression,omitempty"`
+	Size        int64     `json:"size"`
+	Entries     int       `json:"entries"`
+	Created     time.Time `json:"created"`
+}
+
+func runInfo(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print one JSON object per file instead of a table")
+	parseFlags(flags, args)
+	if flags.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	rows := make([]infoRow, 0, flags.NArg())
+	for _, filename := range flags.Args() {
+		meta, err := readInfo(filename)
+		if err != nil {
+			return err
+		}
+		row := infoRow{
+			File:    filename,
+			Version: meta.Version.String(),
+			Size:    meta.Size,
+			Entries: meta.EntryCount,
+			Created: displayTime(meta.Created),
+		}
+		if meta.Compression != segb.CompressionNone {
+			row.Compression = meta.Compression.String()
+		}
+		rows = append(rows, row)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tVERSION\tCOMPRESSION\tSIZE\tENTRIES\tCREATED")
+	for _, row := range rows {
+		compression := row.Compression
+		if compression == "" {
+			compression = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", row.File, row.Version, compression, row.Size, row.Entries, formatTime(row.Created))
+	}
+	return w.Flush()
+}
+
+// readInfo reads the metadata of the named file, which for a v2 file takes
+// only its header: a URL costs a single request.
+func readInfo(filename string) (segb.Metadata, error) {
+	file, closeFile, err := openInput(filename)
+	if err != nil {
+		return segb.Metadata{}, &fileError{filename, fmt.Errorf("opening file: %w", err)}
+	}
+	defer closeFile()
+
+	var stream io.ReadSeeker = file
+	if retries.Attempts > 1 {
+		stream = segb.NewRetryReader(file, retries)
+	}
+	meta, err := segb.ReadMetadata(stream)
+	if err != nil {
+		return segb.Metadata{}, &fileError{filename, fmt.Errorf("reading SEGB header: %w", err)}
+	}
+	return meta, nil
+}
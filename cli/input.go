@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/archive"
+	"github.com/bluefalconhd/segb/biome"
+	"github.com/bluefalconhd/segb/remote"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stdinMemoryLimit is how much piped input is buffered in memory before
+// spilling to a temporary file.
+const stdinMemoryLimit = 64 << 20
+
+// guard, set by --guard, hashes input files as they are opened so that main
+// can tell whether they changed by the end of the run.
+var guard *segb.IntegrityGuard
+
+// retries, set by --retries, is how often reads of input files are tried
+// before giving up.
+var retries segb.RetryPolicy
+
+// cacheDir, set by --cache-dir, is where blocks of remote inputs are kept
+// across runs.
+var cacheDir string
+
+// remoteOptions returns the options remote inputs are opened with.
+func remoteOptions() []remote.Option {
+	if cacheDir == "" {
+		return nil
+	}
+	return []remote.Option{remote.WithCacheDir(cacheDir)}
+}
+
+// setRetries sets retries from --retries, with a backoff from 100ms up to
+// 10s between tries.
+func setRetries(value string) error {
+	attempts, err := strconv.Atoi(value)
+	if err != nil || attempts < 0 {
+		return fmt.Errorf("invalid retry count %q", value)
+	}
+	retries = segb.RetryPolicy{
+		Attempts:   attempts + 1,
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+		OnRetry: func(err error, attempt int) {
+			fmt.Fprintf(os.Stderr, "read failed (%v), retrying (%d of %d)\n", err, attempt, attempts)
+		},
+	}
+	return nil
+}
+
+// openFile opens an input file read-only, through the guard if there is one.
+func openFile(name string) (*os.File, error) {
+	if guard != nil {
+		return guard.Open(name)
+	}
+	return os.Open(name)
+}
+
+// loadOptions returns the options directories and archives of inputs are
+// loaded with.
+func loadOptions() []segb.LoadOption {
+	opts := []segb.LoadOption{segb.WithLoadRetry(retries)}
+	if guard != nil {
+		opts = append(opts, segb.WithGuard(guard))
+	}
+	return opts
+}
+
+// guardArchive hashes an archive through the guard, if there is one, so that
+// the check at the end of the run covers the members read from it.
+func guardArchive(path string) error {
+	if guard == nil {
+		return nil
+	}
+	file, err := guard.Open(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// openBucket returns the bucket of an s3:// or gs:// URL and the key the
+// URL names in it, or false for other names. S3 is configured by the
+// variables of the AWS tools (AWS_REGION, AWS_ACCESS_KEY_ID and so on), GCS
+// by an access token in GOOGLE_OAUTH_ACCESS_TOKEN.
+func openBucket(name string) (*remote.Bucket, string, bool) {
+	scheme, rest, ok := strings.Cut(name, "://")
+	if !ok || (scheme != "s3" && scheme != "gs") {
+		return nil, "", false
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	if scheme == "gs" {
+		config := remote.GCSConfig{Token: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+		return remote.NewGCSBucket(context.Background(), config, bucket, remoteOptions()...), key, true
+	}
+
+	config := remote.S3Config{
+		Region:          cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"),
+		Endpoint:        cmp.Or(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	return remote.NewS3Bucket(context.Background(), config, bucket, remoteOptions()...), key, true
+}
+
+// isArchive reports whether path is a zip or tar archive.
+func isArchive(path string) bool {
+	format, err := archive.Detect(path)
+	return err == nil && format != archive.FormatNone
+}
+
+// openInput opens a named input file, standard input when name is "-", a
+// remote file when name is an http, https, s3 or gs URL, or a member of an
+// archive when name goes on past a zip or tar file, as in
+// "extraction.zip/private/var/...". The returned close function releases
+// the file and any temporary copy.
+func openInput(name string) (io.ReadSeeker, func() error, error) {
+	if bucket, key, ok := openBucket(name); ok {
+		file, err := bucket.OpenObject(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, func() error { return nil }, nil
+	}
+	if isURL(name) {
+		file, err := remote.Open(context.Background(), name, remoteOptions()...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, func() error { return nil }, nil
+	}
+	if _, err := os.Stat(name); err != nil && name != "-" {
+		if path, member, ok := archive.SplitPath(name); ok {
+			data, err := archive.ReadFile(path, member)
+			if err != nil {
+				return nil, nil, err
+			}
+			return bytes.NewReader(data), func() error { return nil }, nil
+		}
+	}
+	if name != "-" {
+		file, err := openFile(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, file.Close, nil
+	}
+	return bufferStdin(os.Stdin)
+}
+
+// bufferStdin makes piped input seekable, which decoding requires. Small
+// inputs are kept in memory, larger ones are copied to a temporary file.
+func bufferStdin(r io.Reader) (io.ReadSeeker, func() error, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, stdinMemoryLimit+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(buf) <= stdinMemoryLimit {
+		return bytes.NewReader(buf), func() error { return nil }, nil
+	}
+
+	tmp, err := os.CreateTemp("", "segb-stdin-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() error {
+		tmp.Close()
+		return os.Remove(tmp.Name())
+	}
+
+	if _, err := tmp.Write(buf); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return tmp, cleanup, nil
+}
+
+// isURL reports whether name is an http, https, s3 or gs URL.
+func isURL(name string) bool {
+	scheme, _, ok := strings.Cut(name, "://")
+	return ok && (scheme == "http" || scheme == "https" || scheme == "s3" || scheme == "gs")
+}
+
+// decodeInputs calls fn with each SEGB file given as arguments decoded, and
+// with those below the directories and in the archives among them, skipping
+// Biome tombstones. Arguments are anything openInput takes. Files below
+// directories or in archives that fail to decode are reported on stderr and
+// skipped.
+func decodeInputs(paths []string, fn func(path string, s segb.Segb) error) error {
+	for _, path := range paths {
+		corpus, err := loadCorpus(path)
+		if err != nil {
+			return err
+		}
+		if corpus == nil {
+			s, err := openAndDecode(path)
+			if err != nil {
+				return err
+			}
+			if err := fn(path, s); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, file := range corpus.Paths() {
+			result := corpus.Files[file]
+			if segment, ok := biome.ParsePath(file); ok && segment.Location == biome.LocationTombstone {
+				continue
+			}
+			if result.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", file, result.Err)
+				continue
+			}
+			if err := fn(file, result.Segb); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadCorpus decodes every SEGB file below path if it is a directory, or in
+// it if it is a zip or tar archive. It returns nil for any other input, a
+// single file for openInput: standard input, a URL, a member of an archive
+// or a plain file.
+func loadCorpus(path string) (*segb.Corpus, error) {
+	if path == "-" || isURL(path) {
+		return nil, nil
+	}
+	if isArchive(path) {
+		if err := guardArchive(path); err != nil {
+			return nil, &fileError{path, err}
+		}
+		corpus, err := archive.Load(path, loadOptions()...)
+		if err != nil {
+			return nil, &fileError{path, err}
+		}
+		return corpus, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if _, _, ok := archive.SplitPath(path); ok {
+			return nil, nil
+		}
+		return nil, &fileError{path, err}
+	}
+	if !info.IsDir() {
+		return nil, nil
+	}
+	corpus, err := segb.LoadDir(path, loadOptions()...)
+	if err != nil {
+		return nil, &fileError{path, err}
+	}
+	return corpus, nil
+}
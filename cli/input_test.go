@@ -0,0 +1,116 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/segbtest"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeZip writes an archive of the named files, stored under their base
+// names, to a temporary directory.
+func writeZip(t *testing.T, paths ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "extraction.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zw := zip.NewWriter(file)
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := zw.Create(filepath.Base(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDecodeInputs(t *testing.T) {
+	v1, v2 := fixtures(t)
+	zipped := writeZip(t, v1, v2)
+	stdin, err := os.Open(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+
+	var got []string
+	err = decodeInputs([]string{"-", zipped, zipped + "/" + segbtest.V1Fixture, filepath.Dir(v1)}, func(path string, s segb.Segb) error {
+		if len(s.Entries) != len(segbtest.Entries) {
+			t.Errorf("%s: %d entries, want %d", path, len(s.Entries), len(segbtest.Entries))
+		}
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"-",
+		zipped + "/" + segbtest.V1Fixture,
+		zipped + "/" + segbtest.V2Fixture,
+		zipped + "/" + segbtest.V1Fixture,
+		v1,
+		v2,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("decoded %q, want %q", got, want)
+	}
+
+	if err := decodeInputs([]string{filepath.Join(filepath.Dir(v1), "missing")}, func(string, segb.Segb) error { return nil }); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v", err)
+	}
+}
+
+func TestDecodeInputsGuard(t *testing.T) {
+	v1, _ := fixtures(t)
+	dir := filepath.Dir(v1)
+	zipped := writeZip(t, v1)
+	guard = segb.NewIntegrityGuard()
+	defer func() { guard = nil }()
+
+	// Another program appends to a file below the directory mid-run
+	err := decodeInputs([]string{zipped, dir}, func(path string, s segb.Segb) error {
+		if path != v1 {
+			return nil
+		}
+		file, err := os.OpenFile(v1, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = file.WriteString("appended")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = guard.Check()
+	if kind, code := classify(err); code != exitChanged || kind != "source_changed" {
+		t.Errorf("classify(%v) = %s, %d; want source_changed, %d", err, kind, code, exitChanged)
+	}
+	if !strings.Contains(err.Error(), v1) || strings.Contains(err.Error(), zipped) {
+		t.Errorf("Check() = %v; want only %s changed", err, v1)
+	}
+}
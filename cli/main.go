@@ -1,75 +1,200 @@
 package main
 
-// Just a simple CLI tool to demonstrate the use of the segb-go library.
-// All it does is take in a SEGB file and print out the contents.
+// Just a simple CLI tool to demonstrate the use of the segb library.
+// Without a command it takes in a SEGB file and prints out the contents;
+// the other commands are listed in usage below.
 
 import (
-	"flag"
 	"fmt"
 	"github.com/bluefalconhd/segb"
 	"os"
+	"sort"
+	"strings"
 )
 
-func PrettyHexdump(data []byte) {
-	for i := 0; i < len(data); i += 16 {
-		fmt.Printf("%08x: ", i)
-		for j := 0; j < 16; j++ {
-			if i+j < len(data) {
-				fmt.Printf("%02x ", data[i+j])
-			} else {
-				fmt.Print("   ")
-			}
-		}
-		fmt.Print(" ")
-		for j := 0; j < 16; j++ {
-			if i+j < len(data) {
-				if data[i+j] >= 32 && data[i+j] <= 126 {
-					fmt.Printf("%c", data[i+j])
-				} else {
-					fmt.Print(".")
-				}
-			}
-		}
-		fmt.Println()
-	}
+// command is a CLI subcommand. run receives the arguments following the
+// command name.
+type command struct {
+	usage   string
+	summary string
+	run     func(args []string) error
 }
 
-func main() {
-	// Parse the command line arguments
-	flag.Parse()
+var commands map[string]command
 
-	// Get the filename from the command line arguments
-	filename := flag.Arg(0)
+func init() {
+	commands = map[string]command{
+		"anonymize":   {"anonymize [flags] FILE", "write a copy of a SEGB file with random payloads and shifted times", runAnonymize},
+		"carve":       {"carve --output DIR IMAGE", "recover SEGB files and lone entries from a disk image", runCarve},
+		"changes":     {"changes [--json] OLD NEW", "report entries added, deleted or gone between two acquisitions", runChanges},
+		"classify":    {"classify FILE...", "print the content type of each entry and a histogram", runClassify},
+		"cmp":         {"cmp FILE N M", "compare the payloads of two entries byte by byte and field by field", runCmp},
+		"dump":        {"dump [flags] FILE", "print every entry of a SEGB file (default)", runDump},
+		"export":      {"export [flags] PATH...", "write the entries of SEGB files, or below directories, as records, Timesketch or Plaso events, or to Elasticsearch", runExport},
+		"extract":     {"extract [flags] PATH...", "write the entry payloads of SEGB files, or below directories, to files", runExtract},
+		"info":        {"info [--json] FILE...", "print the version, size, entry count and creation time of SEGB files", runInfo},
+		"manifest":    {"manifest [verify] FILE...", "record the hashes, sizes and times of files and entries, signed or not", runManifest},
+		"media":       {"media --output DIR FILE...", "extract images and videos embedded in entry payloads", runMedia},
+		"redact":      {"redact [flags] FILE", "write a copy of a SEGB file with entry payloads blanked out", runRedact},
+		"report":      {"report [flags] DIR", "summarize every Biome stream below DIR, in any extraction layout", runReport},
+		"fields":      {"fields [flags] DIR", "tabulate protobuf fields per Biome stream below DIR", runFields},
+		"rpc":         {"rpc [--listen ADDR] DIR", "serve the gRPC parsing service for files below DIR", runRPC},
+		"schema":      {"schema [--name N] PATH...", "infer a .proto definition from the entry payloads", runSchema},
+		"scan":        {"scan --rules FILE FILE...", "report YARA rule hits in entry payloads", runScan},
+		"show":        {"show [flags] FILE --entry N", "show one entry, by index, stable ID or --id, with its metadata, hexdump and decoded payload", runShow},
+		"serve":       {"serve [--listen ADDR] DIR", "browse a directory of SEGB files over HTTP", runServe},
+		"strings":     {"strings [-n LEN] FILE", "print printable strings of each entry payload", runStrings},
+		"sysdiagnose": {"sysdiagnose [--json] TARBALL", "print the entries of the Biome streams in a sysdiagnose archive", runSysdiagnose},
+		"tag":         {"tag FILE N [TAG...]", "add or remove tags and a note on an entry, kept next to the file", runTag},
+		"tui":         {"tui FILE", "browse the entries of a SEGB file interactively", runTUI},
+	}
+}
 
-	// Open the file
-	file, err := os.Open(filename)
-	if err != nil {
-		fmt.Printf("Error opening file: %v\n", err)
-		return
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: segb [COMMAND] [flags] FILE\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-28s %s\n", commands[name].usage, commands[name].summary)
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			fmt.Printf("Error closing file: %v\n", err)
+	fmt.Fprintf(os.Stderr, "\nUse - as FILE to read from standard input, or an http(s) URL to read a file served with range requests.\n")
+	fmt.Fprintf(os.Stderr, "FILE may also go on into a zip or tar archive, as in extraction.zip/private/var/..., and OLD, NEW and\n")
+	fmt.Fprintf(os.Stderr, "the DIR of serve may be an archive, whose SEGB members are decoded without extracting it.\n")
+	fmt.Fprintf(os.Stderr, "FILE may also be an s3://BUCKET/KEY or gs://BUCKET/OBJECT URL, and the DIR of serve an s3:// or gs:// prefix;\n")
+	fmt.Fprintf(os.Stderr, "credentials come from the AWS_* environment variables and GOOGLE_OAUTH_ACCESS_TOKEN.\n")
+	fmt.Fprintf(os.Stderr, "Pass --cache-dir DIR to keep the blocks read from URLs in DIR, so later runs fetch little more than the first one.\n")
+	fmt.Fprintf(os.Stderr, "Run 'segb COMMAND -h' for the flags of a command.\n")
+	fmt.Fprintf(os.Stderr, "\nPass --errors-json to report failures as a JSON object on stderr.\n")
+	fmt.Fprintf(os.Stderr, "Pass --tz ZONE (e.g. America/New_York, or local) to show timestamps in a time zone, --utc for UTC (default),\n")
+	fmt.Fprintf(os.Stderr, "and --time-format FORMAT to print them as rfc3339 (default), unix, cocoa or with a Go time layout.\n")
+	fmt.Fprintf(os.Stderr, "Pass --guard to open inputs strictly read-only and fail if any changed during the run.\n")
+	fmt.Fprintf(os.Stderr, "Pass --retries N to retry failed reads N times, backing off, for flaky mounts and media.\n")
+	fmt.Fprintf(os.Stderr, "Defaults for these and for the flags of commands are read from ~/.config/segb/config.toml, or from --config FILE.\n")
+	fmt.Fprintf(os.Stderr, "Exit codes: %d error, %d usage, %d bad magic, %d CRC mismatch, %d truncated file, %d I/O error, %d input changed\n",
+		exitError, exitUsage, exitBadMagic, exitCRC, exitTruncated, exitIO, exitChanged)
+}
+
+func main() {
+	// --config, --errors-json, --guard, --retries, --cache-dir, --tz, --utc
+	// and --time-format apply to every command, wherever they are given
+	var args []string
+	errorsJSON, guarded := false, false
+	configPath, timeZone, timeFormat, retryCount := "", "", "", ""
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if !strings.HasPrefix(arg, "-") {
+			name = ""
+		}
+		switch {
+		case name == "config" && hasValue:
+			configPath = value
+		case name == "config" && i+1 < len(os.Args):
+			i++
+			configPath = os.Args[i]
+		case name == "errors-json" && !hasValue:
+			errorsJSON = true
+		case name == "guard" && !hasValue:
+			guarded = true
+		case name == "cache-dir" && hasValue:
+			cacheDir = value
+		case name == "cache-dir" && i+1 < len(os.Args):
+			i++
+			cacheDir = os.Args[i]
+		case name == "retries" && hasValue:
+			retryCount = value
+		case name == "retries" && i+1 < len(os.Args):
+			i++
+			retryCount = os.Args[i]
+		case name == "utc" && !hasValue:
+			timeZone = "UTC"
+		case name == "tz" && hasValue:
+			timeZone = value
+		case name == "tz" && i+1 < len(os.Args):
+			i++
+			timeZone = os.Args[i]
+		case name == "time-format" && hasValue:
+			timeFormat = value
+		case name == "time-format" && i+1 < len(os.Args):
+			i++
+			timeFormat = os.Args[i]
+		default:
+			args = append(args, arg)
 		}
-	}(file)
+	}
 
-	// Decode the SEGB file
-	segbData, err := segb.Decode(file)
+	// The configuration file sets the defaults of global flags not given,
+	// and external payload decoders
+	var err error
+	if configPath != "" {
+		settings, err = loadConfig(configPath, true)
+	} else if path := defaultConfigPath(); path != "" {
+		settings, err = loadConfig(path, false)
+	}
 	if err != nil {
-		fmt.Printf("Error decoding SEGB file: %v\n", err)
-		return
+		fail(err, errorsJSON)
+	}
+	registerExternalDecoders(settings)
+	for _, global := range []struct {
+		name  string
+		value *string
+	}{{"tz", &timeZone}, {"time-format", &timeFormat}, {"retries", &retryCount}, {"cache-dir", &cacheDir}} {
+		if value, ok := settings.global(global.name); ok && *global.value == "" {
+			*global.value = value
+		}
+	}
+	if value, _ := settings.global("utc"); value == "true" && timeZone == "" {
+		timeZone = "UTC"
+	}
+	if value, _ := settings.global("errors-json"); value == "true" {
+		errorsJSON = true
+	}
+	if value, _ := settings.global("guard"); value == "true" {
+		guarded = true
+	}
+	if guarded {
+		guard = segb.NewIntegrityGuard()
+	}
+	if timeZone != "" {
+		if err := setTimeZone(timeZone); err != nil {
+			fail(err, errorsJSON)
+		}
+	}
+	if timeFormat != "" {
+		if err := setTimeFormat(timeFormat); err != nil {
+			fail(err, errorsJSON)
+		}
+	}
+	if retryCount != "" {
+		if err := setRetries(retryCount); err != nil {
+			fail(err, errorsJSON)
+		}
 	}
 
-	fmt.Printf("Version: %v\n", segbData.Version)
-	fmt.Printf("Created: %v\n", segbData.Created.String())
-	fmt.Println("Entries:")
-	for i, entry := range segbData.Entries {
-		fmt.Printf("Entry %d:\n", i)
-		fmt.Printf("  State: %v\n", entry.State)
-		fmt.Printf("  Created: %s\n", entry.Created.String())
-		PrettyHexdump(entry.Data)
+	// Without a known command name, behave like "dump"
+	run := runDump
+	if len(args) > 0 {
+		if args[0] == "help" {
+			usage()
+			return
+		}
+		if cmd, ok := commands[args[0]]; ok {
+			run = cmd.run
+			args = args[1:]
+		}
+	}
 
-		fmt.Println("--------------------")
+	err = run(args)
+	// A changed input makes any result, or failure, suspect
+	if guard != nil {
+		if changed := guard.Check(); changed != nil {
+			err = changed
+		}
+	}
+	if err != nil {
+		fail(err, errorsJSON)
 	}
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb/manifest"
+	"io"
+	"os"
+)
+
+func runManifest(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "keygen":
+			return runManifestKeygen(args[1:])
+		case "sign":
+			return runManifestSign(args[1:])
+		case "verify":
+			return runManifestVerify(args[1:])
+		}
+	}
+
+	flags := flag.NewFlagSet("manifest", flag.ExitOnError)
+	flags.Usage = manifestUsage(flags)
+	output := flags.String("output", "", "file to write the manifest to (default: standard output)")
+	key := flags.String("sign", "", "Ed25519 private key (PEM) to sign the manifest with, into OUTPUT"+manifest.SignatureSuffix+"; requires --output")
+	parseFlags(flags, args)
+	if flags.NArg() == 0 || (*key != "" && *output == "") {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+
+	m := manifest.New()
+	for _, filename := range flags.Args() {
+		if filename == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return &fileError{filename, err}
+			}
+			m.Files = append(m.Files, manifest.NewFile(filename, data))
+		} else if err := m.AddFile(filename); err != nil {
+			return &fileError{filename, err}
+		}
+		// Files that do not decode are still listed, with their error
+		if file := m.Files[len(m.Files)-1]; file.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, file.Error)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(m); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		return &fileError{*output, err}
+	}
+	if *key != "" {
+		return signFile(*output, *key)
+	}
+	return nil
+}
+
+// manifestUsage lists the subcommands of manifest along with its flags.
+func manifestUsage(flags *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: segb manifest [--output FILE [--sign KEY]] FILE...\n")
+		fmt.Fprintf(os.Stderr, "       segb manifest keygen NAME          write NAME.key and NAME.pub\n")
+		fmt.Fprintf(os.Stderr, "       segb manifest sign --key KEY FILE  sign a manifest or export into FILE%s\n", manifest.SignatureSuffix)
+		fmt.Fprintf(os.Stderr, "       segb manifest verify [--key PUB] MANIFEST\n\n")
+		flags.PrintDefaults()
+	}
+}
+
+func runManifestKeygen(args []string) error {
+	if len(args) != 1 {
+		manifestUsage(flag.NewFlagSet("manifest", flag.ExitOnError))()
+		os.Exit(exitUsage)
+	}
+	private, public, err := manifest.GenerateKey()
+	if err != nil {
+		return err
+	}
+	// Never overwrite a key, signatures made with it could not be verified
+	privatePath, publicPath := args[0]+".key", args[0]+".pub"
+	file, err := os.OpenFile(privatePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return &fileError{privatePath, err}
+	}
+	if _, err := file.Write(private); err != nil {
+		file.Close()
+		return &fileError{privatePath, err}
+	}
+	if err := file.Close(); err != nil {
+		return &fileError{privatePath, err}
+	}
+	if err := os.WriteFile(publicPath, public, 0o644); err != nil {
+		return &fileError{publicPath, err}
+	}
+	fmt.Printf("wrote %s (keep it secret) and %s\n", privatePath, publicPath)
+	return nil
+}
+
+func runManifestSign(args []string) error {
+	flags := flag.NewFlagSet("manifest sign", flag.ExitOnError)
+	key := flags.String("key", "", "Ed25519 private key (PEM) to sign with (required)")
+	parseFlags(flags, args)
+	if *key == "" || flags.NArg() == 0 {
+		manifestUsage(flags)()
+		os.Exit(exitUsage)
+	}
+	for _, filename := range flags.Args() {
+		if err := signFile(filename, *key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signFile writes the detached signature of a file next to it.
+func signFile(filename, keyPath string) error {
+	key, err := manifest.LoadPrivateKey(keyPath)
+	if err != nil {
+		return &fileError{keyPath, err}
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return &fileError{filename, err}
+	}
+	signature := filename + manifest.SignatureSuffix
+	if err := os.WriteFile(signature, manifest.Sign(data, key), 0o644); err != nil {
+		return &fileError{signature, err}
+	}
+	fmt.Fprintf(os.Stderr, "signed %s -> %s\n", filename, signature)
+	return nil
+}
+
+func runManifestVerify(args []string) error {
+	flags := flag.NewFlagSet("manifest verify", flag.ExitOnError)
+	key := flags.String("key", "", "Ed25519 public key (PEM) to check the signature FILE"+manifest.SignatureSuffix+" with")
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		manifestUsage(flags)()
+		os.Exit(exitUsage)
+	}
+	filename := flags.Arg(0)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return &fileError{filename, err}
+	}
+
+	if *key != "" {
+		public, err := manifest.LoadPublicKey(*key)
+		if err != nil {
+			return &fileError{*key, err}
+		}
+		signature, err := os.ReadFile(filename + manifest.SignatureSuffix)
+		if err != nil {
+			return &fileError{filename + manifest.SignatureSuffix, err}
+		}
+		if err := manifest.Verify(data, signature, public); err != nil {
+			return &fileError{filename, err}
+		}
+		fmt.Printf("%s: signature OK\n", filename)
+	}
+
+	// Signed exports are not manifests, their signature is all there is to
+	// check
+	var m manifest.Manifest
+	if err := json.Unmarshal(data, &m); err != nil || m.Tool != manifest.Tool {
+		if *key == "" {
+			return fmt.Errorf("%s: not a manifest, pass --key to check its signature", filename)
+		}
+		return nil
+	}
+	errs := m.Check()
+	for _, err := range errs {
+		fmt.Printf("FAILED %v\n", err)
+	}
+	fmt.Printf("%s: %d of %d files unchanged\n", filename, len(m.Files)-len(errs), len(m.Files))
+	if len(errs) > 0 {
+		return errors.New("files changed since the manifest was made")
+	}
+	return nil
+}
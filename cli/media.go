@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runMedia(args []string) error {
+	flags := flag.NewFlagSet("media", flag.ExitOnError)
+	output := flags.String("output", "", "directory to write the media to (required)")
+	parseFlags(flags, args)
+	if *output == "" || flags.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	if err := os.MkdirAll(*output, 0o755); err != nil {
+		return err
+	}
+
+	for _, filename := range flags.Args() {
+		segbData, err := openAndDecode(filename)
+		if err != nil {
+			return err
+		}
+		base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		if filename == "-" {
+			base = "stdin"
+		}
+		for i, entry := range segbData.Entries {
+			for _, media := range entry.Media() {
+				// Named after where the media was found, to trace it back
+				name := fmt.Sprintf("%s-entry%d-%x%s", base, i, media.Offset, media.Type.Extension())
+				if err := os.WriteFile(filepath.Join(*output, name), media.Data, 0o644); err != nil {
+					return err
+				}
+				fmt.Printf("%s  entry %d  %s at 0x%x, %d bytes  -> %s\n", filename, i, media.Type, media.Offset, len(media.Data), name)
+			}
+		}
+	}
+	return nil
+}
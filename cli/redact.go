@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"strings"
+)
+
+func runRedact(args []string) error {
+	flags := flag.NewFlagSet("redact", flag.ExitOnError)
+	entries := flags.String("entries", "", "comma-separated indexes or stable IDs of the entries to redact (default: all)")
+	zero := flags.Bool("zero-payload", false, "zero the payloads instead of filling them with "+segb.RedactedText)
+	output := flags.String("output", "", "file to write the redacted copy to (default: FILE.redacted)")
+
+	// Flags may follow FILE, as in 'redact FILE --entries 3,7'
+	var positional []string
+	for parseFlags(flags, args); flags.NArg() > 0; flags.Parse(args) {
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) != 1 || positional[0] == "-" {
+		usage()
+		os.Exit(exitUsage)
+	}
+	filename := positional[0]
+	if *output == "" {
+		*output = filename + ".redacted"
+	}
+
+	segbData, err := openAndDecode(filename, segb.WithRoundTrip())
+	if err != nil {
+		return err
+	}
+	var indexes []int
+	if *entries == "" {
+		for i := range segbData.Entries {
+			indexes = append(indexes, i)
+		}
+	} else {
+		for _, field := range strings.Split(*entries, ",") {
+			index, err := entryIndex(&segbData, filename, strings.TrimSpace(field))
+			if err != nil {
+				return err
+			}
+			indexes = append(indexes, index)
+		}
+	}
+
+	redaction := segb.RedactPlaceholder
+	if *zero {
+		redaction = segb.RedactZero
+	}
+	for _, index := range indexes {
+		if err := segbData.Redact(index, redaction); err != nil {
+			return &fileError{filename, err}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := segbData.Encode(&buf); err != nil {
+		return &fileError{filename, err}
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		return &fileError{*output, err}
+	}
+	fmt.Printf("redacted %d of %d entries of %s -> %s\n", len(indexes), len(segbData.Entries), filename, *output)
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/biome"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// reportRow is a line of the report in JSON form.
+type reportRow struct {
+	Stream         string    `json:"stream"`
+	Access         string    `json:"access"`
+	LocalSegments  int       `json:"local_segments"`
+	RemoteSegments int       `json:"remote_segments"`
+	Tombstones     int       `json:"tombstones"`
+	Failed         int       `json:"failed"`
+	Entries        int       `json:"entries"`
+	Written        int       `json:"written"`
+	Deleted        int       `json:"deleted"`
+	Unknown        int       `json:"unknown"`
+	DeletedRatio   float64   `json:"deleted_ratio"`
+	First          time.Time `json:"first"`
+	Last           time.Time `json:"last"`
+	Bytes          int64     `json:"bytes"`
+}
+
+func runReport(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print one JSON object per stream instead of a table")
+	profile := flags.String("profile", "", "directory layout of the extraction: itunes-backup, cellebrite, graykey or filesystem (default: detected)")
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	root := flags.Arg(0)
+	streams, err := enumerateStreams(root, *profile)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return fmt.Errorf("%s: no Biome streams found", root)
+	}
+
+	var rows []reportRow
+	total := reportRow{Stream: "total"}
+	for _, stream := range streams {
+		summary, err := stream.Summarize()
+		if err != nil {
+			return err
+		}
+		row := reportRow{
+			Stream:         summary.Stream,
+			Access:         summary.Access,
+			LocalSegments:  summary.LocalSegments,
+			RemoteSegments: summary.RemoteSegments,
+			Tombstones:     summary.Tombstones,
+			Failed:         summary.Failed,
+			Entries:        summary.Entries,
+			Written:        summary.EntriesByState[segb.EntryStateWritten],
+			Deleted:        summary.EntriesByState[segb.EntryStateDeleted],
+			Unknown:        summary.EntriesByState[segb.EntryStateUnknown],
+			DeletedRatio:   summary.DeletedRatio(),
+			First:          displayTime(summary.First),
+			Last:           displayTime(summary.Last),
+			Bytes:          summary.Bytes,
+		}
+		rows = append(rows, row)
+
+		total.LocalSegments += row.LocalSegments
+		total.RemoteSegments += row.RemoteSegments
+		total.Tombstones += row.Tombstones
+		total.Failed += row.Failed
+		total.Entries += row.Entries
+		total.Written += row.Written
+		total.Deleted += row.Deleted
+		total.Unknown += row.Unknown
+		total.Bytes += row.Bytes
+		if !row.First.IsZero() && (total.First.IsZero() || row.First.Before(total.First)) {
+			total.First = row.First
+		}
+		if row.Last.After(total.Last) {
+			total.Last = row.Last
+		}
+	}
+	if total.Entries > 0 {
+		total.DeletedRatio = float64(total.Deleted) / float64(total.Entries)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STREAM\tACCESS\tLOCAL\tREMOTE\tTOMBSTONES\tFAILED\tENTRIES\tWRITTEN\tDELETED\tUNKNOWN\tDELETED %\tFIRST\tLAST\tSIZE")
+	for _, row := range append(rows, total) {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f\t%s\t%s\t%s\n",
+			row.Stream, row.Access, row.LocalSegments, row.RemoteSegments, row.Tombstones, row.Failed,
+			row.Entries, row.Written, row.Deleted, row.Unknown, 100*row.DeletedRatio,
+			formatDate(row.First), formatDate(row.Last), formatBytes(row.Bytes))
+	}
+	return w.Flush()
+}
+
+// formatDate formats the day of t in the display time zone, or "-" for the
+// zero time.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return displayTime(t).Format(time.DateOnly)
+}
+
+// enumerateStreams returns the streams of the extraction at root, laid out
+// as the named profile, or as detected if name is empty.
+func enumerateStreams(root, name string) ([]*biome.Stream, error) {
+	profile := biome.DetectProfile(root)
+	if name != "" {
+		var err error
+		if profile, err = biome.ProfileByName(name); err != nil {
+			return nil, err
+		}
+	} else if profile.Name != biome.FileSystem.Name {
+		fmt.Fprintf(os.Stderr, "%s: reading as %s layout\n", root, profile.Name)
+	}
+	streams, err := biome.EnumerateProfile(root, profile)
+	if err != nil {
+		return nil, &fileError{root, err}
+	}
+	return streams, nil
+}
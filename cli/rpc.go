@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb/rpc"
+	"github.com/bluefalconhd/segb/rpc/segbpb"
+	"google.golang.org/grpc"
+	"net"
+	"os"
+)
+
+func runRPC(args []string) error {
+	flags := flag.NewFlagSet("rpc", flag.ExitOnError)
+	listen := flags.String("listen", ":9090", "address to listen on")
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	segbpb.RegisterSegbServer(server, rpc.NewServer(flags.Arg(0)))
+
+	fmt.Fprintf(os.Stderr, "Serving gRPC for %s on %s\n", flags.Arg(0), lis.Addr())
+	return server.Serve(lis)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb/scan"
+	"os"
+	"strings"
+)
+
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	rulesPath := flags.String("rules", "", "YARA rules file (required)")
+	noColor := flags.Bool("no-color", false, "disable colored output (default: color when stdout is a terminal)")
+	parseFlags(flags, args)
+	if *rulesPath == "" || flags.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	rules, err := scan.CompileFile(*rulesPath)
+	if err != nil {
+		return err
+	}
+
+	s := newStyle(os.Stdout, *noColor)
+	for _, filename := range flags.Args() {
+		segbData, err := openAndDecode(filename)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		hits, err := scan.ScanSegb(rules, segbData)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		for _, hit := range hits {
+			rule := s.paint(sgrBoldRed, hit.Rule)
+			if len(hit.Tags) > 0 {
+				rule += s.dim(" [" + strings.Join(hit.Tags, ",") + "]")
+			}
+			matches := make([]string, len(hit.Strings))
+			for i, m := range hit.Strings {
+				matches[i] = fmt.Sprintf("%s@0x%x", m.ID, m.Offset)
+			}
+			fmt.Printf("%s  entry %d  %s  %s\n", filename, hit.Index, rule, strings.Join(matches, " "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/biome"
+	"github.com/bluefalconhd/segb/protoschema"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+func runSchema(args []string) error {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	name := flags.String("name", "", "name of the inferred message (default: from the stream name)")
+	parseFlags(flags, args)
+	if flags.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	payloads, stream, err := collectPayloads(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	message := protoschema.NewMessage()
+	skipped := 0
+	for _, payload := range payloads {
+		if message.Add(payload) != nil {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d payloads are not protobuf messages and were skipped\n", skipped, len(payloads))
+	}
+	if message.Samples == 0 {
+		return fmt.Errorf("no protobuf payloads found")
+	}
+
+	if *name == "" {
+		*name = messageName(stream)
+	}
+	return message.WriteProto(os.Stdout, *name)
+}
+
+// collectPayloads decodes the SEGB files given as arguments, and those below
+// the directories among them, see decodeInputs. It returns every entry
+// payload and the stream name of the first file that has one.
+func collectPayloads(paths []string) ([][]byte, string, error) {
+	var payloads [][]byte
+	var stream string
+	err := decodeInputs(paths, func(path string, s segb.Segb) error {
+		if stream == "" {
+			if abs, err := filepath.Abs(path); err == nil {
+				stream = biome.StreamName(abs)
+			}
+		}
+		for _, entry := range s.Entries {
+			payloads = append(payloads, entry.Data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return payloads, stream, nil
+}
+
+// messageName turns a stream name such as "App.InFocus" into a message
+// name, "AppInFocus".
+func messageName(stream string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, stream)
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return "Entry" + name
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/archive"
+	"github.com/bluefalconhd/segb/server"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "address to listen on")
+	workers := flags.Int("workers", 0, "files decoded in parallel at startup (0: one per CPU)")
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	opts := append(loadOptions(), segb.WithWorkers(*workers))
+	var srv *server.Server
+	var err error
+	if bucket, prefix, ok := openBucket(flags.Arg(0)); ok {
+		// Objects are decoded in place, the prefix is the directory served
+		root := strings.Trim(prefix, "/")
+		if root == "" {
+			root = "."
+		}
+		srv, err = server.NewFS(bucket, root, opts...)
+	} else if isArchive(flags.Arg(0)) {
+		var corpus *segb.Corpus
+		if err = guardArchive(flags.Arg(0)); err != nil {
+			return err
+		}
+		if corpus, err = archive.Load(flags.Arg(0), opts...); err == nil {
+			srv = server.NewFromCorpus(corpus)
+		}
+	} else {
+		srv, err = server.New(flags.Arg(0), opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	srv.Location = displayLocation
+
+	fmt.Fprintf(os.Stderr, "Serving %s on %s\n", flags.Arg(0), *listen)
+	return http.ListenAndServe(*listen, srv)
+}
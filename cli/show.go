@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/biome"
+	"github.com/bluefalconhd/segb/hexdump"
+	"github.com/bluefalconhd/segb/protoschema"
+	"github.com/bluefalconhd/segb/timeconv"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// showMaxDepth is how deep show --decode descends into nested messages.
+const showMaxDepth = 8
+
+func runShow(args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	noColor := flags.Bool("no-color", false, "disable colored output (default: color when stdout is a terminal)")
+	entryArg := flags.String("entry", "", "entry to show, by index or stable ID")
+	id := flags.Int("id", -1, "entry to show, by the ID stored in the file")
+	decode := flags.Bool("decode", false, "also show the payload decoded: text, JSON, property lists and protobuf fields")
+	descriptors := flags.String("descriptors", "", "compiled protobuf FileDescriptorSet to decode the payload with")
+	typeMap := flags.String("types", "", "JSON file mapping stream names to message types in --descriptors")
+	messageType := flags.String("message", "", "message type in --descriptors for streams --types does not map")
+	parseFlags(flags, args)
+	if flags.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	// The file may come before the flags, as in "show FILE --entry 12"
+	filename := flags.Arg(0)
+	flags.Parse(flags.Args()[1:])
+	if flags.NArg() != 0 || (*entryArg == "") == (*id < 0) {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	segbData, err := openAndDecode(filename)
+	if err != nil {
+		return err
+	}
+	index := -1
+	if *entryArg != "" {
+		if index, err = entryIndex(&segbData, filename, *entryArg); err != nil {
+			return err
+		}
+	} else {
+		for i, entry := range segbData.Entries {
+			if entry.ID == *id {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("no entry with ID %d in %s", *id, filename)
+		}
+	}
+	var decoder *protoschema.Decoder
+	if *descriptors != "" {
+		if decoder, err = loadProtoDecoder(*descriptors, *typeMap, *messageType); err != nil {
+			return err
+		}
+	}
+	annotations, err := loadAnnotations(filename)
+	if err != nil {
+		return err
+	}
+
+	s := newStyle(os.Stdout, *noColor)
+	entry := segbData.Entries[index]
+	stream := ""
+	if abs, err := filepath.Abs(filename); err == nil && filename != "-" {
+		stream = biome.StreamName(abs)
+	}
+
+	fmt.Printf("%s %s  %s\n", s.bold(fmt.Sprintf("Entry %d", index)), s.stateBadge(entry.State), s.crcStatus(entry.CRCValid, entry.CRCRepaired))
+	field := func(name, format string, args ...any) {
+		fmt.Printf("%s %s\n", s.bold(fmt.Sprintf("%-12s", name+":")), fmt.Sprintf(format, args...))
+	}
+	if stream != "" {
+		field("Stream", "%s", stream)
+	}
+	field("ID", "%d", entry.ID)
+	field("Stable ID", "%s", entry.StableID())
+	field("Created", "%s", formatTime(entry.Created))
+	field("Offset", "0x%x", entry.Offset)
+	field("Size", "%d bytes, %d stored (%s)", len(entry.Data), entry.StoredSize, entry.LengthSource)
+	field("CRC", "%08x", entry.Checksum)
+	field("Content", "%s", entry.ContentType)
+	if entry.Compression != segb.CompressionNone {
+		field("Compression", "%v, %d bytes stored", entry.Compression, len(entry.Raw))
+	}
+	field("Entropy", "%s", s.entropy(entry.Data))
+	field("SHA-256", "%s", segb.HashPayload(entry.Data))
+	if entry.Residual {
+		field("Residual", "%s", s.paint(sgrBoldRed, "recovered from past the end of data"))
+	}
+	for _, media := range entry.Media() {
+		field("Media", "%s at 0x%x, %d bytes", media.Type, media.Offset, len(media.Data))
+	}
+	if annotation := annotations.For(entry); !annotation.IsZero() {
+		printAnnotation(s, annotation)
+	}
+
+	fmt.Println()
+	hexdump.Dump(os.Stdout, entry.Data, hexdump.Options{Color: s.color})
+
+	if decoder != nil {
+		fmt.Println()
+		fmt.Println(s.bold("Decoded with --descriptors:"))
+		if data, err := decoder.DecodeJSON(stream, entry.Data); err == nil {
+			fmt.Println(string(data))
+		} else {
+			fmt.Println(s.dim(err.Error()))
+		}
+	}
+	printDecoded(s, stream, entry, true)
+	if *decode {
+		switch {
+		case textual(entry.ContentType):
+			fmt.Println()
+			fmt.Println(s.bold(fmt.Sprintf("Decoded as %s:", entry.ContentType)))
+			fmt.Println(renderText(entry.Data, entry.ContentType))
+		case entry.ContentType == segb.ContentProtobuf:
+			fmt.Println()
+			fmt.Println(s.bold("Protobuf fields:"))
+			printWireFields(s, entry.Data, "  ", 0)
+		}
+	}
+	return checkCRCs(filename, segb.Segb{Entries: []segb.Entry{entry}})
+}
+
+// printWireFields prints the fields of a protobuf message without a schema:
+// number, wire type and value, with the time a value reads as, if any.
+// Length-delimited values that parse as messages are printed nested.
+func printWireFields(s style, data []byte, indent string, depth int) {
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			fmt.Printf("%s%s\n", indent, s.dim(fmt.Sprintf("%d bytes that do not parse", len(data))))
+			return
+		}
+		data = data[n:]
+		var value []byte
+		if typ == protowire.BytesType {
+			value, n = protowire.ConsumeBytes(data)
+		} else {
+			n = protowire.ConsumeFieldValue(number, typ, data)
+			if n >= 0 {
+				value = data[:n]
+			}
+		}
+		if n < 0 {
+			fmt.Printf("%s%s\n", indent, s.dim(fmt.Sprintf("field %d: truncated", number)))
+			return
+		}
+		data = data[n:]
+
+		name := fmt.Sprintf("%s%-4s %-8s", indent, strconv.Itoa(int(number)), wireTypeName(typ))
+		if typ == protowire.BytesType && depth < showMaxDepth && segb.Classify(value) == segb.ContentProtobuf {
+			fmt.Printf("%s {\n", name)
+			printWireFields(s, value, indent+"  ", depth+1)
+			fmt.Printf("%s}\n", indent)
+			continue
+		}
+		fmt.Printf("%s %s%s\n", name, cmpValue(typ, value), wireTime(s, typ, value))
+	}
+}
+
+// wireTime returns the time a varint or double reads as, for printing after
+// its value, or "".
+func wireTime(s style, typ protowire.Type, value []byte) string {
+	kind, t := timeconv.None, time.Time{}
+	switch typ {
+	case protowire.VarintType:
+		v, _ := protowire.ConsumeVarint(value)
+		if v <= math.MaxInt64 {
+			kind, t = timeconv.GuessInt(int64(v))
+		}
+	case protowire.Fixed64Type:
+		v, _ := protowire.ConsumeFixed64(value)
+		kind, t = timeconv.GuessFloat(math.Float64frombits(v))
+	}
+	if kind == timeconv.None {
+		return ""
+	}
+	return s.dim(fmt.Sprintf("  (%s %s)", kind, formatTime(t)))
+}
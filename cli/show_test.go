@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestShowProcess is not a test: it is the process TestShow runs, which
+// runs show with the arguments after "--", as main would.
+func TestShowProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if err := runShow(args[1:]); err != nil {
+		fail(err, false)
+	}
+	os.Exit(0)
+}
+
+func TestShow(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	_, v2 := fixtures(t)
+	file, err := os.Open(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := segb.Decode(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := s.Entries[2]
+
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string // Expected in the output
+		stderr string // Expected in the error
+	}{
+		{"index", []string{v2, "--entry", "2"}, 0, "Entry 2", ""},
+		{"flags first", []string{"--entry", "2", v2}, 0, "Entry 2", ""},
+		{"stable ID", []string{v2, "--entry", entry.StableID()}, 0, "Entry 2", ""},
+		{"ID", []string{v2, "--id", fmt.Sprint(entry.ID)}, 0, "Entry 2", ""},
+		{"both", []string{v2, "--entry", "2", "--id", fmt.Sprint(entry.ID)}, exitUsage, "", "Usage: segb"},
+		{"neither", []string{v2}, exitUsage, "", "Usage: segb"},
+		{"no file", []string{"--entry", "2"}, exitUsage, "", "Usage: segb"},
+		{"unknown ID", []string{v2, "--id", "4242"}, exitError, "", "no entry with ID 4242 in " + v2},
+		{"unknown stable ID", []string{v2, "--entry", "nosuch"}, exitError, "", `invalid entry "nosuch"`},
+		{"index out of range", []string{v2, "--entry", "3"}, exitError, "", ""},
+	} {
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestShowProcess$", "--"}, tc.args...)...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if code != tc.code || !strings.Contains(stdout.String(), tc.stdout) || !strings.Contains(stderr.String(), tc.stderr) {
+			t.Errorf("%s: exit %d, stdout %q, stderr %q; want %d, %q, %q", tc.name, code, stdout.String(), stderr.String(), tc.code, tc.stdout, tc.stderr)
+			continue
+		}
+		if code == 0 && (!strings.Contains(stdout.String(), entry.StableID()) || !bytes.Contains(stdout.Bytes(), entry.Data[:8])) {
+			t.Errorf("%s: stable ID or payload missing from\n%s", tc.name, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"slices"
+	"strings"
+)
+
+func runStrings(args []string) error {
+	flags := flag.NewFlagSet("strings", flag.ExitOnError)
+	minLength := flags.Int("n", segb.DefaultMinStringLength, "minimum string length")
+	encoding := flags.String("encoding", "all", "encodings to report, comma-separated: ascii, utf8, utf16le, utf16be or all")
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	encodings := make(map[string]bool)
+	for _, name := range strings.Split(*encoding, ",") {
+		if name != "all" && !slices.ContainsFunc(segb.StringEncodings, func(e segb.StringEncoding) bool { return e.String() == name }) {
+			return fmt.Errorf("unknown encoding %q", name)
+		}
+		encodings[name] = true
+	}
+
+	segbData, err := openAndDecode(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, str := range segbData.Strings(*minLength) {
+		if !encodings["all"] && !encodings[str.Encoding.String()] {
+			continue
+		}
+		fmt.Printf("%d\t0x%x\t%s\t%s\n", str.Entry, str.Offset, str.Encoding, str.Text)
+	}
+	return nil
+}
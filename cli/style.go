@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"strings"
+)
+
+// ANSI SGR codes used by the CLI.
+const (
+	sgrBold      = "1"
+	sgrDim       = "2"
+	sgrRed       = "31"
+	sgrGreen     = "32"
+	sgrBlack     = "30"
+	sgrBgRed     = "41"
+	sgrBgGreen   = "42"
+	sgrBgYellow  = "43"
+	sgrBoldRed   = sgrBold + ";" + sgrRed
+	sgrBadgeBase = sgrBold + ";" + sgrBlack
+)
+
+// style renders text with or without ANSI colors.
+type style struct {
+	color bool
+}
+
+// newStyle enables color when f is a terminal, unless disabled by flag or by
+// the NO_COLOR convention (https://no-color.org).
+func newStyle(f *os.File, noColor bool) style {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return style{}
+	}
+	return style{color: isTerminal(f)}
+}
+
+// isTerminal reports whether f is attached to a character device.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps text in the given SGR code if color is enabled.
+func (s style) paint(code, text string) string {
+	if !s.color {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+func (s style) bold(text string) string { return s.paint(sgrBold, text) }
+func (s style) dim(text string) string  { return s.paint(sgrDim, text) }
+
+// stateBadge renders an entry state as a fixed-width badge.
+func (s style) stateBadge(state segb.EntryState) string {
+	label := " " + strings.ToUpper(state.String()) + " "
+	if !s.color {
+		return "[" + strings.ToUpper(state.String()) + "]"
+	}
+	switch state {
+	case segb.EntryStateWritten:
+		return s.paint(sgrBadgeBase+";"+sgrBgGreen, label)
+	case segb.EntryStateDeleted:
+		return s.paint(sgrBadgeBase+";"+sgrBgRed, label)
+	default:
+		return s.paint(sgrBadgeBase+";"+sgrBgYellow, label)
+	}
+}
+
+// crcStatus renders the result of a CRC check, highlighting mismatches.
+func (s style) crcStatus(valid, repaired bool) string {
+	if repaired {
+		return s.bold("crc repaired")
+	}
+	if valid {
+		return s.paint(sgrGreen, "crc ok")
+	}
+	return s.paint(sgrBoldRed, "CRC MISMATCH")
+}
+
+// entropy renders the entropy of a payload, highlighting likely encrypted or
+// compressed data.
+func (s style) entropy(data []byte) string {
+	text := fmt.Sprintf("entropy %.2f, %.0f%% zero, gzip %.0f%%", segb.Entropy(data), 100*segb.ZeroRatio(data), 100*segb.GzipRatio(data))
+	if segb.HighEntropy(data) {
+		return s.paint(sgrBoldRed, text+" HIGH")
+	}
+	return s.dim(text)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/export"
+	"github.com/bluefalconhd/segb/sysdiagnose"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+)
+
+func runSysdiagnose(args []string) error {
+	flags := flag.NewFlagSet("sysdiagnose", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print one JSON object per entry (an export record) instead of a table")
+	withData := flags.Bool("data", false, "include the payloads in JSON output")
+	streamName := flags.String("stream", "", "only print the entries of this Biome stream")
+	selection := addColumnFlags(flags)
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	columns, err := selection.columns()
+	if err != nil {
+		return err
+	}
+
+	filename := flags.Arg(0)
+	results, err := sysdiagnose.Decode(filename, segb.WithRetry(retries))
+	if err != nil {
+		return &fileError{filename, err}
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("%s: no Biome SEGB files found", filename)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Segment, results[j].Segment
+		if a.Stream != b.Stream {
+			return a.Stream < b.Stream
+		}
+		return a.Created.Before(b.Created)
+	})
+
+	var records []export.Record
+	for _, result := range results {
+		if *streamName != "" && result.Segment.Stream != *streamName {
+			continue
+		}
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", result.Member, result.Err)
+			continue
+		}
+		records = append(records, export.Records(result.Member, result.Segb, *withData)...)
+	}
+	for i := range records {
+		records[i].Created = displayTime(records[i].Created)
+	}
+
+	if *asJSON {
+		return export.WriteJSONL(os.Stdout, records)
+	}
+	if columns != nil {
+		return writeColumns(os.Stdout, records, columns, *selection.csv)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STREAM\tSEGMENT\tENTRY\tSTATE\tCREATED\tSIZE\tTYPE")
+	for _, record := range records {
+		stream := record.Stream
+		if stream == "" {
+			stream = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%d\t%s\n", stream, path.Base(record.File), record.Index, record.State,
+			formatTime(record.Created), record.Size, record.ContentType)
+	}
+	return w.Flush()
+}
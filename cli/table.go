@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb/export"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// columnFlags are the flags of commands printing export records as a table
+// or CSV, selecting the fields shown.
+type columnFlags struct {
+	fields *string
+	wide   *bool
+	narrow *bool
+	csv    *bool
+}
+
+func addColumnFlags(flags *flag.FlagSet) columnFlags {
+	return columnFlags{
+		fields: flags.String("fields", "", "comma-separated record fields to show as columns, e.g. id,created,state,sha256,size"),
+		wide:   flags.Bool("wide", false, "show every record field but the payload"),
+		narrow: flags.Bool("narrow", false, "show "+strings.Join(export.NarrowFields, ",")),
+		csv:    flags.Bool("csv", false, "print CSV instead of a table"),
+	}
+}
+
+// columns returns the fields selected, or nil if none of the flags is given
+// and the command prints its own table. CSV defaults to the narrow set.
+func (c columnFlags) columns() ([]string, error) {
+	switch {
+	case *c.fields != "" && (*c.wide || *c.narrow), *c.wide && *c.narrow:
+		return nil, fmt.Errorf("--fields, --wide and --narrow are exclusive")
+	case *c.fields != "":
+		return export.ParseFields(*c.fields)
+	case *c.wide:
+		return export.WideFields, nil
+	case *c.narrow, *c.csv:
+		return export.NarrowFields, nil
+	}
+	return nil, nil
+}
+
+// cellReplacer removes the tabs and newlines that would break the columns
+// of a table.
+var cellReplacer = strings.NewReplacer("\t", " ", "\n", " ")
+
+// writeColumns prints the selected fields of records, as a table or CSV,
+// with a header line naming them.
+func writeColumns(w io.Writer, records []export.Record, columns []string, asCSV bool) error {
+	row := make([]string, len(columns))
+	if asCSV {
+		cw := csv.NewWriter(w)
+		cw.Write(columns)
+		for _, record := range records {
+			for i, name := range columns {
+				row[i] = fieldText(record, name)
+			}
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, name := range columns {
+		row[i] = strings.ToUpper(name)
+	}
+	fmt.Fprintln(tw, strings.Join(row, "\t"))
+	for _, record := range records {
+		for i, name := range columns {
+			row[i] = cellReplacer.Replace(fieldText(record, name))
+			if row[i] == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// fieldText formats a field of a record for a column: times as the rest of
+// the output, tags joined with ";", payloads in base64 and decoded or
+// derived values as JSON.
+func fieldText(record export.Record, name string) string {
+	value, _ := record.Field(name)
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return formatTime(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 4, 64)
+	case []string:
+		return strings.Join(v, ";")
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case int, int64, uint32, bool:
+		return fmt.Sprint(v)
+	case map[string]any:
+		if len(v) == 0 {
+			return ""
+		}
+	case nil:
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
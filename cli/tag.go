@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+	"strings"
+)
+
+// loadAnnotations reads the annotation sidecar of a SEGB file, if it has
+// one. Only local files do: not standard input, URLs or members of
+// archives.
+func loadAnnotations(filename string) (segb.Annotations, error) {
+	if _, err := os.Stat(filename); filename == "-" || err != nil {
+		return segb.Annotations{}, nil
+	}
+	annotations, err := segb.LoadAnnotations(filename)
+	if err != nil {
+		return nil, &fileError{segb.AnnotationsPath(filename), err}
+	}
+	return annotations, nil
+}
+
+func runTag(args []string) error {
+	flags := flag.NewFlagSet("tag", flag.ExitOnError)
+	remove := flags.Bool("remove", false, "remove the given tags instead of adding them")
+	note := flags.String("note", "", "set the note of the entry (use --clear-note to remove it)")
+	clearNote := flags.Bool("clear-note", false, "remove the note of the entry")
+	parseFlags(flags, args)
+	if flags.NArg() < 2 || flags.Arg(0) == "-" {
+		usage()
+		os.Exit(exitUsage)
+	}
+	filename := flags.Arg(0)
+	segbData, err := openAndDecode(filename)
+	if err != nil {
+		return err
+	}
+	index, err := entryIndex(&segbData, filename, flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	annotations, err := loadAnnotations(filename)
+	if err != nil {
+		return err
+	}
+
+	entry := segbData.Entries[index]
+	annotation := annotations.For(entry)
+	if *remove {
+		annotation.RemoveTags(flags.Args()[2:]...)
+	} else {
+		annotation.AddTags(flags.Args()[2:]...)
+	}
+	if *note != "" {
+		annotation.Note = *note
+	}
+	if *clearNote {
+		annotation.Note = ""
+	}
+	annotations.Set(entry, annotation)
+	if err := annotations.Save(filename); err != nil {
+		return &fileError{segb.AnnotationsPath(filename), err}
+	}
+
+	fmt.Printf("entry %d  tags: %s", index, strings.Join(annotation.Tags, ", "))
+	if annotation.Note != "" {
+		fmt.Printf("  note: %s", annotation.Note)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/bluefalconhd/segb/export"
+	"github.com/bluefalconhd/segb/hexdump"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the helpers available to --template, on top of the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"hex":     hex.EncodeToString,
+	"text":    func(data []byte) string { return string(data) },
+	"hexdump": func(data []byte) string { return hexdump.String(data, hexdump.Options{}) },
+	"time":    formatTime,
+	"json": func(v any) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+}
+
+// entryTemplate formats each entry with a user supplied template, executed
+// against its export.Record.
+type entryTemplate struct {
+	tmpl    *template.Template
+	newline bool // Add a newline after each entry
+}
+
+// parseEntryTemplate parses a per-entry output template. A newline is added
+// after each entry unless the template ends with one.
+func parseEntryTemplate(text string) (*entryTemplate, error) {
+	tmpl, err := template.New("entry").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	return &entryTemplate{tmpl: tmpl, newline: !strings.HasSuffix(text, "\n")}, nil
+}
+
+// execute runs the template for the records of the entries of a file,
+// built with their payloads.
+func (t *entryTemplate) execute(w io.Writer, records []export.Record) error {
+	for _, record := range records {
+		record.Created = displayTime(record.Created)
+		if err := t.tmpl.Execute(w, record); err != nil {
+			return fmt.Errorf("executing template: %w", err)
+		}
+		if t.newline {
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
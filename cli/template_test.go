@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/export"
+	"github.com/bluefalconhd/segb/segbtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtureRecords returns the records of the entries of the v1 fixture.
+func fixtureRecords(t *testing.T) []export.Record {
+	t.Helper()
+	data, err := segbtest.Bytes(segb.SEGB_VERSION_1, segbtest.Entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := segb.DecodeBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return export.Records("", s, true)
+}
+
+func TestEntryTemplate(t *testing.T) {
+	records := fixtureRecords(t)
+	state := segb.EntryStateWritten.String()
+	created := func(i int) time.Time { return segbtest.Entries[i].Created }
+
+	for _, tc := range []struct {
+		text string
+		want string
+	}{
+		// Fields print as fmt prints them
+		{"{{.Index}} {{.Created}} {{.State}}", fmt.Sprintf("0 %v %s\n1 %v %s\n2 %v %s\n", created(0), state, created(1), state, created(2), state)},
+		// A newline is only added when the template lacks one
+		{"{{.Index}}\n", "0\n1\n2\n"},
+		{"{{.Index}},", "0,\n1,\n2,\n"},
+		{`{{if eq .Index 1}}{{hex .Data}}{{end}}`, "\n" + fmt.Sprintf("%x", segbtest.Entries[1].Data) + "\n\n"},
+		{`{{if eq .Index 2}}{{text .Data}} {{json .Size}} {{json .State}}{{end}}`, fmt.Sprintf("\n\n%s %d %q\n", segbtest.Entries[2].Data, len(segbtest.Entries[2].Data), state)},
+		{"{{time .Created}}", "2007-01-09T00:00:00.000000Z\n2007-06-29T00:00:00.000000Z\n2011-10-05T00:00:00.000000Z\n"},
+	} {
+		tmpl, err := parseEntryTemplate(tc.text)
+		if err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+			continue
+		}
+		var out bytes.Buffer
+		if err := tmpl.execute(&out, records); err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+		}
+		if out.String() != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.text, out.String(), tc.want)
+		}
+	}
+
+	// Timestamps follow --tz and --time-format
+	defer func() { displayLocation, timeLayout = time.UTC, defaultTimeLayout }()
+	if err := setTimeZone("America/New_York"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setTimeFormat("2006-01-02 15:04 MST"); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := parseEntryTemplate("{{time .Created}} {{.Created.Hour}}")
+	var out bytes.Buffer
+	if err := tmpl.execute(&out, records[:1]); err != nil || out.String() != "2007-01-08 19:00 EST 19\n" {
+		t.Errorf("in New York: got %q, %v", out.String(), err)
+	}
+}
+
+func TestEntryTemplateErrors(t *testing.T) {
+	if _, err := parseEntryTemplate("{{.Index"); err == nil || !strings.HasPrefix(err.Error(), "parsing template: ") {
+		t.Errorf("unclosed action: got %v, want a parsing template error", err)
+	}
+	if _, err := parseEntryTemplate("{{nosuch .Data}}"); err == nil || !strings.HasPrefix(err.Error(), "parsing template: ") {
+		t.Errorf("unknown function: got %v, want a parsing template error", err)
+	}
+
+	tmpl, err := parseEntryTemplate("{{.Missing}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.execute(&bytes.Buffer{}, fixtureRecords(t)); err == nil || !strings.HasPrefix(err.Error(), "executing template: ") {
+		t.Errorf("unknown field: got %v, want an executing template error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"github.com/bluefalconhd/segb/timeconv"
+	"time"
+)
+
+// defaultTimeLayout is RFC 3339 with microseconds, the precision of Cocoa
+// timestamps, in fixed width so that printed timestamps sort as text.
+const defaultTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// timeLayout is the Go layout timestamps are printed with, or "unix" or
+// "cocoa" for seconds since either epoch, set by --time-format.
+var timeLayout = defaultTimeLayout
+
+// displayLocation is the time zone timestamps are printed and exported in,
+// set by --tz and --utc.
+var displayLocation = time.UTC
+
+// setTimeZone sets displayLocation from an IANA time zone name, or "local"
+// for the system time zone.
+func setTimeZone(name string) error {
+	if name == "local" {
+		displayLocation = time.Local
+		return nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	displayLocation = location
+	return nil
+}
+
+// displayTime returns t in displayLocation, leaving the zero time as is.
+func displayTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(displayLocation)
+}
+
+// setTimeFormat sets timeLayout from --time-format: rfc3339 (the default),
+// unix, cocoa or a Go time layout such as "Jan 2 15:04:05".
+func setTimeFormat(format string) error {
+	switch format {
+	case "":
+		return fmt.Errorf("empty time format")
+	case "rfc3339":
+		timeLayout = defaultTimeLayout
+	default:
+		timeLayout = format
+	}
+	return nil
+}
+
+// formatTime formats t with timeLayout in displayLocation.
+func formatTime(t time.Time) string {
+	switch timeLayout {
+	case "unix":
+		return fmt.Sprintf("%.6f", timeconv.ToUnix(t))
+	case "cocoa":
+		return fmt.Sprintf("%.6f", timeconv.ToCocoa(t))
+	}
+	return displayTime(t).Format(timeLayout)
+}
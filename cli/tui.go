@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/hexdump"
+	"golang.org/x/term"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The TUI is a small hand-rolled full screen browser: a list of entries on
+// the left and the selected entry's payload on the right. All state lives in
+// the tui struct; handleKey mutates it and render turns it into screen lines,
+// so the terminal plumbing in runTUI stays trivial.
+
+const tuiHelp = "j/k move  J/K scroll  / search  n/N next/prev  s state  t time  v hex/text  q quit"
+
+// Input modes.
+const (
+	modeNormal = iota
+	modeSearch
+	modeTime
+)
+
+// stateFilters is the cycle of state filters toggled by 's'. Zero means all.
+var stateFilters = []segb.EntryState{0, segb.EntryStateWritten, segb.EntryStateDeleted, segb.EntryStateUnknown}
+
+type tui struct {
+	name        string
+	data        segb.Segb
+	annotations segb.Annotations
+	s           style
+
+	visible []int // Indexes into data.Entries that pass the filters
+	cursor  int   // Selected row in visible
+	top     int   // First row of visible shown in the list
+	scroll  int   // First line of the detail pane shown
+	text    bool  // Show payloads as hex if they read as text, and the other way around
+
+	stateFilter int       // Index into stateFilters
+	from, to    time.Time // Time filter bounds, zero if open
+
+	mode         int
+	input        string
+	query        string
+	searchOrigin int
+	status       string
+	quit         bool
+}
+
+func newTUI(name string, data segb.Segb, annotations segb.Annotations, s style) *tui {
+	t := &tui{name: name, data: data, annotations: annotations, s: s}
+	t.applyFilters()
+	return t
+}
+
+// applyFilters recomputes the visible entries, keeping the selection on the
+// same entry when it is still visible.
+func (t *tui) applyFilters() {
+	selected := t.selected()
+
+	t.visible = t.visible[:0]
+	state := stateFilters[t.stateFilter]
+	for i, entry := range t.data.Entries {
+		if state != 0 && entry.State != state {
+			continue
+		}
+		if !t.from.IsZero() && entry.Created.Before(t.from) {
+			continue
+		}
+		if !t.to.IsZero() && entry.Created.After(t.to) {
+			continue
+		}
+		t.visible = append(t.visible, i)
+	}
+
+	t.cursor = 0
+	for row, index := range t.visible {
+		if index == selected {
+			t.cursor = row
+		}
+	}
+	t.scroll = 0
+}
+
+// selected returns the index of the selected entry, or -1.
+func (t *tui) selected() int {
+	if t.cursor < 0 || t.cursor >= len(t.visible) {
+		return -1
+	}
+	return t.visible[t.cursor]
+}
+
+func (t *tui) move(delta int) {
+	t.cursor = max(0, min(len(t.visible)-1, t.cursor+delta))
+	t.scroll = 0
+}
+
+// matches reports whether an entry's payload, tags or note contain the
+// query. Queries starting with "x:" are hex byte patterns matched against
+// the payload, others are case-insensitive text.
+func (t *tui) matches(index int, query string) bool {
+	data := t.data.Entries[index].Data
+	if pattern, ok := strings.CutPrefix(query, "x:"); ok {
+		needle, err := hex.DecodeString(strings.ReplaceAll(pattern, " ", ""))
+		return err == nil && len(needle) > 0 && bytes.Contains(data, needle)
+	}
+	annotation := t.annotations.For(t.data.Entries[index])
+	text := slices.Concat(data, []byte(strings.Join(annotation.Tags, "\n")+"\n"+annotation.Note))
+	return bytes.Contains(bytes.ToLower(text), bytes.ToLower([]byte(query)))
+}
+
+// find moves the cursor to the next visible entry matching the query,
+// starting at row from and searching in direction dir (wrapping around).
+func (t *tui) find(query string, from, dir int) bool {
+	n := len(t.visible)
+	if query == "" || n == 0 {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		row := ((from+i*dir)%n + n) % n
+		if t.matches(t.visible[row], query) {
+			t.cursor = row
+			t.scroll = 0
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimeBound parses an RFC 3339 timestamp or a YYYY-MM-DD date, taken
+// in the display time zone. A date used as an end bound covers the whole day.
+func parseTimeBound(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, displayLocation)
+	if err == nil && end {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, err
+}
+
+func (t *tui) handleKey(k key) {
+	switch t.mode {
+	case modeSearch, modeTime:
+		t.handleInput(k)
+		return
+	}
+
+	t.status = ""
+	switch {
+	case k.is('q'), k.name == "ctrl-c":
+		t.quit = true
+	case k.is('j'), k.name == "down":
+		t.move(1)
+	case k.is('k'), k.name == "up":
+		t.move(-1)
+	case k.name == "pgdn", k.is(' '):
+		t.move(10)
+	case k.name == "pgup":
+		t.move(-10)
+	case k.is('g'), k.name == "home":
+		t.move(-len(t.visible))
+	case k.is('G'), k.name == "end":
+		t.move(len(t.visible))
+	case k.is('J'):
+		t.scroll++
+	case k.is('K'):
+		t.scroll = max(0, t.scroll-1)
+	case k.is('v'):
+		t.text = !t.text
+		t.scroll = 0
+	case k.is('s'):
+		t.stateFilter = (t.stateFilter + 1) % len(stateFilters)
+		t.applyFilters()
+	case k.is('t'):
+		t.mode, t.input = modeTime, ""
+	case k.is('/'):
+		t.mode, t.input, t.searchOrigin = modeSearch, "", t.cursor
+	case k.is('n'), k.is('N'):
+		dir := 1
+		if k.is('N') {
+			dir = -1
+		}
+		if !t.find(t.query, t.cursor+dir, dir) {
+			t.status = "no match"
+		}
+	}
+}
+
+func (t *tui) handleInput(k key) {
+	switch k.name {
+	case "esc", "ctrl-c":
+		if t.mode == modeSearch {
+			t.cursor = t.searchOrigin
+		}
+		t.mode = modeNormal
+		return
+	case "backspace":
+		if t.input != "" {
+			_, size := utf8.DecodeLastRuneInString(t.input)
+			t.input = t.input[:len(t.input)-size]
+		}
+	case "enter":
+		mode := t.mode
+		t.mode = modeNormal
+		if mode == modeSearch {
+			t.query = t.input
+		} else {
+			t.applyTimeRange(t.input)
+		}
+		return
+	default:
+		if k.name == "" {
+			t.input += string(k.r)
+		}
+	}
+
+	// Search is incremental: re-run it from the original position after
+	// every keystroke
+	if t.mode == modeSearch {
+		t.cursor = t.searchOrigin
+		t.status = ""
+		if t.input != "" && !t.find(t.input, t.searchOrigin, 1) {
+			t.status = "no match"
+		}
+	}
+}
+
+// applyTimeRange parses a "FROM..TO" range (either side may be empty) and
+// filters on it. An empty range clears the time filter.
+func (t *tui) applyTimeRange(input string) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		t.from, t.to = time.Time{}, time.Time{}
+		t.applyFilters()
+		return
+	}
+
+	from, to, ok := strings.Cut(input, "..")
+	if !ok {
+		t.status = "time range must be FROM..TO"
+		return
+	}
+	fromTime, err := parseTimeBound(strings.TrimSpace(from), false)
+	if err != nil {
+		t.status = "bad start time: " + err.Error()
+		return
+	}
+	toTime, err := parseTimeBound(strings.TrimSpace(to), true)
+	if err != nil {
+		t.status = "bad end time: " + err.Error()
+		return
+	}
+	t.from, t.to = fromTime, toTime
+	t.applyFilters()
+}
+
+// render draws the full screen as a slice of lines.
+func (t *tui) render(width, height int) []string {
+	lines := make([]string, 0, height)
+
+	// Title bar
+	filter := "all"
+	if state := stateFilters[t.stateFilter]; state != 0 {
+		filter = state.String()
+	}
+	timeFilter := "any time"
+	if !t.from.IsZero() || !t.to.IsZero() {
+		timeFilter = formatBound(t.from) + ".." + formatBound(t.to)
+	}
+	title := fmt.Sprintf(" %s  %v  %d/%d entries  state: %s  %s", t.name, t.data.Version, len(t.visible), len(t.data.Entries), filter, timeFilter)
+	lines = append(lines, t.s.paint("7", pad(title, width)))
+
+	bodyHeight := max(1, height-2)
+	listWidth := min(48, width/2)
+	detailWidth := max(0, width-listWidth-1)
+
+	// Keep the cursor within the list viewport
+	if t.cursor < t.top {
+		t.top = t.cursor
+	}
+	if t.cursor >= t.top+bodyHeight {
+		t.top = t.cursor - bodyHeight + 1
+	}
+
+	detail := t.renderDetail(detailWidth)
+	t.scroll = max(0, min(t.scroll, len(detail)-1))
+	detail = detail[t.scroll:]
+
+	for row := 0; row < bodyHeight; row++ {
+		left := ""
+		if r := t.top + row; r < len(t.visible) {
+			entry := t.data.Entries[t.visible[r]]
+			left = pad(fmt.Sprintf("%5d %-7s %s %7d", t.visible[r], entry.State, displayTime(entry.Created).Format(time.DateTime), len(entry.Data)), listWidth)
+			if r == t.cursor {
+				left = t.s.paint("7", left)
+			} else if entry.State != segb.EntryStateWritten {
+				left = t.s.dim(left)
+			}
+		} else {
+			left = pad("", listWidth)
+		}
+
+		right := ""
+		if row < len(detail) {
+			right = detail[row]
+		}
+		lines = append(lines, left+t.s.dim("│")+right)
+	}
+
+	// Status / prompt line
+	var bottom string
+	switch t.mode {
+	case modeSearch:
+		bottom = "/" + t.input
+	case modeTime:
+		bottom = "time range (FROM..TO, YYYY-MM-DD or RFC 3339, empty to clear): " + t.input
+	default:
+		bottom = tuiHelp
+	}
+	if t.status != "" {
+		bottom += "  [" + t.status + "]"
+	}
+	lines = append(lines, pad(bottom, width))
+
+	return lines
+}
+
+// renderDetail returns the lines of the detail pane for the selected entry.
+func (t *tui) renderDetail(width int) []string {
+	index := t.selected()
+	if index < 0 {
+		return []string{" no entries match the current filters"}
+	}
+	entry := t.data.Entries[index]
+
+	lines := []string{
+		fmt.Sprintf(" Entry %d  %s  %s", index, t.s.stateBadge(entry.State), t.s.crcStatus(entry.CRCValid, entry.CRCRepaired)),
+		" Created: " + formatTime(entry.Created),
+		fmt.Sprintf(" Size:    %d bytes %s", len(entry.Data), entry.ContentType),
+	}
+	annotation := t.annotations.For(entry)
+	if len(annotation.Tags) > 0 {
+		lines = append(lines, " Tags:    "+strings.Join(annotation.Tags, ", "))
+	}
+	if annotation.Note != "" {
+		lines = append(lines, " Note:    "+annotation.Note)
+	}
+	lines = append(lines, "")
+
+	if t.text != textual(entry.ContentType) {
+		// Wrap on runes, as pad counts them, not to split characters
+		for _, line := range strings.Split(renderText(entry.Data, entry.ContentType), "\n") {
+			runes := []rune(line)
+			for len(runes) > width-1 && width > 1 {
+				lines = append(lines, " "+string(runes[:width-1]))
+				runes = runes[width-1:]
+			}
+			lines = append(lines, " "+string(runes))
+		}
+		return lines
+	}
+
+	// Pick the widest hexdump that fits: offset (10) + 3 per byte + ASCII
+	bytesPerLine := 4
+	for _, n := range []int{32, 16, 8} {
+		if 11+4*n+1 <= width {
+			bytesPerLine = n
+			break
+		}
+	}
+	dump := hexdump.String(entry.Data, hexdump.Options{Width: bytesPerLine, Color: t.s.color})
+	for _, line := range strings.Split(strings.TrimSuffix(dump, "\n"), "\n") {
+		lines = append(lines, " "+line)
+	}
+	return lines
+}
+
+// printableText renders a payload as text, escaping non-printable bytes.
+func printableText(data []byte) string {
+	var sb strings.Builder
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		switch {
+		case r == '\n' || r == '\t':
+			sb.WriteRune(r)
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f:
+			fmt.Fprintf(&sb, "\\x%02x", data[0])
+		default:
+			sb.WriteRune(r)
+		}
+		data = data[size:]
+	}
+	return sb.String()
+}
+
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return displayTime(t).Format(time.DateOnly)
+}
+
+// pad truncates or right-pads s to exactly width runes.
+func pad(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		return string([]rune(s)[:width])
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// key is a decoded key press: either a printable rune or a named key.
+type key struct {
+	r    rune
+	name string
+}
+
+func (k key) is(r rune) bool { return k.name == "" && k.r == r }
+
+// parseKeys splits raw terminal input into key presses.
+func parseKeys(buf []byte) []key {
+	var keys []key
+	for len(buf) > 0 {
+		switch {
+		case buf[0] == 0x1b && len(buf) >= 3 && (buf[1] == '[' || buf[1] == 'O'):
+			name, n := "", 3
+			switch buf[2] {
+			case 'A':
+				name = "up"
+			case 'B':
+				name = "down"
+			case 'H':
+				name = "home"
+			case 'F':
+				name = "end"
+			case '5', '6':
+				if len(buf) >= 4 && buf[3] == '~' {
+					name, n = map[byte]string{'5': "pgup", '6': "pgdn"}[buf[2]], 4
+				}
+			}
+			keys = append(keys, key{name: name})
+			buf = buf[n:]
+		case buf[0] == 0x1b:
+			keys = append(keys, key{name: "esc"})
+			buf = buf[1:]
+		case buf[0] == '\r' || buf[0] == '\n':
+			keys = append(keys, key{name: "enter"})
+			buf = buf[1:]
+		case buf[0] == 0x7f || buf[0] == 0x08:
+			keys = append(keys, key{name: "backspace"})
+			buf = buf[1:]
+		case buf[0] == 0x03:
+			keys = append(keys, key{name: "ctrl-c"})
+			buf = buf[1:]
+		default:
+			r, size := utf8.DecodeRune(buf)
+			if r >= 0x20 {
+				keys = append(keys, key{r: r})
+			}
+			buf = buf[size:]
+		}
+	}
+	return keys
+}
+
+func runTUI(args []string) error {
+	flags := flag.NewFlagSet("tui", flag.ExitOnError)
+	noColor := flags.Bool("no-color", false, "disable colors")
+	parseFlags(flags, args)
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("tui requires an interactive terminal")
+	}
+
+	data, err := openAndDecode(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	annotations, err := loadAnnotations(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	t := newTUI(flags.Arg(0), data, annotations, newStyle(os.Stdout, *noColor))
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// Alternate screen, hidden cursor; undone on exit
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 64)
+	for !t.quit {
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+
+		var screen strings.Builder
+		screen.WriteString("\x1b[H\x1b[2J")
+		screen.WriteString(strings.Join(t.render(width, height), "\r\n"))
+		os.Stdout.WriteString(screen.String())
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			t.handleKey(k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/bluefalconhd/segb"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// testTUI returns a TUI over four entries, the third deleted, and the note
+// of an annotation on the second.
+func testTUI() *tui {
+	data := segb.Segb{Version: segb.SEGB_VERSION_2}
+	for i, text := range []string{"alpha", "bravo", "charlie", strings.Repeat("é", 30)} {
+		entry := segb.Entry{
+			Data:    []byte(text),
+			Created: time.Date(2024, 5, 1+i, 12, 0, 0, 0, time.UTC),
+			State:   segb.EntryStateWritten,
+		}
+		if i == 2 {
+			entry.State = segb.EntryStateDeleted
+		}
+		entry.ContentType = segb.Classify(entry.Data)
+		data.Entries = append(data.Entries, entry)
+	}
+	annotations := make(segb.Annotations)
+	annotations.Set(data.Entries[1], segb.Annotation{Note: "Seen in the report"})
+	return newTUI("test.segb", data, annotations, style{})
+}
+
+// press sends keys to the TUI, runes as typed and named keys in brackets.
+func press(t *tui, keys ...string) {
+	for _, k := range keys {
+		if name, ok := strings.CutPrefix(k, "<"); ok {
+			t.handleKey(key{name: strings.TrimSuffix(name, ">")})
+			continue
+		}
+		for _, r := range k {
+			t.handleKey(key{r: r})
+		}
+	}
+}
+
+func TestTUIKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		keys     []string
+		selected int
+		visible  int
+		status   string
+	}{
+		{"start", nil, 0, 4, ""},
+		{"down", []string{"jj"}, 2, 4, ""},
+		{"past the end", []string{"jjjjjj"}, 3, 4, ""},
+		{"up", []string{"G", "<up>"}, 2, 4, ""},
+		{"search", []string{"/", "char", "<enter>"}, 2, 4, ""},
+		{"search notes", []string{"/", "REPORT", "<enter>"}, 1, 4, ""},
+		{"search hex", []string{"/", "x:61 6c", "<enter>"}, 0, 4, ""},
+		{"search cancelled", []string{"j", "/", "char", "<esc>"}, 1, 4, ""},
+		{"no match", []string{"/", "zulu"}, 0, 4, "no match"},
+		{"next match", []string{"/", "a", "<enter>", "n"}, 1, 4, ""},
+		{"previous match", []string{"/", "a", "<enter>", "N"}, 2, 4, ""},
+		{"backspace", []string{"/", "chz", "<backspace>", "<backspace>", "<enter>", "n"}, 2, 4, ""},
+		{"written only", []string{"jjj", "s"}, 3, 3, ""},
+		{"deleted only", []string{"ss"}, 2, 1, ""},
+		{"time range", []string{"t", "2024-05-02..2024-05-03", "<enter>"}, 1, 2, ""},
+		{"open time range", []string{"t", "2024-05-03..", "<enter>"}, 2, 2, ""},
+		{"cleared time range", []string{"t", "2024-05-03..", "<enter>", "t", "<enter>"}, 2, 4, ""},
+		{"bad time range", []string{"t", "yesterday", "<enter>"}, 0, 4, "time range must be FROM..TO"},
+	} {
+		ui := testTUI()
+		press(ui, tc.keys...)
+		if ui.selected() != tc.selected || len(ui.visible) != tc.visible || ui.status != tc.status {
+			t.Errorf("%s: selected %d of %d, status %q; want %d of %d, %q", tc.name, ui.selected(), len(ui.visible), ui.status, tc.selected, tc.visible, tc.status)
+		}
+	}
+
+	ui := testTUI()
+	press(ui, "q")
+	if !ui.quit {
+		t.Error("q did not quit")
+	}
+}
+
+func TestTUIRender(t *testing.T) {
+	ui := testTUI()
+	press(ui, "G")
+	const width, height = 61, 12
+	lines := ui.render(width, height)
+	if len(lines) != height {
+		t.Fatalf("%d lines, want %d", len(lines), height)
+	}
+	if !strings.Contains(lines[0], "test.segb") || !strings.Contains(lines[0], "4/4 entries") {
+		t.Errorf("title %q", lines[0])
+	}
+	if lines[height-1] != pad(tuiHelp, width) {
+		t.Errorf("status line %q", lines[height-1])
+	}
+
+	// The payload of 30 two-byte runes wraps on runes to the detail pane
+	var payload []string
+	for _, line := range lines[1 : height-1] {
+		_, right, _ := strings.Cut(line, "│")
+		if !strings.Contains(right, "é") {
+			continue
+		}
+		if !utf8.ValidString(line) || utf8.RuneCountInString(line) > width {
+			t.Errorf("payload line %q", line)
+		}
+		payload = append(payload, strings.TrimSpace(right))
+	}
+	if got := strings.Join(payload, ""); got != strings.Repeat("é", 30) || len(payload) < 2 {
+		t.Errorf("payload lines %q", payload)
+	}
+
+	press(ui, "/", "zulu")
+	if bottom := ui.render(width, height)[height-1]; !strings.HasPrefix(bottom, "/zulu  [no match]") {
+		t.Errorf("prompt line %q", bottom)
+	}
+}
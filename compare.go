@@ -0,0 +1,248 @@
+package segb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"slices"
+	"strconv"
+)
+
+// maxCompareEdits bounds the number of inserted and deleted bytes the
+// alignment of two payloads looks for. Payloads that differ more are
+// reported as one differing range between their common prefix and suffix.
+const maxCompareEdits = 1024
+
+// maxCompareDepth bounds how deep nested messages are compared.
+const maxCompareDepth = 8
+
+// Hunk is a range of one payload that differs from a range of the other:
+// A[AOffset:AOffset+ALen] stands where B has B[BOffset:BOffset+BLen]. One
+// of the lengths is 0 for bytes only one payload has.
+type Hunk struct {
+	AOffset, ALen int
+	BOffset, BLen int
+}
+
+// FieldDiff is a protobuf field that differs between two payloads.
+type FieldDiff struct {
+	// Path of field numbers from the top-level message, e.g. "4.2", with
+	// the occurrence in brackets for fields that are repeated, e.g. "3[1]"
+	Path string
+	Type protowire.Type
+	A, B []byte // The encoded values, nil where the field is absent
+}
+
+// Comparison is the structural difference between two payloads, as a help
+// in figuring out what their bytes mean.
+type Comparison struct {
+	Prefix   int         // Length of the common prefix
+	Suffix   int         // Length of the common suffix, not overlapping the prefix
+	Hunks    []Hunk      // Differing ranges, in order, once the payloads are aligned
+	Protobuf bool        // Both payloads parse as protobuf messages
+	Fields   []FieldDiff // Differing fields if Protobuf, in order of field number
+}
+
+// Equal reports whether the payloads compared are identical.
+func (c Comparison) Equal() bool {
+	return len(c.Hunks) == 0
+}
+
+// ComparePayloads aligns two payloads, as diff does lines, and reports the
+// ranges that differ and, if both are protobuf messages, the fields that do.
+func ComparePayloads(a, b []byte) Comparison {
+	var c Comparison
+	for c.Prefix < len(a) && c.Prefix < len(b) && a[c.Prefix] == b[c.Prefix] {
+		c.Prefix++
+	}
+	for c.Suffix < min(len(a), len(b))-c.Prefix && a[len(a)-1-c.Suffix] == b[len(b)-1-c.Suffix] {
+		c.Suffix++
+	}
+	for _, h := range alignBytes(a[c.Prefix:len(a)-c.Suffix], b[c.Prefix:len(b)-c.Suffix]) {
+		h.AOffset += c.Prefix
+		h.BOffset += c.Prefix
+		c.Hunks = append(c.Hunks, h)
+	}
+
+	if isProtobuf(a) && isProtobuf(b) {
+		c.Protobuf = true
+		c.Fields = compareFields(a, b, "", 0)
+	}
+	return c
+}
+
+// alignBytes returns the hunks of a shortest edit script from a to b, found
+// with Myers' algorithm, or a single hunk if it takes more than
+// maxCompareEdits edits.
+func alignBytes(a, b []byte) []Hunk {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	whole := []Hunk{{AOffset: 0, ALen: n, BOffset: 0, BLen: m}}
+	if n == 0 || m == 0 {
+		return whole
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k = x-y; trace
+	// keeps v[offset-d:offset+d+1] as it was after each d
+	maxEdits := min(n+m, maxCompareEdits)
+	offset := maxEdits + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	edits := -1
+	for d := 0; d <= maxEdits && edits < 0; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				edits = d
+				break
+			}
+		}
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+	}
+	if edits < 0 {
+		return whole
+	}
+
+	// Walk the edits back, collecting the runs of equal bytes between them
+	type run struct{ a, b, n int }
+	var runs []run
+	x, y := n, m
+	for d := edits; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		// The edit leads to (startX, startY), from which the run goes to (x, y)
+		startX, startY := prevX, prevY+1
+		if prevK == k-1 {
+			startX, startY = prevX+1, prevY
+		}
+		if x > startX {
+			runs = append(runs, run{startX, startY, x - startX})
+		}
+		x, y = prevX, prevY
+	}
+	if x > 0 {
+		runs = append(runs, run{0, 0, x})
+	}
+	slices.Reverse(runs)
+
+	var hunks []Hunk
+	nextA, nextB := 0, 0
+	for _, r := range append(runs, run{n, m, 0}) {
+		if r.a > nextA || r.b > nextB {
+			hunks = append(hunks, Hunk{AOffset: nextA, ALen: r.a - nextA, BOffset: nextB, BLen: r.b - nextB})
+		}
+		nextA, nextB = r.a+r.n, r.b+r.n
+	}
+	return hunks
+}
+
+// wireField is one field of a message on the wire.
+type wireField struct {
+	number protowire.Number
+	typ    protowire.Type
+	value  []byte // Encoded value, the contents of length-delimited ones
+}
+
+// wireFields splits a message that isProtobuf accepts into its fields.
+func wireFields(data []byte) []wireField {
+	var fields []wireField
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		data = data[n:]
+		field := wireField{number: number, typ: typ}
+		if typ == protowire.BytesType {
+			field.value, n = protowire.ConsumeBytes(data)
+		} else {
+			n = protowire.ConsumeFieldValue(number, typ, data)
+			field.value = data[:n]
+		}
+		data = data[n:]
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// compareFields returns the fields that differ between two messages,
+// comparing the occurrences of each field number in order and descending
+// into values that both parse as messages.
+func compareFields(a, b []byte, prefix string, depth int) []FieldDiff {
+	byNumber := func(fields []wireField) map[protowire.Number][]wireField {
+		grouped := make(map[protowire.Number][]wireField)
+		for _, f := range fields {
+			grouped[f.number] = append(grouped[f.number], f)
+		}
+		return grouped
+	}
+	fieldsA, fieldsB := byNumber(wireFields(a)), byNumber(wireFields(b))
+	var numbers []protowire.Number
+	for number := range fieldsA {
+		numbers = append(numbers, number)
+	}
+	for number := range fieldsB {
+		if _, ok := fieldsA[number]; !ok {
+			numbers = append(numbers, number)
+		}
+	}
+	slices.Sort(numbers)
+
+	var diffs []FieldDiff
+	for _, number := range numbers {
+		occurrencesA, occurrencesB := fieldsA[number], fieldsB[number]
+		repeated := len(occurrencesA) > 1 || len(occurrencesB) > 1
+		for i := range max(len(occurrencesA), len(occurrencesB)) {
+			path := prefix + strconv.Itoa(int(number))
+			if repeated {
+				path += "[" + strconv.Itoa(i) + "]"
+			}
+			switch {
+			case i >= len(occurrencesA):
+				diffs = append(diffs, FieldDiff{Path: path, Type: occurrencesB[i].typ, B: occurrencesB[i].value})
+			case i >= len(occurrencesB):
+				diffs = append(diffs, FieldDiff{Path: path, Type: occurrencesA[i].typ, A: occurrencesA[i].value})
+			case occurrencesA[i].typ != occurrencesB[i].typ:
+				// The same number with another wire type is another field
+				diffs = append(diffs,
+					FieldDiff{Path: path, Type: occurrencesA[i].typ, A: occurrencesA[i].value},
+					FieldDiff{Path: path, Type: occurrencesB[i].typ, B: occurrencesB[i].value})
+			default:
+				diffs = append(diffs, compareValues(path, occurrencesA[i], occurrencesB[i], depth)...)
+			}
+		}
+	}
+	return diffs
+}
+
+// compareValues compares two occurrences of a field of the same wire type.
+func compareValues(path string, a, b wireField, depth int) []FieldDiff {
+	if string(a.value) == string(b.value) {
+		return nil
+	}
+	if a.typ == protowire.BytesType && depth < maxCompareDepth && nestedMessage(a.value) && nestedMessage(b.value) {
+		return compareFields(a.value, b.value, path+".", depth+1)
+	}
+	return []FieldDiff{{Path: path, Type: a.typ, A: a.value, B: b.value}}
+}
+
+// nestedMessage reports whether a length-delimited value reads as a nested
+// message rather than a string.
+func nestedMessage(data []byte) bool {
+	return len(data) > 0 && !isText(data) && isProtobuf(data)
+}
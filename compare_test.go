@@ -0,0 +1,75 @@
+package segb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"reflect"
+	"testing"
+)
+
+func TestComparePayloads(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		a, b           string
+		prefix, suffix int
+		hunks          []Hunk
+	}{
+		{"equal", "abcdef", "abcdef", 6, 0, nil},
+		{"substitution", "abcXef", "abcYef", 3, 2, []Hunk{{3, 1, 3, 1}}},
+		{"insertion", "abcdef", "abc12def", 3, 3, []Hunk{{3, 0, 3, 2}}},
+		{"two changes", "the quick fox", "the quiet fox!", 7, 0, []Hunk{{7, 2, 7, 2}, {13, 0, 13, 1}}},
+		{"realigned", "xaaab", "aaabx", 0, 0, []Hunk{{0, 1, 0, 0}, {5, 0, 4, 1}}},
+		{"disjoint", "abc", "xyz", 0, 0, []Hunk{{0, 3, 0, 3}}},
+		{"empty", "", "abc", 0, 0, []Hunk{{0, 0, 0, 3}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ComparePayloads([]byte(tc.a), []byte(tc.b))
+			if c.Prefix != tc.prefix || c.Suffix != tc.suffix {
+				t.Errorf("prefix, suffix = %d, %d; want %d, %d", c.Prefix, c.Suffix, tc.prefix, tc.suffix)
+			}
+			if !reflect.DeepEqual(c.Hunks, tc.hunks) {
+				t.Errorf("Hunks = %v; want %v", c.Hunks, tc.hunks)
+			}
+			if c.Equal() != (tc.a == tc.b) {
+				t.Errorf("Equal() = %v; want %v", c.Equal(), tc.a == tc.b)
+			}
+		})
+	}
+}
+
+func TestComparePayloadsFields(t *testing.T) {
+	message := func(id uint64, name string, extra bool) []byte {
+		var nested []byte
+		nested = protowire.AppendTag(nested, 1, protowire.VarintType)
+		nested = protowire.AppendVarint(nested, id)
+		nested = protowire.AppendTag(nested, 2, protowire.Fixed64Type)
+		nested = protowire.AppendFixed64(nested, 42)
+
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, name)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, nested)
+		if extra {
+			b = protowire.AppendTag(b, 5, protowire.VarintType)
+			b = protowire.AppendVarint(b, 1)
+		}
+		return b
+	}
+
+	c := ComparePayloads(message(1, "com.apple.Maps", false), message(300, "com.apple.Music", true))
+	if !c.Protobuf {
+		t.Fatal("Protobuf = false; want true")
+	}
+	want := []FieldDiff{
+		{Path: "1", Type: protowire.BytesType, A: []byte("com.apple.Maps"), B: []byte("com.apple.Music")},
+		{Path: "3.1", Type: protowire.VarintType, A: protowire.AppendVarint(nil, 1), B: protowire.AppendVarint(nil, 300)},
+		{Path: "5", Type: protowire.VarintType, B: protowire.AppendVarint(nil, 1)},
+	}
+	if !reflect.DeepEqual(c.Fields, want) {
+		t.Errorf("Fields = %q; want %q", c.Fields, want)
+	}
+
+	if c := ComparePayloads([]byte{0xff, 0xff}, message(1, "a", false)); c.Protobuf || c.Fields != nil {
+		t.Errorf("Compare(not protobuf) = %v, %v; want false, nil", c.Protobuf, c.Fields)
+	}
+}
@@ -0,0 +1,74 @@
+package segb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// StreamPart is one of the SEGB structures of a concatenated input, see
+// DecodeAllStreams.
+type StreamPart struct {
+	Offset int64 // Absolute offset of the structure in the input
+	Length int64 // Number of bytes decoded as the structure
+	Segb   Segb  // Offsets in it are absolute, like Offset
+}
+
+// DecodeAllStreams decodes an input holding several SEGB files back to back,
+// as some exports produce. It returns each in input order, along with the
+// byte range it was decoded from. A single SEGB file yields a single part.
+//
+// The input must start with a SEGB structure. Each following one is located
+// like Carve does, by its magic; the space between the end of data of a v1
+// file and the next structure is decoded as part of the v1 file, like the
+// preallocated space of a standalone one, while bytes after a v2 trailer
+// are skipped. opts apply to every part.
+func DecodeAllStreams(stream io.ReadSeeker, opts ...DecodeOption) ([]StreamPart, error) {
+	cfg := decodeConfig{payloads: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// Unwrap compressed input once for all parts
+	stream, _, err := decompress(stream, cfg.decompressLimit())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	carved, err := Carve(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(carved) == 0 || carved[0].Offset != 0 {
+		// Fail like Decode would on the whole input
+		if _, err := DecodeBytes(data, opts...); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no SEGB structure at the start of the input")
+	}
+
+	parts := make([]StreamPart, 0, len(carved))
+	for i, c := range carved {
+		end := c.Offset + c.Length
+		if c.Version == SEGB_VERSION_1 {
+			end = int64(len(data))
+			if i+1 < len(carved) {
+				end = carved[i+1].Offset
+			}
+		}
+		decoded, err := DecodeBytes(data[c.Offset:end], opts...)
+		if err != nil {
+			return nil, fmt.Errorf("SEGB structure at offset 0x%x: %w", c.Offset, err)
+		}
+		decoded.shiftOffsets(c.Offset)
+		parts = append(parts, StreamPart{Offset: c.Offset, Length: end - c.Offset, Segb: decoded})
+	}
+	return parts, nil
+}
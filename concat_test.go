@@ -0,0 +1,60 @@
+package segb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeAllStreams(t *testing.T) {
+	first := v1File(8, []byte(expectedEntryData[0]), []byte(expectedEntryData[1]))
+	first = append(first, make([]byte, 64)...) // Preallocated space
+	second := v2File([]byte(expectedEntryData[2]))
+	third := v1File(8, []byte(expectedEntryData[0]))
+	input := append(append(append([]byte(nil), first...), second...), third...)
+
+	parts, err := DecodeAllStreams(bytes.NewReader(input), WithRoundTrip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		offset, length int
+		version        SegbVersion
+		entries        []string
+	}{
+		{0, len(first), SEGB_VERSION_1, expectedEntryData[:2]},
+		{len(first), len(second), SEGB_VERSION_2, expectedEntryData[2:]},
+		{len(first) + len(second), len(third), SEGB_VERSION_1, expectedEntryData[:1]},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("DecodeAllStreams() = %d parts; want %d", len(parts), len(want))
+	}
+	for i, part := range parts {
+		w := want[i]
+		if part.Offset != int64(w.offset) || part.Length != int64(w.length) || part.Segb.Version != w.version || len(part.Segb.Entries) != len(w.entries) {
+			t.Errorf("part %d = %v at %d+%d, %d entries; want %v at %d+%d", i, part.Segb.Version, part.Offset, part.Length, len(part.Segb.Entries), w.version, w.offset, w.length)
+			continue
+		}
+		for j, entry := range part.Segb.Entries {
+			// Offsets are absolute
+			if string(entry.Data) != w.entries[j] || !bytes.Equal(input[entry.Offset:entry.Offset+entry.Size], entry.Data) {
+				t.Errorf("part %d entry %d = %q at %d", i, j, entry.Data, entry.Offset)
+			}
+		}
+
+		// Parts encode back to their own bytes
+		var buf bytes.Buffer
+		if err := part.Segb.Encode(&buf); err != nil {
+			t.Errorf("part %d: Encode() = %v", i, err)
+		} else if !bytes.Equal(buf.Bytes(), input[part.Offset:part.Offset+part.Length]) {
+			t.Errorf("part %d: Encode() differs from the input", i)
+		}
+	}
+
+	// A single file is a single part
+	if parts, err := DecodeAllStreams(bytes.NewReader(second)); err != nil || len(parts) != 1 {
+		t.Errorf("DecodeAllStreams(single file) = %d parts, %v; want 1", len(parts), err)
+	}
+	if _, err := DecodeAllStreams(bytes.NewReader(bytes.Repeat([]byte{0xAA}, 100))); err == nil {
+		t.Error("DecodeAllStreams(junk) succeeded")
+	}
+}
@@ -0,0 +1,304 @@
+package segb
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// FileResult holds the outcome of decoding a single file found by LoadDir.
+type FileResult struct {
+	Path    string
+	Size    int64
+	Version SegbVersion
+	Segb    Segb
+	Err     error // Non-nil if the file looked like SEGB but could not be opened or decoded
+	Cached  bool  // Segb came from the cache given to WithCache
+
+	cacheMiss bool
+}
+
+// CorpusStats aggregates counters over every file visited by LoadDir.
+type CorpusStats struct {
+	FilesScanned   int                // Regular files visited
+	SegbFiles      int                // Files detected as SEGB
+	Decoded        int                // SEGB files decoded without error
+	Failed         int                // Files that could not be opened or decoded
+	Entries        int                // Total entries across decoded files
+	EntriesByState map[EntryState]int // Entry counts keyed by state
+	Bytes          int64              // Total on-disk size of SEGB files
+	CacheHits      int                // SEGB files found in the cache
+	CacheMisses    int                // SEGB files decoded with a cache set
+}
+
+// Corpus is the result of loading every SEGB file below a directory.
+type Corpus struct {
+	Root  string
+	Files map[string]*FileResult // Keyed by path (root joined with the relative path, slash-separated for LoadFS)
+	Stats CorpusStats
+}
+
+// Paths returns the paths of all files in the corpus in sorted order.
+func (c *Corpus) Paths() []string {
+	paths := make([]string, 0, len(c.Files))
+	for path := range c.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+type loadConfig struct {
+	workers  int
+	cache    Cache
+	cacheKey CacheKey
+	retry    RetryPolicy
+	guard    *IntegrityGuard
+	fsys     fs.FS // File system the files are read from, nil for the OS's
+}
+
+// LoadOption configures LoadDir.
+type LoadOption func(*loadConfig)
+
+// WithWorkers sets how many files are decoded in parallel. A value of zero
+// or less uses one worker per CPU. The default is 1 (sequential).
+func WithWorkers(n int) LoadOption {
+	return func(c *loadConfig) {
+		if n <= 0 {
+			n = runtime.NumCPU()
+		}
+		c.workers = n
+	}
+}
+
+// LoadDir walks the directory tree rooted at root, sniffs every regular file
+// for a SEGB header (file names are ignored, Biome segments usually have
+// none), decodes the ones that match and returns the per-file results along
+// with aggregate statistics.
+func LoadDir(root string, opts ...LoadOption) (*Corpus, error) {
+	return load(nil, root, opts)
+}
+
+// LoadFS is LoadDir for the directory root of fsys, e.g. a bucket of
+// object storage opened with the remote package, so that files are decoded
+// in place rather than copied to disk first. The files of fsys must
+// implement io.Seeker. Stat cache keys (see WithCache) hold paths within
+// fsys, so a Cache shared with other file systems should use
+// CacheKeyContent.
+func LoadFS(fsys fs.FS, root string, opts ...LoadOption) (*Corpus, error) {
+	return load(fsys, root, opts)
+}
+
+// load implements LoadDir and LoadFS, reading from the OS's file system if
+// fsys is nil.
+func load(fsys fs.FS, root string, opts []LoadOption) (*Corpus, error) {
+	config := loadConfig{workers: 1, fsys: fsys}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	corpus := &Corpus{
+		Root:  root,
+		Files: make(map[string]*FileResult),
+		Stats: CorpusStats{EntriesByState: make(map[EntryState]int)},
+	}
+
+	// Collect the candidate files first so the workers only do decoding
+	var paths []string
+	collect := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	}
+	var err error
+	if fsys == nil {
+		err = filepath.WalkDir(root, collect)
+	} else {
+		err = fs.WalkDir(fsys, root, collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+	corpus.Stats.FilesScanned = len(paths)
+
+	jobs := make(chan string)
+	results := make(chan *FileResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				if result := loadFile(path, config); result != nil {
+					results <- result
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, path := range paths {
+			jobs <- path
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		corpus.Files[result.Path] = result
+		corpus.Stats.add(result)
+	}
+
+	return corpus, nil
+}
+
+// loadFile sniffs and decodes a single file. It returns nil if the file is
+// not a SEGB file.
+func loadFile(path string, config loadConfig) *FileResult {
+	file, err := config.open(path)
+	if err != nil {
+		return &FileResult{Path: path, Err: err}
+	}
+	defer file.Close()
+
+	var key string
+	if config.cache != nil {
+		name := path
+		if config.fsys == nil {
+			if name, err = filepath.Abs(path); err != nil {
+				return &FileResult{Path: path, Err: err}
+			}
+		}
+		if key, err = cacheKey(file, name, config.cacheKey); err != nil {
+			return &FileResult{Path: path, Err: err}
+		}
+		if s, ok := config.cache.Get(key); ok {
+			result := &FileResult{Path: path, Version: s.Version, Segb: s, Cached: true}
+			if info, err := file.Stat(); err == nil {
+				result.Size = info.Size()
+			}
+			return result
+		}
+	}
+
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	result := loadStream(path, file, size, config)
+	if result != nil && config.cache != nil && result.Err == nil {
+		result.cacheMiss = true
+		config.cache.Put(key, result.Segb)
+	}
+	return result
+}
+
+// LoadStream sniffs and decodes a single file of the given size read from
+// stream, as LoadDir does for each file it finds, for files that are not
+// in a directory, such as the members of an archive. Of the options, only
+// WithLoadRetry applies. It returns nil if the file is not a SEGB file.
+func LoadStream(path string, stream io.ReadSeeker, size int64, opts ...LoadOption) *FileResult {
+	var config loadConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return loadStream(path, stream, size, config)
+}
+
+// loadStream implements LoadStream.
+func loadStream(path string, source io.ReadSeeker, size int64, config loadConfig) *FileResult {
+	if config.retry.Attempts > 1 {
+		source = NewRetryReader(source, config.retry)
+	}
+
+	// Compressed files are sniffed by their contents
+	stream, _, err := Decompress(source)
+	if err != nil {
+		return nil
+	}
+
+	// Ambiguous files are still SEGB, Decode settles their version
+	version, err := DetectVersion(stream)
+	if (err != nil && !errors.Is(err, ErrAmbiguousVersion)) || version == NONE {
+		return nil
+	}
+
+	result := &FileResult{Path: path, Version: version, Size: size}
+	result.Segb, result.Err = Decode(stream)
+	return result
+}
+
+// Add records a file visited outside LoadDir, e.g. while reading an
+// archive: it is counted in Stats, and result, the outcome of LoadStream,
+// is added to Files unless it is nil.
+func (c *Corpus) Add(result *FileResult) {
+	if c.Files == nil {
+		c.Files = make(map[string]*FileResult)
+	}
+	if c.Stats.EntriesByState == nil {
+		c.Stats.EntriesByState = make(map[EntryState]int)
+	}
+	c.Stats.FilesScanned++
+	if result != nil {
+		c.Files[result.Path] = result
+		c.Stats.add(result)
+	}
+}
+
+// seekFile is a file that decoding can seek in.
+type seekFile interface {
+	fs.File
+	io.Seeker
+}
+
+// open opens the file at path for loadFile.
+func (c loadConfig) open(path string) (seekFile, error) {
+	if c.fsys == nil && c.guard != nil {
+		return c.guard.Open(path)
+	}
+	if c.fsys == nil {
+		return os.Open(path)
+	}
+	file, err := c.fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	seeker, ok := file.(seekFile)
+	if !ok {
+		file.Close()
+		return nil, &fs.PathError{Op: "open", Path: path, Err: errors.New("file does not support seeking")}
+	}
+	return seeker, nil
+}
+
+func (s *CorpusStats) add(result *FileResult) {
+	if result.Version != NONE {
+		s.SegbFiles++
+		s.Bytes += result.Size
+	}
+	if result.Err != nil {
+		s.Failed++
+		return
+	}
+	if result.Cached {
+		s.CacheHits++
+	} else if result.cacheMiss {
+		s.CacheMisses++
+	}
+	s.Decoded++
+	s.Entries += len(result.Segb.Entries)
+	for _, entry := range result.Segb.Entries {
+		s.EntriesByState[entry.State]++
+	}
+}
@@ -0,0 +1,122 @@
+package segb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDir(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	// Lay out a small tree with extension-less segment names and a non-SEGB file
+	root := t.TempDir()
+	layout := map[string]string{
+		"segb_version1.bin": filepath.Join("a", "local", "764213212345"),
+		"segb_version2.bin": filepath.Join("a", "remote", "764213298765"),
+	}
+	for src, dst := range layout {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst = filepath.Join(root, dst)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	corpus, err := LoadDir(root, WithWorkers(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if corpus.Stats.FilesScanned != 3 {
+		t.Errorf("Stats.FilesScanned = %d; want 3", corpus.Stats.FilesScanned)
+	}
+	if corpus.Stats.SegbFiles != 2 || corpus.Stats.Decoded != 2 || corpus.Stats.Failed != 0 {
+		t.Errorf("Stats = %+v; want 2 SEGB files, 2 decoded, 0 failed", corpus.Stats)
+	}
+	if corpus.Stats.Entries != 6 || corpus.Stats.EntriesByState[EntryStateWritten] != 6 {
+		t.Errorf("Stats.Entries = %d (%d written); want 6", corpus.Stats.Entries, corpus.Stats.EntriesByState[EntryStateWritten])
+	}
+
+	paths := corpus.Paths()
+	if len(paths) != 2 {
+		t.Fatalf("len(Paths()) = %d; want 2", len(paths))
+	}
+	for _, path := range paths {
+		result := corpus.Files[path]
+		if result.Err != nil {
+			t.Errorf("%s: %v", path, result.Err)
+		}
+		CheckForEntries(t, result.Segb.Entries)
+	}
+}
+
+func TestLoadDirCache(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	root := t.TempDir()
+	for _, name := range []string{"segb_version1.bin", "segb_version2.bin"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirCache, err := NewDirCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		cache Cache
+		key   CacheKey
+	}{
+		{"memory", NewMemoryCache(), CacheKeyStat},
+		{"dir", dirCache, CacheKeyContent},
+	} {
+		first, err := LoadDir(root, WithCache(tc.cache, tc.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Stats.CacheHits != 0 || first.Stats.CacheMisses != 2 {
+			t.Errorf("%s: first run hits, misses = %d, %d; want 0, 2", tc.name, first.Stats.CacheHits, first.Stats.CacheMisses)
+		}
+
+		second, err := LoadDir(root, WithCache(tc.cache, tc.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second.Stats.CacheHits != 2 || second.Stats.CacheMisses != 0 || second.Stats.Entries != 6 {
+			t.Errorf("%s: second run stats = %+v", tc.name, second.Stats)
+		}
+		for _, path := range second.Paths() {
+			result := second.Files[path]
+			if !result.Cached {
+				t.Errorf("%s: %s not cached", tc.name, path)
+			}
+			CheckForEntries(t, result.Segb.Entries)
+
+			// Cached results still map file offsets to entries
+			entry := result.Segb.Entries[1]
+			if loc, ok := result.Segb.EntryAt(entry.Offset + 1); !ok || loc.Entry != 1 || loc.PayloadOffset != 1 {
+				t.Errorf("%s: EntryAt() = %+v, %v", tc.name, loc, ok)
+			}
+		}
+	}
+}
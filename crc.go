@@ -0,0 +1,162 @@
+package segb
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/bits"
+)
+
+// ErrCRCMismatch is returned by Decode under CRCStrict when an entry fails its
+// CRC check.
+var ErrCRCMismatch = errors.New("CRC mismatch")
+
+// CRCPolicy controls what Decode does with entries that fail their CRC check.
+type CRCPolicy int
+
+const (
+	// CRCMark records the result in Entry.CRCValid and keeps the entry.
+	CRCMark CRCPolicy = iota
+	// CRCStrict fails the decode on the first mismatch; with
+	// WithLenientParsing, failing entries are skipped into Segb.Errors
+	// instead.
+	CRCStrict
+	// CRCRepair is CRCMark, but first tries to correct a single flipped bit
+	// in the stored payload or checksum, see Entry.CRCRepaired.
+	CRCRepair
+)
+
+// String returns the lowercase name of the policy.
+func (p CRCPolicy) String() string {
+	switch p {
+	case CRCStrict:
+		return "strict"
+	case CRCRepair:
+		return "repair"
+	default:
+		return "mark"
+	}
+}
+
+// WithCRCPolicy sets how Decode handles CRC mismatches. The default is
+// CRCMark.
+func WithCRCPolicy(policy CRCPolicy) DecodeOption {
+	return func(c *decodeConfig) {
+		c.crcPolicy = policy
+	}
+}
+
+// applyCRCPolicy checks the CRC of every entry. It must run before payloads
+// are decompressed, so repairs apply to the payload as stored.
+func (s *Segb) applyCRCPolicy(policy CRCPolicy, lenient bool) error {
+	kept := s.Entries[:0]
+	for i := range s.Entries {
+		entry := s.Entries[i]
+		entry.CRCValid = entry.CheckCRC()
+		if !entry.CRCValid {
+			switch policy {
+			case CRCStrict:
+				if !lenient {
+					return fmt.Errorf("entry %d at offset 0x%x: %w", i, entry.headerOffset, ErrCRCMismatch)
+				}
+				s.Errors = append(s.Errors, EntryError{Index: entry.ID, Offset: entry.headerOffset, Err: ErrCRCMismatch})
+				continue
+			case CRCRepair:
+				entry.repairCRC()
+			}
+		}
+		kept = append(kept, entry)
+	}
+	s.Entries = kept
+	return nil
+}
+
+// repairCRC corrects a single flipped bit in the stored payload or checksum,
+// if one explains the mismatch.
+func (e *Entry) repairCRC() {
+	bit, ok := findBitFlip(e.checksummed(), e.Checksum)
+	if !ok {
+		return
+	}
+	if bit < 0 {
+		e.Checksum = crc32.ChecksumIEEE(e.checksummed())
+	} else {
+		repaired := append([]byte(nil), e.Stored()...)
+		repaired[bit/8] ^= 1 << (bit % 8)
+		if e.Raw != nil {
+			e.Raw = repaired
+		} else {
+			e.Data = repaired
+		}
+	}
+	e.CRCValid = true
+	e.CRCRepaired = true
+}
+
+// findBitFlip looks for a single bit whose flip makes data match checksum.
+// It returns the bit index in data (byte*8 + bit, least significant first),
+// or -1 if the checksum itself has a flipped bit.
+//
+// CRC-32 is affine, so flipping a bit changes the CRC by a value that only
+// depends on how many bytes follow it. Those values are computed from the
+// last byte backwards, which keeps the search linear in len(data).
+func findBitFlip(data []byte, checksum uint32) (int, bool) {
+	syndrome := crc32.ChecksumIEEE(data) ^ checksum
+	if syndrome == 0 {
+		return 0, false
+	}
+	if bits.OnesCount32(syndrome) == 1 {
+		return -1, true
+	}
+
+	var deltas [8]uint32
+	for b := range deltas {
+		deltas[b] = crc32.IEEETable[1<<b]
+	}
+	for i := len(data) - 1; i >= 0; i-- {
+		for b, delta := range deltas {
+			if delta == syndrome {
+				return i*8 + b, true
+			}
+			// One more byte now follows the bit
+			deltas[b] = crc32.IEEETable[byte(delta)] ^ delta>>8
+		}
+	}
+	return 0, false
+}
+
+// CRCFailure is an entry whose payload does not match its checksum.
+type CRCFailure struct {
+	Index    int    // Index of the entry in Segb.Entries
+	ID       int    // Entry.ID
+	Offset   int64  // Entry.Offset
+	Stored   uint32 // Checksum stored in the file
+	Computed uint32 // Checksum of the payload as stored
+}
+
+func (f CRCFailure) Error() string {
+	return fmt.Sprintf("entry %d at offset 0x%x: stored CRC %08x, computed %08x: %v", f.Index, f.Offset, f.Stored, f.Computed, ErrCRCMismatch)
+}
+
+func (f CRCFailure) Unwrap() error { return ErrCRCMismatch }
+
+// VerifyAll checks the CRC of every entry and returns those that fail, in
+// entry order, or nil if all pass. Unlike Entry.CRCValid, it reflects
+// changes made to the entries since decoding.
+func (s *Segb) VerifyAll() []CRCFailure {
+	var failures []CRCFailure
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		computed := crc32.ChecksumIEEE(entry.checksummed())
+		if computed != entry.Checksum {
+			failures = append(failures, CRCFailure{
+				Index:    i,
+				ID:       entry.ID,
+				Offset:   entry.Offset,
+				Stored:   entry.Checksum,
+				Computed: computed,
+			})
+		}
+	}
+	return failures
+}
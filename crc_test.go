@@ -0,0 +1,90 @@
+package segb
+
+import (
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+func TestFindBitFlip(t *testing.T) {
+	data := []byte("Here's to the crazy ones.")
+	checksum := crc32.ChecksumIEEE(data)
+
+	for _, bit := range []int{0, 7, 8, 100, len(data)*8 - 1} {
+		corrupt := append([]byte(nil), data...)
+		corrupt[bit/8] ^= 1 << (bit % 8)
+		if got, ok := findBitFlip(corrupt, checksum); !ok || got != bit {
+			t.Errorf("findBitFlip(bit %d) = %d, %v", bit, got, ok)
+		}
+	}
+
+	if got, ok := findBitFlip(data, checksum^1<<17); !ok || got != -1 {
+		t.Errorf("findBitFlip(checksum flip) = %d, %v", got, ok)
+	}
+	if _, ok := findBitFlip(data, checksum^0x11); ok {
+		t.Error("findBitFlip() explained a two bit error")
+	}
+}
+
+func TestCRCPolicy(t *testing.T) {
+	file := v2File([]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels."))
+	// Flip a bit in the payload of entry 1 (header 32, entry 0 36 bytes, CRC and unknown 8)
+	file[32+36+8+4] ^= 0x02
+
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Entries[0].CRCValid || decoded.Entries[1].CRCValid || !decoded.Entries[2].CRCValid {
+		t.Errorf("CRCValid = %v %v %v; want true false true", decoded.Entries[0].CRCValid, decoded.Entries[1].CRCValid, decoded.Entries[2].CRCValid)
+	}
+
+	if _, err := DecodeBytes(file, WithCRCPolicy(CRCStrict)); !errors.Is(err, ErrCRCMismatch) {
+		t.Errorf("CRCStrict: err = %v; want ErrCRCMismatch", err)
+	}
+
+	decoded, err = DecodeBytes(file, WithCRCPolicy(CRCStrict), WithLenientParsing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 2 || len(decoded.Errors) != 1 || decoded.Errors[0].Index != 1 || !errors.Is(decoded.Errors[0], ErrCRCMismatch) {
+		t.Errorf("CRCStrict lenient: %d entries, errors %v", len(decoded.Entries), decoded.Errors)
+	}
+
+	decoded, err = DecodeBytes(file, WithCRCPolicy(CRCRepair))
+	if err != nil {
+		t.Fatal(err)
+	}
+	CheckForEntries(t, decoded.Entries)
+	if entry := decoded.Entries[1]; !entry.CRCValid || !entry.CRCRepaired || decoded.Entries[0].CRCRepaired {
+		t.Errorf("CRCRepair: entry 1 = %+v", entry)
+	}
+}
+
+func TestVerifyAll(t *testing.T) {
+	file := v2File([]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels."))
+	file[32+36+8+4] ^= 0x02
+
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures := decoded.VerifyAll()
+	if len(failures) != 1 || failures[0].Index != 1 || failures[0].Stored != decoded.Entries[1].Checksum {
+		t.Fatalf("VerifyAll() = %+v; want entry 1", failures)
+	}
+	if want := crc32.ChecksumIEEE(decoded.Entries[1].Data); failures[0].Computed != want {
+		t.Errorf("Computed = %08x; want %08x", failures[0].Computed, want)
+	}
+	if !errors.Is(failures[0], ErrCRCMismatch) {
+		t.Error("CRCFailure is not ErrCRCMismatch")
+	}
+
+	decoded, err = DecodeBytes(file, WithCRCPolicy(CRCRepair))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures := decoded.VerifyAll(); failures != nil {
+		t.Errorf("VerifyAll() after repair = %+v; want nil", failures)
+	}
+}
@@ -0,0 +1,104 @@
+package segb
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PayloadDecoder turns the payloads of the entries it matches into values,
+// such as structs or maps, for the CLI and the exports to show in place of
+// the raw bytes. Decoders for the payloads of a stream are plugged in with
+// RegisterDecoder.
+type PayloadDecoder interface {
+	// Match reports whether the decoder handles the entry
+	Match(entry PayloadEntry) bool
+	// Decode returns the payload of an entry Match accepted, decoded. The
+	// value is serialized with encoding/json.
+	Decode(entry PayloadEntry) (any, error)
+}
+
+// PayloadEntry is an entry handed to a PayloadDecoder, along with the name
+// of the stream it was read from: the Biome stream of its file, or "" when
+// not known.
+type PayloadEntry struct {
+	Stream string
+	Entry
+}
+
+// namedDecoder is a registered PayloadDecoder.
+type namedDecoder struct {
+	name    string
+	decoder PayloadDecoder
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   []namedDecoder // In the order they are tried
+)
+
+// RegisterDecoder makes DecodePayload, and so the CLI and the exports, use
+// a payload decoder, under a name shown with the values it returns.
+// Decoders are tried in the order they were registered, the first one to
+// match an entry decoding it.
+// RegisterDecoder panics if the name is empty or already registered, or
+// the decoder is nil.
+func RegisterDecoder(name string, decoder PayloadDecoder) {
+	if name == "" || decoder == nil {
+		panic("segb: RegisterDecoder with an empty name or a nil decoder")
+	}
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	for _, d := range decoders {
+		if d.name == name {
+			panic(fmt.Sprintf("segb: RegisterDecoder called twice for %s", name))
+		}
+	}
+	decoders = append(decoders, namedDecoder{name: name, decoder: decoder})
+}
+
+// Decoders returns the names of the registered payload decoders, in the
+// order they are tried.
+func Decoders() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	names := make([]string, len(decoders))
+	for i, d := range decoders {
+		names[i] = d.name
+	}
+	return names
+}
+
+// DecodePayload decodes the payload of an entry of a stream with the first
+// registered decoder that matches it, returning the name of the decoder
+// and the value. It returns an empty name if none matches.
+func DecodePayload(stream string, entry Entry) (string, any, error) {
+	payload := PayloadEntry{Stream: stream, Entry: entry}
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	for _, d := range decoders {
+		if d.decoder.Match(payload) {
+			value, err := d.decoder.Decode(payload)
+			return d.name, value, err
+		}
+	}
+	return "", nil, nil
+}
+
+// StreamDecoder returns a PayloadDecoder matching every entry of the named
+// stream, decoding them with decode.
+func StreamDecoder(stream string, decode func(entry PayloadEntry) (any, error)) PayloadDecoder {
+	return streamDecoder{stream: stream, decode: decode}
+}
+
+type streamDecoder struct {
+	stream string
+	decode func(PayloadEntry) (any, error)
+}
+
+func (d streamDecoder) Match(entry PayloadEntry) bool {
+	return entry.Stream == d.stream
+}
+
+func (d streamDecoder) Decode(entry PayloadEntry) (any, error) {
+	return d.decode(entry)
+}
@@ -0,0 +1,43 @@
+package segb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodePayload(t *testing.T) {
+	errShort := errors.New("too short")
+	RegisterDecoder("test-upper", StreamDecoder("Test.Upper", func(entry PayloadEntry) (any, error) {
+		if len(entry.Data) < 2 {
+			return nil, errShort
+		}
+		return map[string]string{"text": strings.ToUpper(string(entry.Data))}, nil
+	}))
+
+	name, value, err := DecodePayload("Test.Upper", Entry{Data: []byte("hello")})
+	if err != nil || name != "test-upper" || value.(map[string]string)["text"] != "HELLO" {
+		t.Errorf("DecodePayload() = %q, %v, %v; want test-upper, HELLO", name, value, err)
+	}
+	if name, _, err := DecodePayload("Test.Upper", Entry{Data: []byte("h")}); name != "test-upper" || !errors.Is(err, errShort) {
+		t.Errorf("DecodePayload(short) = %q, %v; want test-upper, %v", name, err, errShort)
+	}
+	if name, value, err := DecodePayload("Test.Other", Entry{Data: []byte("hello")}); name != "" || value != nil || err != nil {
+		t.Errorf("DecodePayload(other stream) = %q, %v, %v; want no decoder", name, value, err)
+	}
+
+	found := false
+	for _, name := range Decoders() {
+		found = found || name == "test-upper"
+	}
+	if !found {
+		t.Errorf("Decoders() = %v; want test-upper listed", Decoders())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterDecoder(test-upper) twice did not panic")
+		}
+	}()
+	RegisterDecoder("test-upper", StreamDecoder("Test.Upper", nil))
+}
@@ -0,0 +1,143 @@
+package segb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/bluefalconhd/segb/internal/lz4"
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+// MaxDecompressedSize bounds how many bytes a compressed input may expand
+// to. Decompressed input is held in memory.
+const MaxDecompressedSize = 1 << 30
+
+var ErrDecompressedTooLarge = errors.New("decompressed input exceeds MaxDecompressedSize")
+
+// Compression identifies the compression of an input file or of an entry
+// payload.
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+	CompressionLZ4
+	CompressionZlib
+	CompressionLZFSE
+)
+
+// String returns the lowercase name of the compression format.
+func (c Compression) String() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	case CompressionLZ4:
+		return "lz4"
+	case CompressionZlib:
+		return "zlib"
+	case CompressionLZFSE:
+		return "lzfse"
+	default:
+		return "none"
+	}
+}
+
+// DetectCompression sniffs the compression magic at the start of the
+// stream. Streams too short to hold any magic are reported as uncompressed.
+func DetectCompression(stream io.ReadSeeker) (Compression, error) {
+	_, err := stream.Seek(0, io.SeekStart)
+	if err != nil {
+		return CompressionNone, err
+	}
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(stream, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return CompressionNone, err
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return CompressionGzip, nil
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return CompressionZstd, nil
+	case bytes.Equal(magic, []byte{0x04, 0x22, 0x4d, 0x18}):
+		return CompressionLZ4, nil
+	default:
+		return CompressionNone, nil
+	}
+}
+
+// Decompress returns a stream of the decompressed contents of a gzip, zstd
+// or LZ4 compressed stream, along with the detected format. Uncompressed
+// streams are returned as is, rewound to the start.
+func Decompress(stream io.ReadSeeker) (io.ReadSeeker, Compression, error) {
+	return decompress(stream, MaxDecompressedSize)
+}
+
+// decompress is Decompress expanding to at most limit bytes.
+func decompress(stream io.ReadSeeker, limit int64) (io.ReadSeeker, Compression, error) {
+	c, err := DetectCompression(stream)
+	if err != nil {
+		return nil, c, err
+	}
+	_, err = stream.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, c, err
+	}
+
+	var data []byte
+	switch c {
+	case CompressionGzip:
+		zr, err := gzip.NewReader(stream)
+		if err != nil {
+			return nil, c, err
+		}
+		data, err = readLimited(zr, limit)
+		if err != nil {
+			return nil, c, err
+		}
+	case CompressionZstd:
+		zr, err := zstd.NewReader(stream, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(limit)))
+		if err != nil {
+			return nil, c, err
+		}
+		defer zr.Close()
+		data, err = readLimited(zr, limit)
+		if err != nil {
+			return nil, c, err
+		}
+	case CompressionLZ4:
+		compressed, err := io.ReadAll(stream)
+		if err != nil {
+			return nil, c, err
+		}
+		data, err = lz4.DecodeFrames(compressed, int(limit))
+		if err == lz4.ErrTooLarge {
+			return nil, c, ErrDecompressedTooLarge
+		}
+		if err != nil {
+			return nil, c, err
+		}
+	default:
+		return stream, c, nil
+	}
+	return bytes.NewReader(data), c, nil
+}
+
+// readLimited reads r to the end, failing if it yields more than limit
+// bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrDecompressedTooLarge
+	}
+	return data, nil
+}
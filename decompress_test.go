@@ -0,0 +1,74 @@
+package segb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"github.com/klauspost/compress/zstd"
+	"os"
+	"testing"
+)
+
+// lz4Frame wraps data in an LZ4 frame holding a single uncompressed block.
+func lz4Frame(data []byte) []byte {
+	frame := []byte{0x04, 0x22, 0x4d, 0x18, 0x60, 0x70, 0x00}
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(data))|0x80000000)
+	frame = append(frame, data...)
+	return append(frame, 0, 0, 0, 0)
+}
+
+func TestDecompress(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	for _, name := range []string{"segb_version1.bin", "segb_version2.bin"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		zw.Write(data)
+		zw.Close()
+
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zst := enc.EncodeAll(data, nil)
+		enc.Close()
+
+		for _, tc := range []struct {
+			input []byte
+			want  Compression
+		}{
+			{data, CompressionNone},
+			{gz.Bytes(), CompressionGzip},
+			{zst, CompressionZstd},
+			{lz4Frame(data), CompressionLZ4},
+		} {
+			stream, c, err := Decompress(bytes.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("%s %v: %v", name, tc.want, err)
+			}
+			if c != tc.want {
+				t.Errorf("%s: detected %v; want %v", name, c, tc.want)
+			}
+
+			decoded, err := Decode(stream)
+			if err != nil {
+				t.Fatalf("%s %v: %v", name, tc.want, err)
+			}
+			CheckForEntries(t, decoded.Entries)
+
+			// Decode unwraps compressed input on its own
+			decoded, err = DecodeBytes(tc.input)
+			if err != nil {
+				t.Fatalf("%s %v: %v", name, tc.want, err)
+			}
+			CheckForEntries(t, decoded.Entries)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package segb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// PayloadHash is the SHA-256 digest of an entry payload.
+type PayloadHash [sha256.Size]byte
+
+// String returns the hash as a lowercase hex string.
+func (h PayloadHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// HashPayload returns the SHA-256 digest of the given payload.
+func HashPayload(data []byte) PayloadHash {
+	return sha256.Sum256(data)
+}
+
+// PayloadSource identifies one occurrence of a payload in a decoded file.
+type PayloadSource struct {
+	Path  string // Path (or any caller-chosen name) of the file the entry came from
+	Index int    // Index of the entry in Segb.Entries
+	Entry Entry  // The entry itself
+}
+
+// UniquePayload is a payload seen one or more times across a corpus.
+type UniquePayload struct {
+	Hash    PayloadHash
+	Data    []byte
+	Sources []PayloadSource
+}
+
+// Deduplicator groups entries from any number of decoded files by the
+// SHA-256 of their payload. Identical entries frequently appear in several
+// segments (e.g. local and remote copies of the same stream), so this gives
+// a view of each distinct payload together with every place it was found.
+type Deduplicator struct {
+	payloads map[PayloadHash]*UniquePayload
+	order    []PayloadHash
+}
+
+// NewDeduplicator returns an empty Deduplicator.
+func NewDeduplicator() *Deduplicator {
+	return &Deduplicator{payloads: make(map[PayloadHash]*UniquePayload)}
+}
+
+// Add records every entry of s under the given path.
+func (d *Deduplicator) Add(path string, s Segb) {
+	for i, entry := range s.Entries {
+		hash := HashPayload(entry.Data)
+
+		payload, ok := d.payloads[hash]
+		if !ok {
+			payload = &UniquePayload{Hash: hash, Data: entry.Data}
+			d.payloads[hash] = payload
+			d.order = append(d.order, hash)
+		}
+		payload.Sources = append(payload.Sources, PayloadSource{Path: path, Index: i, Entry: entry})
+	}
+}
+
+// Len returns the number of distinct payloads seen so far.
+func (d *Deduplicator) Len() int {
+	return len(d.order)
+}
+
+// Payloads returns every distinct payload in the order it was first seen.
+func (d *Deduplicator) Payloads() []*UniquePayload {
+	payloads := make([]*UniquePayload, len(d.order))
+	for i, hash := range d.order {
+		payloads[i] = d.payloads[hash]
+	}
+	return payloads
+}
+
+// Duplicates returns only the payloads that were seen more than once.
+func (d *Deduplicator) Duplicates() []*UniquePayload {
+	var duplicates []*UniquePayload
+	for _, hash := range d.order {
+		if payload := d.payloads[hash]; len(payload.Sources) > 1 {
+			duplicates = append(duplicates, payload)
+		}
+	}
+	return duplicates
+}
+
+// Dedup is a convenience wrapper that deduplicates a set of decoded files
+// keyed by path. Files are processed in sorted path order so the result is
+// deterministic.
+func Dedup(files map[string]Segb) []*UniquePayload {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	d := NewDeduplicator()
+	for _, path := range paths {
+		d.Add(path, files[path])
+	}
+	return d.Payloads()
+}
@@ -0,0 +1,48 @@
+package segb
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	files := map[string]Segb{}
+	for _, name := range []string{"segb_version1.bin", "segb_version2.bin"} {
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = decoded
+	}
+
+	// Both fixtures contain the same three payloads
+	payloads := Dedup(files)
+	if len(payloads) != len(expectedEntryData) {
+		t.Fatalf("len(payloads) = %d; want %d", len(payloads), len(expectedEntryData))
+	}
+
+	for i, payload := range payloads {
+		if string(payload.Data) != expectedEntryData[i] {
+			t.Errorf("payload.Data = %s; want %s", payload.Data, expectedEntryData[i])
+		}
+		if len(payload.Sources) != 2 {
+			t.Errorf("len(payload.Sources) = %d; want 2", len(payload.Sources))
+			continue
+		}
+		if payload.Sources[0].Path != "segb_version1.bin" || payload.Sources[1].Path != "segb_version2.bin" {
+			t.Errorf("payload.Sources paths = %s, %s", payload.Sources[0].Path, payload.Sources[1].Path)
+		}
+		if payload.Hash != HashPayload([]byte(expectedEntryData[i])) {
+			t.Errorf("payload.Hash = %s; want hash of %q", payload.Hash, expectedEntryData[i])
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package segb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math"
+)
+
+// HighEntropyThreshold is the normalized entropy (see NormalizedEntropy) at
+// and above which a payload is considered likely encrypted or compressed.
+const HighEntropyThreshold = 0.9
+
+// MinEntropySize is the shortest payload HighEntropy will flag. Below it,
+// too few bytes are available for the estimate to mean much.
+const MinEntropySize = 64
+
+// Entropy returns the Shannon entropy of data in bits per byte, between 0
+// and 8.
+func Entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	n := float64(len(data))
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// NormalizedEntropy returns the entropy of data relative to the highest
+// entropy a payload of its length can reach: 8 bits per byte, or log2(n) for
+// payloads shorter than 256 bytes. The result is between 0 and 1.
+func NormalizedEntropy(data []byte) float64 {
+	if len(data) < 2 {
+		return 0
+	}
+	return Entropy(data) / min(8, math.Log2(float64(len(data))))
+}
+
+// HighEntropy reports whether data looks encrypted or compressed, i.e. is
+// unlikely to yield to string or protobuf analysis.
+func HighEntropy(data []byte) bool {
+	return len(data) >= MinEntropySize && NormalizedEntropy(data) >= HighEntropyThreshold
+}
+
+// ZeroRatio returns the fraction of data that is zero bytes, e.g. padding
+// or sparse fixed-width fields. It is 0 for empty data.
+func ZeroRatio(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	return float64(bytes.Count(data, []byte{0})) / float64(len(data))
+}
+
+// GzipSize returns the size of data compressed with gzip at the default
+// level, to estimate what it takes to store compressed.
+func GzipSize(data []byte) int {
+	var counter byteCounter
+	w := gzip.NewWriter(&counter)
+	w.Write(data)
+	w.Close()
+	return int(counter)
+}
+
+// GzipRatio returns GzipSize relative to the size of data: well below 1 for
+// compressible payloads, around or above 1 for already compressed or
+// encrypted ones. It is 0 for empty data.
+func GzipRatio(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	return float64(GzipSize(data)) / float64(len(data))
+}
+
+// byteCounter is an io.Writer that only counts what is written to it.
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// EntropyReport is the entropy analysis of one entry.
+type EntropyReport struct {
+	Index      int     // Index of the entry in Segb.Entries
+	Entropy    float64 // Bits per byte
+	Normalized float64
+	High       bool
+	ZeroRatio  float64 // See ZeroRatio
+	GzipSize   int     // See GzipSize
+	GzipRatio  float64 // See GzipRatio
+}
+
+// AnalyzeEntropy computes the entropy and compressibility of every entry
+// payload.
+func (s *Segb) AnalyzeEntropy() []EntropyReport {
+	reports := make([]EntropyReport, len(s.Entries))
+	for i, entry := range s.Entries {
+		reports[i] = EntropyReport{
+			Index:      i,
+			Entropy:    Entropy(entry.Data),
+			Normalized: NormalizedEntropy(entry.Data),
+			High:       HighEntropy(entry.Data),
+			ZeroRatio:  ZeroRatio(entry.Data),
+			GzipSize:   GzipSize(entry.Data),
+		}
+		if len(entry.Data) > 0 {
+			reports[i].GzipRatio = float64(reports[i].GzipSize) / float64(len(entry.Data))
+		}
+	}
+	return reports
+}
@@ -0,0 +1,73 @@
+package segb
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestEntropy(t *testing.T) {
+	if e := Entropy(nil); e != 0 {
+		t.Errorf("Entropy(nil) = %f; want 0", e)
+	}
+	if e := Entropy(bytes.Repeat([]byte{'a'}, 100)); e != 0 {
+		t.Errorf("Entropy(constant) = %f; want 0", e)
+	}
+	if e := Entropy([]byte("abababab")); math.Abs(e-1) > 1e-9 {
+		t.Errorf("Entropy(two symbols) = %f; want 1", e)
+	}
+
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	if e := Entropy(all); math.Abs(e-8) > 1e-9 {
+		t.Errorf("Entropy(every byte) = %f; want 8", e)
+	}
+}
+
+func TestHighEntropy(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := bytes.Repeat([]byte("Here's to the crazy ones. The misfits. The rebels. "), 20)
+
+	if !HighEntropy(random) {
+		t.Errorf("random payload not flagged (normalized entropy %f)", NormalizedEntropy(random))
+	}
+	if HighEntropy(text) {
+		t.Errorf("text payload flagged (normalized entropy %f)", NormalizedEntropy(text))
+	}
+	if HighEntropy(random[:MinEntropySize-1]) {
+		t.Error("payload shorter than MinEntropySize flagged")
+	}
+
+	s := Segb{Entries: []Entry{{Data: text}, {Data: random}}}
+	reports := s.AnalyzeEntropy()
+	if reports[0].High || !reports[1].High || reports[1].Index != 1 {
+		t.Errorf("AnalyzeEntropy() = %+v", reports)
+	}
+	// Repeated text compresses well, random bytes do not
+	if reports[0].GzipRatio > 0.2 || reports[1].GzipRatio < 1 || reports[1].GzipSize != GzipSize(random) {
+		t.Errorf("AnalyzeEntropy() gzip ratios = %f, %f", reports[0].GzipRatio, reports[1].GzipRatio)
+	}
+}
+
+func TestZeroRatio(t *testing.T) {
+	for _, tc := range []struct {
+		data []byte
+		want float64
+	}{
+		{nil, 0},
+		{[]byte{0, 0, 0, 0}, 1},
+		{[]byte{0, 1, 0, 1}, 0.5},
+		{[]byte("text"), 0},
+	} {
+		if got := ZeroRatio(tc.data); got != tc.want {
+			t.Errorf("ZeroRatio(%q) = %f; want %f", tc.data, got, tc.want)
+		}
+	}
+	if r := GzipRatio(nil); r != 0 {
+		t.Errorf("GzipRatio(nil) = %f; want 0", r)
+	}
+}
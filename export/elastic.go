@@ -0,0 +1,75 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+)
+
+// ElasticMapping is the index mapping for records in Elasticsearch: the
+// creation time as a date, identifiers, hashes and enumerations as
+// keywords, the note as text, and the decoded payload and derived fields,
+// whose shape differs from stream to stream, as flattened objects so they
+// cannot conflict.
+var ElasticMapping = map[string]any{
+	"mappings": map[string]any{
+		"dynamic": false,
+		"properties": map[string]any{
+			"file":         map[string]any{"type": "keyword"},
+			"stream":       map[string]any{"type": "keyword"},
+			"index":        map[string]any{"type": "integer"},
+			"id":           map[string]any{"type": "long"},
+			"stable_id":    map[string]any{"type": "keyword"},
+			"state":        map[string]any{"type": "keyword"},
+			"created":      map[string]any{"type": "date", "format": "strict_date_optional_time_nanos"},
+			"offset":       map[string]any{"type": "long"},
+			"size":         map[string]any{"type": "long"},
+			"stored_size":  map[string]any{"type": "long"},
+			"sha256":       map[string]any{"type": "keyword"},
+			"crc":          map[string]any{"type": "long"},
+			"crc_valid":    map[string]any{"type": "boolean"},
+			"compression":  map[string]any{"type": "keyword"},
+			"content_type": map[string]any{"type": "keyword"},
+			"entropy":      map[string]any{"type": "float"},
+			"zero_ratio":   map[string]any{"type": "float"},
+			"gzip_size":    map[string]any{"type": "long"},
+			"residual":     map[string]any{"type": "boolean"},
+			"tags":         map[string]any{"type": "keyword"},
+			"note":         map[string]any{"type": "text"},
+			"decoder":      map[string]any{"type": "keyword"},
+			"decoded":      map[string]any{"type": "flattened"},
+			"decode_error": map[string]any{"type": "text"},
+			"derived":      map[string]any{"type": "flattened"},
+			"data":         map[string]any{"type": "binary"},
+		},
+	},
+}
+
+// ElasticID returns the document ID of a record: a hash of its file and
+// stable ID, so that exporting the same files again updates the documents
+// instead of duplicating them.
+func ElasticID(r Record) string {
+	sum := sha256.Sum256([]byte(r.File + "\x00" + r.StableID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// WriteElasticBulk writes the records as the body of an Elasticsearch bulk
+// API request indexing them into index: for each, an action line and the
+// record, one JSON object per line.
+func WriteElasticBulk(w io.Writer, index string, records []Record) error {
+	type action struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	}
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(map[string]action{"index": {Index: index, ID: ElasticID(record)}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
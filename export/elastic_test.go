@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestElasticID(t *testing.T) {
+	id := ElasticID(testRecord)
+	if len(id) != 32 || id != ElasticID(testRecord) {
+		t.Errorf("ElasticID() = %q", id)
+	}
+
+	// Only the file and stable ID identify a document
+	other := testRecord
+	other.Note = "edited"
+	if ElasticID(other) != id {
+		t.Error("the ID changed with the note")
+	}
+	other.StableID = "v2:1:4"
+	if ElasticID(other) == id {
+		t.Error("two entries have the same ID")
+	}
+}
+
+func TestWriteElasticBulk(t *testing.T) {
+	second := testRecord
+	second.StableID = "v2:1:4"
+	var buf bytes.Buffer
+	if err := WriteElasticBulk(&buf, "cases", []Record{testRecord, second}); err != nil {
+		t.Fatal(err)
+	}
+	lines := decodeLines(t, buf.Bytes())
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	for i, record := range []Record{testRecord, second} {
+		action, _ := lines[2*i]["index"].(map[string]any)
+		if len(lines[2*i]) != 1 || action["_index"] != "cases" || action["_id"] != ElasticID(record) {
+			t.Errorf("action %d = %v", i, lines[2*i])
+		}
+		if document := lines[2*i+1]; document["stable_id"] != record.StableID || document["created"] != "2024-05-01T12:30:45.123456Z" {
+			t.Errorf("document %d = %v", i, document)
+		}
+	}
+}
+
+func TestElasticMapping(t *testing.T) {
+	if _, err := json.Marshal(ElasticMapping); err != nil {
+		t.Fatal(err)
+	}
+	properties := ElasticMapping["mappings"].(map[string]any)["properties"].(map[string]any)
+	for _, name := range FieldNames() {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("field %s is not mapped", name)
+		}
+	}
+}
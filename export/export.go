@@ -0,0 +1,125 @@
+// Package export converts decoded SEGB entries into flat records suitable
+// for serialization (JSON, JSON Lines, ...).
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/biome"
+	"io"
+	"time"
+)
+
+// Record is the flat, serializable form of an entry shared by every output
+// format.
+type Record struct {
+	File        string         `json:"file,omitempty"`   // Path of the file the entry was read from
+	Stream      string         `json:"stream,omitempty"` // Biome stream inferred from File, see biome.StreamName
+	Index       int            `json:"index"`            // Index of the entry in Segb.Entries
+	ID          int            `json:"id"`
+	StableID    string         `json:"stable_id"` // Survives parsing with other options, see segb.Entry.StableID
+	State       string         `json:"state"`
+	Created     time.Time      `json:"created"`
+	Offset      int64          `json:"offset"` // Absolute position of the payload in the file
+	Size        int            `json:"size"`
+	StoredSize  int64          `json:"stored_size"` // Bytes occupied in the file, padding included
+	SHA256      string         `json:"sha256"`
+	CRC         uint32         `json:"crc"`
+	CRCValid    bool           `json:"crc_valid"`              // Payload matches CRC, see segb.Entry.CheckCRC
+	Compression string         `json:"compression,omitempty"`  // Compression removed from the payload, if any
+	ContentType string         `json:"content_type"`           // Kind of data in the payload, see segb.Classify
+	Entropy     float64        `json:"entropy"`                // Bits per byte, see segb.Entropy
+	ZeroRatio   float64        `json:"zero_ratio"`             // Fraction of zero bytes
+	GzipSize    int            `json:"gzip_size"`              // Size of the payload gzipped, see segb.GzipSize
+	Residual    bool           `json:"residual,omitempty"`     // Recovered from past the end of data, see segb.WithResidualEntries
+	Tags        []string       `json:"tags,omitempty"`         // Analyst tags from the sidecar, see Annotate
+	Note        string         `json:"note,omitempty"`         // Analyst note from the sidecar
+	Decoder     string         `json:"decoder,omitempty"`      // Registered decoder of the payload, see segb.RegisterDecoder
+	Decoded     any            `json:"decoded,omitempty"`      // Payload as Decoder decoded it
+	DecodeError string         `json:"decode_error,omitempty"` // Why Decoder failed, if it did
+	Derived     map[string]any `json:"derived,omitempty"`      // Fields computed by a script, see package script
+	Data        []byte         `json:"data,omitempty"`         // Payload, base64 encoded in JSON
+}
+
+// NewRecord builds the record for one entry. The payload is only included
+// when withData is true.
+func NewRecord(file string, index int, entry segb.Entry, withData bool) Record {
+	record := Record{
+		File:        file,
+		Stream:      biome.StreamName(file),
+		Index:       index,
+		ID:          entry.ID,
+		StableID:    entry.StableID(),
+		State:       entry.State.String(),
+		Created:     entry.Created,
+		Offset:      entry.Offset,
+		Size:        len(entry.Data),
+		StoredSize:  entry.StoredSize,
+		SHA256:      segb.HashPayload(entry.Data).String(),
+		CRC:         entry.Checksum,
+		CRCValid:    entry.CheckCRC(),
+		ContentType: entry.ContentType.String(),
+		Entropy:     segb.Entropy(entry.Data),
+		ZeroRatio:   segb.ZeroRatio(entry.Data),
+		GzipSize:    segb.GzipSize(entry.Data),
+		Residual:    entry.Residual,
+	}
+	var err error
+	if record.Decoder, record.Decoded, err = segb.DecodePayload(record.Stream, entry); err != nil {
+		record.DecodeError = err.Error()
+	}
+	if entry.Compression != segb.CompressionNone {
+		record.Compression = entry.Compression.String()
+	}
+	if withData {
+		record.Data = entry.Data
+	}
+	return record
+}
+
+// Records builds the records for every entry of a decoded file.
+func Records(file string, s segb.Segb, withData bool) []Record {
+	records := make([]Record, len(s.Entries))
+	for i, entry := range s.Entries {
+		records[i] = NewRecord(file, i, entry, withData)
+	}
+	return records
+}
+
+// Annotate fills in the tags and notes of the records from the annotations
+// of their file, see segb.LoadAnnotations.
+func Annotate(records []Record, annotations segb.Annotations) {
+	for i := range records {
+		annotation := annotations[records[i].SHA256]
+		records[i].Tags = annotation.Tags
+		records[i].Note = annotation.Note
+	}
+}
+
+// WriteJSONL writes one JSON object per line.
+func WriteJSONL(w io.Writer, records []Record) error {
+	return writeLines(w, records, func(r Record) any { return r })
+}
+
+// writeLines writes what convert makes of each record, one JSON object per
+// line.
+func writeLines(w io.Writer, records []Record, convert func(Record) any) error {
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(convert(record)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// message describes a record in one line for timeline tools, e.g.
+// "App.InFocus entry 3 (ID 7) Written: 120 bytes protobuf".
+func message(r Record) string {
+	source := r.Stream
+	if source == "" {
+		source = r.File
+	}
+	return fmt.Sprintf("%s entry %d (ID %d) %s: %d bytes %s", source, r.Index, r.ID, r.State, r.Size, r.ContentType)
+}
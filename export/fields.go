@@ -0,0 +1,77 @@
+package export
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NarrowFields and WideFields are the default field selections of tabular
+// output: the few fields that identify an entry, and every field but the
+// payload.
+var (
+	NarrowFields = []string{"stream", "index", "state", "created", "size", "content_type"}
+	WideFields   = wideFields()
+)
+
+// fieldIndexes maps the names of the fields of Record, as in JSON, to their
+// index in the struct.
+var fieldIndexes, fieldNames = recordFields()
+
+func recordFields() (map[string]int, []string) {
+	indexes := make(map[string]int)
+	var names []string
+	t := reflect.TypeOf(Record{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		indexes[name] = i
+		names = append(names, name)
+	}
+	return indexes, names
+}
+
+func wideFields() []string {
+	var names []string
+	for _, name := range fieldNames {
+		if name != "data" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// FieldNames returns the names of the fields of Record, as in JSON, in
+// order.
+func FieldNames() []string {
+	return append([]string(nil), fieldNames...)
+}
+
+// ParseFields parses a comma-separated list of field names, such as
+// "id,created,state,sha256,size".
+func ParseFields(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := fieldIndexes[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q (want one of %s)", name, strings.Join(fieldNames, ", "))
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no fields in %q", list)
+	}
+	return names, nil
+}
+
+// Field returns the value of the named field of the record, or false if
+// there is no such field.
+func (r Record) Field(name string) (any, bool) {
+	i, ok := fieldIndexes[name]
+	if !ok {
+		return nil, false
+	}
+	return reflect.ValueOf(r).Field(i).Interface(), true
+}
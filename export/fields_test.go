@@ -0,0 +1,69 @@
+package export
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	for _, tc := range []struct {
+		list string
+		want []string
+	}{
+		{"id,created,state,sha256,size", []string{"id", "created", "state", "sha256", "size"}},
+		{" stream , index ,", []string{"stream", "index"}},
+		{"data", []string{"data"}},
+	} {
+		got, err := ParseFields(tc.list)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("ParseFields(%q) = %q, %v; want %q", tc.list, got, err, tc.want)
+		}
+	}
+
+	for _, list := range []string{"", " , ", "id,bogus", "ID"} {
+		if got, err := ParseFields(list); err == nil {
+			t.Errorf("ParseFields(%q) = %q; want an error", list, got)
+		}
+	}
+}
+
+func TestRecordField(t *testing.T) {
+	for name, want := range map[string]any{
+		"stream":    "App.InFocus",
+		"index":     3,
+		"created":   testRecord.Created,
+		"crc_valid": true,
+		"tags":      []string{"reviewed"},
+		"derived":   map[string]any{"app": "Safari"},
+		"data":      []byte(nil),
+	} {
+		got, ok := testRecord.Field(name)
+		if !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("Field(%q) = %v, %v; want %v", name, got, ok, want)
+		}
+	}
+	if _, ok := testRecord.Field("Stream"); ok {
+		t.Error("Field(\"Stream\") found a field by its Go name")
+	}
+}
+
+func TestFieldSelections(t *testing.T) {
+	names := FieldNames()
+	if len(names) != reflect.TypeOf(Record{}).NumField() || names[0] != "file" || names[len(names)-1] != "data" {
+		t.Errorf("FieldNames() = %q", names)
+	}
+	names[0] = "changed"
+	if FieldNames()[0] != "file" {
+		t.Error("FieldNames() returns a shared slice")
+	}
+
+	if slices.Contains(WideFields, "data") || len(WideFields) != len(names)-1 {
+		t.Errorf("WideFields = %q", WideFields)
+	}
+	for _, name := range NarrowFields {
+		if !slices.Contains(WideFields, name) {
+			t.Errorf("narrow field %s is not a wide field", name)
+		}
+	}
+}
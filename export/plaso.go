@@ -0,0 +1,89 @@
+package export
+
+import (
+	"io"
+)
+
+// Names of the Plaso parser and data type of the events of Plaso exports,
+// and the meaning of their timestamp, as Plaso words it.
+const (
+	PlasoParser        = "segb"
+	PlasoDataType      = "apple:biome:segb:entry"
+	PlasoTimestampDesc = "Creation Time"
+)
+
+// PlasoEvent is a record in the form Plaso's json_line output module writes
+// events: the attribute container markers, data_type, parser, display_name,
+// filename and offset, timestamp (microseconds since the Unix epoch) with
+// its date_time and timestamp_desc, and message, followed by the event data
+// of the entry. The output merges into super timelines built with psort.
+type PlasoEvent struct {
+	ContainerType string         `json:"__container_type__"`
+	Type          string         `json:"__type__"`
+	DataType      string         `json:"data_type"`
+	Parser        string         `json:"parser"`
+	DisplayName   string         `json:"display_name"`
+	Filename      string         `json:"filename"`
+	Offset        int64          `json:"offset"`
+	Timestamp     int64          `json:"timestamp"`
+	DateTime      PlasoDateTime  `json:"date_time"`
+	TimestampDesc string         `json:"timestamp_desc"`
+	Message       string         `json:"message"`
+	Stream        string         `json:"stream,omitempty"`
+	Index         int            `json:"entry_index"`
+	ID            int            `json:"entry_id"`
+	State         string         `json:"state"`
+	Size          int            `json:"size"`
+	SHA256        string         `json:"sha256"`
+	CRCValid      bool           `json:"crc_valid"`
+	ContentType   string         `json:"content_type"`
+	Residual      bool           `json:"residual,omitempty"`
+	Tag           []string       `json:"tag,omitempty"`
+	Note          string         `json:"note,omitempty"`
+	Decoded       any            `json:"decoded,omitempty"`
+	Derived       map[string]any `json:"derived,omitempty"`
+}
+
+// PlasoDateTime is the serialized form of a dfdatetime value with
+// microsecond precision.
+type PlasoDateTime struct {
+	ClassName string `json:"__class_name__"`
+	Type      string `json:"__type__"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// NewPlasoEvent converts a record to a Plaso event.
+func NewPlasoEvent(r Record) PlasoEvent {
+	timestamp := r.Created.UnixMicro()
+	return PlasoEvent{
+		ContainerType: "event",
+		Type:          "AttributeContainer",
+		DataType:      PlasoDataType,
+		Parser:        PlasoParser,
+		DisplayName:   "OS:" + r.File,
+		Filename:      r.File,
+		Offset:        r.Offset,
+		Timestamp:     timestamp,
+		DateTime:      PlasoDateTime{ClassName: "PosixTimeInMicroseconds", Type: "DateTimeValues", Timestamp: timestamp},
+		TimestampDesc: PlasoTimestampDesc,
+		Message:       message(r),
+		Stream:        r.Stream,
+		Index:         r.Index,
+		ID:            r.ID,
+		State:         r.State,
+		Size:          r.Size,
+		SHA256:        r.SHA256,
+		CRCValid:      r.CRCValid,
+		ContentType:   r.ContentType,
+		Residual:      r.Residual,
+		Tag:           r.Tags,
+		Note:          r.Note,
+		Decoded:       r.Decoded,
+		Derived:       r.Derived,
+	}
+}
+
+// WritePlaso writes the records as Plaso events, one JSON object per line.
+func WritePlaso(w io.Writer, records []Record) error {
+	return writeLines(w, records, func(r Record) any { return NewPlasoEvent(r) })
+}
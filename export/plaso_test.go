@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWritePlaso(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePlaso(&buf, []Record{testRecord}); err != nil {
+		t.Fatal(err)
+	}
+	lines := decodeLines(t, buf.Bytes())
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	event := lines[0]
+
+	// The attribute container shape of Plaso's json_line output
+	for key, want := range map[string]any{
+		"__container_type__": "event",
+		"__type__":           "AttributeContainer",
+		"data_type":          PlasoDataType,
+		"parser":             PlasoParser,
+		"display_name":       "OS:" + testRecord.File,
+		"filename":           testRecord.File,
+		"offset":             json.Number("312"),
+		"timestamp":          json.Number("1714566645123456"),
+		"timestamp_desc":     PlasoTimestampDesc,
+		"message":            "App.InFocus entry 3 (ID 7) Written: 120 bytes protobuf",
+		"entry_index":        json.Number("3"),
+		"entry_id":           json.Number("7"),
+	} {
+		if event[key] != want {
+			t.Errorf("%s = %v; want %v", key, event[key], want)
+		}
+	}
+	dateTime, _ := event["date_time"].(map[string]any)
+	if dateTime["__class_name__"] != "PosixTimeInMicroseconds" || dateTime["__type__"] != "DateTimeValues" ||
+		dateTime["timestamp"] != json.Number("1714566645123456") {
+		t.Errorf("date_time = %v", event["date_time"])
+	}
+	if decoded, _ := event["decoded"].(map[string]any); decoded["bundle_id"] != "com.apple.mobilesafari" {
+		t.Errorf("decoded = %v", event["decoded"])
+	}
+
+	// Without a stream, the message names the file
+	record := testRecord
+	record.Stream = ""
+	if got := NewPlasoEvent(record).Message; got != testRecord.File+" entry 3 (ID 7) Written: 120 bytes protobuf" {
+		t.Errorf("message = %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// TimesketchSource is the source of the events of Timesketch exports, and
+// TimesketchTimestampDesc the meaning of their timestamp.
+const (
+	TimesketchSource        = "SEGB"
+	TimesketchTimestampDesc = "SEGB Entry Created"
+)
+
+// TimesketchEvent is a record in the form Timesketch imports from JSON
+// Lines: message, datetime, timestamp (microseconds since the Unix epoch)
+// and timestamp_desc, followed by the fields of the record as attributes.
+// Nested values, the decoded payload and derived fields, are flattened to
+// JSON text, which Timesketch shows and searches as is.
+type TimesketchEvent struct {
+	Message       string    `json:"message"`
+	Datetime      time.Time `json:"datetime"`
+	Timestamp     int64     `json:"timestamp"`
+	TimestampDesc string    `json:"timestamp_desc"`
+	Source        string    `json:"source"`
+	File          string    `json:"file,omitempty"`
+	Stream        string    `json:"stream,omitempty"`
+	Index         int       `json:"entry_index"`
+	ID            int       `json:"entry_id"`
+	StableID      string    `json:"stable_id"`
+	State         string    `json:"state"`
+	Offset        int64     `json:"offset"`
+	Size          int       `json:"size"`
+	SHA256        string    `json:"sha256"`
+	CRCValid      bool      `json:"crc_valid"`
+	ContentType   string    `json:"content_type"`
+	Residual      bool      `json:"residual,omitempty"`
+	Tag           []string  `json:"tag,omitempty"` // Timesketch's own name for tags
+	Note          string    `json:"note,omitempty"`
+	Decoder       string    `json:"decoder,omitempty"`
+	Decoded       string    `json:"decoded,omitempty"`
+	Derived       string    `json:"derived,omitempty"`
+	Data          []byte    `json:"data,omitempty"`
+}
+
+// NewTimesketchEvent converts a record to a Timesketch event.
+func NewTimesketchEvent(r Record) TimesketchEvent {
+	return TimesketchEvent{
+		Message:       message(r),
+		Datetime:      r.Created.UTC(),
+		Timestamp:     r.Created.UnixMicro(),
+		TimestampDesc: TimesketchTimestampDesc,
+		Source:        TimesketchSource,
+		File:          r.File,
+		Stream:        r.Stream,
+		Index:         r.Index,
+		ID:            r.ID,
+		StableID:      r.StableID,
+		State:         r.State,
+		Offset:        r.Offset,
+		Size:          r.Size,
+		SHA256:        r.SHA256,
+		CRCValid:      r.CRCValid,
+		ContentType:   r.ContentType,
+		Residual:      r.Residual,
+		Tag:           r.Tags,
+		Note:          r.Note,
+		Decoder:       r.Decoder,
+		Decoded:       jsonText(r.Decoded),
+		Derived:       jsonText(r.Derived),
+		Data:          r.Data,
+	}
+}
+
+// WriteTimesketch writes the records as Timesketch events, one JSON object
+// per line.
+func WriteTimesketch(w io.Writer, records []Record) error {
+	return writeLines(w, records, func(r Record) any { return NewTimesketchEvent(r) })
+}
+
+// jsonText returns a value as JSON text, or "" for nil and empty maps.
+func jsonText(v any) string {
+	if m, ok := v.(map[string]any); v == nil || ok && len(m) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,95 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRecord is a record of a decoded entry with tags and derived fields.
+var testRecord = Record{
+	File:        "/cases/biome/streams/restricted/App.InFocus/local/1",
+	Stream:      "App.InFocus",
+	Index:       3,
+	ID:          7,
+	StableID:    "v2:1:3",
+	State:       "Written",
+	Created:     time.Date(2024, 5, 1, 12, 30, 45, 123456000, time.UTC),
+	Offset:      312,
+	Size:        120,
+	SHA256:      "ab12",
+	CRCValid:    true,
+	ContentType: "protobuf",
+	Tags:        []string{"reviewed"},
+	Decoder:     "protobuf",
+	Decoded:     map[string]any{"bundle_id": "com.apple.mobilesafari"},
+	Derived:     map[string]any{"app": "Safari"},
+}
+
+// decodeLines decodes JSON lines into maps, keeping numbers exact.
+func decodeLines(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) || bytes.Count(data, []byte("\n")) != len(lines) {
+		t.Fatalf("not one object per line:\n%s", data)
+	}
+	return lines
+}
+
+func TestWriteTimesketch(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTimesketch(&buf, []Record{testRecord, testRecord}); err != nil {
+		t.Fatal(err)
+	}
+	lines := decodeLines(t, buf.Bytes())
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	event := lines[0]
+
+	// The fields Timesketch requires
+	message, _ := event["message"].(string)
+	if !strings.Contains(message, "App.InFocus") || !strings.Contains(message, "entry 3") {
+		t.Errorf("message = %q", message)
+	}
+	if event["datetime"] != "2024-05-01T12:30:45.123456Z" {
+		t.Errorf("datetime = %v", event["datetime"])
+	}
+	if event["timestamp"] != json.Number("1714566645123456") {
+		t.Errorf("timestamp = %v", event["timestamp"])
+	}
+	if event["timestamp_desc"] != TimesketchTimestampDesc {
+		t.Errorf("timestamp_desc = %v", event["timestamp_desc"])
+	}
+
+	// Attributes, nested ones as JSON text
+	for key, want := range map[string]any{
+		"source":      TimesketchSource,
+		"stream":      "App.InFocus",
+		"entry_index": json.Number("3"),
+		"entry_id":    json.Number("7"),
+		"decoded":     `{"bundle_id":"com.apple.mobilesafari"}`,
+		"derived":     `{"app":"Safari"}`,
+	} {
+		if event[key] != want {
+			t.Errorf("%s = %v; want %v", key, event[key], want)
+		}
+	}
+	if tags, _ := event["tag"].([]any); len(tags) != 1 || tags[0] != "reviewed" {
+		t.Errorf("tag = %v", event["tag"])
+	}
+	if _, ok := event["data"]; ok {
+		t.Error("data present without a payload")
+	}
+}
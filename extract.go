@@ -0,0 +1,164 @@
+package segb
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// DefaultMinStringLength is the minimum string length used by the strings
+// command, matching strings(1).
+const DefaultMinStringLength = 4
+
+// StringEncoding is the encoding a string was found in.
+type StringEncoding int
+
+const (
+	StringASCII StringEncoding = iota
+	StringUTF16LE
+	StringUTF16BE
+	StringUTF8 // UTF-8 with at least one non-ASCII character
+)
+
+// StringEncodings lists every encoding ExtractStrings looks for.
+var StringEncodings = []StringEncoding{StringASCII, StringUTF8, StringUTF16LE, StringUTF16BE}
+
+// String returns the lowercase name of the encoding.
+func (e StringEncoding) String() string {
+	switch e {
+	case StringUTF16LE:
+		return "utf16le"
+	case StringUTF16BE:
+		return "utf16be"
+	case StringUTF8:
+		return "utf8"
+	default:
+		return "ascii"
+	}
+}
+
+// FoundString is a printable string found in an entry payload.
+type FoundString struct {
+	Entry    int // Index of the entry in Segb.Entries
+	Offset   int // Offset of the string within the payload
+	Encoding StringEncoding
+	Text     string
+}
+
+// ExtractStrings returns the runs of at least minLength printable characters
+// in data, ordered by offset. Entry is left zero. Runs are looked for in
+// UTF-8, reported as ASCII when they are, and in UTF-16 of both byte orders
+// at both alignments. UTF-16 runs are limited to the characters below
+// U+2000, which cover the alphabetic scripts, as pairs of ASCII bytes would
+// otherwise read as CJK text. UTF-16 text also reads, give or take its last
+// character, in the other byte order one byte off: a UTF-16BE run starting
+// one byte before ASCII text in UTF-16LE is left out, as Apple platforms are
+// little-endian, while other text is reported both ways.
+func ExtractStrings(data []byte, minLength int) []FoundString {
+	if minLength < 1 {
+		minLength = 1
+	}
+	var found []FoundString
+
+	// ASCII and UTF-8
+	start, length, ascii := -1, 0, true
+	for i := 0; i <= len(data); {
+		r, size := rune(utf8.RuneError), 1
+		if i < len(data) {
+			r, size = utf8.DecodeRune(data[i:])
+		}
+		if i < len(data) && (size == 1 && isPrintable(data[i]) || size > 1 && unicode.IsPrint(r)) {
+			if start < 0 {
+				start, length, ascii = i, 0, true
+			}
+			length++
+			ascii = ascii && size == 1
+			i += size
+			continue
+		}
+		if start >= 0 && length >= minLength {
+			encoding := StringUTF8
+			if ascii {
+				encoding = StringASCII
+			}
+			found = append(found, FoundString{Offset: start, Encoding: encoding, Text: string(data[start:i])})
+		}
+		start = -1
+		i += size
+	}
+
+	// UTF-16, in both byte orders at both alignments
+	littleEndian := make(map[int]string)
+	for _, encoding := range []StringEncoding{StringUTF16LE, StringUTF16BE} {
+		for parity := 0; parity < 2; parity++ {
+			start := -1
+			var units []uint16
+			for i := parity; i <= len(data); i += 2 {
+				var unit uint16
+				if i+1 < len(data) {
+					unit = uint16(data[i]) | uint16(data[i+1])<<8
+					if encoding == StringUTF16BE {
+						unit = uint16(data[i])<<8 | uint16(data[i+1])
+					}
+				}
+				if i+1 < len(data) && isPrintableUTF16(unit) {
+					if start < 0 {
+						start = i
+					}
+					units = append(units, unit)
+					continue
+				}
+				text := string(utf16.Decode(units))
+				if start >= 0 && encoding == StringUTF16LE {
+					littleEndian[start] = text
+				}
+				if start >= 0 && len(units) >= minLength && (encoding == StringUTF16LE || !isASCII(littleEndian[start+1])) {
+					found = append(found, FoundString{Offset: start, Encoding: encoding, Text: text})
+				}
+				start = -1
+				units = units[:0]
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Offset < found[j].Offset
+	})
+	return found
+}
+
+// Strings extracts the strings of every entry payload, see ExtractStrings.
+func (s *Segb) Strings(minLength int) []FoundString {
+	var found []FoundString
+	for i, entry := range s.Entries {
+		for _, str := range ExtractStrings(entry.Data, minLength) {
+			str.Entry = i
+			found = append(found, str)
+		}
+	}
+	return found
+}
+
+func isPrintable(b byte) bool {
+	return b >= 0x20 && b <= 0x7e || b == '\t'
+}
+
+// isASCII reports whether text is non-empty and all ASCII.
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= 0x80 {
+			return false
+		}
+	}
+	return text != ""
+}
+
+// isPrintableUTF16 reports whether a UTF-16 code unit is a printable
+// character below U+2000.
+func isPrintableUTF16(unit uint16) bool {
+	if unit < 0x80 {
+		return isPrintable(byte(unit))
+	}
+	return unit < 0x2000 && unicode.IsPrint(rune(unit))
+}
@@ -0,0 +1,66 @@
+package segb
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractStrings(t *testing.T) {
+	data := []byte("\x01\x02hello\x00\x00w\x00o\x00r\x00l\x00d\x00\xffab\x00cdef")
+
+	found := ExtractStrings(data, DefaultMinStringLength)
+	want := []FoundString{
+		{Offset: 2, Encoding: StringASCII, Text: "hello"},
+		{Offset: 9, Encoding: StringUTF16LE, Text: "world"},
+		{Offset: 23, Encoding: StringASCII, Text: "cdef"},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("ExtractStrings() = %+v; want %+v", found, want)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("found[%d] = %+v; want %+v", i, found[i], want[i])
+		}
+	}
+
+	if found := ExtractStrings(data, 6); len(found) != 0 {
+		t.Errorf("ExtractStrings(6) = %+v; want none", found)
+	}
+}
+
+func TestExtractStringsEncodings(t *testing.T) {
+	utf16le := []byte{'C', 0, 'a', 0, 'f', 0, 0xe9, 0}                // "Café"
+	utf16be := []byte{0x04, 0x1c, 0x04, 0x38, 0x04, 0x40, 0x04, 0x3e} // "Миро"
+	data := append([]byte("\xffcrème brûlée\xff\xff"), utf16le...)
+	data = append(append(data, 0xff, 0xff), utf16be...)
+
+	// Non-ASCII UTF-16 text also reads one byte off in the other byte order
+	found := ExtractStrings(data, DefaultMinStringLength)
+	for _, want := range []FoundString{
+		{Offset: 1, Encoding: StringUTF8, Text: "crème brûlée"},
+		{Offset: 18, Encoding: StringUTF16LE, Text: "Café"},
+		{Offset: 28, Encoding: StringUTF16BE, Text: "Миро"},
+	} {
+		if !slices.Contains(found, want) {
+			t.Errorf("ExtractStrings() = %+v; want %+v among them", found, want)
+		}
+	}
+}
+
+func TestSegbStrings(t *testing.T) {
+	decoded, err := DecodeBytes(v2File([]byte("\x00\x01Here's to the crazy ones."), []byte("x"), []byte("The rebels.")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := decoded.Strings(DefaultMinStringLength)
+	if len(found) != 2 {
+		t.Fatalf("Strings() = %+v", found)
+	}
+	if found[0].Entry != 0 || found[0].Offset != 2 || found[0].Text != expectedEntryData[0] {
+		t.Errorf("found[0] = %+v", found[0])
+	}
+	if found[1].Entry != 2 || found[1].Offset != 0 || found[1].Text != expectedEntryData[2] {
+		t.Errorf("found[1] = %+v", found[1])
+	}
+}
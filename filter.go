@@ -0,0 +1,29 @@
+package segb
+
+import (
+	"time"
+)
+
+// EntryMeta is what is known about an entry before its payload is read.
+type EntryMeta struct {
+	ID      int
+	State   EntryState
+	Created time.Time
+	Offset  int64 // As Entry.Offset
+
+	// Size is Entry.Size for v1. v2 records no payload length, so it is
+	// Entry.StoredSize, alignment padding included.
+	Size int64
+}
+
+// WithEntryFilter makes Decode read the payload of, and return, only the
+// entries for which filter returns true, e.g. to select a time window:
+//
+//	segb.WithEntryFilter(func(m segb.EntryMeta) bool {
+//		return m.State == segb.EntryStateWritten && m.Created.After(since)
+//	})
+func WithEntryFilter(filter func(EntryMeta) bool) DecodeOption {
+	return func(c *decodeConfig) {
+		c.filter = filter
+	}
+}
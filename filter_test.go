@@ -0,0 +1,48 @@
+package segb
+
+import (
+	"bytes"
+	"testing"
+)
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	*bytes.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestWithEntryFilter(t *testing.T) {
+	big := bytes.Repeat([]byte("Here's to the crazy ones. "), 100)
+	payloads := [][]byte{big, []byte("The misfits."), []byte("The rebels.")}
+
+	for name, file := range map[string][]byte{
+		"v1": v1File(8, payloads...),
+		"v2": v2File(payloads...),
+	} {
+		var seen []EntryMeta
+		r := &countingReader{Reader: bytes.NewReader(file)}
+		decoded, err := Decode(r, WithEntryFilter(func(m EntryMeta) bool {
+			seen = append(seen, m)
+			return m.Size < 100
+		}))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if len(seen) != 3 || seen[0].Size < int64(len(big)) || seen[1].ID != 1 {
+			t.Errorf("%s: filter saw %+v", name, seen)
+		}
+		if len(decoded.Entries) != 2 || string(decoded.Entries[0].Data) != "The misfits." || decoded.Entries[0].Offset != seen[1].Offset {
+			t.Errorf("%s: entries = %+v", name, decoded.Entries)
+		}
+		if r.n >= len(big) {
+			t.Errorf("%s: read %d bytes; the filtered out payload is %d", name, r.n, len(big))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package segb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"github.com/bluefalconhd/segb/internal/lz4"
+	"runtime"
+	"testing"
+)
+
+func fuzzSeeds(f *testing.F) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), zlibPayload("The misfits."), []byte("The rebels.")}
+	f.Add(v1File(8, payloads...))
+	f.Add(v1File(1, payloads...))
+	f.Add(v2File(payloads...))
+	f.Add(v2File())
+
+	f.Add(gzipped(v2File(payloads...)))
+}
+
+func gzipped(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeUntrusted(t *testing.T) {
+	big := bytes.Repeat([]byte("crazy "), 1000)
+	file := v2File(zlibPayload(string(big)))
+
+	decoded, err := DecodeUntrusted(file)
+	if err != nil || !bytes.Equal(decoded.Entries[0].Data, big) {
+		t.Errorf("DecodeUntrusted() = %v", err)
+	}
+
+	// Payloads expanding past the limit are left as stored, input fails
+	decoded, err = DecodeUntrusted(file, WithMaxDecompressedSize(1024))
+	if err != nil || decoded.Entries[0].Compression != CompressionNone {
+		t.Errorf("payload over the limit: compression %v, %v", decoded.Entries[0].Compression, err)
+	}
+	if _, err := DecodeUntrusted(gzipped(file), WithMaxDecompressedSize(32)); err != ErrDecompressedTooLarge {
+		t.Errorf("input over the limit: err = %v; want ErrDecompressedTooLarge", err)
+	}
+
+	if _, err := DecodeUntrusted(file, WithMaxEntrySize(16)); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("entry over the limit: err = %v; want ErrEntryTooLarge", err)
+	}
+}
+
+func TestAppleLZ4Bomb(t *testing.T) {
+	// A block claiming 16 bytes whose single match expands to about 1 MB
+	block := append([]byte{0x1f, 'a', 0x01, 0x00}, bytes.Repeat([]byte{0xff}, 4000)...)
+	block = append(block, 0x00)
+	payload := append([]byte("bv41"), 16, 0, 0, 0)
+	payload = binary.LittleEndian.AppendUint32(payload, uint32(len(block)))
+	payload = append(payload, block...)
+	payload = append(payload, "bv4$"...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, _, err := DecompressPayload(payload); err != lz4.ErrCorrupt {
+		t.Errorf("DecompressPayload() = %v; want lz4.ErrCorrupt", err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<10 {
+		t.Errorf("DecompressPayload() allocated %d bytes", allocated)
+	}
+}
+
+func FuzzDecodeUntrusted(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range [][]DecodeOption{
+			nil,
+			{WithLenientParsing(), WithCRCPolicy(CRCRepair), WithRoundTrip()},
+		} {
+			decoded, err := DecodeUntrusted(data, opts...)
+			if err != nil {
+				continue
+			}
+			for _, entry := range decoded.Entries {
+				if int64(len(entry.Stored())) > UntrustedMaxEntrySize {
+					t.Fatalf("entry %d stores %d bytes", entry.ID, len(entry.Stored()))
+				}
+			}
+			if decoded.original != nil {
+				var buf bytes.Buffer
+				if err := decoded.Encode(&buf); err != nil {
+					t.Fatalf("Encode(): %v", err)
+				}
+			}
+		}
+	})
+}
+
+func FuzzCarve(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		carved, err := Carve(bytes.NewReader(data), int64(len(data)), WithOrphanEntries())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range carved {
+			if c.Offset < 0 || c.Offset+c.Length > int64(len(data)) {
+				t.Fatalf("carved [%d, %d) out of %d bytes", c.Offset, c.Offset+c.Length, len(data))
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package segb
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrSourceChanged is returned by IntegrityGuard.Check for input files that
+// changed while they were processed.
+var ErrSourceChanged = errors.New("source file changed during processing")
+
+// IntegrityGuard is a safeguard for evidence handling: it opens input files
+// strictly read-only and hashes them when they are opened, so that Check can
+// hash them again once processing is done and tell whether any changed in
+// the meantime, e.g. because another program was still writing to them.
+type IntegrityGuard struct {
+	mu     sync.Mutex
+	hashes map[string]PayloadHash
+}
+
+// NewIntegrityGuard returns a guard with no files opened.
+func NewIntegrityGuard() *IntegrityGuard {
+	return &IntegrityGuard{hashes: make(map[string]PayloadHash)}
+}
+
+// Open opens the file at path read-only, hashes it and returns it positioned
+// at its start.
+func (g *IntegrityGuard) Open(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashReader(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if previous, ok := g.hashes[path]; ok && previous != hash {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, ErrSourceChanged)
+	}
+	g.hashes[path] = hash
+	return file, nil
+}
+
+// Check hashes every file opened so far again, by path so that a file
+// replaced by another counts as changed. It returns an error matching
+// ErrSourceChanged that names the files that changed or can no longer be
+// read.
+func (g *IntegrityGuard) Check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var changed []string
+	for path, want := range g.hashes {
+		file, err := os.Open(path)
+		if err != nil {
+			changed = append(changed, path)
+			continue
+		}
+		hash, err := hashReader(file)
+		file.Close()
+		if err != nil || hash != want {
+			changed = append(changed, path)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+	return fmt.Errorf("%w: %s", ErrSourceChanged, strings.Join(changed, ", "))
+}
+
+// WithGuard makes LoadDir open every file it walks through g, so that
+// g.Check covers them. It has no effect on LoadFS.
+func WithGuard(g *IntegrityGuard) LoadOption {
+	return func(c *loadConfig) {
+		c.guard = g
+	}
+}
+
+// hashReader returns the SHA-256 of everything r holds.
+func hashReader(r io.Reader) (PayloadHash, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return PayloadHash{}, err
+	}
+	return PayloadHash(h.Sum(nil)), nil
+}
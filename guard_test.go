@@ -0,0 +1,71 @@
+package segb
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegrityGuard(t *testing.T) {
+	dir := t.TempDir()
+	stable, changing := filepath.Join(dir, "stable.segb"), filepath.Join(dir, "changing.segb")
+	for _, path := range []string{stable, changing} {
+		if err := os.WriteFile(path, v2File([]byte("payload")), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	guard := NewIntegrityGuard()
+	for _, path := range []string{stable, changing} {
+		file, err := guard.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The file is handed over at its start, and cannot be written
+		if _, err := Decode(file); err != nil {
+			t.Errorf("Decode(%s) = %v", path, err)
+		}
+		if _, err := file.Write([]byte("x")); err == nil {
+			t.Errorf("Write(%s) error = nil; want a read-only file", path)
+		}
+		file.Close()
+	}
+	if err := guard.Check(); err != nil {
+		t.Errorf("Check() = %v; want nil", err)
+	}
+
+	file, err := os.OpenFile(changing, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(file, "appended by another program")
+	file.Close()
+	err = guard.Check()
+	if !errors.Is(err, ErrSourceChanged) || !strings.Contains(err.Error(), changing) || strings.Contains(err.Error(), stable) {
+		t.Errorf("Check() = %v; want ErrSourceChanged naming only %s", err, changing)
+	}
+	if _, err := guard.Open(changing); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("Open(changed file again) = %v; want ErrSourceChanged", err)
+	}
+}
+
+func TestLoadDirGuard(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "segment")
+	if err := os.WriteFile(path, v2File([]byte("payload")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	guard := NewIntegrityGuard()
+	if _, err := LoadDir(dir, WithGuard(guard)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, v2File([]byte("rewritten")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.Check(); !errors.Is(err, ErrSourceChanged) || !strings.Contains(err.Error(), path) {
+		t.Errorf("Check() = %v; want ErrSourceChanged naming %s", err, path)
+	}
+}
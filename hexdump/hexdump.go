@@ -0,0 +1,102 @@
+// Package hexdump renders binary data as a classic offset/hex/ASCII dump.
+package hexdump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultWidth is the number of bytes rendered per line when Options.Width
+// is not set.
+const DefaultWidth = 16
+
+// ANSI escape sequences used when Options.Color is set.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// Options controls how a dump is rendered. The zero value renders 16 bytes
+// per line with offsets starting at zero, no color and an ASCII column.
+type Options struct {
+	Width      int   // Bytes per line, DefaultWidth if zero or negative
+	BaseOffset int64 // Added to every printed offset, e.g. the data's position in a file
+	Color      bool  // Colorize bytes by class (zero, printable, other) using ANSI escapes
+	HideASCII  bool  // Omit the ASCII column
+}
+
+// Dump writes a hexdump of data to w.
+func Dump(w io.Writer, data []byte, opts Options) error {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	bw := bufio.NewWriter(w)
+	for i := 0; i < len(data); i += width {
+		line := data[i:min(i+width, len(data))]
+
+		opts.paint(bw, ansiDim, fmt.Sprintf("%08x:", opts.BaseOffset+int64(i)))
+		bw.WriteString(" ")
+
+		for j := 0; j < width; j++ {
+			if j < len(line) {
+				opts.paint(bw, byteColor(line[j]), fmt.Sprintf("%02x", line[j]))
+				bw.WriteString(" ")
+			} else {
+				bw.WriteString("   ")
+			}
+		}
+
+		if !opts.HideASCII {
+			bw.WriteString(" ")
+			for _, b := range line {
+				opts.paint(bw, byteColor(b), string(printable(b)))
+			}
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// String returns the dump of data as a string.
+func String(data []byte, opts Options) string {
+	var sb strings.Builder
+	_ = Dump(&sb, data, opts)
+	return sb.String()
+}
+
+// paint writes s, wrapped in the given color if coloring is enabled.
+func (o Options) paint(w *bufio.Writer, color, s string) {
+	if !o.Color || color == "" {
+		w.WriteString(s)
+		return
+	}
+	w.WriteString(color)
+	w.WriteString(s)
+	w.WriteString(ansiReset)
+}
+
+// printable returns b if it is printable ASCII and '.' otherwise.
+func printable(b byte) byte {
+	if b >= 32 && b <= 126 {
+		return b
+	}
+	return '.'
+}
+
+// byteColor returns the color class of a byte.
+func byteColor(b byte) string {
+	switch {
+	case b == 0:
+		return ansiDim
+	case b >= 32 && b <= 126:
+		return ansiGreen
+	default:
+		return ansiYellow
+	}
+}
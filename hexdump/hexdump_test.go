@@ -0,0 +1,35 @@
+package hexdump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	data := []byte("Here's to the crazy ones.\x00\x01")
+
+	got := String(data, Options{})
+	want := "" +
+		"00000000: 48 65 72 65 27 73 20 74 6f 20 74 68 65 20 63 72  Here's to the cr\n" +
+		"00000010: 61 7a 79 20 6f 6e 65 73 2e 00 01                 azy ones...\n"
+	if got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDumpOptions(t *testing.T) {
+	data := []byte("abcdef")
+
+	got := String(data, Options{Width: 4, BaseOffset: 0x100, HideASCII: true})
+	want := "" +
+		"00000100: 61 62 63 64 \n" +
+		"00000104: 65 66       \n"
+	if got != want {
+		t.Errorf("String() =\n%q\nwant\n%q", got, want)
+	}
+
+	colored := String(data, Options{Color: true})
+	if !strings.Contains(colored, ansiGreen+"61"+ansiReset) {
+		t.Errorf("String() with Color = %q; want colored bytes", colored)
+	}
+}
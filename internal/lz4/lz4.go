@@ -0,0 +1,221 @@
+// Package lz4 decodes LZ4 compressed data: raw blocks and the LZ4 frame
+// format (https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md).
+//
+// Only decompression is implemented. Frame and block checksums are skipped,
+// not verified.
+package lz4
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// FrameMagic is the magic number at the start of every LZ4 frame.
+const FrameMagic = 0x184D2204
+
+var (
+	ErrCorrupt  = errors.New("lz4: corrupt input")
+	ErrTooLarge = errors.New("lz4: decompressed data exceeds limit")
+)
+
+// DecodeBlock decompresses one raw LZ4 block and appends the result to dst.
+// Matches may reach back into the existing contents of dst, which is how
+// linked blocks of a frame share history.
+func DecodeBlock(dst, src []byte) ([]byte, error) {
+	return decodeBlock(dst, src, 0, -1)
+}
+
+// DecodeBlockLimit is DecodeBlock failing with ErrTooLarge once dst would
+// grow past limit bytes, for blocks whose decompressed size is known.
+func DecodeBlockLimit(dst, src []byte, limit int) ([]byte, error) {
+	return decodeBlock(dst, src, 0, limit)
+}
+
+// decodeBlock is DecodeBlock with matches restricted to dst[floor:] and a
+// bound on the length of dst; a negative limit means no bound.
+func decodeBlock(dst, src []byte, floor, limit int) ([]byte, error) {
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		// Literals
+		literals, n, ok := length(src[i:], int(token>>4))
+		if !ok {
+			return dst, ErrCorrupt
+		}
+		i += n
+		if literals > len(src)-i {
+			return dst, ErrCorrupt
+		}
+		if limit >= 0 && len(dst)+literals > limit {
+			return dst, ErrTooLarge
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+
+		// The last sequence has no match
+		if i == len(src) {
+			break
+		}
+
+		// Match
+		if len(src)-i < 2 {
+			return dst, ErrCorrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst)-floor {
+			return dst, ErrCorrupt
+		}
+		match, n, ok := length(src[i:], int(token&0x0f))
+		if !ok {
+			return dst, ErrCorrupt
+		}
+		i += n
+		match += 4
+		if limit >= 0 && len(dst)+match > limit {
+			return dst, ErrTooLarge
+		}
+
+		// Copy byte by byte: the match may overlap the bytes it produces
+		start := len(dst) - offset
+		for j := 0; j < match; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+	return dst, nil
+}
+
+// length decodes a 4-bit length from a token plus its extension bytes,
+// returning the length and the number of extension bytes consumed.
+func length(src []byte, n int) (int, int, bool) {
+	if n != 0x0f {
+		return n, 0, true
+	}
+	for i, b := range src {
+		n += int(b)
+		if b != 0xff {
+			return n, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// DecodeFrames decompresses one or more concatenated LZ4 frames. Skippable
+// frames are ignored. Decoding fails with ErrTooLarge once the output would
+// exceed limit bytes.
+func DecodeFrames(src []byte, limit int) ([]byte, error) {
+	var dst []byte
+	for len(src) > 0 {
+		if len(src) < 4 {
+			return dst, ErrCorrupt
+		}
+		magic := binary.LittleEndian.Uint32(src)
+
+		// Skippable frame: magic, size, data
+		if magic&0xfffffff0 == 0x184D2A50 {
+			if len(src) < 8 {
+				return dst, ErrCorrupt
+			}
+			size := int(binary.LittleEndian.Uint32(src[4:]))
+			if size > len(src)-8 {
+				return dst, ErrCorrupt
+			}
+			src = src[8+size:]
+			continue
+		}
+		if magic != FrameMagic {
+			return dst, ErrCorrupt
+		}
+
+		var err error
+		dst, src, err = decodeFrame(dst, src[4:], limit)
+		if err != nil {
+			return dst, err
+		}
+	}
+	return dst, nil
+}
+
+// decodeFrame decodes the frame following the magic number, appending to
+// dst, and returns the remaining input.
+func decodeFrame(dst, src []byte, limit int) ([]byte, []byte, error) {
+	if len(src) < 3 {
+		return dst, nil, ErrCorrupt
+	}
+	flags := src[0]
+	if flags>>6 != 1 {
+		return dst, nil, ErrCorrupt
+	}
+	blockChecksum := flags&0x10 != 0
+	contentSize := flags&0x08 != 0
+	contentChecksum := flags&0x04 != 0
+	dictID := flags&0x01 != 0
+
+	// Block maximum sizes 4-7 stand for 64KB, 256KB, 1MB and 4MB
+	maxBlock := 1 << (8 + 2*((src[1]>>4)&0x07))
+	if maxBlock < 64<<10 {
+		return dst, nil, ErrCorrupt
+	}
+
+	// FLG, BD, optional content size and dictionary ID, header checksum
+	header := 3
+	if contentSize {
+		header += 8
+	}
+	if dictID {
+		header += 4
+	}
+	if len(src) < header {
+		return dst, nil, ErrCorrupt
+	}
+	src = src[header:]
+
+	// Linked blocks may only refer to output of the same frame. Blocks are
+	// decoded in place, so the output is only ever appended to.
+	frame := len(dst)
+	for {
+		if len(src) < 4 {
+			return dst, nil, ErrCorrupt
+		}
+		size := binary.LittleEndian.Uint32(src)
+		src = src[4:]
+		if size == 0 {
+			break
+		}
+
+		uncompressed := size&0x80000000 != 0
+		size &= 0x7fffffff
+		if int64(size) > int64(len(src)) {
+			return dst, nil, ErrCorrupt
+		}
+		block := src[:size]
+		src = src[size:]
+		if blockChecksum {
+			if len(src) < 4 {
+				return dst, nil, ErrCorrupt
+			}
+			src = src[4:]
+		}
+
+		if uncompressed {
+			dst = append(dst, block...)
+		} else {
+			var err error
+			if dst, err = decodeBlock(dst, block, frame, len(dst)+maxBlock); err != nil {
+				return dst, nil, err
+			}
+		}
+		if len(dst) > limit {
+			return dst, nil, ErrTooLarge
+		}
+	}
+
+	if contentChecksum {
+		if len(src) < 4 {
+			return dst, nil, ErrCorrupt
+		}
+		src = src[4:]
+	}
+	return dst, src, nil
+}
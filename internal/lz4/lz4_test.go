@@ -0,0 +1,123 @@
+package lz4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// "abc" followed by a 9 byte match at offset 3, then "xyz"
+var block = []byte{0x35, 'a', 'b', 'c', 0x03, 0x00, 0x30, 'x', 'y', 'z'}
+
+func TestDecodeBlock(t *testing.T) {
+	out, err := DecodeBlock(nil, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "abcabcabcabcxyz" {
+		t.Errorf("got %q", out)
+	}
+
+	for _, corrupt := range [][]byte{
+		{0x35, 'a', 'b', 'c', 0x04, 0x00}, // Offset before the start
+		{0x35, 'a', 'b', 'c', 0x00, 0x00}, // Zero offset
+		{0x50, 'a'},                       // Truncated literals
+		{0xf0, 0xff},                      // Truncated length
+	} {
+		if _, err := DecodeBlock(nil, corrupt); err != ErrCorrupt {
+			t.Errorf("DecodeBlock(%x) = %v, want ErrCorrupt", corrupt, err)
+		}
+	}
+}
+
+func frame(blocks ...[]byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(FrameMagic))
+	buf.Write([]byte{0x60, 0x40, 0x00}) // Version 1, independent blocks, 64KB
+	for _, b := range blocks {
+		buf.Write(b)
+	}
+	buf.Write([]byte{0, 0, 0, 0})
+	return buf.Bytes()
+}
+
+func sized(data []byte, uncompressed bool) []byte {
+	size := uint32(len(data))
+	if uncompressed {
+		size |= 0x80000000
+	}
+	return binary.LittleEndian.AppendUint32(nil, size)
+}
+
+func TestDecodeFrames(t *testing.T) {
+	raw := []byte("plain block;")
+	input := frame(sized(raw, true), raw, sized(block, false), block)
+
+	// A skippable frame between two frames
+	input = append(input, 0x50, 0x2a, 0x4d, 0x18, 2, 0, 0, 0, 0xaa, 0xbb)
+	input = append(input, frame(sized(raw, true), raw)...)
+
+	out, err := DecodeFrames(input, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "plain block;abcabcabcabcxyzplain block;"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	if _, err := DecodeFrames(input, 10); err != ErrTooLarge {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+	if _, err := DecodeFrames(input[:len(input)-3], 1<<20); err != ErrCorrupt {
+		t.Errorf("got %v, want ErrCorrupt", err)
+	}
+}
+
+func TestDecodeFramesLinkedBlocks(t *testing.T) {
+	// Every block after the first repeats the last 4 bytes of the one before
+	first := []byte{0x40, 'a', 'b', 'c', 'd'}
+	repeat := []byte{0x00, 0x04, 0x00}
+	blocks := [][]byte{sized(first, false), first}
+	for i := 0; i < 1000; i++ {
+		blocks = append(blocks, sized(repeat, false), repeat)
+	}
+	out, err := DecodeFrames(frame(blocks...), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Repeat([]byte("abcd"), 1001); !bytes.Equal(out, want) {
+		t.Errorf("got %d bytes, want %d", len(out), len(want))
+	}
+
+	// A match may not reach into the output of an earlier frame
+	input := append(frame(sized(first, false), first), frame(sized(repeat, false), repeat)...)
+	if _, err := DecodeFrames(input, 1<<20); err != ErrCorrupt {
+		t.Errorf("got %v, want ErrCorrupt", err)
+	}
+}
+
+func BenchmarkDecodeFrames(b *testing.B) {
+	// 1000 blocks of 64KB of literals, 15 in the token plus 255s and a rest
+	literals := bytes.Repeat([]byte{'x'}, 64<<10)
+	block := []byte{0xf0}
+	for n := len(literals) - 15; ; n -= 255 {
+		if n < 255 {
+			block = append(block, byte(n))
+			break
+		}
+		block = append(block, 0xff)
+	}
+	block = append(block, literals...)
+	var blocks [][]byte
+	for i := 0; i < 1000; i++ {
+		blocks = append(blocks, sized(block, false), block)
+	}
+	input := frame(blocks...)
+	b.SetBytes(int64(len(literals) * 1000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeFrames(input, 1<<30); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,481 @@
+// Package lzfse decodes the compressed blocks of LZFSE streams, the format
+// of Apple's compression library (https://github.com/lzfse/lzfse): LZFSE
+// blocks, with either the v1 or the packed v2 header, and LZVN blocks.
+//
+// Only decompression is implemented, and only of the compressed blocks: the
+// stream around them, stored blocks and the end of stream block, is left to
+// the caller.
+package lzfse
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+var (
+	ErrCorrupt  = errors.New("lzfse: corrupt input")
+	ErrTooLarge = errors.New("lzfse: decompressed data exceeds limit")
+)
+
+// Symbol and state counts of the four FSE streams of a block
+const (
+	lSymbols       = 20
+	mSymbols       = 20
+	dSymbols       = 64
+	literalSymbols = 256
+
+	lStates       = 64
+	mStates       = 64
+	dStates       = 256
+	literalStates = 1024
+)
+
+const (
+	// maxLiterals is the number of literals a block holds at most
+	maxLiterals = 40000
+
+	headerV1Size = 772 // Including the padding of the C struct
+	headerV2Size = 32  // Up to the frequency tables
+)
+
+// Extra bits and base values of the L (literal length), M (match length)
+// and D (match distance) symbols
+var (
+	lBits = [lSymbols]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 3, 5, 8}
+	lBase = [lSymbols]uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 20, 28, 60}
+	mBits = [mSymbols]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 5, 8, 11}
+	mBase = [mSymbols]uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 24, 56, 312}
+	dBits = [dSymbols]uint8{
+		0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3,
+		4, 4, 4, 4, 5, 5, 5, 5, 6, 6, 6, 6, 7, 7, 7, 7,
+		8, 8, 8, 8, 9, 9, 9, 9, 10, 10, 10, 10, 11, 11, 11, 11,
+		12, 12, 12, 12, 13, 13, 13, 13, 14, 14, 14, 14, 15, 15, 15, 15}
+	dBase = [dSymbols]uint32{
+		0, 1, 2, 3, 4, 6, 8, 10, 12, 16, 20, 24, 28, 36, 44, 52,
+		60, 76, 92, 108, 124, 156, 188, 220, 252, 316, 380, 444, 508, 636, 764, 892,
+		1020, 1276, 1532, 1788, 2044, 2556, 3068, 3580, 4092, 5116, 6140, 7164, 8188, 10236, 12284, 14332,
+		16380, 20476, 24572, 28668, 32764, 40956, 49148, 57340, 65532, 81916, 98300, 114684, 131068, 163836, 196604, 229372}
+)
+
+// DecodeBlock decompresses the LZFSE (bvx1, bvx2) or LZVN (bvxn) block at
+// the start of src, magic included, and appends the result to dst. Matches
+// may reach back into the existing contents of dst, which is how the blocks
+// of a stream share history. It returns the number of bytes of src the
+// block spans, and fails with ErrTooLarge if dst would grow past limit
+// bytes.
+func DecodeBlock(dst, src []byte, limit int) ([]byte, int, error) {
+	if len(src) < 8 {
+		return dst, 0, ErrCorrupt
+	}
+	raw := binary.LittleEndian.Uint32(src[4:])
+	if int64(len(dst))+int64(raw) > int64(limit) {
+		return dst, 0, ErrTooLarge
+	}
+	end := len(dst) + int(raw)
+
+	var h header
+	var size int
+	var err error
+	switch string(src[:4]) {
+	case "bvxn":
+		if len(src) < 12 {
+			return dst, 0, ErrCorrupt
+		}
+		payload := binary.LittleEndian.Uint32(src[8:])
+		if int64(payload) > int64(len(src)-12) {
+			return dst, 0, ErrCorrupt
+		}
+		out, n, err := decodeLZVN(dst, src[12:12+payload], end)
+		if err == nil && (n != int(payload) || len(out) != end) {
+			err = ErrCorrupt
+		}
+		return out, 12 + int(payload), err
+	case "bvx1":
+		h, size, err = parseV1(src)
+	case "bvx2":
+		h, size, err = parseV2(src)
+	default:
+		return dst, 0, ErrCorrupt
+	}
+	if err != nil {
+		return dst, 0, err
+	}
+	if int64(h.literalPayload)+int64(h.lmdPayload) > int64(len(src)-size) {
+		return dst, 0, ErrCorrupt
+	}
+	literalEnd := size + int(h.literalPayload)
+	lmdEnd := literalEnd + int(h.lmdPayload)
+	literals, err := decodeLiterals(&h, src[size:literalEnd])
+	if err != nil {
+		return dst, 0, err
+	}
+	out, err := decodeMatches(dst, &h, literals, src[literalEnd:lmdEnd], end)
+	if err == nil && len(out) != end {
+		err = ErrCorrupt
+	}
+	return out, lmdEnd, err
+}
+
+// header is the header of an LZFSE block, in its v1 form.
+type header struct {
+	literals       uint32 // Literals decoded, a multiple of 4 once padded
+	matches        uint32 // L, M, D triplets decoded
+	literalPayload uint32 // Size of the literal stream
+	lmdPayload     uint32 // Size of the L, M, D stream
+
+	// Final encoder states, the initial decoder states
+	literalBits  int32 // Minus the unused high bits of the last byte, -7 to 0
+	literalState [4]uint16
+	lmdBits      int32
+	lState       uint16
+	mState       uint16
+	dState       uint16
+
+	// Normalized frequencies of the L, M, D and literal symbols, in this
+	// order
+	freq [lSymbols + mSymbols + dSymbols + literalSymbols]uint16
+}
+
+// Offsets of the frequency tables in header.freq
+const (
+	mFreq       = lSymbols
+	dFreq       = mFreq + mSymbols
+	literalFreq = dFreq + dSymbols
+)
+
+// parseV1 reads the header of a bvx1 block, returning its size.
+func parseV1(src []byte) (header, int, error) {
+	var h header
+	if len(src) < headerV1Size {
+		return h, 0, ErrCorrupt
+	}
+	le := binary.LittleEndian
+	h.literals = le.Uint32(src[12:])
+	h.matches = le.Uint32(src[16:])
+	h.literalPayload = le.Uint32(src[20:])
+	h.lmdPayload = le.Uint32(src[24:])
+	h.literalBits = int32(le.Uint32(src[28:]))
+	for i := range h.literalState {
+		h.literalState[i] = le.Uint16(src[32+2*i:])
+	}
+	h.lmdBits = int32(le.Uint32(src[40:]))
+	h.lState = le.Uint16(src[44:])
+	h.mState = le.Uint16(src[46:])
+	h.dState = le.Uint16(src[48:])
+	for i := range h.freq {
+		h.freq[i] = le.Uint16(src[50+2*i:])
+	}
+	return h, headerV1Size, h.check()
+}
+
+// parseV2 reads the header of a bvx2 block, whose fields are packed into
+// three 64-bit words followed by the variable length frequency tables,
+// returning its size.
+func parseV2(src []byte) (header, int, error) {
+	var h header
+	if len(src) < headerV2Size {
+		return h, 0, ErrCorrupt
+	}
+	v0 := binary.LittleEndian.Uint64(src[8:])
+	v1 := binary.LittleEndian.Uint64(src[16:])
+	v2 := binary.LittleEndian.Uint64(src[24:])
+	size := field(v2, 0, 32)
+	if size < headerV2Size || int64(size) > int64(len(src)) {
+		return h, 0, ErrCorrupt
+	}
+
+	h.literals = field(v0, 0, 20)
+	h.literalPayload = field(v0, 20, 20)
+	h.matches = field(v0, 40, 20)
+	h.literalBits = int32(field(v0, 60, 3)) - 7
+	for i := range h.literalState {
+		h.literalState[i] = uint16(field(v1, 10*i, 10))
+	}
+	h.lmdPayload = field(v1, 40, 20)
+	h.lmdBits = int32(field(v1, 60, 3)) - 7
+	h.lState = uint16(field(v2, 32, 10))
+	h.mState = uint16(field(v2, 42, 10))
+	h.dState = uint16(field(v2, 52, 10))
+
+	// Omitted tables leave every frequency zero
+	if size > headerV2Size && !decodeFreq(h.freq[:], src[headerV2Size:size]) {
+		return h, 0, ErrCorrupt
+	}
+	return h, int(size), h.check()
+}
+
+// field extracts the n bits of v starting at bit offset.
+func field(v uint64, offset, n int) uint32 {
+	return uint32(v >> offset & (1<<n - 1))
+}
+
+// check validates the initial states and frequency tables of a header.
+func (h *header) check() error {
+	if h.literals > maxLiterals || h.literalBits < -7 || h.literalBits > 0 || h.lmdBits < -7 || h.lmdBits > 0 {
+		return ErrCorrupt
+	}
+	for _, state := range h.literalState {
+		if state >= literalStates {
+			return ErrCorrupt
+		}
+	}
+	if h.lState >= lStates || h.mState >= mStates || h.dState >= dStates {
+		return ErrCorrupt
+	}
+	for _, table := range []struct {
+		freq   []uint16
+		states int
+	}{
+		{h.freq[:mFreq], lStates},
+		{h.freq[mFreq:dFreq], mStates},
+		{h.freq[dFreq:literalFreq], dStates},
+		{h.freq[literalFreq:], literalStates},
+	} {
+		sum := 0
+		for _, f := range table.freq {
+			sum += int(f)
+		}
+		if sum > table.states {
+			return ErrCorrupt
+		}
+	}
+	return nil
+}
+
+// decodeFreq decodes the frequency tables of a v2 header, each value a
+// variable length code read from the low bits up. The codes must fill the
+// tables exactly.
+func decodeFreq(freq []uint16, src []byte) bool {
+	var accum uint32
+	n := 0
+	for i := range freq {
+		for len(src) > 0 && n+8 <= 32 {
+			accum |= uint32(src[0]) << n
+			n += 8
+			src = src[1:]
+		}
+		value, size := freqValue(accum)
+		if size > n {
+			return false
+		}
+		freq[i] = value
+		accum >>= size
+		n -= size
+	}
+	return n < 8 && len(src) == 0
+}
+
+// Lengths and values of the frequency codes, indexed by their low 5 bits
+var (
+	freqCodeBits   = [32]uint8{2, 3, 2, 5, 2, 3, 2, 8, 2, 3, 2, 5, 2, 3, 2, 14, 2, 3, 2, 5, 2, 3, 2, 8, 2, 3, 2, 5, 2, 3, 2, 14}
+	freqCodeValues = [32]uint16{0, 2, 1, 4, 0, 3, 1, 0, 0, 2, 1, 5, 0, 3, 1, 0, 0, 2, 1, 6, 0, 3, 1, 0, 0, 2, 1, 7, 0, 3, 1, 0}
+)
+
+// freqValue decodes the frequency code in the low bits of accum, returning
+// the frequency and the length of the code.
+func freqValue(accum uint32) (uint16, int) {
+	b := accum & 31
+	switch n := int(freqCodeBits[b]); n {
+	case 8:
+		return 8 + uint16(accum>>4&0xf), n
+	case 14:
+		return 24 + uint16(accum>>4&0x3ff), n
+	default:
+		return freqCodeValues[b], n
+	}
+}
+
+// entry is a state of an FSE decoding table: the symbol it emits, and the
+// next state, delta plus the next k bits of the stream.
+type entry struct {
+	symbol uint8
+	k      uint8
+	delta  uint16
+}
+
+// valueEntry is a state of an L, M or D decoding table, whose symbols stand
+// for a base value plus extra bits read after the state bits.
+type valueEntry struct {
+	k     uint8
+	extra uint8
+	delta uint16
+	base  uint32
+}
+
+// buildTable fills the decoding table of the normalized frequencies freq,
+// which sum to at most len(table). Each symbol owns a contiguous run of
+// states, in symbol order.
+func buildTable(table []entry, freq []uint16) {
+	n := len(table)
+	state := 0
+	for symbol, f16 := range freq {
+		f := int(f16)
+		if f == 0 {
+			continue
+		}
+		// k is the shift that brings f into [n, 2n)
+		k := bits.LeadingZeros32(uint32(f)) - bits.LeadingZeros32(uint32(n))
+		j0 := (2*n)>>k - f
+		for j := 0; j < f; j++ {
+			e := entry{symbol: uint8(symbol)}
+			if j < j0 {
+				e.k = uint8(k)
+				e.delta = uint16((f+j)<<k - n)
+			} else {
+				e.k = uint8(k - 1)
+				e.delta = uint16((j - j0) << (k - 1))
+			}
+			table[state] = e
+			state++
+		}
+	}
+}
+
+// buildValueTable is buildTable for the L, M and D streams.
+func buildValueTable(table []valueEntry, freq []uint16, extra []uint8, base []uint32) {
+	entries := make([]entry, len(table))
+	buildTable(entries, freq)
+	for i, e := range entries {
+		table[i] = valueEntry{k: e.k, extra: extra[e.symbol], delta: e.delta, base: base[e.symbol]}
+	}
+}
+
+// bitReader reads an FSE stream backwards from its end, where the encoder
+// left the first bits to decode, most significant first.
+type bitReader struct {
+	src   []byte // Bytes not read yet
+	accum uint64
+	n     int // Bits in accum
+}
+
+// newBitReader starts reading src, skipping the -unused high bits of its
+// last byte, which must be zero.
+func newBitReader(src []byte, unused int32) (*bitReader, error) {
+	r := &bitReader{src: src}
+	r.fill()
+	if r.n < int(-unused) {
+		return nil, ErrCorrupt
+	}
+	r.n += int(unused)
+	if r.accum>>r.n != 0 {
+		return nil, ErrCorrupt
+	}
+	return r, nil
+}
+
+// fill loads whole bytes into accum while there is room for them.
+func (r *bitReader) fill() {
+	for r.n <= 56 && len(r.src) > 0 {
+		r.accum = r.accum<<8 | uint64(r.src[len(r.src)-1])
+		r.src = r.src[:len(r.src)-1]
+		r.n += 8
+	}
+}
+
+// read returns the next k bits of the stream.
+func (r *bitReader) read(k int) (uint64, bool) {
+	if k > r.n {
+		r.fill()
+		if k > r.n {
+			return 0, false
+		}
+	}
+	r.n -= k
+	v := r.accum >> r.n
+	r.accum &= 1<<r.n - 1
+	return v, true
+}
+
+// symbol decodes a symbol from the state of a literal stream.
+func (r *bitReader) symbol(state *uint16, table []entry) (byte, bool) {
+	e := table[*state]
+	v, ok := r.read(int(e.k))
+	*state = e.delta + uint16(v)
+	return e.symbol, ok
+}
+
+// value decodes an L, M or D value from the state of its stream.
+func (r *bitReader) value(state *uint16, table []valueEntry) (int, bool) {
+	e := table[*state]
+	v, ok := r.read(int(e.k + e.extra))
+	*state = e.delta + uint16(v>>e.extra)
+	return int(e.base) + int(v&(1<<e.extra-1)), ok
+}
+
+// decodeLiterals decodes the literal stream of a block, four interleaved
+// FSE states taking turns.
+func decodeLiterals(h *header, src []byte) ([]byte, error) {
+	var table [literalStates]entry
+	buildTable(table[:], h.freq[literalFreq:])
+	r, err := newBitReader(src, h.literalBits)
+	if err != nil {
+		return nil, err
+	}
+	literals := make([]byte, (h.literals+3)&^3)
+	states := h.literalState
+	for i := 0; i < len(literals); i += 4 {
+		for j := range states {
+			var ok bool
+			if literals[i+j], ok = r.symbol(&states[j], table[:]); !ok {
+				return nil, ErrCorrupt
+			}
+		}
+	}
+	return literals, nil
+}
+
+// decodeMatches decodes the L, M, D stream of a block, appending to dst
+// the L literals and M byte match at distance D of every triplet, up to
+// end bytes. A zero distance repeats the previous one.
+func decodeMatches(dst []byte, h *header, literals, src []byte, end int) ([]byte, error) {
+	var lTable [lStates]valueEntry
+	var mTable [mStates]valueEntry
+	var dTable [dStates]valueEntry
+	buildValueTable(lTable[:], h.freq[:mFreq], lBits[:], lBase[:])
+	buildValueTable(mTable[:], h.freq[mFreq:dFreq], mBits[:], mBase[:])
+	buildValueTable(dTable[:], h.freq[dFreq:literalFreq], dBits[:], dBase[:])
+	r, err := newBitReader(src, h.lmdBits)
+	if err != nil {
+		return dst, err
+	}
+
+	l, m, d := h.lState, h.mState, h.dState
+	distance := 0
+	for range h.matches {
+		L, lok := r.value(&l, lTable[:])
+		M, mok := r.value(&m, mTable[:])
+		D, dok := r.value(&d, dTable[:])
+		if !lok || !mok || !dok {
+			return dst, ErrCorrupt
+		}
+		if D != 0 {
+			distance = D
+		}
+		if L > len(literals) || L+M > end-len(dst) {
+			return dst, ErrCorrupt
+		}
+		dst = append(dst, literals[:L]...)
+		literals = literals[L:]
+		if M > 0 {
+			if distance == 0 || distance > len(dst) {
+				return dst, ErrCorrupt
+			}
+			dst = copyMatch(dst, distance, M)
+		}
+	}
+	return dst, nil
+}
+
+// copyMatch appends the n bytes found distance bytes back in dst.
+func copyMatch(dst []byte, distance, n int) []byte {
+	start := len(dst) - distance
+	if distance >= n {
+		return append(dst, dst[start:start+n]...)
+	}
+	// Copy byte by byte: the match overlaps the bytes it produces
+	for j := 0; j < n; j++ {
+		dst = append(dst, dst[start+j])
+	}
+	return dst
+}
@@ -0,0 +1,425 @@
+package lzfse
+
+import (
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// push is a k bit value of an FSE stream.
+type push struct {
+	v uint64
+	k int
+}
+
+// fseStream lays out the values of a stream, given in decoding order, the
+// way the decoder reads them back: from the end, most significant bit first.
+// It returns the stream and its count of unused high bits, negated.
+func fseStream(values []push) ([]byte, int32) {
+	total := 0
+	for _, p := range values {
+		total += p.k
+	}
+	out := make([]byte, (total+7)/8)
+	pos := total
+	for _, p := range values {
+		for i := p.k - 1; i >= 0; i-- {
+			pos--
+			if p.v>>i&1 != 0 {
+				out[pos/8] |= 1 << (pos % 8)
+			}
+		}
+	}
+	return out, int32(total - 8*len(out))
+}
+
+// encodeState returns the state of table that decodes symbol and moves to
+// next, with the bits read on the way.
+func encodeState(table []entry, symbol uint8, next uint16) (uint16, push) {
+	for state, e := range table {
+		if e.symbol == symbol && next >= e.delta && int(next) < int(e.delta)+1<<e.k {
+			return uint16(state), push{uint64(next - e.delta), int(e.k)}
+		}
+	}
+	panic("no state reaches the next one")
+}
+
+// normalize scales symbol counts to frequencies summing to n.
+func normalize(counts []int, n int) []uint16 {
+	freq := make([]uint16, len(counts))
+	total, top := 0, 0
+	for s, c := range counts {
+		total += c
+		if c > counts[top] {
+			top = s
+		}
+	}
+	sum := 0
+	for s, c := range counts {
+		if c > 0 {
+			freq[s] = uint16(max(1, c*n/total))
+			sum += int(freq[s])
+		}
+	}
+	freq[top] = uint16(int(freq[top]) + n - sum)
+	return freq
+}
+
+// symbolOf splits an L, M or D value into its symbol and extra bits.
+func symbolOf(v int, base []uint32, extra []uint8) (uint8, push) {
+	s := len(base) - 1
+	for int(base[s]) > v {
+		s--
+	}
+	return uint8(s), push{uint64(v - int(base[s])), int(extra[s])}
+}
+
+// match is an L, M, D triplet; a zero d repeats the previous distance.
+type match struct{ l, m, d int }
+
+// encode compresses literals and matches into the header and streams of an
+// LZFSE block.
+func encode(literals []byte, matches []match) (header, []byte, []byte) {
+	var h header
+	literals = slices.Clone(literals)
+	h.literals = uint32(len(literals))
+	for len(literals)%4 != 0 {
+		literals = append(literals, 0)
+	}
+	h.matches = uint32(len(matches))
+
+	// Frequencies
+	literalCounts := make([]int, literalSymbols)
+	for _, b := range literals {
+		literalCounts[b]++
+	}
+	lCounts, mCounts, dCounts := make([]int, lSymbols), make([]int, mSymbols), make([]int, dSymbols)
+	for _, x := range matches {
+		l, _ := symbolOf(x.l, lBase[:], lBits[:])
+		m, _ := symbolOf(x.m, mBase[:], mBits[:])
+		d, _ := symbolOf(x.d, dBase[:], dBits[:])
+		lCounts[l]++
+		mCounts[m]++
+		dCounts[d]++
+	}
+	copy(h.freq[:], normalize(lCounts, lStates))
+	copy(h.freq[mFreq:], normalize(mCounts, mStates))
+	copy(h.freq[dFreq:], normalize(dCounts, dStates))
+	copy(h.freq[literalFreq:], normalize(literalCounts, literalStates))
+
+	// Literals, encoded last to first from the final state 0
+	literalTable := make([]entry, literalStates)
+	buildTable(literalTable, h.freq[literalFreq:])
+	var values []push
+	for i := len(literals) - 1; i >= 0; i-- {
+		var p push
+		h.literalState[i%4], p = encodeState(literalTable, literals[i], h.literalState[i%4])
+		values = append(values, p)
+	}
+	slices.Reverse(values)
+	literalStream, literalBits := fseStream(values)
+	h.literalBits = literalBits
+	h.literalPayload = uint32(len(literalStream))
+
+	// L, M, D values, the state bits above the extra bits
+	streams := []struct {
+		table []entry
+		state *uint16
+		base  []uint32
+		extra []uint8
+	}{
+		{make([]entry, lStates), &h.lState, lBase[:], lBits[:]},
+		{make([]entry, mStates), &h.mState, mBase[:], mBits[:]},
+		{make([]entry, dStates), &h.dState, dBase[:], dBits[:]},
+	}
+	buildTable(streams[0].table, h.freq[:mFreq])
+	buildTable(streams[1].table, h.freq[mFreq:dFreq])
+	buildTable(streams[2].table, h.freq[dFreq:literalFreq])
+	values = nil
+	for i := len(matches) - 1; i >= 0; i-- {
+		triplet := []int{matches[i].l, matches[i].m, matches[i].d}
+		for j := 2; j >= 0; j-- {
+			s := streams[j]
+			symbol, extra := symbolOf(triplet[j], s.base, s.extra)
+			var p push
+			*s.state, p = encodeState(s.table, symbol, *s.state)
+			values = append(values, push{p.v<<extra.k | extra.v, p.k + extra.k})
+		}
+	}
+	slices.Reverse(values)
+	lmdStream, lmdBits := fseStream(values)
+	h.lmdBits = lmdBits
+	h.lmdPayload = uint32(len(lmdStream))
+	return h, literalStream, lmdStream
+}
+
+// blockV1 lays out a bvx1 block.
+func blockV1(raw int, h header, literalStream, lmdStream []byte) []byte {
+	le := binary.LittleEndian
+	b := make([]byte, headerV1Size)
+	copy(b, "bvx1")
+	le.PutUint32(b[4:], uint32(raw))
+	le.PutUint32(b[8:], h.literalPayload+h.lmdPayload)
+	le.PutUint32(b[12:], h.literals)
+	le.PutUint32(b[16:], h.matches)
+	le.PutUint32(b[20:], h.literalPayload)
+	le.PutUint32(b[24:], h.lmdPayload)
+	le.PutUint32(b[28:], uint32(h.literalBits))
+	for i, state := range h.literalState {
+		le.PutUint16(b[32+2*i:], state)
+	}
+	le.PutUint32(b[40:], uint32(h.lmdBits))
+	le.PutUint16(b[44:], h.lState)
+	le.PutUint16(b[46:], h.mState)
+	le.PutUint16(b[48:], h.dState)
+	for i, f := range h.freq {
+		le.PutUint16(b[50+2*i:], f)
+	}
+	return append(append(b, literalStream...), lmdStream...)
+}
+
+// freqCode returns the variable length code of a frequency.
+func freqCode(f uint16) (uint32, int) {
+	short := [8]struct {
+		code uint32
+		n    int
+	}{{0, 2}, {2, 2}, {1, 3}, {5, 3}, {3, 5}, {11, 5}, {19, 5}, {27, 5}}
+	switch {
+	case f < 8:
+		return short[f].code, short[f].n
+	case f < 24:
+		return 7 | uint32(f-8)<<4, 8
+	default:
+		return 15 | uint32(f-24)<<4, 14
+	}
+}
+
+// blockV2 lays out a bvx2 block.
+func blockV2(raw int, h header, literalStream, lmdStream []byte) []byte {
+	var freq []byte
+	var accum uint64
+	n := 0
+	for _, f := range h.freq {
+		code, size := freqCode(f)
+		accum |= uint64(code) << n
+		for n += size; n >= 8; n -= 8 {
+			freq = append(freq, byte(accum))
+			accum >>= 8
+		}
+	}
+	if n > 0 {
+		freq = append(freq, byte(accum))
+	}
+
+	v0 := uint64(h.literals) | uint64(h.literalPayload)<<20 | uint64(h.matches)<<40 | uint64(h.literalBits+7)<<60
+	v1 := uint64(h.lmdPayload)<<40 | uint64(h.lmdBits+7)<<60
+	for i, state := range h.literalState {
+		v1 |= uint64(state) << (10 * i)
+	}
+	v2 := uint64(headerV2Size+len(freq)) | uint64(h.lState)<<32 | uint64(h.mState)<<42 | uint64(h.dState)<<52
+
+	le := binary.LittleEndian
+	b := []byte("bvx2")
+	b = le.AppendUint32(b, uint32(raw))
+	b = le.AppendUint64(b, v0)
+	b = le.AppendUint64(b, v1)
+	b = le.AppendUint64(b, v2)
+	b = append(b, freq...)
+	return append(append(b, literalStream...), lmdStream...)
+}
+
+// expand applies literals and matches to prefix, the way the decoder should.
+func expand(prefix string, literals string, matches []match) string {
+	out := []byte(prefix)
+	distance := 0
+	for _, x := range matches {
+		out = append(out, literals[:x.l]...)
+		literals = literals[x.l:]
+		if x.d != 0 {
+			distance = x.d
+		}
+		for range x.m {
+			out = append(out, out[len(out)-distance])
+		}
+	}
+	return string(out)
+}
+
+func TestDecodeBlockStates(t *testing.T) {
+	// One literal and one value per stream, so that every state reads no
+	// bits: four literals then an empty match
+	h := header{literals: 4, matches: 1}
+	h.freq[4] = lStates     // L = 4
+	h.freq[mFreq] = mStates // M = 0
+	h.freq[dFreq] = dStates // D = 0
+	h.freq[literalFreq+'a'] = literalStates
+	zeros := make([]byte, 8)
+	h.literalPayload, h.lmdPayload = 8, 8
+	block := blockV1(4, h, zeros, zeros)
+
+	out, n, err := DecodeBlock([]byte("x"), append(block, "bvx$"...), 100)
+	if err != nil || string(out) != "xaaaa" || n != len(block) {
+		t.Errorf("got %q, %d, %v; want %q, %d", out, n, err, "xaaaa", len(block))
+	}
+}
+
+func TestDecodeBlock(t *testing.T) {
+	text := strings.Repeat("SEGB v2 entry payload ", 3)
+	literals := "abcdefghxyz" + text
+	first := []match{
+		{8, 24, 8},   // abcdefgh, four times
+		{3, 300, 11}, // xyz, then a long overlapping match
+		{0, 5, 0},    // Repeated distance
+		{len(text), 0, 0},
+	}
+	want := expand("", literals, first)
+	h, lits, lmd := encode([]byte(literals), first)
+
+	for name, block := range map[string][]byte{
+		"v1": blockV1(len(want), h, lits, lmd),
+		"v2": blockV2(len(want), h, lits, lmd),
+	} {
+		out, n, err := DecodeBlock(nil, append(block, "bvx$"...), 1<<20)
+		if err != nil || string(out) != want || n != len(block) {
+			t.Errorf("%s: got %q, %d, %v; want %q, %d", name, out, n, err, want, len(block))
+		}
+	}
+
+	// A second block matching into the first one
+	second := []match{{1, 20, len(want) + 1}, {2, 40, 400}, {0, 3, 0}}
+	want2 := expand(want, "!?!", second)
+	h, lits, lmd = encode([]byte("!?!"), second)
+	block := blockV2(len(want2)-len(want), h, lits, lmd)
+	out, _, err := DecodeBlock([]byte(want), block, 1<<20)
+	if err != nil || string(out) != want2 {
+		t.Errorf("second block: got %q, %v; want %q", out, err, want2)
+	}
+
+	if _, _, err := DecodeBlock([]byte(want), block, len(want2)-1); err != ErrTooLarge {
+		t.Errorf("limit: got %v, want ErrTooLarge", err)
+	}
+	// Without the first block, the first match reaches before the start
+	if _, _, err := DecodeBlock(nil, block, 1<<20); err != ErrCorrupt {
+		t.Errorf("distance past the start: got %v, want ErrCorrupt", err)
+	}
+}
+
+func TestDecodeBlockCorrupt(t *testing.T) {
+	literals := "abcdefgh"
+	matches := []match{{8, 24, 8}}
+	h, lits, lmd := encode([]byte(literals), matches)
+	valid := blockV2(32, h, lits, lmd)
+
+	state := h
+	state.lState = lStates
+	frequencies := h
+	frequencies.freq[literalFreq] += literalStates
+	unused := h
+	unused.literalBits = -8
+
+	for name, block := range map[string][]byte{
+		"truncated header":    valid[:20],
+		"truncated payload":   valid[:len(valid)-1],
+		"raw size too small":  blockV2(31, h, lits, lmd),
+		"raw size too large":  blockV2(33, h, lits, lmd),
+		"state out of range":  blockV1(32, state, lits, lmd),
+		"frequencies too big": blockV1(32, frequencies, lits, lmd),
+		"unused bits":         blockV1(32, unused, lits, lmd),
+		"missing literals":    blockV2(32, h, nil, lmd),
+		"unknown magic":       append([]byte("bvx3"), valid[4:]...),
+	} {
+		if _, _, err := DecodeBlock(nil, block, 1<<20); err != ErrCorrupt {
+			t.Errorf("%s: got %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+func TestDecodeFreq(t *testing.T) {
+	var h header
+	for i := range h.freq {
+		h.freq[i] = uint16(i % 1047)
+	}
+	h.freq[7] = 1047 // The largest frequency a code holds
+	block := blockV2(0, h, nil, nil)
+	size := int(binary.LittleEndian.Uint32(block[24:]))
+
+	var got header
+	if !decodeFreq(got.freq[:], block[headerV2Size:size]) {
+		t.Fatal("decodeFreq failed")
+	}
+	if got.freq != h.freq {
+		t.Errorf("got %v, want %v", got.freq, h.freq)
+	}
+	if decodeFreq(got.freq[:], block[headerV2Size:size-1]) {
+		t.Error("truncated tables decoded")
+	}
+	if decodeFreq(got.freq[:], append(block[headerV2Size:size:size], 0)) {
+		t.Error("tables with a trailing byte decoded")
+	}
+}
+
+func lzvnBlock(raw int, payload []byte) []byte {
+	b := []byte("bvxn")
+	b = binary.LittleEndian.AppendUint32(b, uint32(raw))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+var eos = []byte{0x06, 0, 0, 0, 0, 0, 0, 0}
+
+func TestDecodeLZVN(t *testing.T) {
+	s := "0123456789abcdefghij"
+	want := s + s
+	want += "X" + want[1:4]
+	want += "Y"
+	want += want[len(want)-40:][:4]
+	want += want[len(want)-40:][:16]
+	want += "abc"
+	want += strings.Repeat("c", 6)
+
+	var payload []byte
+	payload = append(payload, 0xe0, 4) // 20 literals
+	payload = append(payload, s...)
+	payload = append(payload, 0xa4, 0x51, 0x00)    // Medium distance: 20 bytes at 20
+	payload = append(payload, 0x47, 40, 0, 'X')    // Large distance: 1 literal, 3 bytes at 40
+	payload = append(payload, 0x16)                // No operation
+	payload = append(payload, 0x4e, 'Y')           // Previous distance: 1 literal, 4 bytes
+	payload = append(payload, 0xf0, 0x00)          // 16 bytes at the previous distance
+	payload = append(payload, 0xe3, 'a', 'b', 'c') // 3 literals
+	payload = append(payload, 0x18, 0x01)          // Small distance: 6 bytes at 1
+	payload = append(payload, eos...)
+	block := lzvnBlock(len(want), payload)
+
+	out, n, err := DecodeBlock([]byte{}, append(block, "bvx$"...), 1<<20)
+	if err != nil || string(out) != want || n != len(block) {
+		t.Errorf("got %q, %d, %v; want %q, %d", out, n, err, want, len(block))
+	}
+
+	// "abcabcabcabcxyz", the example of the LZ4 container
+	small := []byte{0xd8, 0x03, 'a', 'b', 'c', 0xf3, 0xe3, 'x', 'y', 'z'}
+	if out, _, err := DecodeBlock(nil, lzvnBlock(15, append(small, eos...)), 15); err != nil || string(out) != "abcabcabcabcxyz" {
+		t.Errorf("got %q, %v", out, err)
+	}
+
+	for name, block := range map[string][]byte{
+		"undefined opcode":    lzvnBlock(1, append([]byte{0x1e}, eos...)),
+		"undefined range":     lzvnBlock(1, append([]byte{0x70}, eos...)),
+		"no distance yet":     lzvnBlock(3, append([]byte{0x46, 'a'}, eos...)),
+		"distance past start": lzvnBlock(5, append([]byte{0x48, 0x02, 'a'}, eos...)),
+		"truncated literals":  lzvnBlock(3, []byte{0xe3, 'a'}),
+		"missing end":         lzvnBlock(3, []byte{0xe3, 'a', 'b', 'c'}),
+		"short end":           lzvnBlock(3, []byte{0xe3, 'a', 'b', 'c', 0x06, 0}),
+		"raw size too large":  lzvnBlock(4, append([]byte{0xe3, 'a', 'b', 'c'}, eos...)),
+		"raw size too small":  lzvnBlock(2, append([]byte{0xe3, 'a', 'b', 'c'}, eos...)),
+		"trailing payload":    lzvnBlock(3, append([]byte{0xe3, 'a', 'b', 'c'}, append(eos, 0)...)),
+	} {
+		if _, _, err := DecodeBlock(nil, block, 1<<20); err != ErrCorrupt {
+			t.Errorf("%s: got %v, want ErrCorrupt", name, err)
+		}
+	}
+
+	if _, _, err := DecodeBlock(nil, block, len(want)-1); err != ErrTooLarge {
+		t.Errorf("limit: got %v, want ErrTooLarge", err)
+	}
+}
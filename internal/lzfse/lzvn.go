@@ -0,0 +1,90 @@
+package lzfse
+
+import (
+	"encoding/binary"
+)
+
+// decodeLZVN decodes an LZVN stream up to its end of stream opcode,
+// appending at most end-len(dst) bytes to dst, and returns the number of
+// bytes of src it spans.
+//
+// Each opcode copies L literals following it, then an M byte match at
+// distance D, any of which may be zero; opcodes without a distance repeat
+// the previous one.
+func decodeLZVN(dst, src []byte, end int) ([]byte, int, error) {
+	distance := 0
+	for i := 0; ; {
+		if i >= len(src) {
+			return dst, i, ErrCorrupt
+		}
+		op := src[i]
+		rest := src[i+1:]
+		n, L, M, D := 1, 0, 0, distance
+		switch {
+		case op == 0x06:
+			// End of stream, padded to 8 bytes
+			if len(src)-i < 8 {
+				return dst, i, ErrCorrupt
+			}
+			return dst, i + 8, nil
+		case op == 0x0e || op == 0x16:
+			// No operation
+		case op < 0x40 && op&7 == 6, op >= 0x70 && op < 0x80:
+			return dst, i, ErrCorrupt
+		case op == 0xf0:
+			// Large match: 11110000 MMMMMMMM
+			if len(rest) < 1 {
+				return dst, i, ErrCorrupt
+			}
+			n, M = 2, int(rest[0])+16
+		case op > 0xf0:
+			// Small match: 1111MMMM
+			M = int(op & 0x0f)
+		case op == 0xe0:
+			// Large literal: 11100000 LLLLLLLL
+			if len(rest) < 1 {
+				return dst, i, ErrCorrupt
+			}
+			n, L = 2, int(rest[0])+16
+		case op > 0xe0:
+			// Small literal: 1110LLLL
+			L = int(op & 0x0f)
+		case op >= 0xa0 && op < 0xc0:
+			// Medium distance: 101LLMMM DDDDDDMM DDDDDDDD
+			if len(rest) < 2 {
+				return dst, i, ErrCorrupt
+			}
+			v := int(binary.LittleEndian.Uint16(rest))
+			n, L, M, D = 3, int(op>>3&3), (int(op&7)<<2|v&3)+3, v>>2
+		case op&7 == 7:
+			// Large distance: LLMMM111 DDDDDDDD DDDDDDDD
+			if len(rest) < 2 {
+				return dst, i, ErrCorrupt
+			}
+			n, L, M, D = 3, int(op>>6), int(op>>3&7)+3, int(binary.LittleEndian.Uint16(rest))
+		case op&7 == 6:
+			// Previous distance: LLMMM110
+			L, M = int(op>>6), int(op>>3&7)+3
+		default:
+			// Small distance: LLMMMDDD DDDDDDDD
+			if len(rest) < 1 {
+				return dst, i, ErrCorrupt
+			}
+			n, L, M, D = 2, int(op>>6), int(op>>3&7)+3, int(op&7)<<8|int(rest[0])
+		}
+		i += n
+
+		if L > len(src)-i || L+M > end-len(dst) {
+			return dst, i, ErrCorrupt
+		}
+		dst = append(dst, src[i:i+L]...)
+		i += L
+		if M > 0 {
+			if D == 0 || D > len(dst) {
+				return dst, i, ErrCorrupt
+			}
+			dst = copyMatch(dst, D, M)
+			distance = D
+		}
+	}
+}
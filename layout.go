@@ -0,0 +1,259 @@
+package segb
+
+import (
+	"errors"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"io"
+)
+
+// Sizes of the per-entry headers preceding each payload
+const (
+	v1EntryHeaderSize = 32 // Length, state, two timestamps, CRC, unknown
+	v2EntryHeaderSize = 8  // CRC, unknown
+)
+
+// EntryLocation is the position of a file offset relative to an entry.
+type EntryLocation struct {
+	Entry         int   // Index of the entry in Segb.Entries
+	PayloadOffset int64 // Offset relative to the start of the payload, negative inside the entry header
+}
+
+// EntryAt maps an absolute offset in the decoded file to the entry that
+// contains it, e.g. to pivot from a keyword hit in a disk image. Offsets in
+// an entry's trailing padding belong to that entry. It returns false for
+// offsets in the file header, the v2 trailer, or past the end of the
+// entries.
+//
+// Offsets are interpreted like Entry.Offset.
+func (s *Segb) EntryAt(fileOffset int64) (EntryLocation, bool) {
+	for i, entry := range s.Entries {
+		if entry.Offset == 0 {
+			continue
+		}
+		if fileOffset >= entry.headerOffset && fileOffset < entry.Offset+entry.StoredSize {
+			return EntryLocation{Entry: i, PayloadOffset: fileOffset - entry.Offset}, true
+		}
+	}
+	return EntryLocation{}, false
+}
+
+// shiftOffsets moves the layout of every entry by delta bytes, for
+// structures decoded from a window of a larger input.
+func (s *Segb) shiftOffsets(delta int64) {
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		if entry.Offset == 0 {
+			continue
+		}
+		entry.Offset += delta
+		entry.headerOffset += delta
+	}
+	for i := range s.Errors {
+		s.Errors[i].Offset += delta
+	}
+	s.originalBase += delta
+}
+
+// Slack returns the number of bytes the entry spends on alignment padding
+// or other data past its logical size.
+func (e *Entry) Slack() int64 {
+	return e.StoredSize - e.Size
+}
+
+// Slack returns the total alignment padding of the entries.
+func (s *Segb) Slack() int64 {
+	var slack int64
+	for _, entry := range s.Entries {
+		slack += entry.Slack()
+	}
+	return slack
+}
+
+// alignUp rounds n up to a multiple of alignment.
+func alignUp(n, alignment int64) int64 {
+	return (n + alignment - 1) / alignment * alignment
+}
+
+// WithAlignment overrides the boundary entries are padded to, 8 bytes for
+// SEGB v1 and 4 bytes for v2, for samples written with a different one. An
+// alignment of 1 means entries are not padded. Without it, Decode detects the
+// alignment of v1 files, see Segb.Alignment.
+func WithAlignment(alignment int) DecodeOption {
+	return func(c *decodeConfig) {
+		c.alignment = alignment
+	}
+}
+
+// v1AlignmentCandidates are the alignments probed for v1 files, the default
+// first. 1 stands for unpadded entries.
+var v1AlignmentCandidates = []int{v1.DefaultAlignment, 4, 16, 1}
+
+// alignmentProbeEntries is how many leading entries must pass their CRC for
+// an alignment to be accepted.
+const alignmentProbeEntries = 4
+
+// decodeV1 reads a v1 file padded to alignment or, when alignment is 0,
+// detects it: v1 entries are found by walking from one to the next, so a
+// wrong alignment fails the parse or shifts every entry after the first and
+// breaks its CRC. Each candidate is tried in turn and the first whose leading
+// entries all verify wins; failing that, the one where the most verify.
+func decodeV1(stream io.ReadSeeker, cfg decodeConfig) (Segb, error) {
+	if cfg.alignment > 0 {
+		return readV1(stream, cfg.alignment, cfg)
+	}
+
+	var best Segb
+	bestScore := -1
+	var firstErr error
+	for _, candidate := range v1AlignmentCandidates {
+		if _, err := stream.Seek(0, io.SeekStart); err != nil {
+			return Segb{}, err
+		}
+		decoded, err := readV1(stream, candidate, cfg)
+		if err == ErrBudgetExceeded || errors.Is(err, ErrEntryTooLarge) {
+			return Segb{}, err
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		score, probed := leadingCRCScore(decoded)
+		if score == probed {
+			return decoded, nil
+		}
+		if score > bestScore {
+			best, bestScore = decoded, score
+		}
+	}
+	if bestScore < 0 {
+		return Segb{}, firstErr
+	}
+	return best, nil
+}
+
+func readV1(stream io.ReadSeeker, alignment int, cfg decodeConfig) (Segb, error) {
+	opts := v1.Options{Alignment: alignment}
+	if cfg.visit != nil {
+		opts.Visit = func(e *v1.Entry) error {
+			return cfg.visitEntry(v1ToStandardSegb([]*v1.Entry{e}, int64(alignment)))
+		}
+	}
+	gate := cfg.newEntryGate()
+	if gate != nil {
+		opts.Filter = func(e *v1.Entry) bool {
+			return gate.admit(EntryMeta{
+				ID:      int(e.ID),
+				State:   V1EntryStateToStandardState(e.State),
+				Created: CocoaTimestampToTime(e.Timestamp1),
+				Offset:  e.Offset + v1EntryHeaderSize,
+				Size:    int64(e.Length),
+			})
+		}
+	}
+	var entries []*v1.Entry
+	var entryErrors []v1.EntryError
+	var err error
+	if cfg.lenient {
+		_, entries, entryErrors, err = v1.ReadSegbLenient(stream, opts)
+	} else {
+		_, entries, err = v1.ReadSegbWithOptions(stream, opts)
+	}
+	if err == nil && gate != nil {
+		err = gate.err
+	}
+	if err != nil {
+		return Segb{}, parseError(stream, err)
+	}
+
+	decoded := v1ToStandardSegb(entries, int64(alignment))
+	for _, e := range entryErrors {
+		decoded.Errors = append(decoded.Errors, EntryError{Index: e.Index, Offset: e.Offset, Err: e.Err})
+	}
+	if gate != nil {
+		decoded.Errors = append(decoded.Errors, gate.skipped...)
+	}
+	return decoded, nil
+}
+
+// decodeV2 reads a v2 file. Its entries are located through the trailer,
+// so there is no alignment to detect.
+func decodeV2(stream io.ReadSeeker, cfg decodeConfig) (Segb, error) {
+	opts := v2.Options{Alignment: cfg.alignment}
+	if cfg.visit != nil {
+		opts.Visit = func(e *v2.Entry) error {
+			// The header only sets fields of the file, not of entries
+			return cfg.visitEntry(V2ToStandardSegb(&v2.Header{}, []*v2.Entry{e}))
+		}
+	}
+	gate := cfg.newEntryGate()
+	if gate != nil {
+		opts.Filter = func(e *v2.Entry) bool {
+			return gate.admit(EntryMeta{
+				ID:      int(e.ID),
+				State:   V2EntryStateToStandardState(e.State),
+				Created: CocoaTimestampToTime(e.CreationTimestamp),
+				Offset:  e.Offset + v2EntryHeaderSize,
+				Size:    e.Span - v2EntryHeaderSize,
+			})
+		}
+	}
+	var header *v2.Header
+	var entries []*v2.Entry
+	var entryErrors []v2.EntryError
+	var err error
+	if cfg.lenient {
+		header, _, entries, entryErrors, err = v2.ReadSegbLenient(stream, opts)
+	} else {
+		header, _, entries, err = v2.ReadSegbWithOptions(stream, opts)
+	}
+	if err == nil && gate != nil {
+		err = gate.err
+	}
+	if err != nil {
+		return Segb{}, parseError(stream, err)
+	}
+
+	decoded := V2ToStandardSegb(header, entries)
+	if decoded.Records, err = trailerRecords(stream, header); err != nil {
+		return Segb{}, err
+	}
+	if cfg.alignment > 0 {
+		decoded.Alignment = cfg.alignment
+	}
+	for _, e := range entryErrors {
+		decoded.Errors = append(decoded.Errors, EntryError{Index: e.Index, Offset: e.Offset, Err: e.Err})
+	}
+	if gate != nil {
+		decoded.Errors = append(decoded.Errors, gate.skipped...)
+	}
+	return decoded, nil
+}
+
+// trailerRecords reads the trailer records of a v2 file again, in trailer
+// order, as v2.ReadSegb returns them sorted by offset.
+func trailerRecords(stream io.ReadSeeker, header *v2.Header) ([]v2.Record, error) {
+	records, _, err := v2.ReadTrailer(stream, header)
+	if err != nil {
+		return nil, err
+	}
+	copies := make([]v2.Record, len(records))
+	for i, record := range records {
+		copies[i] = *record
+	}
+	return copies, nil
+}
+
+// leadingCRCScore returns how many of the first alignmentProbeEntries
+// entries pass their CRC, and how many were checked.
+func leadingCRCScore(s Segb) (score, probed int) {
+	for i := 0; i < len(s.Entries) && i < alignmentProbeEntries; i++ {
+		if s.Entries[i].CheckCRC() {
+			score++
+		}
+		probed++
+	}
+	return score, probed
+}
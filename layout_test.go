@@ -0,0 +1,187 @@
+package segb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"os"
+	"testing"
+)
+
+// v1File builds an in-memory SEGB v1 file holding the given payloads, each
+// entry padded to alignment bytes.
+func v1File(alignment int, payloads ...[]byte) []byte {
+	file := make([]byte, 56)
+	copy(file[0x34:], "SEGB")
+	for _, payload := range payloads {
+		file = binary.LittleEndian.AppendUint32(file, uint32(len(payload)))
+		file = binary.LittleEndian.AppendUint32(file, 1)
+		file = binary.LittleEndian.AppendUint64(file, math.Float64bits(0))
+		file = binary.LittleEndian.AppendUint64(file, math.Float64bits(0))
+		file = binary.LittleEndian.AppendUint32(file, crc32.ChecksumIEEE(payload))
+		file = append(file, make([]byte, 4)...)
+		file = append(file, payload...)
+		for len(file)%alignment != 0 {
+			file = append(file, 0)
+		}
+	}
+	binary.LittleEndian.PutUint32(file, uint32(len(file)))
+	return file
+}
+
+func TestEntryAt(t *testing.T) {
+	// Header (32) | entry 0 at 32: CRC, unknown, 25 bytes, 3 bytes padding | entry 1 at 68
+	file := v2File([]byte("Here's to the crazy ones."), []byte("The misfits."))
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, entry := range decoded.Entries {
+		if got := file[entry.Offset : entry.Offset+int64(len(entry.Data))]; !bytes.Equal(got, entry.Data) {
+			t.Errorf("entry %d: bytes at Offset %d = %q; want %q", i, entry.Offset, got, entry.Data)
+		}
+	}
+
+	for _, tc := range []struct {
+		offset int64
+		entry  int
+		rel    int64
+		ok     bool
+	}{
+		{10, 0, 0, false}, // File header
+		{32, 0, -8, true}, // Entry header
+		{45, 0, 5, true},
+		{66, 0, 26, true}, // Padding
+		{76, 1, 0, true},
+		{int64(len(file)) - 1, 0, 0, false}, // Trailer
+	} {
+		loc, ok := decoded.EntryAt(tc.offset)
+		if ok != tc.ok || (ok && (loc.Entry != tc.entry || loc.PayloadOffset != tc.rel)) {
+			t.Errorf("EntryAt(%d) = %+v, %v; want {%d %d}, %v", tc.offset, loc, ok, tc.entry, tc.rel, tc.ok)
+		}
+	}
+
+	// Carved structures map offsets of the scanned input
+	blob := append(make([]byte, 100), file...)
+	carved, err := Carve(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil || len(carved) != 1 {
+		t.Fatalf("Carve() = %v, %v", carved, err)
+	}
+	if loc, ok := carved[0].Segb.EntryAt(145); !ok || loc.Entry != 0 || loc.PayloadOffset != 5 {
+		t.Errorf("carved EntryAt(145) = %+v, %v", loc, ok)
+	}
+}
+
+func TestEntryAtV1(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	data, err := os.ReadFile("segb_version1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, entry := range decoded.Entries {
+		if got := data[entry.Offset : entry.Offset+int64(len(entry.Data))]; !bytes.Equal(got, entry.Data) {
+			t.Errorf("entry %d: bytes at Offset %d = %q; want %q", i, entry.Offset, got, entry.Data)
+		}
+	}
+
+	// The first entry header follows the 56 byte file header
+	if loc, ok := decoded.EntryAt(56 + 32 + 7); !ok || loc.Entry != 0 || loc.PayloadOffset != 7 {
+		t.Errorf("EntryAt() = %+v, %v; want entry 0 at 7", loc, ok)
+	}
+	if _, ok := decoded.EntryAt(20); ok {
+		t.Error("EntryAt() inside the file header succeeded")
+	}
+}
+
+func TestEntrySizes(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	for _, tc := range []struct {
+		name   string
+		size   []int64
+		stored []int64
+	}{
+		// v1 pads to 8 bytes, v2 to 4 bytes
+		{"segb_version1.bin", []int64{25, 12, 11}, []int64{32, 16, 16}},
+		{"segb_version2.bin", []int64{25, 12, 11}, []int64{28, 12, 12}},
+	} {
+		data, err := os.ReadFile(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var slack int64
+		for i, entry := range decoded.Entries {
+			if entry.Size != tc.size[i] || entry.StoredSize != tc.stored[i] {
+				t.Errorf("%s entry %d: Size, StoredSize = %d, %d; want %d, %d", tc.name, i, entry.Size, entry.StoredSize, tc.size[i], tc.stored[i])
+			}
+			slack += tc.stored[i] - tc.size[i]
+		}
+		if got := decoded.Slack(); got != slack {
+			t.Errorf("%s: Slack() = %d; want %d", tc.name, got, slack)
+		}
+	}
+}
+
+func TestWithAlignment(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels.")}
+
+	for _, alignment := range []int{1, 8, 16} {
+		decoded, err := DecodeBytes(v1File(alignment, payloads...), WithAlignment(alignment))
+		if err != nil {
+			t.Fatalf("alignment %d: %v", alignment, err)
+		}
+		CheckForEntries(t, decoded.Entries)
+		for i, entry := range decoded.Entries {
+			if !entry.CheckCRC() {
+				t.Errorf("alignment %d: entry %d failed CheckCRC()", alignment, i)
+			}
+		}
+		last := decoded.Entries[len(decoded.Entries)-1]
+		if want := alignUp(last.Offset+last.Size, int64(alignment)) - last.Offset; last.StoredSize != want {
+			t.Errorf("alignment %d: last StoredSize = %d; want %d", alignment, last.StoredSize, want)
+		}
+	}
+
+	// v2 entries are located through the trailer
+	if _, err := DecodeBytes(v2File(payloads...), WithAlignment(8)); err != nil {
+		t.Errorf("v2 with WithAlignment(8): %v", err)
+	}
+}
+
+func TestDetectAlignment(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels.")}
+
+	for _, alignment := range []int{1, 4, 8, 16} {
+		decoded, err := DecodeBytes(v1File(alignment, payloads...))
+		if err != nil {
+			t.Fatalf("alignment %d: %v", alignment, err)
+		}
+		if decoded.Alignment != alignment {
+			t.Errorf("alignment %d: detected %d", alignment, decoded.Alignment)
+		}
+		CheckForEntries(t, decoded.Entries)
+	}
+
+	// An explicit alignment is used as is
+	decoded, err := DecodeBytes(v1File(16, payloads...), WithAlignment(8))
+	if err == nil && decoded.Alignment == 8 && len(decoded.Entries) == len(payloads) && string(decoded.Entries[1].Data) == string(payloads[1]) {
+		t.Error("WithAlignment(8) read a 16 byte aligned file")
+	}
+}
@@ -0,0 +1,31 @@
+package segb
+
+import (
+	"fmt"
+)
+
+// EntryError is an entry that could not be read and was skipped in lenient
+// mode.
+type EntryError struct {
+	Index  int   // Position of the entry in the file (v1) or trailer (v2)
+	Offset int64 // Offset of the entry in the file
+	Err    error
+}
+
+func (e EntryError) Error() string {
+	return fmt.Sprintf("entry %d at offset 0x%x: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e EntryError) Unwrap() error {
+	return e.Err
+}
+
+// WithLenientParsing keeps decoding past malformed entries instead of failing
+// the whole file. A v1 file resumes at the next plausible entry header and a
+// v2 file at the next trailer record; only an unreadable file header or v2
+// trailer is still fatal. Skipped entries are listed in Segb.Errors.
+func WithLenientParsing() DecodeOption {
+	return func(c *decodeConfig) {
+		c.lenient = true
+	}
+}
@@ -0,0 +1,102 @@
+package segb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"io"
+	"testing"
+)
+
+func TestLenientV1(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels.")}
+	file := v1File(8, payloads...)
+
+	// Entry 1 starts at 56+32+25 rounded up to 8; give it a length past the end
+	binary.LittleEndian.PutUint32(file[120:], 0x7fffffff)
+
+	if _, err := DecodeBytes(file); err == nil {
+		t.Fatal("DecodeBytes() of a malformed entry succeeded")
+	}
+
+	_, entries, entryErrors, err := v1.ReadSegbLenient(bytes.NewReader(file), v1.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entryErrors) != 1 || entryErrors[0].Index != 1 || entryErrors[0].Offset != 120 {
+		t.Errorf("entry errors = %+v; want entry 1 at 120", entryErrors)
+	}
+	if len(entries) != 2 || string(entries[1].Data) != string(payloads[2]) {
+		t.Errorf("ReadSegbLenient() read %d entries", len(entries))
+	}
+
+	decoded, err := DecodeBytes(file, WithLenientParsing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 2 || string(decoded.Entries[0].Data) != string(payloads[0]) || string(decoded.Entries[1].Data) != string(payloads[2]) {
+		t.Errorf("lenient entries = %+v", decoded.Entries)
+	}
+}
+
+func TestLenientV2(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels.")}
+	file := v2File(payloads...)
+
+	// Point the last record past the trailer, which breaks entries 1 and 2
+	binary.LittleEndian.PutUint32(file[len(file)-v2.TrailerRecordSize:], 4096)
+
+	if _, err := DecodeBytes(file); err == nil {
+		t.Fatal("DecodeBytes() of a malformed entry succeeded")
+	}
+
+	_, _, entries, entryErrors, err := v2.ReadSegbLenient(bytes.NewReader(file), v2.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || len(entryErrors) != 2 || entryErrors[0].Index != 1 || entryErrors[1].Index != 2 {
+		t.Errorf("ReadSegbLenient() = %d entries, errors %+v", len(entries), entryErrors)
+	}
+
+	decoded, err := DecodeBytes(file, WithLenientParsing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 1 || string(decoded.Entries[0].Data) != string(payloads[0]) {
+		t.Errorf("lenient entries = %+v", decoded.Entries)
+	}
+}
+
+func TestEntryErrors(t *testing.T) {
+	file := v2File([]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels."))
+	binary.LittleEndian.PutUint32(file[len(file)-v2.TrailerRecordSize:], 4096)
+
+	decoded, err := DecodeBytes(file, WithLenientParsing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Errors) != 2 {
+		t.Fatalf("Errors = %v; want 2", decoded.Errors)
+	}
+	// Entry 1 now runs up to the bogus offset of entry 2, past the trailer
+	if e := decoded.Errors[0]; e.Index != 1 || e.Offset != 32+36 || !errors.Is(e, io.ErrUnexpectedEOF) {
+		t.Errorf("Errors[0] = %v", e)
+	}
+	if e := decoded.Errors[1]; e.Index != 2 || e.Offset != 32+4096 {
+		t.Errorf("Errors[1] = %v", e)
+	}
+
+	// Carved structures report offsets of the scanned input
+	decoded.shiftOffsets(100)
+	if decoded.Errors[0].Offset != 100+32+36 {
+		t.Errorf("shifted Errors[0].Offset = %d", decoded.Errors[0].Offset)
+	}
+
+	// Strict decoding never records entry errors
+	decoded, err = DecodeBytes(v2File([]byte("The rebels.")))
+	if err != nil || decoded.Errors != nil {
+		t.Errorf("DecodeBytes() = %v, %v", decoded.Errors, err)
+	}
+}
@@ -0,0 +1,120 @@
+// Package manifest records what was parsed from a set of SEGB files: the
+// hash and size of each file and the hash, size and timestamps of each of
+// its entries, along with the version of the tool that parsed them. Kept
+// alongside the results of an examination, a manifest is a verifiable record
+// of the evidence they were drawn from. Manifests and exports can be signed
+// with Ed25519 keys, so that their integrity can be proven later.
+package manifest
+
+import (
+	"github.com/bluefalconhd/segb"
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+// Tool is the name manifests give for the tool that wrote them.
+const Tool = "segb"
+
+// Manifest lists the files parsed in one run.
+type Manifest struct {
+	Tool        string    `json:"tool"`
+	ToolVersion string    `json:"tool_version"` // See ToolVersion
+	Created     time.Time `json:"created"`      // When the manifest was made
+	Files       []File    `json:"files"`
+}
+
+// File records one parsed file. Its hash covers the file as read, before
+// any decompression.
+type File struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256"`
+	Version string    `json:"version,omitempty"`
+	Created time.Time `json:"created"`
+	Entries []Entry   `json:"entries"`
+	Error   string    `json:"error,omitempty"` // Why the file could not be decoded, if it could not
+}
+
+// Entry records one entry of a parsed file. Its hash covers the payload as
+// decoded, as in export.Record.
+type Entry struct {
+	Index    int       `json:"index"` // Index of the entry in Segb.Entries
+	ID       int       `json:"id"`
+	StableID string    `json:"stable_id"`
+	State    string    `json:"state"`
+	Created  time.Time `json:"created"`
+	Offset   int64     `json:"offset"`
+	Size     int       `json:"size"`
+	SHA256   string    `json:"sha256"`
+	CRCValid bool      `json:"crc_valid"`
+}
+
+// New returns an empty manifest, dated now, for the running tool.
+func New() *Manifest {
+	return &Manifest{Tool: Tool, ToolVersion: ToolVersion(), Created: time.Now().UTC(), Files: []File{}}
+}
+
+// ToolVersion returns the module version of the running binary, e.g.
+// "v1.2.0", so a manifest tells which code parsed the files. Binaries built
+// from a checkout without a version get the VCS revision instead, e.g.
+// "(devel) 1f2e3d4c…", marked "+dirty" for uncommitted changes.
+func ToolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	if version != "" && version != "(devel)" {
+		return version
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision":
+			version += " " + setting.Value
+		case setting.Key == "vcs.modified" && setting.Value == "true":
+			version += "+dirty"
+		}
+	}
+	return version
+}
+
+// AddFile reads the file at path once, hashes it and records the entries
+// decoded from the bytes hashed. A file that cannot be decoded is recorded
+// with the error, as its hash and size still tell what was examined; the
+// error is only returned if the file cannot be read.
+func (m *Manifest) AddFile(path string, opts ...segb.DecodeOption) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m.Files = append(m.Files, NewFile(path, data, opts...))
+	return nil
+}
+
+// NewFile records the file data was read from.
+func NewFile(path string, data []byte, opts ...segb.DecodeOption) File {
+	file := File{Path: path, Size: int64(len(data)), SHA256: segb.HashPayload(data).String(), Entries: []Entry{}}
+	decoded, err := segb.DecodeBytes(data, opts...)
+	if err != nil {
+		file.Error = err.Error()
+		return file
+	}
+
+	file.Version = decoded.Version.String()
+	file.Created = decoded.Created.UTC()
+	for i, entry := range decoded.Entries {
+		file.Entries = append(file.Entries, Entry{
+			Index:    i,
+			ID:       entry.ID,
+			StableID: entry.StableID(),
+			State:    entry.State.String(),
+			Created:  entry.Created.UTC(),
+			Offset:   entry.Offset,
+			Size:     len(entry.Data),
+			SHA256:   segb.HashPayload(entry.Data).String(),
+			CRCValid: entry.CRCValid,
+		})
+	}
+	return file
+}
@@ -0,0 +1,112 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/bluefalconhd/segb/segbtest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := segbtest.WriteFixtures(dir); err != nil {
+		t.Fatal(err)
+	}
+	garbage := filepath.Join(dir, "garbage.bin")
+	if err := os.WriteFile(garbage, []byte("not a SEGB file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New()
+	for _, name := range []string{"segb_version1.bin", "segb_version2.bin", "garbage.bin"} {
+		if err := m.AddFile(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.AddFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("AddFile(missing) error = nil; want an error")
+	}
+	if m.Tool != "segb" || m.ToolVersion == "" || time.Since(m.Created) > time.Minute || len(m.Files) != 3 {
+		t.Fatalf("manifest = %+v", m)
+	}
+
+	for _, file := range m.Files[:2] {
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(data)
+		if file.SHA256 != hex.EncodeToString(sum[:]) || file.Size != int64(len(data)) || file.Error != "" {
+			t.Errorf("%s: file = %+v", file.Path, file)
+		}
+		if len(file.Entries) != 3 {
+			t.Fatalf("%s: %d entries; want 3", file.Path, len(file.Entries))
+		}
+		entry := file.Entries[1]
+		sum = sha256.Sum256([]byte("The misfits."))
+		if entry.SHA256 != hex.EncodeToString(sum[:]) || entry.Size != 12 || entry.State != "written" || !entry.CRCValid ||
+			!entry.Created.Equal(time.Date(2007, 6, 29, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: entry 1 = %+v", file.Path, entry)
+		}
+	}
+
+	if file := m.Files[2]; file.Error == "" || file.Size != 15 || len(file.Entries) != 0 {
+		t.Errorf("garbage.bin: file = %+v; want an error", file)
+	}
+}
+
+func TestSign(t *testing.T) {
+	dir := t.TempDir()
+	if err := segbtest.WriteFixtures(dir); err != nil {
+		t.Fatal(err)
+	}
+	private, public, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath, publicPath := filepath.Join(dir, "key.pem"), filepath.Join(dir, "key.pub")
+	os.WriteFile(privatePath, private, 0o600)
+	os.WriteFile(publicPath, public, 0o644)
+	privateKey, err := LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPublicKey(privatePath); err == nil {
+		t.Error("LoadPublicKey(private key) error = nil; want an error")
+	}
+
+	data := []byte(`{"tool":"segb"}`)
+	signature := Sign(data, privateKey)
+	if err := Verify(data, signature, publicKey); err != nil {
+		t.Errorf("Verify() = %v; want nil", err)
+	}
+	if err := Verify([]byte(`{"tool":"segc"}`), signature, publicKey); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Verify(tampered) = %v; want ErrBadSignature", err)
+	}
+
+	m := New()
+	path := filepath.Join(dir, "segb_version2.bin")
+	if err := m.AddFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if errs := m.Check(); len(errs) != 0 {
+		t.Errorf("Check() = %v; want none", errs)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0})
+	f.Close()
+	if errs := m.Check(); len(errs) != 1 || !errors.Is(errs[0], ErrChanged) {
+		t.Errorf("Check(changed file) = %v; want ErrChanged", errs)
+	}
+}
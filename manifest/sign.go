@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"os"
+)
+
+// SignatureSuffix is appended to the path of a signed file, a manifest or
+// an export, to name its detached signature, e.g. "manifest.json.sig".
+const SignatureSuffix = ".sig"
+
+var (
+	ErrBadSignature = errors.New("signature does not match")
+	ErrChanged      = errors.New("file changed since the manifest was made")
+)
+
+// GenerateKey returns a new Ed25519 key pair, PEM encoded: the private key
+// as PKCS #8 and the public key as PKIX, the forms openssl writes with
+// "genpkey -algorithm ed25519" and "pkey -pubout".
+func GenerateKey() (private, public []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), nil
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 Ed25519 private key.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return private, nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX Ed25519 public key.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return public, nil
+}
+
+// readPEM returns the contents of the first PEM block of the given type in
+// a file.
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM %s", path, blockType)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// Sign returns the detached signature of data, as stored in a signature
+// file: the base64 Ed25519 signature and a newline.
+func Sign(data []byte, key ed25519.PrivateKey) []byte {
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	return []byte(signature + "\n")
+}
+
+// Verify checks a detached signature of data made by Sign.
+func Verify(data, signature []byte, key ed25519.PublicKey) error {
+	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil || !ed25519.Verify(key, data, raw) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Check reads the files of the manifest again and returns an error for each
+// one that cannot be read or no longer has the size and hash recorded,
+// matching ErrChanged in the latter case.
+func (m *Manifest) Check() []error {
+	var errs []error
+	for _, file := range m.Files {
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if int64(len(data)) != file.Size || segb.HashPayload(data).String() != file.SHA256 {
+			errs = append(errs, fmt.Errorf("%s: %w", file.Path, ErrChanged))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,189 @@
+package segb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxMediaDepth bounds how deep FindMedia follows nested protobuf messages.
+const maxMediaDepth = 8
+
+// Media is an image or video found inside a payload by FindMedia.
+type Media struct {
+	Offset int64 // Position of the media in the payload
+	Type   ContentType
+	Data   []byte
+}
+
+// FindMedia returns the images and videos embedded in a payload: the
+// payload itself if it is one, those held in the fields of protobuf
+// messages, nested ones included, and those found by their magic number
+// elsewhere. JPEG, PNG and ISO base media (HEIC, MP4, QuickTime) files found
+// by their magic number are measured by walking their structure; GIFs are
+// only found as a whole payload or protobuf field.
+func FindMedia(data []byte) []Media {
+	return findMedia(data, 0, 0)
+}
+
+// Media returns the images and videos embedded in the entry payload, see
+// FindMedia.
+func (e *Entry) Media() []Media {
+	return FindMedia(e.Data)
+}
+
+func findMedia(data []byte, base int64, depth int) []Media {
+	if typ := Classify(data); typ.IsMedia() {
+		return []Media{{Offset: base, Type: typ, Data: data}}
+	}
+	if depth < maxMediaDepth && isProtobuf(data) {
+		return findProtobufMedia(data, base, depth)
+	}
+
+	var found []Media
+	for i := 0; i < len(data); {
+		typ, length := mediaAt(data[i:])
+		if length == 0 {
+			i++
+			continue
+		}
+		found = append(found, Media{Offset: base + int64(i), Type: typ, Data: data[i : i+length]})
+		i += length
+	}
+	return found
+}
+
+// findProtobufMedia looks for media in the length-delimited fields of a
+// protobuf message.
+func findProtobufMedia(data []byte, base int64, depth int) []Media {
+	var found []Media
+	start := len(data)
+	for len(data) > 0 {
+		_, typ, n := protowire.ConsumeTag(data)
+		data = data[n:]
+		if typ != protowire.BytesType {
+			data = data[protowire.ConsumeFieldValue(0, typ, data):]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(data)
+		offset := base + int64(start-len(data)+n-len(value))
+		found = append(found, findMedia(value, offset, depth+1)...)
+		data = data[n:]
+	}
+	return found
+}
+
+// mediaAt returns the type and length of the JPEG, PNG or ISO base media
+// file at the start of data, or a zero length if there is none.
+func mediaAt(data []byte) (ContentType, int) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return ContentJPEG, jpegLength(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return ContentPNG, pngLength(data)
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		if typ, ok := isoBrands[string(data[8:12])]; ok {
+			return typ, isoLength(data)
+		}
+	}
+	return ContentUnknown, 0
+}
+
+// jpegLength walks the segments of the JPEG at the start of data to its end
+// of image marker. It returns 0 if the JPEG is malformed or truncated.
+func jpegLength(data []byte) int {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 0
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte
+			i++
+			continue
+		case marker == 0xD9:
+			return i + 2
+		case marker >= 0xD0 && marker <= 0xD7, marker == 0x01:
+			i += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 {
+			return 0
+		}
+		i += 2 + length
+		if marker != 0xDA {
+			continue
+		}
+
+		// Entropy-coded data follows the start of scan, up to the next
+		// marker other than a stuffed 0xFF or a restart marker
+		for i+1 < len(data) {
+			if data[i] == 0xFF && data[i+1] != 0x00 && (data[i+1] < 0xD0 || data[i+1] > 0xD7) {
+				break
+			}
+			i++
+		}
+	}
+	return 0
+}
+
+// pngLength walks the chunks of the PNG at the start of data to its IEND
+// chunk. It returns 0 if the PNG is malformed or truncated.
+func pngLength(data []byte) int {
+	i := 8
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		if length < 0 || length > len(data)-i-12 {
+			return 0
+		}
+		chunk := string(data[i+4 : i+8])
+		i += 12 + length
+		if chunk == "IEND" {
+			return i
+		}
+	}
+	return 0
+}
+
+// isoLength walks the top-level boxes of the ISO base media file at the
+// start of data until the data ends or stops looking like boxes. It returns
+// 0 if only the ftyp box is there.
+func isoLength(data []byte) int {
+	i := 0
+	boxes := 0
+	for i+8 <= len(data) {
+		size := int64(binary.BigEndian.Uint32(data[i:]))
+		header := int64(8)
+		if size == 1 && i+16 <= len(data) {
+			size = int64(binary.BigEndian.Uint64(data[i+8:]))
+			header = 16
+		}
+		if size == 0 {
+			// The last box extends to the end of the file
+			size = int64(len(data) - i)
+		}
+		if size < header || size > int64(len(data)-i) || !isBoxType(data[i+4:i+8]) {
+			break
+		}
+		i += int(size)
+		boxes++
+	}
+	if boxes < 2 {
+		return 0
+	}
+	return i
+}
+
+// isBoxType reports whether b looks like a box type: four printable ASCII
+// characters.
+func isBoxType(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7E {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package segb
+
+import (
+	"bytes"
+	"google.golang.org/protobuf/encoding/protowire"
+	"testing"
+)
+
+// Minimal media files, structurally valid as far as FindMedia looks
+var (
+	testJPEG = []byte{
+		0xFF, 0xD8, // SOI
+		0xFF, 0xE0, 0x00, 0x04, 0xFF, 0xD9, // APP0 holding what looks like EOI
+		0xFF, 0xDA, 0x00, 0x02, // SOS
+		0x12, 0xFF, 0x00, 0x34, 0xFF, 0xD0, 0x56, // Stuffed byte and restart marker
+		0xFF, 0xD9, // EOI
+	}
+	testPNG = append(append([]byte("\x89PNG\r\n\x1a\n"),
+		0, 0, 0, 1, 'I', 'H', 'D', 'R', 7, 1, 2, 3, 4),
+		0, 0, 0, 0, 'I', 'E', 'N', 'D', 5, 6, 7, 8)
+	testMP4 = []byte("\x00\x00\x00\x10ftypisom\x00\x00\x00\x00" + "\x00\x00\x00\x0cmdat\x01\x02\x03\x04")
+)
+
+func TestFindMedia(t *testing.T) {
+	// A whole payload
+	if media := FindMedia(testPNG); len(media) != 1 || media[0].Type != ContentPNG || media[0].Offset != 0 {
+		t.Errorf("FindMedia(PNG) = %+v", media)
+	}
+
+	// In protobuf fields, nested ones included
+	var nested, message []byte
+	nested = protowire.AppendTag(nested, 1, protowire.BytesType)
+	nested = protowire.AppendBytes(nested, testJPEG)
+	message = protowire.AppendTag(message, 1, protowire.VarintType)
+	message = protowire.AppendVarint(message, 42)
+	message = protowire.AppendTag(message, 2, protowire.BytesType)
+	message = protowire.AppendBytes(message, testPNG)
+	message = protowire.AppendTag(message, 3, protowire.BytesType)
+	message = protowire.AppendBytes(message, nested)
+	media := FindMedia(message)
+	if len(media) != 2 || media[0].Type != ContentPNG || media[1].Type != ContentJPEG {
+		t.Fatalf("FindMedia(protobuf) = %+v", media)
+	}
+	for _, m := range media {
+		if !bytes.Equal(message[m.Offset:m.Offset+int64(len(m.Data))], m.Data) {
+			t.Errorf("%v at %d does not match the payload", m.Type, m.Offset)
+		}
+	}
+
+	// Anywhere else, measured by their structure
+	junk := bytes.Repeat([]byte{0x00, 0xFF, 0xD8}, 5)
+	var blob []byte
+	for _, part := range [][]byte{junk, testJPEG, junk, testMP4, junk, testPNG[:20]} {
+		blob = append(blob, part...)
+	}
+	media = FindMedia(blob)
+	if len(media) != 2 {
+		t.Fatalf("FindMedia(blob) = %d media; want JPEG and MP4", len(media))
+	}
+	if !bytes.Equal(media[0].Data, testJPEG) || media[0].Offset != int64(len(junk)) {
+		t.Errorf("JPEG = %x at %d", media[0].Data, media[0].Offset)
+	}
+	if media[1].Type != ContentMP4 || !bytes.Equal(media[1].Data, testMP4) {
+		t.Errorf("MP4 = %v %x", media[1].Type, media[1].Data)
+	}
+}
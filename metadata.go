@@ -0,0 +1,131 @@
+package segb
+
+import (
+	"errors"
+	"fmt"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"io"
+	"time"
+)
+
+// Metadata summarizes a SEGB file without its entry payloads.
+type Metadata struct {
+	Version     SegbVersion
+	Created     time.Time // As Segb.Created
+	EntryCount  int       // v2: as recorded in the header, including records Decode skips
+	Size        int64     // Size of the file, after decompression
+	Compression Compression
+}
+
+// ReadMetadata reads the version, creation time and entry count of a SEGB
+// file without reading any entry payload, as a cheap probe for inventorying
+// many files. A v2 file is only read up to its header; a v1 file has no entry
+// count or creation time in its header, so its entry headers are walked,
+// seeking past the payloads. Of the options, only WithAlignment applies.
+func ReadMetadata(stream io.ReadSeeker, opts ...DecodeOption) (Metadata, error) {
+	var cfg decodeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	stream, v, compression, err := probe(stream)
+	if err != nil {
+		return Metadata{}, err
+	}
+	size, err := stream.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		return Metadata{}, err
+	}
+
+	meta := Metadata{Version: v, Size: size, Compression: compression}
+	switch v {
+	case SEGB_VERSION_1:
+		_, entries, err := v1.ReadSegbWithOptions(stream, v1.Options{Alignment: cfg.alignment, SkipData: true})
+		if err != nil {
+			return Metadata{}, err
+		}
+		meta.Created = v1Created(entries)
+		meta.EntryCount = len(entries)
+	case SEGB_VERSION_2:
+		header, err := v2.ReadHeader(stream)
+		if err != nil {
+			return Metadata{}, err
+		}
+		if !header.IsValidMagic() {
+			return Metadata{}, fmt.Errorf("%w: %s", v2.ErrInvalidMagic, header.MagicString())
+		}
+		meta.Created = CocoaTimestampToTime(header.CreationTimestamp)
+		meta.EntryCount = int(header.EntryCount)
+	default:
+		return Metadata{}, ErrUnsupportedVersion
+	}
+	return meta, nil
+}
+
+// CountEntries returns the number of entries Decode would return, reading
+// only the header and trailer of a v2 file, and only the entry headers of a
+// v1 file. Of the options, only WithAlignment applies.
+func CountEntries(stream io.ReadSeeker, opts ...DecodeOption) (int, error) {
+	var cfg decodeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	stream, v, _, err := probe(stream)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v {
+	case SEGB_VERSION_1:
+		_, entries, err := v1.ReadSegbWithOptions(stream, v1.Options{Alignment: cfg.alignment, SkipData: true})
+		if err != nil {
+			return 0, err
+		}
+		return len(entries), nil
+	case SEGB_VERSION_2:
+		header, err := v2.ReadHeader(stream)
+		if err != nil {
+			return 0, err
+		}
+		if !header.IsValidMagic() {
+			return 0, fmt.Errorf("%w: %s", v2.ErrInvalidMagic, header.MagicString())
+		}
+		records, _, err := v2.ReadTrailer(stream, header)
+		if err != nil {
+			return 0, err
+		}
+		count := 0
+		for _, record := range records {
+			// Decode skips records in the unknown state
+			if record.State != v2.EntryStateUnknown {
+				count++
+			}
+		}
+		return count, nil
+	default:
+		return 0, ErrUnsupportedVersion
+	}
+}
+
+// probe unwraps compressed input and detects its version, leaving the
+// returned stream at its start. An ambiguous file is taken to be of the
+// first version it matches.
+func probe(stream io.ReadSeeker) (io.ReadSeeker, SegbVersion, Compression, error) {
+	stream, compression, err := Decompress(stream)
+	if err != nil {
+		return nil, NONE, CompressionNone, err
+	}
+	v, err := DetectVersion(stream)
+	if err != nil && !errors.Is(err, ErrAmbiguousVersion) {
+		return nil, NONE, CompressionNone, err
+	}
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		return nil, NONE, CompressionNone, err
+	}
+	return stream, v, compression, nil
+}
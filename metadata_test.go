@@ -0,0 +1,78 @@
+package segb
+
+import (
+	"bytes"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"os"
+	"testing"
+)
+
+func TestReadMetadata(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	for _, tc := range []struct {
+		name    string
+		version SegbVersion
+	}{
+		{"segb_version1.bin", SEGB_VERSION_1},
+		{"segb_version2.bin", SEGB_VERSION_2},
+	} {
+		data, err := os.ReadFile(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		meta, err := ReadMetadata(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		want := Metadata{Version: tc.version, Created: decoded.Created, EntryCount: len(decoded.Entries), Size: int64(len(data))}
+		if meta != want {
+			t.Errorf("%s: ReadMetadata() = %+v; want %+v", tc.name, meta, want)
+		}
+	}
+
+	// Padded differently, v1 entries are walked with the given alignment
+	meta, err := ReadMetadata(bytes.NewReader(v1File(16, []byte("The misfits."), []byte("The rebels."))), WithAlignment(16))
+	if err != nil || meta.EntryCount != 2 {
+		t.Errorf("ReadMetadata(WithAlignment(16)) = %+v, %v", meta, err)
+	}
+
+	if _, err := ReadMetadata(bytes.NewReader(make([]byte, 64))); err != ErrUnsupportedVersion {
+		t.Errorf("ReadMetadata(zeros) err = %v; want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestCountEntries(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	for _, name := range []string{"segb_version1.bin", "segb_version2.bin"} {
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if n, err := CountEntries(file); err != nil || n != len(expectedEntryData) {
+			t.Errorf("%s: CountEntries() = %d, %v; want %d", name, n, err, len(expectedEntryData))
+		}
+	}
+
+	// Records in the unknown state are not counted, as Decode skips them
+	file := v2File([]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels."))
+	file[len(file)-v2.TrailerRecordSize+4] = byte(v2.EntryStateUnknown)
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := CountEntries(bytes.NewReader(file)); err != nil || n != len(decoded.Entries) {
+		t.Errorf("CountEntries() = %d, %v; want %d", n, err, len(decoded.Entries))
+	}
+}
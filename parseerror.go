@@ -0,0 +1,74 @@
+package segb
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb/hexdump"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"io"
+)
+
+// parseErrorContext is how many bytes of the file a ParseError holds before
+// and after the offset it failed at.
+const parseErrorContext = 32
+
+// ParseError is returned by Decode for an entry that cannot be parsed. It
+// tells where parsing failed and holds the bytes around that point, to look
+// into the damage without opening the file in a hex editor.
+type ParseError struct {
+	Entry   int    // Index of the entry in the file (v1), or among the trailer records sorted by offset (v2)
+	Offset  int64  // Absolute offset of the entry header in the decoded file
+	Context []byte // Up to 32 bytes of the file on either side of Offset
+	Start   int64  // Offset of Context in the file
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("entry %d at offset 0x%x: %v", e.Entry, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Hexdump returns the dump of Context, with file offsets.
+func (e *ParseError) Hexdump() string {
+	return hexdump.String(e.Context, hexdump.Options{BaseOffset: e.Start})
+}
+
+// parseError turns the entry errors of the v1 and v2 readers into a
+// *ParseError with the bytes around the entry read from stream. Other
+// errors are returned as is.
+func parseError(stream io.ReadSeeker, err error) error {
+	var v1Err v1.EntryError
+	var v2Err v2.EntryError
+	var parseErr *ParseError
+	switch {
+	case errors.As(err, &v1Err):
+		parseErr = &ParseError{Entry: v1Err.Index, Offset: v1Err.Offset, Err: v1Err.Err}
+	case errors.As(err, &v2Err):
+		parseErr = &ParseError{Entry: v2Err.Index, Offset: v2Err.Offset, Err: v2Err.Err}
+	default:
+		return err
+	}
+
+	// The context is best effort, the error stands without it
+	size, seekErr := stream.Seek(0, io.SeekEnd)
+	if seekErr != nil {
+		return parseErr
+	}
+	parseErr.Start = max(parseErr.Offset-parseErrorContext, 0)
+	end := min(parseErr.Offset+parseErrorContext, size)
+	if parseErr.Start >= end {
+		return parseErr
+	}
+	if _, seekErr := stream.Seek(parseErr.Start, io.SeekStart); seekErr != nil {
+		return parseErr
+	}
+	context := make([]byte, end-parseErr.Start)
+	if _, readErr := io.ReadFull(stream, context); readErr == nil {
+		parseErr.Context = context
+	}
+	return parseErr
+}
@@ -0,0 +1,31 @@
+package segb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	// Header (56) | entry 0 at 56: 32 byte header, 5 bytes, 3 bytes padding | entry 1 at 96
+	file := v1File(8, []byte("first"), []byte("second"))
+	binary.LittleEndian.PutUint32(file[96:], 1000)
+
+	_, err := DecodeBytes(file, WithAlignment(8))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("DecodeBytes(truncated entry) = %v; want a *ParseError wrapping io.ErrUnexpectedEOF", err)
+	}
+	if parseErr.Entry != 1 || parseErr.Offset != 96 || parseErr.Start != 64 || !bytes.Equal(parseErr.Context, file[64:128]) {
+		t.Errorf("ParseError = entry %d at %d, context at %d %x", parseErr.Entry, parseErr.Offset, parseErr.Start, parseErr.Context)
+	}
+	if !strings.HasPrefix(err.Error(), "entry 1 at offset 0x60: ") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if dump := parseErr.Hexdump(); !strings.HasPrefix(dump, "00000040: ") || !strings.Contains(dump, "00000060: e8 03 00 00") {
+		t.Errorf("Hexdump() =\n%s", dump)
+	}
+}
@@ -0,0 +1,283 @@
+package segb
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"github.com/bluefalconhd/segb/internal/lz4"
+	"github.com/bluefalconhd/segb/internal/lzfse"
+	"io"
+)
+
+var ErrUnsupportedCompression = errors.New("unsupported payload compression")
+
+// Block magics of the containers written by Apple's compression library
+var (
+	appleLZ4Compressed     = []byte("bv41")
+	appleLZ4Uncompressed   = []byte("bv4-")
+	appleLZ4End            = []byte("bv4$")
+	appleLZFSEV1           = []byte("bvx1")
+	appleLZFSEV2           = []byte("bvx2")
+	appleLZVN              = []byte("bvxn")
+	appleLZFSEUncompressed = []byte("bvx-")
+	appleLZFSEEnd          = []byte("bvx$")
+	appleLZFSEPrefix       = []byte("bvx")
+)
+
+// DecodeOption configures Decode.
+type DecodeOption func(*decodeConfig)
+
+type decodeConfig struct {
+	payloads  bool
+	padded    bool
+	alignment int // 0 for the format default
+	lenient   bool
+	crcPolicy CRCPolicy
+	filter    func(EntryMeta) bool
+	order     SortOrder
+	roundTrip bool
+	residual  bool
+	visit     func(Entry) error // Set by WalkStream
+
+	maxTotalBytes int64 // 0 for no budget
+	maxEntrySize  int64 // 0 for no limit
+
+	maxDecompressed int64 // 0 for MaxDecompressedSize
+
+	retry RetryPolicy
+}
+
+// WithoutPayloadDecompression leaves compressed entry payloads as stored.
+func WithoutPayloadDecompression() DecodeOption {
+	return func(c *decodeConfig) {
+		c.payloads = false
+	}
+}
+
+// WithRawPayloads makes Entry.Data hold exactly the bytes the payload
+// occupies in the file, alignment padding included, for boundary analysis
+// or byte-exact comparisons; Entry.Size still tells the logical length and
+// Entry.Padded is set. Payloads are left compressed, as with
+// WithoutPayloadDecompression. WalkStream ignores it.
+func WithRawPayloads() DecodeOption {
+	return func(c *decodeConfig) {
+		c.padded = true
+		c.payloads = false
+	}
+}
+
+// readPadded replaces the payload of every entry with the bytes it occupies
+// in stream, alignment padding included.
+func (s *Segb) readPadded(stream io.ReadSeeker) error {
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		if entry.StoredSize <= int64(len(entry.Data)) {
+			continue
+		}
+		if _, err := stream.Seek(entry.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		data := make([]byte, entry.StoredSize)
+		if _, err := io.ReadFull(stream, data); err != nil {
+			return err
+		}
+		entry.Data, entry.Padded = data, true
+	}
+	return nil
+}
+
+// zlibProbeSize is how much of a zlib payload DetectPayloadCompression
+// inflates to tell it from other data.
+const zlibProbeSize = 64 << 10
+
+// DetectPayloadCompression sniffs the compression of an entry payload:
+// zlib, LZ4 frames, or the LZ4 and LZFSE containers of Apple's compression
+// library. Zlib headers are common in protobuf (08 1d is field 1 holding
+// 29), so zlib is only reported for payloads that inflate, up to their
+// Adler-32 checksum or for zlibProbeSize bytes.
+func DetectPayloadCompression(data []byte) Compression {
+	switch {
+	case isZlib(data):
+		return CompressionZlib
+	case len(data) >= 4 && binary.LittleEndian.Uint32(data) == lz4.FrameMagic:
+		return CompressionLZ4
+	case bytes.HasPrefix(data, appleLZ4Compressed), bytes.HasPrefix(data, appleLZ4Uncompressed):
+		return CompressionLZ4
+	case bytes.HasPrefix(data, appleLZFSEPrefix) && len(data) >= 4:
+		return CompressionLZFSE
+	default:
+		return CompressionNone
+	}
+}
+
+// isZlib reports whether data holds a zlib header and deflate data that
+// inflates.
+func isZlib(data []byte) bool {
+	if len(data) < 2 || data[0]&0x0f != 8 || data[0]>>4 > 7 || (uint16(data[0])<<8|uint16(data[1]))%31 != 0 {
+		return false
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	// Reading to the end verifies the checksum
+	_, err = io.Copy(io.Discard, io.LimitReader(zr, zlibProbeSize))
+	return err == nil
+}
+
+// DecompressPayload decompresses an entry payload, returning the detected
+// compression. Uncompressed payloads are returned as is.
+func DecompressPayload(data []byte) ([]byte, Compression, error) {
+	return decompressPayload(data, MaxDecompressedSize)
+}
+
+// decompressPayload is DecompressPayload producing at most limit bytes.
+func decompressPayload(data []byte, limit int64) ([]byte, Compression, error) {
+	c := DetectPayloadCompression(data)
+	switch c {
+	case CompressionZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, c, err
+		}
+		out, err := readLimited(zr, limit)
+		if err != nil {
+			return nil, c, err
+		}
+		// Reading to the end verifies the Adler-32 checksum
+		return out, c, zr.Close()
+	case CompressionLZ4:
+		if binary.LittleEndian.Uint32(data) == lz4.FrameMagic {
+			out, err := lz4.DecodeFrames(data, int(limit))
+			if err == lz4.ErrTooLarge {
+				err = ErrDecompressedTooLarge
+			}
+			return out, c, err
+		}
+		out, err := decodeAppleBlocks(data, limit)
+		return out, c, err
+	case CompressionLZFSE:
+		out, err := decodeAppleBlocks(data, limit)
+		return out, c, err
+	default:
+		return data, c, nil
+	}
+}
+
+// decodeAppleBlocks decodes the block container of Apple's compression
+// library: a sequence of blocks, each a 4 byte magic followed by its sizes
+// and data, terminated by an end of stream block. It produces at most limit
+// bytes.
+func decodeAppleBlocks(data []byte, limit int64) ([]byte, error) {
+	var out []byte
+	for {
+		if len(data) < 4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		block := data
+		magic := data[:4]
+		data = data[4:]
+
+		switch {
+		case bytes.Equal(magic, appleLZ4End), bytes.Equal(magic, appleLZFSEEnd):
+			return out, nil
+
+		case bytes.Equal(magic, appleLZ4Uncompressed), bytes.Equal(magic, appleLZFSEUncompressed):
+			if len(data) < 4 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			size := int64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+			if size > int64(len(data)) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if int64(len(out))+size > limit {
+				return nil, ErrDecompressedTooLarge
+			}
+			out = append(out, data[:size]...)
+			data = data[size:]
+
+		case bytes.Equal(magic, appleLZ4Compressed):
+			if len(data) < 8 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			rawSize := int64(binary.LittleEndian.Uint32(data))
+			size := int64(binary.LittleEndian.Uint32(data[4:]))
+			data = data[8:]
+			if size > int64(len(data)) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if int64(len(out))+rawSize > limit {
+				return nil, ErrDecompressedTooLarge
+			}
+			// The block must not expand past the size it claims
+			start := len(out)
+			var err error
+			out, err = lz4.DecodeBlockLimit(out, data[:size], start+int(rawSize))
+			if err == lz4.ErrTooLarge || err == nil && int64(len(out)-start) != rawSize {
+				return nil, lz4.ErrCorrupt
+			}
+			if err != nil {
+				return nil, err
+			}
+			data = data[size:]
+
+		case bytes.Equal(magic, appleLZFSEV1), bytes.Equal(magic, appleLZFSEV2), bytes.Equal(magic, appleLZVN):
+			var n int
+			var err error
+			out, n, err = lzfse.DecodeBlock(out, block, int(limit))
+			if err == lzfse.ErrTooLarge {
+				err = ErrDecompressedTooLarge
+			}
+			if err != nil {
+				return nil, err
+			}
+			data = block[n:]
+
+		default:
+			return nil, ErrUnsupportedCompression
+		}
+	}
+}
+
+// DecompressPayloads replaces the payload of every compressed entry with its
+// decompressed form, keeping the stored bytes in Entry.Raw. Payloads that
+// fail to decompress are left untouched. It returns the number of payloads
+// decompressed.
+func (s *Segb) DecompressPayloads() int {
+	n, _ := s.decompressPayloads(MaxDecompressedSize, -1)
+	return n
+}
+
+// decompressPayloads is DecompressPayloads with payloads expanding to at most
+// maxSize bytes each that, given a budget of bytes (-1 for none), fails with
+// ErrBudgetExceeded once the decompressed payloads would exceed it.
+func (s *Segb) decompressPayloads(maxSize, budget int64) (int, error) {
+	n := 0
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		if entry.Raw != nil {
+			continue
+		}
+		limit := maxSize
+		if budget >= 0 {
+			limit = min(limit, budget)
+		}
+		data, c, err := decompressPayload(entry.Data, limit)
+		if err == ErrDecompressedTooLarge && limit < maxSize {
+			return n, ErrBudgetExceeded
+		}
+		if err != nil || c == CompressionNone {
+			continue
+		}
+		if budget >= 0 {
+			budget -= int64(len(data))
+		}
+		entry.Raw = entry.Data
+		entry.Data = data
+		entry.Compression = c
+		n++
+	}
+	return n, nil
+}
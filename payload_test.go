@@ -0,0 +1,168 @@
+package segb
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"strings"
+	"testing"
+)
+
+// v2File builds an in-memory SEGB v2 file holding the given payloads.
+func v2File(payloads ...[]byte) []byte {
+	var header, body, trailer []byte
+	header = append(header, "SEGB"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(payloads)))
+	header = binary.LittleEndian.AppendUint64(header, math.Float64bits(0))
+	header = append(header, make([]byte, 16)...)
+
+	for _, payload := range payloads {
+		trailer = binary.LittleEndian.AppendUint32(trailer, uint32(len(body)))
+		trailer = binary.LittleEndian.AppendUint32(trailer, 1)
+		trailer = binary.LittleEndian.AppendUint64(trailer, math.Float64bits(0))
+
+		body = binary.LittleEndian.AppendUint32(body, crc32.ChecksumIEEE(payload))
+		body = append(body, make([]byte, 4)...)
+		body = append(body, payload...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	return append(append(header, body...), trailer...)
+}
+
+func zlibPayload(data string) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(data))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompressPayload(t *testing.T) {
+	const text = "Here's to the crazy ones."
+
+	// "abc" followed by a 9 byte match at offset 3
+	lz4Block := []byte{0x35, 'a', 'b', 'c', 0x03, 0x00}
+	appleLZ4 := append([]byte("bv41"), 12, 0, 0, 0, byte(len(lz4Block)), 0, 0, 0)
+	appleLZ4 = append(appleLZ4, lz4Block...)
+	appleLZ4 = append(appleLZ4, "bv4-\x03\x00\x00\x00xyzbv4$"...)
+
+	// The same as LZVN, then a block matching "abc" in the first one
+	appleLZVNBlocks := []byte("bvxn\x0f\x00\x00\x00\x12\x00\x00\x00")
+	appleLZVNBlocks = append(appleLZVNBlocks, 0xd8, 0x03, 'a', 'b', 'c', 0xf3, 0xe3, 'x', 'y', 'z', 0x06, 0, 0, 0, 0, 0, 0, 0)
+	appleLZVNBlocks = append(appleLZVNBlocks, "bvxn\x03\x00\x00\x00\x0a\x00\x00\x00"...)
+	appleLZVNBlocks = append(appleLZVNBlocks, 0x00, 0x0f, 0x06, 0, 0, 0, 0, 0, 0, 0)
+	appleLZVNBlocks = append(appleLZVNBlocks, "bvx$"...)
+
+	for _, tc := range []struct {
+		name  string
+		input []byte
+		want  string
+		c     Compression
+	}{
+		{"plain", []byte(text), text, CompressionNone},
+		{"zlib", zlibPayload(text), text, CompressionZlib},
+		{"lz4 frame", lz4Frame([]byte(text)), text, CompressionLZ4},
+		{"apple lz4", appleLZ4, "abcabcabcabcxyz", CompressionLZ4},
+		{"apple lzfse uncompressed", []byte("bvx-\x03\x00\x00\x00xyzbvx$"), "xyz", CompressionLZFSE},
+		{"apple lzvn", appleLZVNBlocks, "abcabcabcabcxyzabc", CompressionLZFSE},
+	} {
+		out, c, err := DecompressPayload(tc.input)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if c != tc.c || string(out) != tc.want {
+			t.Errorf("%s: got %v %q; want %v %q", tc.name, c, out, tc.c, tc.want)
+		}
+	}
+
+	// Protobuf and broken zlib streams, whose first two bytes make a zlib
+	// header, are left as they are
+	broken := zlibPayload(text)
+	broken[len(broken)-1] ^= 1
+	for name, input := range map[string][]byte{
+		"protobuf":     {0x08, 0x1d, 0x12, 0x05, 'A', 'p', 'p', 'l', 'e'},
+		"zlib header":  zlibPayload(text)[:2],
+		"bad checksum": broken,
+	} {
+		if c := DetectPayloadCompression(input); c != CompressionNone {
+			t.Errorf("%s: detected %v", name, c)
+		}
+		if out, c, err := DecompressPayload(input); err != nil || c != CompressionNone || !bytes.Equal(out, input) {
+			t.Errorf("%s: got %v %x, %v; want the payload as is", name, c, out, err)
+		}
+	}
+	// Long streams are told apart by their start
+	long := zlibPayload(strings.Repeat(text, 10000))
+	if c := DetectPayloadCompression(long[:len(long)/2]); c != CompressionZlib {
+		t.Errorf("start of a long stream: detected %v", c)
+	}
+
+	if _, _, err := DecompressPayload([]byte("bvx3\x10\x00\x00\x00")); err != ErrUnsupportedCompression {
+		t.Errorf("unknown LZFSE block: got %v; want ErrUnsupportedCompression", err)
+	}
+	if _, _, err := decompressPayload(appleLZVNBlocks, 17); err != ErrDecompressedTooLarge {
+		t.Errorf("LZVN block past the limit: got %v; want ErrDecompressedTooLarge", err)
+	}
+}
+
+func TestDecodePayloadDecompression(t *testing.T) {
+	stored := zlibPayload("The misfits.")
+	file := v2File([]byte("The rebels."), stored)
+
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := decoded.Entries[1]
+	if string(entry.Data) != "The misfits." || entry.Compression != CompressionZlib {
+		t.Errorf("got %v %q; want decompressed zlib payload", entry.Compression, entry.Data)
+	}
+	if !bytes.Equal(entry.Raw, stored) {
+		t.Error("Raw does not hold the stored payload")
+	}
+	if !entry.CheckCRC() {
+		t.Error("CheckCRC() failed on a decompressed payload")
+	}
+	if decoded.Entries[0].Raw != nil || decoded.Entries[0].Compression != CompressionNone {
+		t.Error("uncompressed payload was marked as decompressed")
+	}
+
+	decoded, err = DecodeBytes(file, WithoutPayloadDecompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry = decoded.Entries[1]
+	if !bytes.Equal(entry.Data, stored) || entry.Raw != nil {
+		t.Error("WithoutPayloadDecompression() still decompressed the payload")
+	}
+}
+
+func TestRawPayloads(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), zlibPayload("The misfits."), []byte("The rebels.")}
+	for name, file := range map[string][]byte{
+		"v1": v1File(8, payloads...),
+		"v2": v2File(payloads...),
+	} {
+		decoded, err := DecodeBytes(file, WithRawPayloads(), WithCRCPolicy(CRCStrict))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i, entry := range decoded.Entries {
+			stored := file[entry.Offset : entry.Offset+entry.StoredSize]
+			if !bytes.Equal(entry.Data, stored) || entry.Size != int64(len(payloads[i])) {
+				t.Errorf("%s: entry %d = %q (size %d); want %q (size %d)", name, i, entry.Data, entry.Size, stored, len(payloads[i]))
+			}
+			if entry.Padded != (entry.StoredSize > entry.Size) || entry.Compression != CompressionNone {
+				t.Errorf("%s: entry %d: Padded %v, Compression %v", name, i, entry.Padded, entry.Compression)
+			}
+		}
+		if failures := decoded.VerifyAll(); failures != nil {
+			t.Errorf("%s: VerifyAll() = %+v", name, failures)
+		}
+	}
+}
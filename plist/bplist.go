@@ -0,0 +1,261 @@
+// Package plist decodes binary property lists and the NSKeyedArchiver
+// archives stored in them, as found in the payloads of some SEGB streams.
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb/timeconv"
+	"math"
+	"unicode/utf16"
+)
+
+// Magic is the header of a binary property list.
+const Magic = "bplist00"
+
+// trailerSize is the size of the trailer at the end of a binary property list.
+const trailerSize = 32
+
+// maxDepth bounds the nesting of arrays and dictionaries, which a crafted
+// property list can make cyclic.
+const maxDepth = 512
+
+// maxValues bounds the number of values decoded, as a crafted property list
+// can reference the same array many times over at every level.
+const maxValues = 1 << 20
+
+var (
+	ErrNotBplist = errors.New("not a binary property list")
+	ErrMalformed = errors.New("malformed binary property list")
+)
+
+// UID is a reference to an object of an NSKeyedArchiver archive, stored as
+// a plist UID.
+type UID uint64
+
+// MarshalJSON encodes the UID the way plutil shows it.
+func (u UID) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"CF$UID":%d}`, uint64(u))), nil
+}
+
+// parser holds the parts of the property list located by its trailer.
+type parser struct {
+	data          []byte
+	offsets       []uint64
+	objectRefSize int
+	depth         int
+	values        int
+}
+
+// Parse decodes a binary property list into Go values: nil, bool, int64,
+// float64, time.Time (dates), []byte (data), string, UID, []any (arrays and
+// sets) and map[string]any (dictionaries, with keys that are not strings
+// formatted with fmt).
+func Parse(data []byte) (any, error) {
+	if !bytes.HasPrefix(data, []byte(Magic)) {
+		return nil, ErrNotBplist
+	}
+	if len(data) < len(Magic)+trailerSize {
+		return nil, fmt.Errorf("%w: %d bytes is too short", ErrMalformed, len(data))
+	}
+
+	trailer := data[len(data)-trailerSize:]
+	offsetSize := int(trailer[6])
+	objectRefSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:])
+	topObject := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+	if offsetSize < 1 || offsetSize > 8 || objectRefSize < 1 || objectRefSize > 8 {
+		return nil, fmt.Errorf("%w: integer sizes %d, %d", ErrMalformed, offsetSize, objectRefSize)
+	}
+	tableEnd := uint64(len(data) - trailerSize)
+	if tableOffset > tableEnd || numObjects > (tableEnd-tableOffset)/uint64(offsetSize) {
+		return nil, fmt.Errorf("%w: offset table out of bounds", ErrMalformed)
+	}
+	if topObject >= numObjects {
+		return nil, fmt.Errorf("%w: top object %d of %d", ErrMalformed, topObject, numObjects)
+	}
+
+	p := &parser{data: data, objectRefSize: objectRefSize, offsets: make([]uint64, numObjects)}
+	for i := range p.offsets {
+		p.offsets[i] = readUint(data[tableOffset+uint64(i*offsetSize):], offsetSize)
+	}
+	return p.object(topObject)
+}
+
+// readUint reads a big-endian unsigned integer of size bytes.
+func readUint(b []byte, size int) uint64 {
+	var v uint64
+	for _, c := range b[:size] {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// object decodes the object with the given index in the offset table.
+func (p *parser) object(ref uint64) (any, error) {
+	if ref >= uint64(len(p.offsets)) {
+		return nil, fmt.Errorf("%w: object reference %d of %d", ErrMalformed, ref, len(p.offsets))
+	}
+	if p.values++; p.values > maxValues {
+		return nil, fmt.Errorf("%w: more than %d values", ErrMalformed, maxValues)
+	}
+	offset := p.offsets[ref]
+	end := uint64(len(p.data) - trailerSize)
+	if offset >= end {
+		return nil, fmt.Errorf("%w: object %d at offset %d out of bounds", ErrMalformed, ref, offset)
+	}
+	body := p.data[offset+1 : end]
+	marker := p.data[offset]
+	kind, info := marker>>4, int(marker&0x0F)
+
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x00:
+			return nil, nil
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+	case 0x1:
+		size := 1 << info
+		if size > 16 || len(body) < size {
+			break
+		}
+		// 16-byte integers hold 64-bit values in their low half
+		if size == 16 {
+			body, size = body[8:], 8
+		}
+		// Integers of 8 bytes are signed, shorter ones unsigned
+		return int64(readUint(body, size)), nil
+	case 0x2:
+		switch {
+		case info == 2 && len(body) >= 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(body))), nil
+		case info == 3 && len(body) >= 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(body)), nil
+		}
+	case 0x3:
+		if marker == 0x33 && len(body) >= 8 {
+			seconds := math.Float64frombits(binary.BigEndian.Uint64(body))
+			return timeconv.FromCocoa(seconds), nil
+		}
+	case 0x4, 0x5, 0x6:
+		count, body, err := p.count(info, body)
+		if err != nil {
+			return nil, err
+		}
+		size := count
+		if kind == 0x6 {
+			size *= 2
+		}
+		if count > len(body) || size > len(body) {
+			break
+		}
+		switch kind {
+		case 0x4:
+			return bytes.Clone(body[:size]), nil
+		case 0x5:
+			return string(body[:size]), nil
+		default:
+			units := make([]uint16, count)
+			for i := range units {
+				units[i] = binary.BigEndian.Uint16(body[2*i:])
+			}
+			return string(utf16.Decode(units)), nil
+		}
+	case 0x8:
+		if len(body) > info {
+			return UID(readUint(body, info+1)), nil
+		}
+	case 0xA, 0xC:
+		refs, err := p.refs(info, body, 1)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(refs))
+		for i, r := range refs {
+			if values[i], err = p.nested(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case 0xD:
+		refs, err := p.refs(info, body, 2)
+		if err != nil {
+			return nil, err
+		}
+		n := len(refs) / 2
+		dict := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			key, err := p.nested(refs[i])
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.nested(refs[n+i])
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				name = fmt.Sprint(key)
+			}
+			dict[name] = value
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("%w: bad object 0x%02x at offset %d", ErrMalformed, marker, offset)
+}
+
+// nested decodes an element of an array or dictionary.
+func (p *parser) nested(ref uint64) (any, error) {
+	if p.depth >= maxDepth {
+		return nil, fmt.Errorf("%w: nested too deep", ErrMalformed)
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	return p.object(ref)
+}
+
+// count reads the element count of an object from its marker, or from the
+// integer object following it if the marker holds 0xF, and returns the rest
+// of the object.
+func (p *parser) count(info int, body []byte) (int, []byte, error) {
+	if info != 0x0F {
+		return info, body, nil
+	}
+	if len(body) == 0 || body[0]>>4 != 0x1 {
+		return 0, nil, fmt.Errorf("%w: bad object count", ErrMalformed)
+	}
+	size := 1 << (body[0] & 0x0F)
+	if size > 8 || len(body) < 1+size {
+		return 0, nil, fmt.Errorf("%w: bad object count", ErrMalformed)
+	}
+	count := readUint(body[1:], size)
+	if count > uint64(len(body)) {
+		return 0, nil, fmt.Errorf("%w: object count %d out of bounds", ErrMalformed, count)
+	}
+	return int(count), body[1+size:], nil
+}
+
+// refs reads the object references of an array, set or dictionary, of
+// which there are perElement times its count.
+func (p *parser) refs(info int, body []byte, perElement int) ([]uint64, error) {
+	count, body, err := p.count(info, body)
+	if err != nil {
+		return nil, err
+	}
+	n := count * perElement
+	if n*p.objectRefSize > len(body) {
+		return nil, fmt.Errorf("%w: %d object references out of bounds", ErrMalformed, n)
+	}
+	refs := make([]uint64, n)
+	for i := range refs {
+		refs[i] = readUint(body[i*p.objectRefSize:], p.objectRefSize)
+	}
+	return refs, nil
+}
@@ -0,0 +1,253 @@
+package plist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb/timeconv"
+	"maps"
+	"net/url"
+)
+
+// ErrNotKeyedArchive is returned by Unarchive for property lists that are
+// not NSKeyedArchiver archives.
+var ErrNotKeyedArchive = errors.New("not an NSKeyedArchiver archive")
+
+// Object is an archived object of a class Unarchive has no special decoding
+// for: its class name and its encoded keys, decoded.
+type Object struct {
+	Class  string
+	Fields map[string]any
+}
+
+// MarshalJSON encodes the object as its fields plus a "$class" key.
+func (o Object) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(o.Fields)+1)
+	maps.Copy(fields, o.Fields)
+	fields["$class"] = o.Class
+	return json.Marshal(fields)
+}
+
+// IsKeyedArchive reports whether data is a binary property list written by
+// NSKeyedArchiver.
+func IsKeyedArchive(data []byte) bool {
+	top, err := Parse(data)
+	if err != nil {
+		return false
+	}
+	_, _, err = archiveObjects(top)
+	return err == nil
+}
+
+// archiveObjects returns the object table and the root objects of a parsed
+// NSKeyedArchiver archive.
+func archiveObjects(top any) ([]any, map[string]any, error) {
+	dict, ok := top.(map[string]any)
+	if !ok || dict["$archiver"] != "NSKeyedArchiver" {
+		return nil, nil, ErrNotKeyedArchive
+	}
+	objects, ok := dict["$objects"].([]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: no $objects", ErrNotKeyedArchive)
+	}
+	roots, ok := dict["$top"].(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: no $top", ErrNotKeyedArchive)
+	}
+	return objects, roots, nil
+}
+
+// Unarchive decodes an NSKeyedArchiver archive into its object graph. The
+// result is the root object, or a map of the top-level keys when there are
+// several. References are resolved: collections become []any and
+// map[string]any, strings, data, dates, UUIDs and URLs their Go values, and
+// other objects an Object. A reference back to an object being decoded, as
+// in cyclic graphs, is left as its UID.
+func Unarchive(data []byte) (any, error) {
+	top, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	objects, roots, err := archiveObjects(top)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unarchiver{objects: objects, decoding: make(map[UID]bool)}
+	graph := make(map[string]any, len(roots))
+	for key, value := range roots {
+		if graph[key], err = u.value(value); err != nil {
+			return nil, err
+		}
+	}
+	if root, ok := graph["root"]; ok && len(graph) == 1 {
+		return root, nil
+	}
+	return graph, nil
+}
+
+// unarchiver resolves the references of an archive.
+type unarchiver struct {
+	objects  []any
+	decoding map[UID]bool
+	resolved int
+}
+
+// value decodes a value of an archived object, following it if it is a
+// reference.
+func (u *unarchiver) value(v any) (any, error) {
+	switch v := v.(type) {
+	case UID:
+		return u.resolve(v)
+	case []any:
+		values := make([]any, len(v))
+		for i, element := range v {
+			var err error
+			if values[i], err = u.value(element); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return v, nil
+}
+
+// resolve decodes the object a UID refers to.
+func (u *unarchiver) resolve(uid UID) (any, error) {
+	if uint64(uid) >= uint64(len(u.objects)) {
+		return nil, fmt.Errorf("%w: reference %d of %d objects", ErrMalformed, uid, len(u.objects))
+	}
+	if u.decoding[uid] {
+		return uid, nil
+	}
+	// Shared objects are decoded every time they are referenced
+	if u.resolved++; u.resolved > maxValues {
+		return nil, fmt.Errorf("%w: more than %d references", ErrMalformed, maxValues)
+	}
+	u.decoding[uid] = true
+	defer delete(u.decoding, uid)
+
+	object := u.objects[uid]
+	if object == "$null" {
+		return nil, nil
+	}
+	dict, ok := object.(map[string]any)
+	if !ok {
+		return u.value(object)
+	}
+	classRef, ok := dict["$class"].(UID)
+	if !ok {
+		return u.fields(dict)
+	}
+	class, err := u.className(classRef)
+	if err != nil {
+		return nil, err
+	}
+
+	switch class {
+	case "NSArray", "NSMutableArray", "NSSet", "NSMutableSet", "NSOrderedSet", "NSMutableOrderedSet":
+		return u.value(dict["NS.objects"])
+	case "NSDictionary", "NSMutableDictionary":
+		return u.dictionary(dict)
+	case "NSString", "NSMutableString":
+		return dict["NS.string"], nil
+	case "NSData", "NSMutableData":
+		return dict["NS.bytes"], nil
+	case "NSDate":
+		if seconds, ok := dict["NS.time"].(float64); ok {
+			return timeconv.FromCocoa(seconds), nil
+		}
+	case "NSUUID":
+		if b, ok := dict["NS.uuidbytes"].([]byte); ok && len(b) == 16 {
+			return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+		}
+	case "NSURL":
+		return u.url(dict)
+	}
+
+	fields, err := u.fields(dict)
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "$class")
+	return Object{Class: class, Fields: fields}, nil
+}
+
+// className returns the name of the class a $class reference refers to.
+func (u *unarchiver) className(uid UID) (string, error) {
+	if uint64(uid) >= uint64(len(u.objects)) {
+		return "", fmt.Errorf("%w: class reference %d of %d objects", ErrMalformed, uid, len(u.objects))
+	}
+	class, _ := u.objects[uid].(map[string]any)
+	name, ok := class["$classname"].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: object %d is not a class", ErrMalformed, uid)
+	}
+	return name, nil
+}
+
+// fields decodes the values of an archived object.
+func (u *unarchiver) fields(dict map[string]any) (map[string]any, error) {
+	fields := make(map[string]any, len(dict))
+	for key, value := range dict {
+		var err error
+		if fields[key], err = u.value(value); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// dictionary decodes an archived NSDictionary, formatting keys that are not
+// strings with fmt.
+func (u *unarchiver) dictionary(dict map[string]any) (any, error) {
+	keys, err := u.value(dict["NS.keys"])
+	if err != nil {
+		return nil, err
+	}
+	values, err := u.value(dict["NS.objects"])
+	if err != nil {
+		return nil, err
+	}
+	keyList, _ := keys.([]any)
+	valueList, _ := values.([]any)
+	if len(keyList) != len(valueList) {
+		return nil, fmt.Errorf("%w: dictionary of %d keys and %d values", ErrMalformed, len(keyList), len(valueList))
+	}
+	decoded := make(map[string]any, len(keyList))
+	for i, key := range keyList {
+		name, ok := key.(string)
+		if !ok {
+			name = fmt.Sprint(key)
+		}
+		decoded[name] = valueList[i]
+	}
+	return decoded, nil
+}
+
+// url decodes an archived NSURL, resolving it against its base URL if it
+// has one.
+func (u *unarchiver) url(dict map[string]any) (any, error) {
+	relative, err := u.value(dict["NS.relative"])
+	if err != nil {
+		return nil, err
+	}
+	base, err := u.value(dict["NS.base"])
+	if err != nil {
+		return nil, err
+	}
+	relativeString, _ := relative.(string)
+	baseString, ok := base.(string)
+	if !ok {
+		return relativeString, nil
+	}
+	baseURL, err := url.Parse(baseString)
+	if err != nil {
+		return relativeString, nil
+	}
+	ref, err := url.Parse(relativeString)
+	if err != nil {
+		return relativeString, nil
+	}
+	return baseURL.ResolveReference(ref).String(), nil
+}
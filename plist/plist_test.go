@@ -0,0 +1,201 @@
+package plist
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/bluefalconhd/segb/timeconv"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// encode writes a binary property list of v, with one object per value and
+// 1-byte object references, enough for small test lists.
+func encode(t *testing.T, v any) []byte {
+	var objects [][]byte
+	var add func(v any) int
+	marker := func(kind byte, count int) []byte {
+		if count < 15 {
+			return []byte{kind<<4 | byte(count)}
+		}
+		return []byte{kind<<4 | 0x0F, 0x11, byte(count >> 8), byte(count)}
+	}
+	add = func(v any) int {
+		index := len(objects)
+		objects = append(objects, nil)
+		var object []byte
+		switch v := v.(type) {
+		case nil:
+			object = []byte{0x00}
+		case bool:
+			object = []byte{0x08}
+			if v {
+				object[0] = 0x09
+			}
+		case int:
+			object = binary.BigEndian.AppendUint64([]byte{0x13}, uint64(v))
+		case float64:
+			object = binary.BigEndian.AppendUint64([]byte{0x23}, math.Float64bits(v))
+		case time.Time:
+			seconds := timeconv.ToCocoa(v)
+			object = binary.BigEndian.AppendUint64([]byte{0x33}, math.Float64bits(seconds))
+		case string:
+			object = append(marker(0x5, len(v)), v...)
+		case []byte:
+			object = append(marker(0x4, len(v)), v...)
+		case UID:
+			object = []byte{0x80, byte(v)}
+		case []any:
+			object = marker(0xA, len(v))
+			for _, element := range v {
+				object = append(object, byte(add(element)))
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			refs := make([]byte, 2*len(keys))
+			for i, key := range keys {
+				refs[i] = byte(add(key))
+				refs[len(keys)+i] = byte(add(v[key]))
+			}
+			object = append(marker(0xD, len(keys)), refs...)
+		default:
+			t.Fatalf("cannot encode %T", v)
+		}
+		objects[index] = object
+		return index
+	}
+	add(v)
+	if len(objects) > 255 {
+		t.Fatalf("%d objects do not fit 1-byte references", len(objects))
+	}
+
+	data := []byte(Magic)
+	var table []byte
+	for _, object := range objects {
+		table = binary.BigEndian.AppendUint16(table, uint16(len(data)))
+		data = append(data, object...)
+	}
+	tableOffset := len(data)
+	data = append(data, table...)
+	trailer := make([]byte, trailerSize)
+	trailer[6], trailer[7] = 2, 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(tableOffset))
+	return append(data, trailer...)
+}
+
+func TestParse(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	want := map[string]any{
+		"bundleID": "com.apple.mobilesafari",
+		"count":    int64(3),
+		"ratio":    0.5,
+		"on":       true,
+		"none":     nil,
+		"created":  created,
+		"raw":      []byte{0xde, 0xad},
+		"ref":      UID(7),
+		"list":     []any{"a", int64(-1)},
+		"long":     "a string longer than fifteen bytes",
+	}
+	data := encode(t, map[string]any{
+		"bundleID": "com.apple.mobilesafari",
+		"count":    3,
+		"ratio":    0.5,
+		"on":       true,
+		"none":     nil,
+		"created":  created,
+		"raw":      []byte{0xde, 0xad},
+		"ref":      UID(7),
+		"list":     []any{"a", -1},
+		"long":     "a string longer than fifteen bytes",
+	})
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %#v; want %#v", got, want)
+	}
+
+	if _, err := Parse([]byte("not a plist")); !errors.Is(err, ErrNotBplist) {
+		t.Errorf("Parse(text) error = %v; want ErrNotBplist", err)
+	}
+	if _, err := Parse(data[:len(data)-1]); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Parse(truncated) error = %v; want ErrMalformed", err)
+	}
+}
+
+func TestParseCycle(t *testing.T) {
+	// An array containing itself
+	data := encode(t, []any{nil})
+	data[len(Magic)+1] = 0
+	if _, err := Parse(data); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Parse(cycle) error = %v; want ErrMalformed", err)
+	}
+}
+
+// archive returns an NSKeyedArchiver archive with the given root object
+// and object table.
+func archive(t *testing.T, objects ...any) []byte {
+	return encode(t, map[string]any{
+		"$archiver": "NSKeyedArchiver",
+		"$version":  100000,
+		"$top":      map[string]any{"root": UID(1)},
+		"$objects":  append([]any{"$null"}, objects...),
+	})
+}
+
+func TestUnarchive(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := archive(t,
+		// 1: the root, an instance of a custom class
+		map[string]any{"$class": UID(2), "identifier": UID(3), "info": UID(4), "parent": UID(1), "missing": UID(0)},
+		map[string]any{"$classname": "BMAppLaunch", "$classes": []any{"BMAppLaunch", "NSObject"}},
+		"com.apple.mobilesafari",
+		// 4: a dictionary holding an array and a date
+		map[string]any{"$class": UID(5), "NS.keys": []any{UID(6), UID(7)}, "NS.objects": []any{UID(8), UID(10)}},
+		map[string]any{"$classname": "NSDictionary", "$classes": []any{"NSDictionary", "NSObject"}},
+		"launches",
+		"started",
+		map[string]any{"$class": UID(9), "NS.objects": []any{UID(3), UID(3)}},
+		map[string]any{"$classname": "NSArray", "$classes": []any{"NSArray", "NSObject"}},
+		map[string]any{"$class": UID(11), "NS.time": timeconv.ToCocoa(created)},
+		map[string]any{"$classname": "NSDate", "$classes": []any{"NSDate", "NSObject"}},
+	)
+	if !IsKeyedArchive(data) {
+		t.Error("IsKeyedArchive = false; want true")
+	}
+
+	got, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Object{Class: "BMAppLaunch", Fields: map[string]any{
+		"identifier": "com.apple.mobilesafari",
+		"info": map[string]any{
+			"launches": []any{"com.apple.mobilesafari", "com.apple.mobilesafari"},
+			"started":  created,
+		},
+		// A reference back to the root object is left as is
+		"parent":  UID(1),
+		"missing": nil,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unarchive = %#v; want %#v", got, want)
+	}
+
+	plain := encode(t, map[string]any{"key": "value"})
+	if IsKeyedArchive(plain) {
+		t.Error("IsKeyedArchive(plain plist) = true; want false")
+	}
+	if _, err := Unarchive(plain); !errors.Is(err, ErrNotKeyedArchive) {
+		t.Errorf("Unarchive(plain plist) error = %v; want ErrNotKeyedArchive", err)
+	}
+}
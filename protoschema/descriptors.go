@@ -0,0 +1,128 @@
+// Package protoschema decodes protobuf entry payloads with message types
+// loaded at run time, so newly reverse engineered schemas can be used
+// without recompiling anything.
+//
+// Schemas come as a compiled FileDescriptorSet, e.g. from
+//
+//	protoc --include_imports --descriptor_set_out=biome.pb *.proto
+//
+// and a type map assigning a message type to each Biome stream:
+//
+//	{"App.InFocus": "biome.AppInFocus", "*": "biome.Fallback"}
+//
+// The "*" key, if present, applies to streams without an entry of their own.
+//
+// For streams whose schema is not known yet, Message aggregates the wire
+// structure of their payloads and writes a candidate .proto definition.
+package protoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"os"
+)
+
+// AnyStream is the type map key matching streams without their own entry.
+const AnyStream = "*"
+
+// ErrNoMessageType is returned when no message type is mapped to a stream.
+var ErrNoMessageType = errors.New("no message type for stream")
+
+// ParseDescriptorSet parses a serialized FileDescriptorSet.
+func ParseDescriptorSet(data []byte) (*protoregistry.Files, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parsing descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("parsing descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// LoadDescriptorSet reads and parses the FileDescriptorSet at path.
+func LoadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDescriptorSet(data)
+}
+
+// LoadTypeMap reads a JSON object mapping stream names to fully qualified
+// message type names from path.
+func LoadTypeMap(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var types map[string]string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return nil, fmt.Errorf("parsing type map: %w", err)
+	}
+	return types, nil
+}
+
+// Decoder decodes the payloads of each stream as its mapped message type.
+type Decoder struct {
+	types map[string]protoreflect.MessageDescriptor
+}
+
+// NewDecoder resolves the message types of a stream to type name map in
+// files. Every type must be a message defined there.
+func NewDecoder(files *protoregistry.Files, types map[string]string) (*Decoder, error) {
+	d := &Decoder{types: make(map[string]protoreflect.MessageDescriptor, len(types))}
+	for stream, name := range types {
+		desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("message type %s for %s: %w", name, stream, err)
+		}
+		message, ok := desc.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s for %s is not a message type", name, stream)
+		}
+		d.types[stream] = message
+	}
+	return d, nil
+}
+
+// MessageType returns the message type payloads of stream decode as, or nil
+// if none is mapped.
+func (d *Decoder) MessageType(stream string) protoreflect.MessageDescriptor {
+	if message, ok := d.types[stream]; ok {
+		return message
+	}
+	return d.types[AnyStream]
+}
+
+// Decode parses payload as the message type of stream.
+func (d *Decoder) Decode(stream string, payload []byte) (proto.Message, error) {
+	messageType := d.MessageType(stream)
+	if messageType == nil {
+		return nil, fmt.Errorf("%w %q", ErrNoMessageType, stream)
+	}
+	message := dynamicpb.NewMessage(messageType)
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", messageType.FullName(), err)
+	}
+	return message, nil
+}
+
+// DecodeJSON parses payload as the message type of stream and returns it as
+// indented JSON, with field names from the schema.
+func (d *Decoder) DecodeJSON(stream string, payload []byte) ([]byte, error) {
+	message, err := d.Decode(stream, payload)
+	if err != nil {
+		return nil, err
+	}
+	return protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(message)
+}
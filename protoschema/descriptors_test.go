@@ -0,0 +1,96 @@
+package protoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeDescriptorSet writes a descriptor set defining test.AppInFocus
+// {string bundle_id = 1; double started = 2; bool launch = 3;}.
+func writeDescriptorSet(t *testing.T) string {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("AppInFocus"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("bundle_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("started", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+				field("launch", 3, descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+			},
+		}},
+	}}}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "test.pb")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDecoder(t *testing.T) {
+	files, err := LoadDescriptorSet(writeDescriptorSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDecoder(files, map[string]string{"App.InFocus": "test.Missing"}); err == nil {
+		t.Error("NewDecoder() with an unknown type succeeded")
+	}
+	decoder, err := NewDecoder(files, map[string]string{"App.InFocus": "test.AppInFocus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload []byte
+	payload = protowire.AppendTag(payload, 1, protowire.BytesType)
+	payload = protowire.AppendString(payload, "com.apple.mobilesafari")
+	payload = protowire.AppendTag(payload, 3, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 1)
+
+	data, err := decoder.DecodeJSON("App.InFocus", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["bundle_id"] != "com.apple.mobilesafari" || decoded["launch"] != true {
+		t.Errorf("DecodeJSON() = %s", data)
+	}
+
+	if _, err := decoder.DecodeJSON("Safari.History", payload); !errors.Is(err, ErrNoMessageType) {
+		t.Errorf("DecodeJSON() for an unmapped stream = %v; want ErrNoMessageType", err)
+	}
+	if _, err := decoder.DecodeJSON("App.InFocus", []byte{0xff}); err == nil {
+		t.Error("DecodeJSON() of a malformed payload succeeded")
+	}
+
+	// The wildcard covers every other stream
+	decoder, err = NewDecoder(files, map[string]string{AnyStream: "test.AppInFocus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoder.MessageType("Safari.History") == nil {
+		t.Error("MessageType() = nil with a wildcard mapping")
+	}
+}
@@ -0,0 +1,382 @@
+package protoschema
+
+import (
+	"fmt"
+	"github.com/bluefalconhd/segb/timeconv"
+	"google.golang.org/protobuf/encoding/protowire"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxInferDepth bounds how deep nested messages are followed.
+const maxInferDepth = 8
+
+// Message aggregates the wire structure of the payloads, or nested
+// messages, added to it.
+type Message struct {
+	Samples int // Number of messages added
+	Fields  map[protowire.Number]*Field
+	depth   int
+}
+
+// Field aggregates the occurrences of one field number of a Message.
+type Field struct {
+	Number             protowire.Number
+	Present            int                    // Messages the field occurs in
+	Count              int                    // Occurrences, more than Present if repeated
+	Repeated           bool                   // Occurs more than once in a message
+	WireTypes          map[protowire.Type]int // Occurrences by wire type
+	Min, Max           uint64                 // Range of varint values
+	Doubles            int                    // Fixed64 values that are plausible doubles
+	Floats             int                    // Fixed32 values that are plausible floats
+	FloatMin, FloatMax float64                // Range of the plausible doubles and floats
+	MinLen, MaxLen     int                    // Range of the lengths of length-delimited values
+	Timestamps         map[timeconv.Kind]int  // Doubles and varints that read as timestamps, by representation
+	Strings            int                    // Length-delimited values that are printable UTF-8
+	Messages           int                    // Length-delimited values that parse as messages
+	Empty              int                    // Empty length-delimited values
+	Nested             *Message               // Structure of the values that parse as messages
+}
+
+// NewMessage returns an empty aggregate.
+func NewMessage() *Message {
+	return &Message{Fields: make(map[protowire.Number]*Field)}
+}
+
+// Add aggregates the structure of payload. It returns an error, and adds
+// nothing, if payload is not a well-formed protobuf message.
+func (m *Message) Add(payload []byte) error {
+	if _, err := parseFields(payload); err != nil {
+		return err
+	}
+	m.add(payload)
+	return nil
+}
+
+// rawField is one field of a message on the wire.
+type rawField struct {
+	number protowire.Number
+	typ    protowire.Type
+	value  uint64 // Varint, fixed32 and fixed64 values
+	bytes  []byte // Length-delimited values
+}
+
+// parseFields splits a message into its fields.
+func parseFields(data []byte) ([]rawField, error) {
+	var fields []rawField
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		field := rawField{number: number, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			field.value, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(data)
+			field.value = uint64(v)
+		case protowire.Fixed64Type:
+			field.value, n = protowire.ConsumeFixed64(data)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			// Groups are long deprecated and do not occur in Biome data
+			return nil, fmt.Errorf("unsupported wire type %d", typ)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func (m *Message) add(data []byte) {
+	fields, _ := parseFields(data)
+	m.Samples++
+
+	seen := make(map[protowire.Number]bool)
+	for _, raw := range fields {
+		field, ok := m.Fields[raw.number]
+		if !ok {
+			field = &Field{
+				Number:     raw.number,
+				WireTypes:  make(map[protowire.Type]int),
+				Timestamps: make(map[timeconv.Kind]int),
+				Min:        math.MaxUint64,
+				FloatMin:   math.Inf(1),
+				FloatMax:   math.Inf(-1),
+				MinLen:     math.MaxInt,
+			}
+			m.Fields[raw.number] = field
+		}
+		if seen[raw.number] {
+			field.Repeated = true
+		} else {
+			field.Present++
+			seen[raw.number] = true
+		}
+		field.Count++
+		field.WireTypes[raw.typ]++
+
+		switch raw.typ {
+		case protowire.VarintType:
+			field.Min = min(field.Min, raw.value)
+			field.Max = max(field.Max, raw.value)
+			if kind, _ := timeconv.GuessInt(int64(raw.value)); kind != timeconv.None {
+				field.Timestamps[kind]++
+			}
+		case protowire.Fixed64Type:
+			if v := math.Float64frombits(raw.value); plausibleFloat(v) {
+				field.Doubles++
+				field.FloatMin, field.FloatMax = min(field.FloatMin, v), max(field.FloatMax, v)
+				if kind, _ := timeconv.GuessFloat(v); kind != timeconv.None {
+					field.Timestamps[kind]++
+				}
+			}
+		case protowire.Fixed32Type:
+			if v := float64(math.Float32frombits(uint32(raw.value))); plausibleFloat(v) {
+				field.Floats++
+				field.FloatMin, field.FloatMax = min(field.FloatMin, v), max(field.FloatMax, v)
+			}
+		case protowire.BytesType:
+			field.MinLen, field.MaxLen = min(field.MinLen, len(raw.bytes)), max(field.MaxLen, len(raw.bytes))
+			field.addBytes(raw.bytes, m.depth)
+		}
+	}
+}
+
+func (f *Field) addBytes(data []byte, depth int) {
+	if len(data) == 0 {
+		f.Empty++
+		return
+	}
+	if printable(data) {
+		f.Strings++
+	}
+	if depth+1 >= maxInferDepth {
+		return
+	}
+	if nested, err := parseFields(data); err == nil && len(nested) > 0 {
+		f.Messages++
+		if f.Nested == nil {
+			f.Nested = NewMessage()
+			f.Nested.depth = depth + 1
+		}
+		f.Nested.add(data)
+	}
+}
+
+// plausibleFloat reports whether v looks like a deliberately stored number
+// rather than other data read as a float: finite, and zero or of a sensible
+// magnitude.
+func plausibleFloat(v float64) bool {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return false
+	}
+	abs := math.Abs(v)
+	return abs == 0 || (abs > 1e-9 && abs < 1e15)
+}
+
+// printable reports whether data is valid UTF-8 without control characters
+// other than whitespace.
+func printable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// WireType returns the most common wire type of the field.
+func (f *Field) WireType() protowire.Type {
+	var best protowire.Type
+	bestCount := -1
+	for typ, count := range f.WireTypes {
+		if count > bestCount || (count == bestCount && typ < best) {
+			best, bestCount = typ, count
+		}
+	}
+	return best
+}
+
+// Type returns the protobuf type inferred for the field: a scalar type
+// name, or "message" when its values parse as nested messages.
+func (f *Field) Type() string {
+	count := f.WireTypes[f.WireType()]
+	switch f.WireType() {
+	case protowire.VarintType:
+		if f.Max <= 1 {
+			return "bool"
+		}
+		return "int64"
+	case protowire.Fixed64Type:
+		if f.Doubles == count {
+			return "double"
+		}
+		return "fixed64"
+	case protowire.Fixed32Type:
+		if f.Floats == count {
+			return "float"
+		}
+		return "fixed32"
+	default:
+		// Short text often happens to parse as a message too, so text wins
+		values := count - f.Empty
+		switch {
+		case values > 0 && f.Strings == values:
+			return "string"
+		case values > 0 && f.Messages == values:
+			return "message"
+		default:
+			return "bytes"
+		}
+	}
+}
+
+// Presence returns the fraction of messages the field occurs in.
+func (f *Field) Presence(m *Message) float64 {
+	if m.Samples == 0 {
+		return 0
+	}
+	return float64(f.Present) / float64(m.Samples)
+}
+
+// SortedFields returns the fields of m by number.
+func (m *Message) SortedFields() []*Field {
+	fields := make([]*Field, 0, len(m.Fields))
+	for _, field := range m.Fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Number < fields[j].Number })
+	return fields
+}
+
+// WriteProto writes a candidate .proto definition of m, as message name,
+// to w. Fields are named after their numbers and commented with how often
+// they occur and any hints about their meaning.
+func (m *Message) WriteProto(w io.Writer, name string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Inferred from %d payloads\n", m.Samples)
+	b.WriteString("syntax = \"proto2\";\n\n")
+	m.writeMessage(&b, name, "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (m *Message) writeMessage(b *strings.Builder, name, indent string) {
+	fmt.Fprintf(b, "%smessage %s {\n", indent, name)
+	for _, field := range m.SortedFields() {
+		label := "optional"
+		if field.Repeated {
+			label = "repeated"
+		}
+		typ := field.Type()
+		if typ == "message" {
+			typ = fmt.Sprintf("Field%d", field.Number)
+		}
+		fmt.Fprintf(b, "%s  %s %s field_%d = %d; // %s\n", indent, label, typ, field.Number, field.Number, field.hints(m))
+	}
+	for _, field := range m.SortedFields() {
+		if field.Type() == "message" {
+			b.WriteString("\n")
+			field.Nested.writeMessage(b, fmt.Sprintf("Field%d", field.Number), indent+"  ")
+		}
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// hints describes the field for the comment of its definition.
+func (f *Field) hints(m *Message) string {
+	hints := []string{fmt.Sprintf("in %.0f%% of messages", 100*f.Presence(m))}
+	if len(f.WireTypes) > 1 {
+		hints = append(hints, "mixed wire types")
+	}
+	switch f.Type() {
+	case "int64":
+		hints = append(hints, fmt.Sprintf("values %d to %d", f.Min, f.Max))
+	case "bytes":
+		if f.Messages > 0 || f.Strings > 0 {
+			hints = append(hints, fmt.Sprintf("%d text, %d message-like values", f.Strings, f.Messages))
+		}
+	}
+	if kind := f.TimestampKind(); kind != timeconv.None {
+		hints = append(hints, kind.String()+" timestamps")
+	}
+	return strings.Join(hints, ", ")
+}
+
+// TimestampKind returns the timestamp representation all values of the
+// field, as its inferred type, read as, or timeconv.None if they do not all
+// read as timestamps in one representation.
+func (f *Field) TimestampKind() timeconv.Kind {
+	var values int
+	switch f.Type() {
+	case "double":
+		values = f.WireTypes[protowire.Fixed64Type]
+	case "int64":
+		values = f.WireTypes[protowire.VarintType]
+	}
+	for kind, n := range f.Timestamps {
+		if values > 0 && n == values {
+			return kind
+		}
+	}
+	return timeconv.None
+}
+
+// Range describes the values of the field for its inferred type, e.g. "5 to
+// 9" for integers or "3 to 22 bytes" for strings, "" if there is nothing to
+// describe.
+func (f *Field) Range() string {
+	switch f.Type() {
+	case "int64", "bool":
+		return fmt.Sprintf("%d to %d", f.Min, f.Max)
+	case "double", "float":
+		return fmt.Sprintf("%g to %g", f.FloatMin, f.FloatMax)
+	case "string", "bytes", "message":
+		return fmt.Sprintf("%d to %d bytes", f.MinLen, f.MaxLen)
+	default:
+		return ""
+	}
+}
+
+// FieldStat is a field of a message or of one nested in it, as listed by
+// Message.Stats.
+type FieldStat struct {
+	Path     string // Field numbers from the outer message, e.g. "3.1"
+	Field    *Field
+	Presence float64 // Fraction of the enclosing messages the field occurs in
+}
+
+// Stats lists the fields of m and, depth first, of the messages nested in
+// it, by number.
+func (m *Message) Stats() []FieldStat {
+	var stats []FieldStat
+	m.stats("", &stats)
+	return stats
+}
+
+func (m *Message) stats(prefix string, stats *[]FieldStat) {
+	for _, field := range m.SortedFields() {
+		path := prefix + strconv.Itoa(int(field.Number))
+		*stats = append(*stats, FieldStat{Path: path, Field: field, Presence: field.Presence(m)})
+		if field.Type() == "message" {
+			field.Nested.stats(path+".", stats)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package protoschema
+
+import (
+	"github.com/bluefalconhd/segb/timeconv"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"strings"
+	"testing"
+)
+
+// samplePayload builds a message with a string (1), a Cocoa timestamp (2),
+// a nested message (3), a repeated varint (4) and, if optional is set, a
+// bool (5).
+func samplePayload(i int, optional bool) []byte {
+	var nested []byte
+	nested = protowire.AppendTag(nested, 1, protowire.VarintType)
+	nested = protowire.AppendVarint(nested, uint64(1000+i))
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "com.apple.mobilesafari")
+	b = protowire.AppendTag(b, 2, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(7.6e8+float64(i)))
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, nested)
+	for j := 0; j < 2; j++ {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(j+5))
+	}
+	if optional {
+		b = protowire.AppendTag(b, 5, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	return b
+}
+
+func TestInfer(t *testing.T) {
+	m := NewMessage()
+	for i := 0; i < 4; i++ {
+		if err := m.Add(samplePayload(i, i%2 == 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add([]byte{0x0a, 0xff}); err == nil {
+		t.Error("Add() of a malformed payload succeeded")
+	}
+	if m.Samples != 4 {
+		t.Fatalf("Samples = %d; want 4", m.Samples)
+	}
+
+	want := map[protowire.Number]string{1: "string", 2: "double", 3: "message", 4: "int64", 5: "bool"}
+	for number, typ := range want {
+		field := m.Fields[number]
+		if field == nil || field.Type() != typ {
+			t.Errorf("field %d = %+v; want %s", number, field, typ)
+		}
+	}
+	if !m.Fields[4].Repeated || m.Fields[1].Repeated {
+		t.Error("only field 4 should be repeated")
+	}
+	if p := m.Fields[5].Presence(m); p != 0.5 {
+		t.Errorf("field 5 presence = %v; want 0.5", p)
+	}
+	if nested := m.Fields[3].Nested; nested == nil || nested.Fields[1].Min != 1000 || nested.Fields[1].Max != 1003 {
+		t.Errorf("field 3 nested = %+v", nested)
+	}
+
+	stats := m.Stats()
+	paths := make([]string, len(stats))
+	for i, stat := range stats {
+		paths[i] = stat.Path
+	}
+	if got := strings.Join(paths, " "); got != "1 2 3 3.1 4 5" {
+		t.Errorf("Stats() paths = %s; want 1 2 3 3.1 4 5", got)
+	}
+	if r := m.Fields[4].Range(); r != "5 to 6" {
+		t.Errorf("field 4 Range() = %q; want 5 to 6", r)
+	}
+	if r := m.Fields[1].Range(); r != "22 to 22 bytes" {
+		t.Errorf("field 1 Range() = %q; want 22 to 22 bytes", r)
+	}
+
+	var proto strings.Builder
+	if err := m.WriteProto(&proto, "AppInFocus"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"message AppInFocus {",
+		"optional string field_1 = 1;",
+		"optional double field_2 = 2; // in 100% of messages, Cocoa timestamps",
+		"optional Field3 field_3 = 3;",
+		"repeated int64 field_4 = 4;",
+		"optional bool field_5 = 5; // in 50% of messages",
+		"  message Field3 {",
+	} {
+		if !strings.Contains(proto.String(), line) {
+			t.Errorf("WriteProto() is missing %q:\n%s", line, proto.String())
+		}
+	}
+}
+
+func TestInferTimestamps(t *testing.T) {
+	m := NewMessage()
+	for i := 0; i < 3; i++ {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(1709294400000+i)) // Unix milliseconds in 2024
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(i))
+		if err := m.Add(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if kind := m.Fields[1].TimestampKind(); kind != timeconv.UnixMillis {
+		t.Errorf("field 1 TimestampKind() = %v; want %v", kind, timeconv.UnixMillis)
+	}
+	if kind := m.Fields[2].TimestampKind(); kind != timeconv.None {
+		t.Errorf("field 2 TimestampKind() = %v; want none", kind)
+	}
+}
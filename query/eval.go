@@ -0,0 +1,280 @@
+package query
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type evalContext struct {
+	vars map[string]any
+}
+
+type expr interface {
+	eval(ctx *evalContext, input any) any
+}
+
+type (
+	identity struct{}
+	literal  struct{ v any }
+	variable string
+	array    []expr
+	index    struct{ x, key expr } // x[key], x.key
+	pipe     struct{ x, y expr }
+	andExpr  struct{ x, y expr }
+	orExpr   struct{ x, y expr }
+	compare  struct {
+		op   string
+		x, y expr
+	}
+	call struct {
+		name string
+		args []expr
+		re   *regexp.Regexp // Compiled at parse time for test with a literal
+	}
+)
+
+func (identity) eval(_ *evalContext, input any) any { return input }
+
+func (e literal) eval(*evalContext, any) any { return e.v }
+
+func (e variable) eval(ctx *evalContext, _ any) any { return ctx.vars[string(e)] }
+
+func (e array) eval(ctx *evalContext, input any) any {
+	items := make([]any, len(e))
+	for i, item := range e {
+		items[i] = item.eval(ctx, input)
+	}
+	return items
+}
+
+func (e index) eval(ctx *evalContext, input any) any {
+	x := e.x.eval(ctx, input)
+	switch key := e.key.eval(ctx, input).(type) {
+	case string:
+		if object, ok := x.(map[string]any); ok {
+			return object[key]
+		}
+	case float64:
+		if list, ok := x.([]any); ok {
+			i := int(key)
+			if i < 0 {
+				i += len(list)
+			}
+			if i >= 0 && i < len(list) && float64(int(key)) == key {
+				return list[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (e pipe) eval(ctx *evalContext, input any) any {
+	return e.y.eval(ctx, e.x.eval(ctx, input))
+}
+
+func (e andExpr) eval(ctx *evalContext, input any) any {
+	return truth(e.x.eval(ctx, input)) && truth(e.y.eval(ctx, input))
+}
+
+func (e orExpr) eval(ctx *evalContext, input any) any {
+	return truth(e.x.eval(ctx, input)) || truth(e.y.eval(ctx, input))
+}
+
+func (e compare) eval(ctx *evalContext, input any) any {
+	x, y := e.x.eval(ctx, input), e.y.eval(ctx, input)
+	switch e.op {
+	case "==":
+		return reflect.DeepEqual(x, y)
+	case "!=":
+		return !reflect.DeepEqual(x, y)
+	}
+	var c int
+	switch x := x.(type) {
+	case float64:
+		y, ok := y.(float64)
+		if !ok {
+			return false
+		}
+		c = cmpFloat(x, y)
+	case string:
+		y, ok := y.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(x, y)
+	default:
+		return false
+	}
+	switch e.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// builtins are the functions call knows, by name, with their number of
+// arguments.
+var builtins = map[string]int{
+	"length": 0, "not": 0, "keys": 0, "ascii_downcase": 0, "ascii_upcase": 0, "tostring": 0, "tonumber": 0,
+	"has": 1, "contains": 1, "startswith": 1, "endswith": 1, "test": 1,
+}
+
+func (e call) eval(ctx *evalContext, input any) any {
+	args := make([]any, len(e.args))
+	for i, arg := range e.args {
+		args[i] = arg.eval(ctx, input)
+	}
+	s, isString := input.(string)
+	switch e.name {
+	case "length":
+		switch v := input.(type) {
+		case string:
+			return float64(len([]rune(v)))
+		case []any:
+			return float64(len(v))
+		case map[string]any:
+			return float64(len(v))
+		case float64:
+			if v < 0 {
+				return -v
+			}
+			return v
+		}
+		return float64(0)
+	case "not":
+		return !truth(input)
+	case "keys":
+		object, ok := input.(map[string]any)
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		list := make([]any, len(keys))
+		for i, key := range keys {
+			list[i] = key
+		}
+		return list
+	case "ascii_downcase", "ascii_upcase":
+		if !isString {
+			return nil
+		}
+		if e.name == "ascii_downcase" {
+			return strings.ToLower(s)
+		}
+		return strings.ToUpper(s)
+	case "tostring":
+		if isString {
+			return s
+		}
+		data, _ := json.Marshal(input)
+		return string(data)
+	case "tonumber":
+		if n, ok := input.(float64); ok {
+			return n
+		}
+		if n, err := strconv.ParseFloat(s, 64); isString && err == nil {
+			return n
+		}
+		return nil
+	case "has":
+		switch key := args[0].(type) {
+		case string:
+			object, ok := input.(map[string]any)
+			_, has := object[key]
+			return ok && has
+		case float64:
+			list, ok := input.([]any)
+			return ok && key >= 0 && int(key) < len(list)
+		}
+		return false
+	case "contains":
+		return contains(input, args[0])
+	case "startswith", "endswith":
+		prefix, ok := args[0].(string)
+		if !isString || !ok {
+			return false
+		}
+		if e.name == "startswith" {
+			return strings.HasPrefix(s, prefix)
+		}
+		return strings.HasSuffix(s, prefix)
+	case "test":
+		re := e.re
+		if re == nil {
+			pattern, ok := args[0].(string)
+			if !ok {
+				return false
+			}
+			var err error
+			if re, err = regexp.Compile(pattern); err != nil {
+				return false
+			}
+		}
+		return isString && re.MatchString(s)
+	}
+	return nil
+}
+
+// contains is jq's contains: substrings, and recursively, elements of
+// arrays and fields of objects.
+func contains(x, y any) bool {
+	switch x := x.(type) {
+	case string:
+		y, ok := y.(string)
+		return ok && strings.Contains(x, y)
+	case []any:
+		y, ok := y.([]any)
+		if !ok {
+			return false
+		}
+		for _, want := range y {
+			found := false
+			for _, have := range x {
+				if contains(have, want) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := y.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, want := range y {
+			have, ok := x[key]
+			if !ok || !contains(have, want) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(x, y)
+}
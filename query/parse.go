@@ -0,0 +1,401 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kinds of lexical tokens
+const (
+	tEOF = iota
+	tIdent
+	tVariable // $name
+	tString   // "..."
+	tNumber
+	tPunct
+)
+
+type token struct {
+	kind int
+	text string // Identifier, variable name, unquoted string or punctuation
+	num  float64
+	pos  int
+}
+
+// lexer splits an expression into tokens.
+type lexer struct {
+	src    string
+	pos    int
+	peeked *token
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+// twoCharPuncts are the punctuation tokens of two characters.
+var twoCharPuncts = []string{"==", "!=", "<=", ">="}
+
+func (l *lexer) next() (token, error) {
+	if l.peeked != nil {
+		t := *l.peeked
+		l.peeked = nil
+		return t, nil
+	}
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos == len(l.src) {
+		return token{kind: tEOF, pos: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case isIdentStart(c) || c == '$':
+		l.pos++
+		for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		if c == '$' {
+			if l.pos == start+1 {
+				return token{}, fmt.Errorf("at %d: $ without a variable name", start)
+			}
+			return token{kind: tVariable, text: l.src[start+1 : l.pos], pos: start}, nil
+		}
+		return token{kind: tIdent, text: l.src[start:l.pos], pos: start}, nil
+	case isDigit(c) || c == '-' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]):
+		l.pos++
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || strings.ContainsRune(".eE+-", rune(l.src[l.pos]))) {
+			l.pos++
+		}
+		n, err := strconv.ParseFloat(l.src[start:l.pos], 64)
+		if err != nil {
+			return token{}, fmt.Errorf("at %d: invalid number %s", start, l.src[start:l.pos])
+		}
+		return token{kind: tNumber, num: n, pos: start}, nil
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("at %d: unterminated string", start)
+		}
+		l.pos++
+		text, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return token{}, fmt.Errorf("at %d: invalid string %s", start, l.src[start:l.pos])
+		}
+		return token{kind: tString, text: text, pos: start}, nil
+	}
+	for _, punct := range twoCharPuncts {
+		if strings.HasPrefix(l.src[l.pos:], punct) {
+			l.pos += 2
+			return token{kind: tPunct, text: punct, pos: start}, nil
+		}
+	}
+	if strings.IndexByte(".[](),|;<>", c) >= 0 {
+		l.pos++
+		return token{kind: tPunct, text: string(c), pos: start}, nil
+	}
+	return token{}, fmt.Errorf("at %d: unexpected %q", start, c)
+}
+
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		t, err := l.next()
+		if err != nil {
+			return t, err
+		}
+		l.peeked = &t
+	}
+	return *l.peeked, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser builds the expression tree by recursive descent, lowest precedence
+// first: pipes, or, and, comparisons, then paths.
+type parser struct {
+	lex *lexer
+}
+
+func (p *parser) parse() (expr, error) {
+	e, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind != tEOF {
+		return nil, fmt.Errorf("at %d: unexpected %s", t.pos, describe(t))
+	}
+	return e, nil
+}
+
+// accept consumes the next token if it is the given punctuation or keyword.
+func (p *parser) accept(text string) (bool, error) {
+	t, err := p.lex.peek()
+	if err != nil {
+		return false, err
+	}
+	if (t.kind == tPunct || t.kind == tIdent) && t.text == text {
+		p.lex.next()
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *parser) expect(text string) error {
+	ok, err := p.accept(text)
+	if err != nil || ok {
+		return err
+	}
+	t, _ := p.lex.peek()
+	return fmt.Errorf("at %d: expected %s, found %s", t.pos, text, describe(t))
+}
+
+// binary parses operands joined by a left-associative operator.
+func (p *parser) binary(op string, operand func() (expr, error), join func(x, y expr) expr) (expr, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := p.accept(op)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return x, nil
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = join(x, y)
+	}
+}
+
+func (p *parser) pipe() (expr, error) {
+	return p.binary("|", p.or, func(x, y expr) expr { return pipe{x, y} })
+}
+
+func (p *parser) or() (expr, error) {
+	return p.binary("or", p.and, func(x, y expr) expr { return orExpr{x, y} })
+}
+
+func (p *parser) and() (expr, error) {
+	return p.binary("and", p.comparison, func(x, y expr) expr { return andExpr{x, y} })
+}
+
+func (p *parser) comparison() (expr, error) {
+	x, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.lex.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if t.kind != tPunct {
+			return x, nil
+		}
+		p.lex.next()
+		y, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		return compare{op: t.text, x: x, y: y}, nil
+	}
+	return x, nil
+}
+
+// postfix parses a primary expression followed by field and element
+// accesses: .a, ."a", [0], ["a"].
+func (p *parser) postfix() (expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, err := p.lex.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.kind == tPunct && t.text == ".":
+			p.lex.next()
+			key, err := p.field()
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				return nil, fmt.Errorf("at %d: expected a field name after .", t.pos)
+			}
+			x = index{x, key}
+		case t.kind == tPunct && t.text == "[":
+			p.lex.next()
+			key, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = index{x, key}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// field parses the name after a dot, or returns nil if there is none.
+func (p *parser) field() (expr, error) {
+	t, err := p.lex.peek()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind == tIdent || t.kind == tString {
+		p.lex.next()
+		return literal{t.text}, nil
+	}
+	return nil, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case tNumber:
+		return literal{t.num}, nil
+	case tString:
+		return literal{t.text}, nil
+	case tVariable:
+		return variable(t.text), nil
+	case tIdent:
+		switch t.text {
+		case "true", "false":
+			return literal{t.text == "true"}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		return p.call(t)
+	case tPunct:
+		switch t.text {
+		case ".":
+			// A path: ".", ".a" or ".[0]", the brackets left to postfix
+			key, err := p.field()
+			if err != nil || key == nil {
+				return identity{}, err
+			}
+			return index{identity{}, key}, nil
+		case "(":
+			x, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			var items array
+			for {
+				if ok, err := p.accept("]"); err != nil || ok {
+					return items, err
+				}
+				if len(items) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				item, err := p.or()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+		}
+	}
+	return nil, fmt.Errorf("at %d: unexpected %s", t.pos, describe(t))
+}
+
+// call parses a call of a builtin, whose name is t.
+func (p *parser) call(t token) (expr, error) {
+	arity, ok := builtins[t.text]
+	if !ok {
+		return nil, fmt.Errorf("at %d: unknown function %s", t.pos, t.text)
+	}
+	c := call{name: t.text}
+	if arity > 0 {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for i := 0; i < arity; i++ {
+			if i > 0 {
+				if err := p.expect(";"); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			c.args = append(c.args, arg)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if pattern, ok := c.literalArg(); ok && c.name == "test" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("at %d: test: %w", t.pos, err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+// literalArg returns the first argument of a call, if it is a literal
+// string.
+func (c call) literalArg() (string, bool) {
+	if len(c.args) == 0 {
+		return "", false
+	}
+	lit, ok := c.args[0].(literal)
+	if !ok {
+		return "", false
+	}
+	s, ok := lit.v.(string)
+	return s, ok
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tEOF:
+		return "end of expression"
+	case tNumber:
+		return strconv.FormatFloat(t.num, 'g', -1, 64)
+	case tString:
+		return strconv.Quote(t.text)
+	case tVariable:
+		return "$" + t.text
+	}
+	return t.text
+}
@@ -0,0 +1,93 @@
+// Package query evaluates small jq-like expressions against JSON values,
+// such as the decoded payloads of entries, to filter them on the command
+// line without piping everything through jq.
+//
+// The language is a subset of jq's:
+//
+//	.                      the input
+//	.a.b, .["a b"], .[0]   object fields and array elements; null if absent
+//	$name                  a variable, e.g. $entry for the record of the entry
+//	"text", 12.5, true, false, null, [A, B]
+//	== != < <= > >=        comparisons; < and friends on numbers and strings
+//	and, or                boolean operators; only false and null are false
+//	A | B                  B evaluated with A as its input
+//	(A)                    grouping
+//
+// and the builtins length, not, keys, ascii_downcase, ascii_upcase,
+// tostring, tonumber, has(KEY), contains(X), startswith(S), endswith(S)
+// and test(REGEX), which, as in jq, apply to their input:
+//
+//	.bundle_id == "com.apple.mobilesafari"
+//	.title | test("(?i)invoice") and $entry.state == "written"
+package query
+
+import (
+	"encoding/json"
+)
+
+// Query is a parsed expression.
+type Query struct {
+	src  string
+	root expr
+}
+
+// Parse parses an expression.
+func Parse(src string) (*Query, error) {
+	p := &parser{lex: newLexer(src)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{src: src, root: root}, nil
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+// Eval evaluates the expression against input, with the given variables.
+// Input and variables may be any value encoding/json serializes; results
+// are as encoding/json decodes into an any, with float64 numbers.
+func (q *Query) Eval(input any, vars map[string]any) (any, error) {
+	ctx := &evalContext{vars: make(map[string]any, len(vars))}
+	for name, v := range vars {
+		normalized, err := normalize(v)
+		if err != nil {
+			return nil, err
+		}
+		ctx.vars[name] = normalized
+	}
+	normalized, err := normalize(input)
+	if err != nil {
+		return nil, err
+	}
+	return q.root.eval(ctx, normalized), nil
+}
+
+// Match reports whether the expression evaluates to true against input,
+// i.e. to anything but false and null.
+func (q *Query) Match(input any, vars map[string]any) (bool, error) {
+	v, err := q.Eval(input, vars)
+	return truth(v), err
+}
+
+// normalize turns a value into what encoding/json decodes it to.
+func normalize(v any) (any, error) {
+	switch v.(type) {
+	case nil, bool, float64, string:
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// truth reports whether a value counts as true: all but false and null do.
+func truth(v any) bool {
+	b, isBool := v.(bool)
+	return v != nil && (!isBool || b)
+}
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testInput = map[string]any{
+	"bundle_id": "com.apple.mobilesafari",
+	"title":     "Invoice 42",
+	"count":     3,
+	"tags":      []string{"web", "browser"},
+	"nested":    map[string]any{"a b": map[string]any{"c": true}},
+	"missing":   nil,
+}
+
+var testVars = map[string]any{"entry": map[string]any{"state": "written", "size": 120}}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{`.bundle_id == "com.apple.mobilesafari"`, true},
+		{`.bundle_id != "com.apple.mobilesafari"`, false},
+		{`.count`, 3.0},
+		{`.count >= 3 and .count < 4`, true},
+		{`.count > 3 or .title == "Invoice 42"`, true},
+		{`.tags[1]`, "browser"},
+		{`.tags[-1]`, "browser"},
+		{`.tags[5]`, nil},
+		{`.nested["a b"].c`, true},
+		{`.nested."a b".c`, true},
+		{`.nope.deeper`, nil},
+		{`.title | test("(?i)invoice")`, true},
+		{`.title | startswith("Inv") and endswith("42")`, true},
+		{`.tags | contains(["web"])`, true},
+		{`.tags | length`, 2.0},
+		{`.bundle_id | ascii_upcase`, "COM.APPLE.MOBILESAFARI"},
+		{`has("missing") and (.missing | not)`, true},
+		{`keys | length`, 6.0},
+		{`$entry.state == "written" and $entry.size > 100`, true},
+		{`$nope`, nil},
+		{`"12" | tonumber == 12`, true},
+		{`.count | tostring`, "3"},
+		{`[.count, .missing]`, []any{3.0, nil}},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%s) error = %v", tt.expr, err)
+			continue
+		}
+		got, err := q.Eval(testInput, testVars)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, %v; want %#v", tt.expr, got, err, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	q, err := Parse(`.title`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := q.Match(testInput, nil); !ok {
+		t.Error("Match(string) = false; want true")
+	}
+	if ok, _ := q.Match(nil, nil); ok {
+		t.Error("Match(null input) = true; want false")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`.a ==`,
+		`.a ==  == 1`,
+		`(.a`,
+		`.a]`,
+		`"unterminated`,
+		`nosuchfunction`,
+		`test`,
+		`test("[")`,
+		`startswith("a"; "b")`,
+		`$`,
+		`.a # comment`,
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%s) did not fail", expr)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package segb
+
+import (
+	"crypto/rand"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// Redaction is what Segb.Redact puts in place of a payload.
+type Redaction int
+
+const (
+	RedactPlaceholder Redaction = iota // RedactedText repeated, to tell redacted payloads apart in a hexdump
+	RedactZero                         // Zero bytes
+	RedactRandom                       // Random bytes, as Anonymize does
+)
+
+// RedactedText is the placeholder RedactPlaceholder fills payloads with.
+const RedactedText = "REDACTED"
+
+// Redact replaces the payload of the entry at index i of s.Entries with
+// placeholder bytes of the same stored length and updates its checksum to
+// match. The entry keeps its state, creation time and size, so a Segb
+// decoded WithRoundTrip encodes to a copy of the file that shows its
+// structure without exposing the payload, and still passes its CRC checks.
+// A compressed payload is replaced as stored and reads as uncompressed.
+func (s *Segb) Redact(i int, r Redaction) error {
+	if i < 0 || i >= len(s.Entries) {
+		return fmt.Errorf("entry %d out of range, %d entries", i, len(s.Entries))
+	}
+	entry := &s.Entries[i]
+	payload := make([]byte, len(entry.Stored()))
+	switch r {
+	case RedactPlaceholder:
+		for j := range payload {
+			payload[j] = RedactedText[j%len(RedactedText)]
+		}
+	case RedactRandom:
+		rand.Read(payload)
+	}
+	entry.Data, entry.Raw = payload, nil
+	entry.Compression = CompressionNone
+	entry.ContentType = Classify(payload)
+	entry.Checksum = crc32.ChecksumIEEE(payload)
+	entry.CRCValid, entry.CRCRepaired = true, false
+	return nil
+}
+
+// Anonymize replaces every payload with random bytes of the same length, as
+// Redact does with RedactRandom, and shifts the creation time of the file,
+// of every entry and of every trailer record by shift. Sizes, states and the
+// order of the entries are kept, so a Segb decoded WithRoundTrip encodes to
+// a file with the structure of the original and none of its content, e.g.
+// to attach to a bug report. Pick the shift at random and keep it secret, as
+// it gives the original times back.
+func (s *Segb) Anonymize(shift time.Duration) {
+	for i := range s.Entries {
+		s.Redact(i, RedactRandom)
+		if !s.Entries[i].Created.IsZero() {
+			s.Entries[i].Created = s.Entries[i].Created.Add(shift)
+		}
+	}
+	if !s.Created.IsZero() {
+		s.Created = s.Created.Add(shift)
+	}
+	for i := range s.Records {
+		if s.Records[i].CreationTimestamp != 0 {
+			s.Records[i].CreationTimestamp += shift.Seconds()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package segb
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), zlibPayload("The misfits."), []byte("The rebels.")}
+
+	for name, file := range map[string][]byte{
+		"v1": v1File(8, payloads...),
+		"v2": v2File(payloads...),
+	} {
+		decoded, err := DecodeBytes(file, WithRoundTrip())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.Redact(0, RedactPlaceholder); err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.Redact(1, RedactZero); err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.Redact(3, RedactZero); err == nil {
+			t.Errorf("%s: Redact(3) error = nil; want out of range", name)
+		}
+		var buf bytes.Buffer
+		if err := decoded.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != len(file) {
+			t.Errorf("%s: redacted file is %d bytes; want %d", name, buf.Len(), len(file))
+		}
+
+		redacted, err := DecodeBytes(buf.Bytes(), WithCRCPolicy(CRCStrict))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := [][]byte{
+			[]byte("REDACTEDREDACTEDREDACTEDR"),
+			make([]byte, len(payloads[1])),
+			payloads[2],
+		}
+		for i, entry := range redacted.Entries {
+			if !bytes.Equal(entry.Data, want[i]) {
+				t.Errorf("%s: entry %d = %q; want %q", name, i, entry.Data, want[i])
+			}
+		}
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	const shift = 400*24*time.Hour + 1234567*time.Microsecond
+	for _, name := range []string{"segb_version1.bin", "segb_version2.bin"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original, err := DecodeBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBytes(data, WithRoundTrip())
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded.Anonymize(shift)
+		var buf bytes.Buffer
+		if err := decoded.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		anonymized, err := DecodeBytes(buf.Bytes(), WithCRCPolicy(CRCStrict))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if buf.Len() != len(data) || len(anonymized.Entries) != len(original.Entries) {
+			t.Fatalf("%s: anonymized to %d bytes, %d entries; want %d, %d", name, buf.Len(), len(anonymized.Entries), len(data), len(original.Entries))
+		}
+		// v1 files have no creation time of their own
+		if anonymized.Version == SEGB_VERSION_2 && !anonymized.Created.Equal(original.Created.Add(shift)) {
+			t.Errorf("%s: Created = %v; want %v", name, anonymized.Created, original.Created.Add(shift))
+		}
+		for i, entry := range anonymized.Entries {
+			want := original.Entries[i]
+			if len(entry.Data) != len(want.Data) || entry.State != want.State || !entry.Created.Equal(want.Created.Add(shift)) {
+				t.Errorf("%s: entry %d = %d bytes, %v, %v; want %d bytes, %v, %v", name, i,
+					len(entry.Data), entry.State, entry.Created, len(want.Data), want.State, want.Created.Add(shift))
+			}
+			if bytes.Equal(entry.Data, want.Data) {
+				t.Errorf("%s: entry %d payload was kept", name, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package segb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb/timeconv"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"io"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Sniffer reports whether a stream, positioned at its start, holds a SEGB
+// file of the version it is registered for. It may leave the stream at any
+// offset.
+type Sniffer func(stream io.ReadSeeker) (bool, error)
+
+// Reader decodes a SEGB file of the version it is registered for from a
+// stream positioned at its start. Decode applies the CRC policy, payload
+// decompression and sorting to the result, so a reader only has to fill in
+// the header fields and the entries, with their payloads as stored.
+type Reader func(stream io.ReadSeeker, opts ReadOptions) (Segb, error)
+
+// ReadOptions are the decode options that concern a Reader.
+type ReadOptions struct {
+	Alignment    int   // Entry alignment, 0 for the format default
+	Lenient      bool  // Skip malformed entries, recording them in Segb.Errors
+	MaxEntrySize int64 // Largest entry payload accepted, 0 for no limit
+}
+
+// ErrAmbiguousVersion is matched by the AmbiguousVersionError DetectVersion
+// returns for files that look like several versions.
+var ErrAmbiguousVersion = errors.New("ambiguous version")
+
+// AmbiguousVersionError is returned by DetectVersion for a file the sniffers
+// of several versions match, such as a v2 file with "SEGB" in the payload
+// at 0x34 that also passes the v1 checks.
+type AmbiguousVersionError struct {
+	Versions []SegbVersion // In the order they are tried
+}
+
+func (e *AmbiguousVersionError) Error() string {
+	names := make([]string, len(e.Versions))
+	for i, v := range e.Versions {
+		names[i] = v.String()
+	}
+	return fmt.Sprintf("%v: file matches %s", ErrAmbiguousVersion, strings.Join(names, " and "))
+}
+
+func (e *AmbiguousVersionError) Unwrap() error {
+	return ErrAmbiguousVersion
+}
+
+// versionHandler sniffs and decodes one SEGB version.
+type versionHandler struct {
+	version SegbVersion
+	sniff   Sniffer
+	read    func(stream io.ReadSeeker, cfg decodeConfig) (Segb, error)
+}
+
+var (
+	handlersMu sync.RWMutex
+	handlers   []versionHandler // In the order they are tried
+)
+
+func init() {
+	// v2 first: its magic is at the start, v1's at 0x34 could be payload
+	register(SEGB_VERSION_2, sniffV2, decodeV2)
+	register(SEGB_VERSION_1, sniffV1, func(stream io.ReadSeeker, cfg decodeConfig) (Segb, error) {
+		decoded, err := decodeV1(stream, cfg)
+		if err == nil && cfg.residual {
+			err = decoded.recoverResidual(stream, cfg)
+		}
+		return decoded, err
+	})
+}
+
+// Register makes Decode and DetectVersion support a SEGB version, such as
+// one observed in a beta release, without changes to this package. Handlers
+// are tried in the order they were registered, after the built-in v2 and
+// v1 ones; a file several sniffers match is ambiguous, see
+// AmbiguousVersionError.
+// Register panics if the version is already registered or either function
+// is nil.
+func Register(version SegbVersion, sniffer Sniffer, reader Reader) {
+	if sniffer == nil || reader == nil {
+		panic("segb: Register with a nil sniffer or reader")
+	}
+	register(version, sniffer, func(stream io.ReadSeeker, cfg decodeConfig) (Segb, error) {
+		return reader(stream, ReadOptions{Alignment: cfg.alignment, Lenient: cfg.lenient, MaxEntrySize: cfg.maxEntrySize})
+	})
+}
+
+func register(version SegbVersion, sniffer Sniffer, read func(io.ReadSeeker, decodeConfig) (Segb, error)) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	if version == NONE {
+		panic("segb: Register of version none")
+	}
+	for _, h := range handlers {
+		if h.version == version {
+			panic(fmt.Sprintf("segb: Register called twice for version %v", version))
+		}
+	}
+	handlers = append(handlers, versionHandler{version: version, sniff: sniffer, read: read})
+}
+
+// handlerFor returns the handler of a version.
+func handlerFor(version SegbVersion) (versionHandler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	for _, h := range handlers {
+		if h.version == version {
+			return h, true
+		}
+	}
+	return versionHandler{}, false
+}
+
+// readAt reads len(buf) bytes at offset. It returns false, without an
+// error, if the stream ends first.
+func readAt(stream io.ReadSeeker, offset int64, buf []byte) (bool, error) {
+	if _, err := stream.Seek(offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// sniffV2 matches files with the "SEGB" magic at the start, a creation time
+// between 2001 and 2100 and an entry count whose trailer fits in the file.
+func sniffV2(stream io.ReadSeeker) (bool, error) {
+	header := make([]byte, binary.Size(v2.Header{}))
+	if ok, err := readAt(stream, 0, header); !ok || err != nil {
+		return false, err
+	}
+	if string(header[:4]) != v2.FileMagic {
+		return false, nil
+	}
+	size, err := stream.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+	count := int64(int32(binary.LittleEndian.Uint32(header[4:])))
+	created := math.Float64frombits(binary.LittleEndian.Uint64(header[8:]))
+	return count >= 0 && count*v2.TrailerRecordSize <= size-int64(len(header)) && timeconv.PlausibleCocoa(created), nil
+}
+
+// sniffV1 matches files with the "SEGB" magic at 0x34 and an end of data
+// offset past the header. The end of data may lie past the end of the file,
+// which is then truncated.
+func sniffV1(stream io.ReadSeeker) (bool, error) {
+	header := make([]byte, binary.Size(v1.Header{}))
+	if ok, err := readAt(stream, 0, header); !ok || err != nil {
+		return false, err
+	}
+	if string(header[0x34:]) != v1.FileMagic {
+		return false, nil
+	}
+	end := int64(int32(binary.LittleEndian.Uint32(header)))
+	return end >= int64(len(header)), nil
+}
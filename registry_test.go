@@ -0,0 +1,76 @@
+package segb
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sync"
+	"testing"
+)
+
+// testVersion3 is a made-up format: "SGB3" and a single payload.
+const testVersion3 SegbVersion = 3
+
+var registerV3 sync.Once
+
+func registerTestVersion3() {
+	registerV3.Do(func() {
+		Register(testVersion3,
+			func(stream io.ReadSeeker) (bool, error) {
+				magic := make([]byte, 4)
+				if _, err := io.ReadFull(stream, magic); err != nil {
+					return false, nil
+				}
+				return string(magic) == "SGB3", nil
+			},
+			func(stream io.ReadSeeker, opts ReadOptions) (Segb, error) {
+				data, err := io.ReadAll(stream)
+				if err != nil {
+					return Segb{}, err
+				}
+				payload := data[4:]
+				return Segb{Version: testVersion3, Entries: []Entry{{
+					State:    EntryStateWritten,
+					Data:     payload,
+					Checksum: crc32.ChecksumIEEE(payload),
+					Offset:   4,
+					Size:     int64(len(payload)),
+				}}}, nil
+			})
+	})
+}
+
+func TestRegister(t *testing.T) {
+	registerTestVersion3()
+
+	file := []byte("SGB3" + expectedEntryData[0])
+	if v, err := DetectVersion(bytes.NewReader(file)); err != nil || v != testVersion3 {
+		t.Fatalf("DetectVersion() = %v, %v; want v3", v, err)
+	}
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version.String() != "v3" || len(decoded.Entries) != 1 {
+		t.Fatalf("Decode() = %v with %d entries; want v3 with 1", decoded.Version, len(decoded.Entries))
+	}
+	entry := decoded.Entries[0]
+	if string(entry.Data) != expectedEntryData[0] || !entry.CRCValid || entry.ContentType != ContentText {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	// Built-in versions are still detected first
+	if v, err := DetectVersion(bytes.NewReader(v2File([]byte("x")))); err != nil || v != SEGB_VERSION_2 {
+		t.Errorf("DetectVersion(v2) = %v, %v; want v2", v, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register(v2) did not panic")
+		}
+	}()
+	Register(SEGB_VERSION_2, func(io.ReadSeeker) (bool, error) { return false, nil }, func(io.ReadSeeker, ReadOptions) (Segb, error) {
+		return Segb{}, errors.New("unreachable")
+	})
+}
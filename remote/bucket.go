@@ -0,0 +1,274 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Bucket is a bucket of object storage, S3 or GCS, as a read-only fs.FS, so
+// that an evidence collection kept in the cloud can be loaded in place with
+// segb.LoadFS. Object keys are split at "/" into directories. Files are
+// read with ranged GETs through a File, with its read-ahead cache, and
+// implement io.ReaderAt and io.Seeker.
+type Bucket struct {
+	ctx    context.Context
+	store  store
+	client *http.Client
+	header http.Header
+	opts   []Option // For every File opened, the client and headers above included
+}
+
+// store is the API of an object storage service.
+type store interface {
+	// objectURL returns the URL of the object with the given key.
+	objectURL(key string) string
+	// listURL returns the URL of the page of objects under prefix that
+	// page, empty for the first one, names, with keys cut at the next "/"
+	// after prefix.
+	listURL(prefix, page string) string
+	// parseList parses a page of objects.
+	parseList(body io.Reader) (listing, error)
+}
+
+// listing is a page of objects.
+type listing struct {
+	objects  []objectInfo
+	prefixes []string // Common prefixes of the keys cut at "/", with the "/"
+	next     string   // Token of the next page, empty for the last one
+}
+
+// newBucket returns a Bucket of store. wrap, if set, wraps the transport of
+// the client given with WithClient, e.g. to sign requests.
+func newBucket(ctx context.Context, store store, opts []Option, wrap func(http.RoundTripper) http.RoundTripper) *Bucket {
+	// Options are made for a File, collect the client and headers they set
+	settings := &File{client: http.DefaultClient, header: make(http.Header)}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	client := settings.client
+	if wrap != nil {
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		wrapped := *client
+		wrapped.Transport = wrap(transport)
+		client = &wrapped
+	}
+	return &Bucket{
+		ctx:    ctx,
+		store:  store,
+		client: client,
+		header: settings.header,
+		opts:   append(slices.Clip(opts), WithClient(client)),
+	}
+}
+
+// OpenObject opens the object with the given key as a File, without
+// checking first that it exists as Open does.
+func (b *Bucket) OpenObject(key string) (*File, error) {
+	return Open(b.ctx, b.store.objectURL(key), b.opts...)
+}
+
+// Open implements fs.FS.
+func (b *Bucket) Open(name string) (fs.File, error) {
+	info, err := b.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.Unwrap(err)}
+	}
+	if info.IsDir() {
+		return &dirFile{bucket: b, name: name, info: info}, nil
+	}
+	file, err := b.OpenObject(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &objectFile{File: file, info: info}, nil
+}
+
+// Stat implements fs.StatFS.
+func (b *Bucket) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return objectInfo{key: ".", dir: true}, nil
+	}
+
+	// The key itself is an object, keys below it make a directory
+	var found fs.FileInfo
+	err := b.list(name, func(page listing) bool {
+		for _, object := range page.objects {
+			if object.key == name {
+				found = object
+				return false
+			}
+		}
+		if slices.Contains(page.prefixes, name+"/") {
+			found = objectInfo{key: name, dir: true}
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if found == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return found, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (b *Bucket) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+
+	var entries []fs.DirEntry
+	err := b.list(prefix, func(page listing) bool {
+		for _, object := range page.objects {
+			// Skip the empty objects some tools create as directory markers,
+			// and keys that make no valid path
+			if fs.ValidPath(object.key) && object.key != strings.TrimSuffix(prefix, "/") {
+				entries = append(entries, object)
+			}
+		}
+		for _, dir := range page.prefixes {
+			if key := strings.TrimSuffix(dir, "/"); fs.ValidPath(key) {
+				entries = append(entries, objectInfo{key: key, dir: true})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	// Object storage has no empty directories
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// list calls fn with each page of objects under prefix until fn returns
+// false.
+func (b *Bucket) list(prefix string, fn func(listing) bool) error {
+	page := ""
+	for {
+		url := b.store.listURL(prefix, page)
+		req, err := http.NewRequestWithContext(b.ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		for key, values := range b.header {
+			req.Header[key] = values
+		}
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return err
+		}
+		var result listing
+		if resp.StatusCode == http.StatusOK {
+			result, err = b.store.parseList(resp.Body)
+		} else {
+			err = fmt.Errorf("remote: %s: %s", url, resp.Status)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if !fn(result) || result.next == "" {
+			return nil
+		}
+		page = result.next
+	}
+}
+
+// objectInfo describes an object, or a directory of objects. It is both
+// the fs.FileInfo and the fs.DirEntry of Bucket.
+type objectInfo struct {
+	key     string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i objectInfo) Name() string       { return path.Base(i.key) }
+func (i objectInfo) Size() int64        { return i.size }
+func (i objectInfo) ModTime() time.Time { return i.modTime }
+func (i objectInfo) IsDir() bool        { return i.dir }
+func (i objectInfo) Sys() any           { return nil }
+
+func (i objectInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func (i objectInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i objectInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// objectFile is an object opened by Bucket.Open.
+type objectFile struct {
+	*File
+	info fs.FileInfo
+}
+
+func (f *objectFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *objectFile) Close() error               { return nil }
+
+// dirFile is a directory opened by Bucket.Open. Its entries are listed on
+// the first call to ReadDir.
+type dirFile struct {
+	bucket  *Bucket
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		entries, err := d.bucket.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.listed = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
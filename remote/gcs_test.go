@@ -0,0 +1,79 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/bluefalconhd/segb"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveGCS serves objects as the GCS bucket "evidence", and fails requests
+// without the bearer token "token".
+func serveGCS(t *testing.T, objects map[string][]byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/storage/v1/b/evidence/o" {
+			keys, prefixes := listObjects(objects, r.URL.Query().Get("prefix"))
+			type item struct {
+				Name    string    `json:"name"`
+				Size    string    `json:"size"`
+				Updated time.Time `json:"updated"`
+			}
+			items := []item{}
+			for _, key := range keys {
+				items = append(items, item{key, strconv.Itoa(len(objects[key])), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"items": items, "prefixes": prefixes})
+			return
+		}
+		key, ok := strings.CutPrefix(r.URL.Path, "/evidence/")
+		data, found := objects[key]
+		if !ok || !found {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGCSBucket(t *testing.T) {
+	objects := testObjects(t)
+	server := serveGCS(t, objects)
+	bucket := NewGCSBucket(context.Background(), GCSConfig{Endpoint: server.URL, Token: "token"}, "evidence")
+
+	corpus, err := segb.LoadFS(bucket, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corpus.Stats.FilesScanned != 3 || corpus.Stats.Decoded != 2 {
+		t.Fatalf("scanned %d files, decoded %d; want 3 and 2", corpus.Stats.FilesScanned, corpus.Stats.Decoded)
+	}
+
+	file, err := bucket.OpenObject("case-42/Safari.History/local/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := segb.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Entries) != 1 || string(s.Entries[0].Data) != "case-42/Safari.History/local/2" {
+		t.Errorf("decoded %d entries; want the one of the object", len(s.Entries))
+	}
+
+	unauthenticated := NewGCSBucket(context.Background(), GCSConfig{Endpoint: server.URL}, "evidence")
+	if _, err := segb.LoadFS(unauthenticated, "."); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("LoadFS without token: %v; want 401", err)
+	}
+}
@@ -0,0 +1,354 @@
+// Package remote reads files served over HTTP with range requests, so that a
+// SEGB file hosted on a case server can be decoded without downloading it
+// whole. The layout of v2 files suits this well: the header and the trailer
+// at the end locate every entry, and only the entries read are fetched.
+//
+//	file, err := remote.Open(ctx, "https://evidence.example/case-42/segment")
+//	if err != nil {
+//		return err
+//	}
+//	s, err := segb.Decode(file)
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults for WithBlockSize and WithCacheBlocks.
+const (
+	DefaultBlockSize   = 64 << 10
+	DefaultCacheBlocks = 64
+)
+
+// ErrRangesNotSupported is returned by Open for servers that answer range
+// requests with the whole file.
+var ErrRangesNotSupported = errors.New("server does not support range requests")
+
+// File is a file read over HTTP with range requests. Reads are served from
+// a cache of fixed size blocks, each fetched with one request, which makes
+// the many small reads of a decoder cheap. ReadAt is safe for concurrent
+// use; Read and Seek are not.
+type File struct {
+	ctx       context.Context
+	url       string
+	client    *http.Client
+	header    http.Header
+	size      int64
+	pos       int64
+	blockSize int64
+	cacheDir  string // Directory given to WithCacheDir
+	diskDir   string // Directory of the blocks of this version of the file in cacheDir
+
+	mu        sync.Mutex
+	blocks    map[int64][]byte // Cached blocks by index
+	recent    []int64          // Cached block indexes, least recently used first
+	maxBlocks int
+	requests  int
+}
+
+// Option configures Open.
+type Option func(*File)
+
+// WithClient makes Open use client instead of http.DefaultClient, e.g. for
+// timeouts or a transport that authenticates requests.
+func WithClient(client *http.Client) Option {
+	return func(f *File) {
+		f.client = client
+	}
+}
+
+// WithHeader adds a header to every request, e.g. Authorization.
+func WithHeader(key, value string) Option {
+	return func(f *File) {
+		f.header.Add(key, value)
+	}
+}
+
+// WithBlockSize sets how many bytes each request fetches, reading ahead of
+// small reads. The default is DefaultBlockSize.
+func WithBlockSize(n int) Option {
+	return func(f *File) {
+		if n > 0 {
+			f.blockSize = int64(n)
+		}
+	}
+}
+
+// WithCacheBlocks sets how many blocks are kept in memory, the least
+// recently used being dropped first. The default is DefaultCacheBlocks.
+func WithCacheBlocks(n int) Option {
+	return func(f *File) {
+		if n > 0 {
+			f.maxBlocks = n
+		}
+	}
+}
+
+// WithCacheDir keeps the fetched blocks in files below dir as well as in
+// memory, so that opening the same file again, in this process or a later
+// one, fetches only its first block. Blocks are kept per version of the
+// file, told by its size and its ETag or Last-Modified header. Failures to
+// write the cache are ignored.
+func WithCacheDir(dir string) Option {
+	return func(f *File) {
+		f.cacheDir = dir
+	}
+}
+
+// Open opens the file at url. It fetches the first block, which tells the
+// size of the file and whether the server supports range requests. ctx
+// applies to every request made through the File.
+func Open(ctx context.Context, url string, opts ...Option) (*File, error) {
+	f := &File{
+		ctx:       ctx,
+		url:       url,
+		client:    http.DefaultClient,
+		header:    make(http.Header),
+		blockSize: DefaultBlockSize,
+		blocks:    make(map[int64][]byte),
+		maxBlocks: DefaultCacheBlocks,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	block, size, version, err := f.get(0, f.blockSize)
+	if err != nil {
+		return nil, err
+	}
+	f.size = size
+	if f.cacheDir != "" {
+		key := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%s\n%d", f.url, size, version, f.blockSize)))
+		f.diskDir = filepath.Join(f.cacheDir, hex.EncodeToString(key[:16]))
+	}
+	if size > 0 {
+		f.store(0, block)
+		f.save(0, block)
+	}
+	return f, nil
+}
+
+// Size returns the size of the file.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Requests returns how many requests have been made for the file.
+func (f *File) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("remote: negative offset %d", off)
+	}
+	n := 0
+	for n < len(p) && off+int64(n) < f.size {
+		pos := off + int64(n)
+		block, err := f.block(pos / f.blockSize)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], block[pos%f.blockSize:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, fmt.Errorf("remote: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("remote: negative position %d", offset)
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+// block returns the block with the given index, from the cache or fetched.
+func (f *File) block(index int64) ([]byte, error) {
+	f.mu.Lock()
+	block, ok := f.blocks[index]
+	if ok {
+		f.touch(index)
+	}
+	f.mu.Unlock()
+	if ok {
+		return block, nil
+	}
+
+	start := index * f.blockSize
+	length := min(f.blockSize, f.size-start)
+	if block, ok := f.load(index, length); ok {
+		f.store(index, block)
+		return block, nil
+	}
+	block, _, _, err := f.get(start, length)
+	if err != nil {
+		return nil, err
+	}
+	f.store(index, block)
+	f.save(index, block)
+	return block, nil
+}
+
+// load reads a block of the given length from the cache directory.
+func (f *File) load(index, length int64) ([]byte, bool) {
+	if f.diskDir == "" {
+		return nil, false
+	}
+	block, err := os.ReadFile(filepath.Join(f.diskDir, strconv.FormatInt(index, 10)))
+	return block, err == nil && int64(len(block)) == length
+}
+
+// save writes a block to the cache directory. It goes to a temporary file
+// first, so that a concurrent load never sees part of it.
+func (f *File) save(index int64, block []byte) {
+	if f.diskDir == "" {
+		return
+	}
+	if err := os.MkdirAll(f.diskDir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(f.diskDir, "block-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(block)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(f.diskDir, strconv.FormatInt(index, 10)))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// store caches a block, dropping the least recently used one if the cache
+// is full.
+func (f *File) store(index int64, block []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.blocks[index]; ok {
+		return
+	}
+	if len(f.recent) >= f.maxBlocks {
+		delete(f.blocks, f.recent[0])
+		f.recent = f.recent[1:]
+	}
+	f.blocks[index] = block
+	f.recent = append(f.recent, index)
+}
+
+// touch marks a cached block as the most recently used. f.mu must be held.
+func (f *File) touch(index int64) {
+	for i, cached := range f.recent {
+		if cached == index {
+			f.recent = append(append(f.recent[:i:i], f.recent[i+1:]...), index)
+			return
+		}
+	}
+}
+
+// get fetches length bytes from start and returns them with the size of
+// the file and its version, the ETag or Last-Modified header if any.
+func (f *File) get(start, length int64) ([]byte, int64, string, error) {
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	for key, values := range f.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, start+length-1))
+
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Only an empty file has no first byte
+		if size, ok := contentRangeSize(resp.Header.Get("Content-Range")); ok && size == 0 && start == 0 {
+			return nil, 0, "", nil
+		}
+		return nil, 0, "", fmt.Errorf("remote: %s: range %d-%d not satisfiable", f.url, start, start+length-1)
+	case http.StatusOK:
+		// Servers may ignore the range for an empty file, it has no bytes
+		if resp.ContentLength == 0 && start == 0 {
+			return nil, 0, "", nil
+		}
+		return nil, 0, "", fmt.Errorf("remote: %s: %w", f.url, ErrRangesNotSupported)
+	default:
+		return nil, 0, "", fmt.Errorf("remote: %s: %s", f.url, resp.Status)
+	}
+
+	size, ok := contentRangeSize(resp.Header.Get("Content-Range"))
+	if !ok {
+		return nil, 0, "", fmt.Errorf("remote: %s: invalid Content-Range %q", f.url, resp.Header.Get("Content-Range"))
+	}
+	if size <= start {
+		return nil, 0, "", fmt.Errorf("remote: %s: file shrank to %d bytes", f.url, size)
+	}
+	// The last block of the file is short
+	want := min(length, size-start)
+	data := make([]byte, want)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, 0, "", fmt.Errorf("remote: %s: %w", f.url, err)
+	}
+	version := resp.Header.Get("ETag")
+	if version == "" {
+		version = resp.Header.Get("Last-Modified")
+	}
+	return data, size, version, nil
+}
+
+// contentRangeSize returns the complete length of a Content-Range header,
+// e.g. 1234 for "bytes 0-99/1234".
+func contentRangeSize(header string) (int64, bool) {
+	_, size, ok := strings.Cut(header, "/")
+	if !ok || !strings.HasPrefix(header, "bytes ") {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	return n, err == nil && n >= 0
+}
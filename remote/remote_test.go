@@ -0,0 +1,153 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/segbtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serve serves data with range support.
+func serve(t *testing.T, data []byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "segment", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDecodeRemote(t *testing.T) {
+	var entries []segbtest.Entry
+	for i := range 200 {
+		entries = append(entries, segbtest.Entry{
+			Data:    bytes.Repeat([]byte(fmt.Sprintf("entry %d ", i)), 50),
+			Created: time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC),
+		})
+	}
+	data, err := segbtest.Bytes(segb.SEGB_VERSION_2, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := serve(t, data)
+
+	const blockSize = 4096
+	file, err := Open(context.Background(), server.URL, WithBlockSize(blockSize), WithCacheBlocks(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Size() != int64(len(data)) {
+		t.Fatalf("Size() = %d; want %d", file.Size(), len(data))
+	}
+
+	// Only the header, the trailer and the last entry are fetched
+	last := int64(len(entries) - 1)
+	decoded, err := segb.Decode(file, segb.WithEntryFilter(func(m segb.EntryMeta) bool {
+		return m.Created.Equal(entries[last].Created)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 1 || !bytes.Equal(decoded.Entries[0].Data, entries[last].Data) {
+		t.Fatalf("decoded %d entries; want the last one", len(decoded.Entries))
+	}
+	if blocks := (len(data) + blockSize - 1) / blockSize; file.Requests() >= blocks/2 {
+		t.Errorf("%d requests for a file of %d blocks", file.Requests(), blocks)
+	}
+
+	// Reads across blocks, and past the end
+	buf := make([]byte, 3*blockSize)
+	n, err := file.ReadAt(buf, int64(len(data)-2*blockSize-10))
+	if n != 2*blockSize+10 || err != io.EOF || !bytes.Equal(buf[:n], data[len(data)-n:]) {
+		t.Errorf("ReadAt(end) = %d, %v", n, err)
+	}
+	all, err := io.ReadAll(io.NewSectionReader(file, 0, file.Size()))
+	if err != nil || !bytes.Equal(all, data) {
+		t.Errorf("ReadAll() = %d bytes, %v; want the file", len(all), err)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	noRanges := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("whole file"))
+	}))
+	defer noRanges.Close()
+	if _, err := Open(context.Background(), noRanges.URL); !errors.Is(err, ErrRangesNotSupported) {
+		t.Errorf("Open(no ranges) error = %v; want ErrRangesNotSupported", err)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	if _, err := Open(context.Background(), missing.URL); err == nil {
+		t.Error("Open(404) error = nil")
+	}
+
+	file, err := Open(context.Background(), serve(t, nil).URL)
+	if err != nil || file.Size() != 0 {
+		t.Fatalf("Open(empty) = %v, %v", file, err)
+	}
+	if n, err := file.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Read(empty) = %d, %v; want io.EOF", n, err)
+	}
+}
+
+func TestAuthHeader(t *testing.T) {
+	data := []byte("SEGB....")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "segment", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	if _, err := Open(context.Background(), server.URL); err == nil {
+		t.Error("Open(without credentials) error = nil")
+	}
+	if _, err := Open(context.Background(), server.URL, WithHeader("Authorization", "Bearer token")); err != nil {
+		t.Errorf("Open(with credentials) = %v", err)
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 100)
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "segment", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	read := func() *File {
+		t.Helper()
+		file, err := Open(context.Background(), server.URL, WithBlockSize(256), WithCacheDir(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		all, err := io.ReadAll(io.NewSectionReader(file, 0, file.Size()))
+		if err != nil || !bytes.Equal(all, data) {
+			t.Fatalf("ReadAll() = %d bytes, %v; want the file", len(all), err)
+		}
+		return file
+	}
+
+	if file := read(); file.Requests() != 7 {
+		t.Errorf("first read: %d requests; want 7", file.Requests())
+	}
+	// Only the first block is fetched again, to tell the version
+	if file := read(); file.Requests() != 1 {
+		t.Errorf("cached read: %d requests; want 1", file.Requests())
+	}
+	data, etag = bytes.Repeat([]byte("fedcba9876543210"), 100), `"v2"`
+	if file := read(); file.Requests() != 7 {
+		t.Errorf("read of a new version: %d requests; want 7", file.Requests())
+	}
+}
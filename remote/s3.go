@@ -0,0 +1,214 @@
+package remote
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// emptySHA256 is the SHA-256 of an empty request body, which every request
+// to S3 has.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config locates and authenticates an S3 bucket.
+type S3Config struct {
+	Region string // e.g. "us-east-1"
+	// Endpoint is the base URL of an S3 compatible service, such as
+	// "http://localhost:9000" for MinIO, whose buckets are addressed by
+	// path. Empty for AWS, whose buckets are addressed by host name.
+	Endpoint string
+	// Credentials; requests are not signed without an access key, which
+	// suits public buckets
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // For temporary credentials
+}
+
+// NewS3Bucket returns the S3 bucket with the given name. Requests are
+// signed with AWS Signature Version 4. ctx applies to every request made
+// through the Bucket and the files opened from it.
+func NewS3Bucket(ctx context.Context, config S3Config, bucket string, opts ...Option) *Bucket {
+	store := s3Store{config: config, bucket: bucket}
+	return newBucket(ctx, store, opts, func(base http.RoundTripper) http.RoundTripper {
+		return &s3Transport{config: config, base: base}
+	})
+}
+
+// s3Store is the API of S3.
+type s3Store struct {
+	config S3Config
+	bucket string
+}
+
+// bucketURL returns the URL of the bucket, ending in "/".
+func (s s3Store) bucketURL() string {
+	if s.config.Endpoint == "" {
+		return "https://" + s.bucket + ".s3." + s.config.Region + ".amazonaws.com/"
+	}
+	return strings.TrimSuffix(s.config.Endpoint, "/") + "/" + uriEncode(s.bucket, true) + "/"
+}
+
+func (s s3Store) objectURL(key string) string {
+	return s.bucketURL() + uriEncode(key, false)
+}
+
+func (s s3Store) listURL(prefix, page string) string {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}}
+	if page != "" {
+		query.Set("continuation-token", page)
+	}
+	// S3 reads "+" as itself, spaces must be escaped as "%20"
+	return s.bucketURL() + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func (s s3Store) parseList(body io.Reader) (listing, error) {
+	var result struct {
+		Contents []struct {
+			Key          string
+			Size         int64
+			LastModified time.Time
+		}
+		CommonPrefixes []struct {
+			Prefix string
+		}
+		IsTruncated           bool
+		NextContinuationToken string
+	}
+	if err := xml.NewDecoder(body).Decode(&result); err != nil {
+		return listing{}, err
+	}
+
+	var page listing
+	for _, object := range result.Contents {
+		page.objects = append(page.objects, objectInfo{key: object.Key, size: object.Size, modTime: object.LastModified})
+	}
+	for _, prefix := range result.CommonPrefixes {
+		page.prefixes = append(page.prefixes, prefix.Prefix)
+	}
+	if result.IsTruncated {
+		page.next = result.NextContinuationToken
+	}
+	return page, nil
+}
+
+// s3Transport signs requests with AWS Signature Version 4.
+type s3Transport struct {
+	config S3Config
+	base   http.RoundTripper
+}
+
+func (t *s3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.config.AccessKeyID == "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	t.sign(req, time.Now())
+	return t.base.RoundTrip(req)
+}
+
+// sign adds the headers of Signature Version 4 to a request without body,
+// as made at the given time.
+func (t *s3Transport) sign(req *http.Request, now time.Time) {
+	timestamp := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", timestamp)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if t.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", t.config.SessionToken)
+	}
+
+	// Sign the host, the range and the headers of the signature
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for key, values := range req.Header {
+		key = strings.ToLower(key)
+		if key == "range" || strings.HasPrefix(key, "x-amz-") {
+			headers[key] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := uriEncode(req.URL.Path, false)
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptySHA256,
+	}, "\n")
+
+	date := timestamp[:8]
+	scope := date + "/" + t.config.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + t.config.SecretAccessKey)
+	for _, part := range []string{date, t.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+t.config.AccessKeyID+"/"+scope+
+		",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery returns the query of a request as Signature Version 4
+// signs it: sorted by name, then value, and escaped with uriEncode.
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	slices.Sort(params)
+	return strings.Join(params, "&")
+}
+
+// uriEncode escapes every byte of s but the unreserved characters of RFC
+// 3986, and "/" if encodeSlash is false, as Signature Version 4 requires.
+func uriEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package segb
+
+import (
+	v1 "github.com/bluefalconhd/segb/v1"
+	"io"
+)
+
+// WithResidualEntries makes Decode also scan the space past the end of data
+// offset of v1 files for residual entries. Apple's writer preallocates v1
+// files and, when it rewrites one, the region past the new end of data often
+// still holds older entries that were overwritten but not erased. Candidates
+// that have a plausible entry header and pass their CRC are appended to
+// Segb.Entries, after the regular entries, with Entry.Residual set.
+//
+// It has no effect on v2 files.
+func WithResidualEntries() DecodeOption {
+	return func(c *decodeConfig) {
+		c.residual = true
+	}
+}
+
+// recoverResidual appends the residual entries found past the end of data of
+// the v1 file in stream.
+func (s *Segb) recoverResidual(stream io.ReadSeeker, cfg decodeConfig) error {
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	header, err := v1.ReadHeader(stream)
+	if err != nil {
+		return err
+	}
+	start := max(int64(header.EndOfDataOffset), v1HeaderSize)
+	if _, err := stream.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	region, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+
+	// Residual entries are filtered and count against the limits like
+	// regular ones
+	gate := cfg.newEntryGate()
+	if gate != nil {
+		for _, entry := range s.Entries {
+			gate.used += entry.Size
+		}
+	}
+
+	alignment := int64(max(s.Alignment, 1))
+	id := 0
+	for _, entry := range s.Entries {
+		id = max(id, entry.ID+1)
+	}
+	size := start + int64(len(region))
+	for offset := alignUp(start, alignment); offset+v1EntryHeaderSize <= size; {
+		header := region[offset-start : offset-start+v1EntryHeaderSize]
+		length, ok := plausibleV1Entry(header, size-offset-v1EntryHeaderSize)
+		if !ok {
+			offset += alignment
+			continue
+		}
+		dataStart := offset - start + v1EntryHeaderSize
+		entry, ok := v1EntryAt(header, region[dataStart:dataStart+length:dataStart+length], offset)
+		if !ok {
+			offset += alignment
+			continue
+		}
+
+		next := alignUp(entry.Offset+length, alignment)
+		entry.ID, entry.Sequence = id, id
+		entry.StoredSize = min(next, size) - entry.Offset
+		entry.Residual = true
+		if gate != nil && !gate.admit(EntryMeta{ID: id, State: entry.State, Created: entry.Created, Offset: entry.Offset, Size: length}) {
+			if gate.err != nil {
+				return gate.err
+			}
+		} else {
+			s.Entries = append(s.Entries, entry)
+		}
+		id++
+		offset = next
+	}
+	if gate != nil {
+		s.Errors = append(s.Errors, gate.skipped...)
+	}
+	return nil
+}
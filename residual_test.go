@@ -0,0 +1,49 @@
+package segb
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestResidualEntries(t *testing.T) {
+	file := v1File(8, []byte(expectedEntryData[0]), []byte(expectedEntryData[1]), []byte(expectedEntryData[2]))
+	var headers []int64
+	for i := int64(56); i < int64(len(file)); i = alignUp(i+32+int64(binary.LittleEndian.Uint32(file[i:])), 8) {
+		binary.LittleEndian.PutUint64(file[i+8:], math.Float64bits(1e8))
+		headers = append(headers, i)
+	}
+	// Rewind the end of data over the last entry, as a rewrite with fewer
+	// entries would, and leave preallocated space after it
+	binary.LittleEndian.PutUint32(file, uint32(headers[2]))
+	file = append(file, make([]byte, 100)...)
+
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 2 {
+		t.Fatalf("Decode() = %d entries; want 2", len(decoded.Entries))
+	}
+
+	decoded, err = DecodeBytes(file, WithResidualEntries())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 3 {
+		t.Fatalf("Decode(WithResidualEntries()) = %d entries; want 3", len(decoded.Entries))
+	}
+	for i, entry := range decoded.Entries {
+		if entry.Residual != (i == 2) || string(entry.Data) != expectedEntryData[i] || entry.ID != i {
+			t.Errorf("entry %d = %q, ID %d, residual %v", i, entry.Data, entry.ID, entry.Residual)
+		}
+	}
+	if residual := decoded.Entries[2]; residual.Offset != headers[2]+32 || !residual.CRCValid {
+		t.Errorf("residual entry at %d, CRC valid %v; want at %d", residual.Offset, residual.CRCValid, headers[2]+32)
+	}
+
+	// Residual entries count against the limits
+	if _, err := DecodeBytes(file, WithResidualEntries(), WithMaxTotalBytes(int64(len(expectedEntryData[0])+len(expectedEntryData[1])))); err != ErrBudgetExceeded {
+		t.Errorf("Decode() with a budget for the regular entries = %v; want ErrBudgetExceeded", err)
+	}
+}
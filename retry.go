@@ -0,0 +1,104 @@
+package segb
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// RetryPolicy says how often, and after how long, a failed read is tried
+// again by a RetryReader.
+type RetryPolicy struct {
+	Attempts int           // Tries per read, the first included; 1 or less never retries
+	Backoff  time.Duration // Wait before the first retry, doubled for each one after
+	// MaxBackoff caps the wait between retries, if set
+	MaxBackoff time.Duration
+	// OnRetry, if set, is called with the error of each failed try that is
+	// retried, and the number of the try, e.g. to log flaky sources
+	OnRetry func(err error, attempt int)
+}
+
+// wait returns how long to wait after the given failed try.
+func (p RetryPolicy) wait(attempt int) time.Duration {
+	wait := p.Backoff << (attempt - 1)
+	if p.MaxBackoff > 0 && (wait > p.MaxBackoff || wait < 0) {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// RetryReader retries the reads and seeks of a source whose errors may be
+// transient, such as a network mount, failing media or a write blocker
+// that drops out, so that one bad read does not end a long run over many
+// files. A read that fails is tried again from the same position, after
+// seeking back to it; end of file is never retried.
+type RetryReader struct {
+	r      io.ReadSeeker
+	policy RetryPolicy
+	pos    int64 // Position in r, as far as reads and seeks have moved it
+}
+
+// NewRetryReader returns a RetryReader reading r from its current position.
+func NewRetryReader(r io.ReadSeeker, policy RetryPolicy) *RetryReader {
+	pos, _ := r.Seek(0, io.SeekCurrent)
+	return &RetryReader{r: r, policy: policy, pos: pos}
+}
+
+func (r *RetryReader) Read(p []byte) (int, error) {
+	var n int
+	err := r.retry(func() (err error) {
+		n, err = r.r.Read(p)
+		if n > 0 {
+			// Return what was read, a persistent error comes back next time
+			r.pos += int64(n)
+			return nil
+		}
+		return err
+	})
+	return n, err
+}
+
+func (r *RetryReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	err := r.retry(func() (err error) {
+		pos, err = r.r.Seek(offset, whence)
+		return err
+	})
+	if err == nil {
+		r.pos = pos
+	}
+	return pos, err
+}
+
+// retry calls op until it succeeds, returns io.EOF or the tries run out,
+// seeking back to the current position before each retry.
+func (r *RetryReader) retry(op func() error) error {
+	err := op()
+	for attempt := 1; attempt < r.policy.Attempts && err != nil && !errors.Is(err, io.EOF); attempt++ {
+		if r.policy.OnRetry != nil {
+			r.policy.OnRetry(err, attempt)
+		}
+		time.Sleep(r.policy.wait(attempt))
+		if _, seekErr := r.r.Seek(r.pos, io.SeekStart); seekErr != nil {
+			err = seekErr
+			continue
+		}
+		err = op()
+	}
+	return err
+}
+
+// WithRetry makes Decode read through a RetryReader with the given policy.
+func WithRetry(policy RetryPolicy) DecodeOption {
+	return func(c *decodeConfig) {
+		c.retry = policy
+	}
+}
+
+// WithLoadRetry makes LoadDir read every file through a RetryReader with
+// the given policy.
+func WithLoadRetry(policy RetryPolicy) LoadOption {
+	return func(c *loadConfig) {
+		c.retry = policy
+	}
+}
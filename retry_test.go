@@ -0,0 +1,55 @@
+package segb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+var errFlaky = errors.New("transient I/O error")
+
+// flakyReader fails every other read, or every read once broken.
+type flakyReader struct {
+	*bytes.Reader
+	reads  int
+	broken bool
+}
+
+func (r *flakyReader) Read(p []byte) (int, error) {
+	r.reads++
+	if r.broken || r.reads%2 == 0 {
+		// Fail partway, as a short read past the error would
+		r.Reader.Seek(1, io.SeekCurrent)
+		return 0, errFlaky
+	}
+	return r.Reader.Read(p)
+}
+
+func TestRetryReader(t *testing.T) {
+	file := v2File([]byte("first"), []byte("second"), []byte("third"))
+	if _, err := Decode(&flakyReader{Reader: bytes.NewReader(file)}); !errors.Is(err, errFlaky) {
+		t.Fatalf("Decode(flaky) error = %v; want the read error", err)
+	}
+
+	retries := 0
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Microsecond, OnRetry: func(err error, attempt int) { retries++ }}
+	decoded, err := Decode(&flakyReader{Reader: bytes.NewReader(file)}, WithRetry(policy))
+	if err != nil || len(decoded.Entries) != 3 || string(decoded.Entries[2].Data) != "third" {
+		t.Fatalf("Decode(flaky, WithRetry) = %d entries, %v", len(decoded.Entries), err)
+	}
+	if retries == 0 {
+		t.Error("OnRetry was never called")
+	}
+
+	retries = 0
+	_, err = io.ReadAll(NewRetryReader(&flakyReader{Reader: bytes.NewReader(file), broken: true}, policy))
+	if !errors.Is(err, errFlaky) || retries != 2 {
+		t.Errorf("ReadAll(broken) = %v after %d retries; want the read error after 2", err, retries)
+	}
+
+	if wait := (RetryPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second}).wait(3); wait != 3*time.Second {
+		t.Errorf("wait(3) = %v; want the 3s cap", wait)
+	}
+}
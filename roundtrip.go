@@ -0,0 +1,134 @@
+package segb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"io"
+	"math"
+	"slices"
+)
+
+// ErrNoOriginal is returned by Encode for a Segb decoded without
+// WithRoundTrip.
+var ErrNoOriginal = errors.New("not decoded with WithRoundTrip")
+
+// WithRoundTrip makes Decode keep the bytes of the decoded file, so that
+// Segb.Encode can reproduce it exactly: header fields of unknown purpose,
+// padding, slack and trailer order included. For compressed input, that is
+// the decompressed file.
+func WithRoundTrip() DecodeOption {
+	return func(c *decodeConfig) {
+		c.roundTrip = true
+	}
+}
+
+// Encode writes the file s was decoded from with WithRoundTrip, applying the
+// edits made to s since: the payload, checksum, state and creation time of
+// each entry, and for v2 the header creation time and unknown bytes. All
+// other bytes are copied from the original, so an unedited Segb encodes to
+// its input byte for byte and an edit, such as redacting a payload, changes
+// only the bytes it has to.
+//
+// Payloads are written as stored, i.e. Entry.Raw for decompressed entries. A
+// v1 payload must keep its length and a v2 one must fit in its StoredSize.
+// Entries removed from s are left in the file as they were.
+func (s *Segb) Encode(w io.Writer) error {
+	if s.original == nil {
+		return ErrNoOriginal
+	}
+
+	file := append([]byte(nil), s.original...)
+
+	// Entries of a part of a larger input are located in that input
+	local := s
+	if s.originalBase != 0 {
+		local = &Segb{Version: s.Version, Created: s.Created, HeaderUnknown: s.HeaderUnknown, Entries: slices.Clone(s.Entries)}
+		local.shiftOffsets(-s.originalBase)
+	}
+
+	var err error
+	switch s.Version {
+	case SEGB_VERSION_1:
+		err = local.patchV1(file)
+	case SEGB_VERSION_2:
+		err = local.patchV2(file)
+	default:
+		err = ErrUnsupportedVersion
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(file)
+	return err
+}
+
+// patchV1 writes the entries of s into the v1 file.
+func (s *Segb) patchV1(file []byte) error {
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		header := file[entry.headerOffset : entry.headerOffset+v1EntryHeaderSize]
+		payload := entry.checksummed()
+		if length := int(int32(binary.LittleEndian.Uint32(header))); len(payload) != length {
+			return fmt.Errorf("entry %d: payload of %d bytes does not fit the %d stored", entry.ID, len(payload), length)
+		}
+
+		patchState(header[4:], entry.State)
+		patchTimestamp(header[8:], entry)
+		binary.LittleEndian.PutUint32(header[24:], entry.Checksum)
+		copy(file[entry.Offset:], payload)
+	}
+	return nil
+}
+
+// patchV2 writes the header, entries and trailer records of s into the v2
+// file.
+func (s *Segb) patchV2(file []byte) error {
+	if created := math.Float64frombits(binary.LittleEndian.Uint64(file[8:])); !CocoaTimestampToTime(created).Equal(s.Created) {
+		binary.LittleEndian.PutUint64(file[8:], math.Float64bits(TimeToCocoaTimestamp(s.Created)))
+	}
+	if len(s.HeaderUnknown) == 16 {
+		copy(file[16:32], s.HeaderUnknown)
+	}
+
+	count := int64(binary.LittleEndian.Uint32(file[4:]))
+	trailer := int64(len(file)) - count*v2.TrailerRecordSize
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		payload := entry.checksummed()
+		if int64(len(payload)) > entry.StoredSize {
+			return fmt.Errorf("entry %d: payload of %d bytes does not fit the %d stored", entry.ID, len(payload), entry.StoredSize)
+		}
+		if entry.Sequence < 0 || int64(entry.Sequence) >= count {
+			return fmt.Errorf("entry %d: no trailer record %d", entry.ID, entry.Sequence)
+		}
+
+		record := file[trailer+int64(entry.Sequence)*v2.TrailerRecordSize:]
+		patchState(record[4:], entry.State)
+		patchTimestamp(record[8:], entry)
+		binary.LittleEndian.PutUint32(file[entry.headerOffset:], entry.Checksum)
+		stored := file[entry.Offset : entry.Offset+entry.StoredSize]
+		clear(stored[copy(stored, payload):])
+	}
+	return nil
+}
+
+// patchState writes state over the stored one unless both mean the same.
+// v1 and v2 share their state values.
+func patchState(field []byte, state EntryState) {
+	if V1EntryStateToStandardState(v1.EntryState(binary.LittleEndian.Uint32(field))) != state {
+		binary.LittleEndian.PutUint32(field, uint32(state))
+	}
+}
+
+// patchTimestamp writes the entry creation time over the stored one unless
+// both are the same. Decoding truncates Created to the microsecond, so
+// rewriting it unconditionally would change untouched entries.
+func patchTimestamp(field []byte, entry *Entry) {
+	stored := math.Float64frombits(binary.LittleEndian.Uint64(field))
+	if !CocoaTimestampToTime(stored).Equal(entry.Created) {
+		binary.LittleEndian.PutUint64(field, math.Float64bits(TimeToCocoaTimestamp(entry.Created)))
+	}
+}
@@ -0,0 +1,118 @@
+package segb
+
+import (
+	"bytes"
+	"hash/crc32"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	// v1 padding and v2 header bytes no field describes
+	v1Padded := v1File(8, []byte("Here's to the crazy ones."), []byte("The misfits."))
+	for i := 56 + 32 + 25; i < 120; i++ {
+		v1Padded[i] = 0xaa
+	}
+	v2Unknown := v2File([]byte("The rebels."), zlibPayload("The troublemakers."))
+	copy(v2Unknown[16:], "unknown purpose")
+
+	files := map[string][]byte{"v1 padding": v1Padded, "v2 unknown": v2Unknown}
+	for _, name := range []string{"segb_version1.bin", "segb_version2.bin"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = data
+	}
+
+	for name, file := range files {
+		for _, opts := range [][]DecodeOption{
+			{WithRoundTrip()},
+			{WithRoundTrip(), WithoutPayloadDecompression(), WithSortOrder(SortByCreated)},
+		} {
+			decoded, err := DecodeBytes(file, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := decoded.Encode(&buf); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !bytes.Equal(buf.Bytes(), file) {
+				t.Errorf("%s: Encode() does not reproduce the input", name)
+			}
+		}
+	}
+
+	decoded, err := DecodeBytes(v1Padded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.Encode(&bytes.Buffer{}); err != ErrNoOriginal {
+		t.Errorf("Encode() without WithRoundTrip = %v; want ErrNoOriginal", err)
+	}
+}
+
+func TestRoundTripEdit(t *testing.T) {
+	payloads := [][]byte{[]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels.")}
+
+	for name, file := range map[string][]byte{
+		"v1": v1File(8, payloads...),
+		"v2": v2File(payloads...),
+	} {
+		decoded, err := DecodeBytes(file, WithRoundTrip())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Redact the second entry
+		entry := &decoded.Entries[1]
+		entry.Data = bytes.Repeat([]byte("X"), len(entry.Data))
+		entry.Checksum = crc32.ChecksumIEEE(entry.Data)
+		var buf bytes.Buffer
+		if err := decoded.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		edited := buf.Bytes()
+		for i := range file {
+			inPayload := int64(i) >= entry.Offset && int64(i) < entry.Offset+entry.Size
+			inChecksum := int64(i) >= entry.headerOffset && int64(i) < entry.headerOffset+v1EntryHeaderSize
+			if edited[i] != file[i] && !inPayload && !inChecksum {
+				t.Errorf("%s: byte at 0x%x changed", name, i)
+			}
+		}
+
+		redecoded, err := DecodeBytes(edited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := redecoded.Entries[1]; !bytes.Equal(got.Data, entry.Data) || !got.CRCValid {
+			t.Errorf("%s: redacted entry = %q (CRC valid %v)", name, got.Data, got.CRCValid)
+		}
+
+		// Other fields
+		decoded.Entries[0].State = EntryStateDeleted
+		decoded.Entries[2].Created = time.Date(2024, 6, 10, 17, 0, 0, 0, time.UTC)
+		buf.Reset()
+		if err := decoded.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if redecoded, err = DecodeBytes(buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		if redecoded.Entries[0].State != EntryStateDeleted || !redecoded.Entries[2].Created.Equal(decoded.Entries[2].Created) {
+			t.Errorf("%s: state %v, created %v", name, redecoded.Entries[0].State, redecoded.Entries[2].Created)
+		}
+
+		// Payloads cannot grow
+		decoded.Entries[1].Data = append(decoded.Entries[1].Data, "and then some"...)
+		if err := decoded.Encode(&buf); err == nil {
+			t.Errorf("%s: Encode() of a longer payload succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// Package segbpb contains the generated protobuf and gRPC code for the
+// remote parsing service defined in segb.proto.
+package segbpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative segb.proto
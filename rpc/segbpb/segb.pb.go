@@ -0,0 +1,545 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: segb.proto
+
+package segbpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EntryState int32
+
+const (
+	EntryState_ENTRY_STATE_UNSPECIFIED EntryState = 0
+	EntryState_ENTRY_STATE_WRITTEN     EntryState = 1
+	EntryState_ENTRY_STATE_DELETED     EntryState = 3
+	EntryState_ENTRY_STATE_UNKNOWN     EntryState = 4
+)
+
+// Enum value maps for EntryState.
+var (
+	EntryState_name = map[int32]string{
+		0: "ENTRY_STATE_UNSPECIFIED",
+		1: "ENTRY_STATE_WRITTEN",
+		3: "ENTRY_STATE_DELETED",
+		4: "ENTRY_STATE_UNKNOWN",
+	}
+	EntryState_value = map[string]int32{
+		"ENTRY_STATE_UNSPECIFIED": 0,
+		"ENTRY_STATE_WRITTEN":     1,
+		"ENTRY_STATE_DELETED":     3,
+		"ENTRY_STATE_UNKNOWN":     4,
+	}
+)
+
+func (x EntryState) Enum() *EntryState {
+	p := new(EntryState)
+	*p = x
+	return p
+}
+
+func (x EntryState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EntryState) Descriptor() protoreflect.EnumDescriptor {
+	return file_segb_proto_enumTypes[0].Descriptor()
+}
+
+func (EntryState) Type() protoreflect.EnumType {
+	return &file_segb_proto_enumTypes[0]
+}
+
+func (x EntryState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EntryState.Descriptor instead.
+func (EntryState) EnumDescriptor() ([]byte, []int) {
+	return file_segb_proto_rawDescGZIP(), []int{0}
+}
+
+type DecodeFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	IncludeData   bool                   `protobuf:"varint,2,opt,name=include_data,json=includeData,proto3" json:"include_data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecodeFileRequest) Reset() {
+	*x = DecodeFileRequest{}
+	mi := &file_segb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeFileRequest) ProtoMessage() {}
+
+func (x *DecodeFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_segb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeFileRequest.ProtoReflect.Descriptor instead.
+func (*DecodeFileRequest) Descriptor() ([]byte, []int) {
+	return file_segb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DecodeFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *DecodeFileRequest) GetIncludeData() bool {
+	if x != nil {
+		return x.IncludeData
+	}
+	return false
+}
+
+type DecodeFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Created       *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created,proto3" json:"created,omitempty"`
+	Entries       []*Entry               `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecodeFileResponse) Reset() {
+	*x = DecodeFileResponse{}
+	mi := &file_segb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeFileResponse) ProtoMessage() {}
+
+func (x *DecodeFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_segb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeFileResponse.ProtoReflect.Descriptor instead.
+func (*DecodeFileResponse) Descriptor() ([]byte, []int) {
+	return file_segb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DecodeFileResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *DecodeFileResponse) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *DecodeFileResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type Entry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	State         EntryState             `protobuf:"varint,3,opt,name=state,proto3,enum=segb.v1.EntryState" json:"state,omitempty"`
+	Created       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created,proto3" json:"created,omitempty"`
+	Checksum      uint32                 `protobuf:"varint,5,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	Size          int64                  `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	Data          []byte                 `protobuf:"bytes,7,opt,name=data,proto3" json:"data,omitempty"`
+	Offset        int64                  `protobuf:"varint,8,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_segb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_segb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_segb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Entry) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Entry) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Entry) GetState() EntryState {
+	if x != nil {
+		return x.State
+	}
+	return EntryState_ENTRY_STATE_UNSPECIFIED
+}
+
+func (x *Entry) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *Entry) GetChecksum() uint32 {
+	if x != nil {
+		return x.Checksum
+	}
+	return 0
+}
+
+func (x *Entry) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Entry) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Entry) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type CarveRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Path           string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	IncludeEntries bool                   `protobuf:"varint,2,opt,name=include_entries,json=includeEntries,proto3" json:"include_entries,omitempty"`
+	IncludeData    bool                   `protobuf:"varint,3,opt,name=include_data,json=includeData,proto3" json:"include_data,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CarveRequest) Reset() {
+	*x = CarveRequest{}
+	mi := &file_segb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CarveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CarveRequest) ProtoMessage() {}
+
+func (x *CarveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_segb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CarveRequest.ProtoReflect.Descriptor instead.
+func (*CarveRequest) Descriptor() ([]byte, []int) {
+	return file_segb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CarveRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *CarveRequest) GetIncludeEntries() bool {
+	if x != nil {
+		return x.IncludeEntries
+	}
+	return false
+}
+
+func (x *CarveRequest) GetIncludeData() bool {
+	if x != nil {
+		return x.IncludeData
+	}
+	return false
+}
+
+type CarvedStructure struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length        int64                  `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Created       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created,proto3" json:"created,omitempty"`
+	EntryCount    int32                  `protobuf:"varint,5,opt,name=entry_count,json=entryCount,proto3" json:"entry_count,omitempty"`
+	Entries       []*Entry               `protobuf:"bytes,6,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CarvedStructure) Reset() {
+	*x = CarvedStructure{}
+	mi := &file_segb_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CarvedStructure) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CarvedStructure) ProtoMessage() {}
+
+func (x *CarvedStructure) ProtoReflect() protoreflect.Message {
+	mi := &file_segb_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CarvedStructure.ProtoReflect.Descriptor instead.
+func (*CarvedStructure) Descriptor() ([]byte, []int) {
+	return file_segb_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CarvedStructure) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *CarvedStructure) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *CarvedStructure) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *CarvedStructure) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *CarvedStructure) GetEntryCount() int32 {
+	if x != nil {
+		return x.EntryCount
+	}
+	return 0
+}
+
+func (x *CarvedStructure) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+var File_segb_proto protoreflect.FileDescriptor
+
+const file_segb_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"segb.proto\x12\asegb.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"J\n" +
+	"\x11DecodeFileRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12!\n" +
+	"\finclude_data\x18\x02 \x01(\bR\vincludeData\"\x8e\x01\n" +
+	"\x12DecodeFileResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x124\n" +
+	"\acreated\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x12(\n" +
+	"\aentries\x18\x03 \x03(\v2\x0e.segb.v1.EntryR\aentries\"\xea\x01\n" +
+	"\x05Entry\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\x12)\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x13.segb.v1.EntryStateR\x05state\x124\n" +
+	"\acreated\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x12\x1a\n" +
+	"\bchecksum\x18\x05 \x01(\rR\bchecksum\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04data\x18\a \x01(\fR\x04data\x12\x16\n" +
+	"\x06offset\x18\b \x01(\x03R\x06offset\"n\n" +
+	"\fCarveRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12'\n" +
+	"\x0finclude_entries\x18\x02 \x01(\bR\x0eincludeEntries\x12!\n" +
+	"\finclude_data\x18\x03 \x01(\bR\vincludeData\"\xdc\x01\n" +
+	"\x0fCarvedStructure\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x02 \x01(\x03R\x06length\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x124\n" +
+	"\acreated\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x12\x1f\n" +
+	"\ventry_count\x18\x05 \x01(\x05R\n" +
+	"entryCount\x12(\n" +
+	"\aentries\x18\x06 \x03(\v2\x0e.segb.v1.EntryR\aentries*t\n" +
+	"\n" +
+	"EntryState\x12\x1b\n" +
+	"\x17ENTRY_STATE_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13ENTRY_STATE_WRITTEN\x10\x01\x12\x17\n" +
+	"\x13ENTRY_STATE_DELETED\x10\x03\x12\x17\n" +
+	"\x13ENTRY_STATE_UNKNOWN\x10\x042\xc8\x01\n" +
+	"\x04Segb\x12E\n" +
+	"\n" +
+	"DecodeFile\x12\x1a.segb.v1.DecodeFileRequest\x1a\x1b.segb.v1.DecodeFileResponse\x12=\n" +
+	"\rStreamEntries\x12\x1a.segb.v1.DecodeFileRequest\x1a\x0e.segb.v1.Entry0\x01\x12:\n" +
+	"\x05Carve\x12\x15.segb.v1.CarveRequest\x1a\x18.segb.v1.CarvedStructure0\x01B)Z'github.com/bluefalconhd/segb/rpc/segbpbb\x06proto3"
+
+var (
+	file_segb_proto_rawDescOnce sync.Once
+	file_segb_proto_rawDescData []byte
+)
+
+func file_segb_proto_rawDescGZIP() []byte {
+	file_segb_proto_rawDescOnce.Do(func() {
+		file_segb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_segb_proto_rawDesc), len(file_segb_proto_rawDesc)))
+	})
+	return file_segb_proto_rawDescData
+}
+
+var file_segb_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_segb_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_segb_proto_goTypes = []any{
+	(EntryState)(0),               // 0: segb.v1.EntryState
+	(*DecodeFileRequest)(nil),     // 1: segb.v1.DecodeFileRequest
+	(*DecodeFileResponse)(nil),    // 2: segb.v1.DecodeFileResponse
+	(*Entry)(nil),                 // 3: segb.v1.Entry
+	(*CarveRequest)(nil),          // 4: segb.v1.CarveRequest
+	(*CarvedStructure)(nil),       // 5: segb.v1.CarvedStructure
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_segb_proto_depIdxs = []int32{
+	6, // 0: segb.v1.DecodeFileResponse.created:type_name -> google.protobuf.Timestamp
+	3, // 1: segb.v1.DecodeFileResponse.entries:type_name -> segb.v1.Entry
+	0, // 2: segb.v1.Entry.state:type_name -> segb.v1.EntryState
+	6, // 3: segb.v1.Entry.created:type_name -> google.protobuf.Timestamp
+	6, // 4: segb.v1.CarvedStructure.created:type_name -> google.protobuf.Timestamp
+	3, // 5: segb.v1.CarvedStructure.entries:type_name -> segb.v1.Entry
+	1, // 6: segb.v1.Segb.DecodeFile:input_type -> segb.v1.DecodeFileRequest
+	1, // 7: segb.v1.Segb.StreamEntries:input_type -> segb.v1.DecodeFileRequest
+	4, // 8: segb.v1.Segb.Carve:input_type -> segb.v1.CarveRequest
+	2, // 9: segb.v1.Segb.DecodeFile:output_type -> segb.v1.DecodeFileResponse
+	3, // 10: segb.v1.Segb.StreamEntries:output_type -> segb.v1.Entry
+	5, // 11: segb.v1.Segb.Carve:output_type -> segb.v1.CarvedStructure
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_segb_proto_init() }
+func file_segb_proto_init() {
+	if File_segb_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_segb_proto_rawDesc), len(file_segb_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_segb_proto_goTypes,
+		DependencyIndexes: file_segb_proto_depIdxs,
+		EnumInfos:         file_segb_proto_enumTypes,
+		MessageInfos:      file_segb_proto_msgTypes,
+	}.Build()
+	File_segb_proto = out.File
+	file_segb_proto_goTypes = nil
+	file_segb_proto_depIdxs = nil
+}
@@ -0,0 +1,204 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: segb.proto
+
+package segbpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Segb_DecodeFile_FullMethodName    = "/segb.v1.Segb/DecodeFile"
+	Segb_StreamEntries_FullMethodName = "/segb.v1.Segb/StreamEntries"
+	Segb_Carve_FullMethodName         = "/segb.v1.Segb/Carve"
+)
+
+// SegbClient is the client API for Segb service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SegbClient interface {
+	DecodeFile(ctx context.Context, in *DecodeFileRequest, opts ...grpc.CallOption) (*DecodeFileResponse, error)
+	StreamEntries(ctx context.Context, in *DecodeFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entry], error)
+	Carve(ctx context.Context, in *CarveRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CarvedStructure], error)
+}
+
+type segbClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSegbClient(cc grpc.ClientConnInterface) SegbClient {
+	return &segbClient{cc}
+}
+
+func (c *segbClient) DecodeFile(ctx context.Context, in *DecodeFileRequest, opts ...grpc.CallOption) (*DecodeFileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DecodeFileResponse)
+	err := c.cc.Invoke(ctx, Segb_DecodeFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *segbClient) StreamEntries(ctx context.Context, in *DecodeFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Segb_ServiceDesc.Streams[0], Segb_StreamEntries_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DecodeFileRequest, Entry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Segb_StreamEntriesClient = grpc.ServerStreamingClient[Entry]
+
+func (c *segbClient) Carve(ctx context.Context, in *CarveRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CarvedStructure], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Segb_ServiceDesc.Streams[1], Segb_Carve_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CarveRequest, CarvedStructure]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Segb_CarveClient = grpc.ServerStreamingClient[CarvedStructure]
+
+// SegbServer is the server API for Segb service.
+// All implementations must embed UnimplementedSegbServer
+// for forward compatibility.
+type SegbServer interface {
+	DecodeFile(context.Context, *DecodeFileRequest) (*DecodeFileResponse, error)
+	StreamEntries(*DecodeFileRequest, grpc.ServerStreamingServer[Entry]) error
+	Carve(*CarveRequest, grpc.ServerStreamingServer[CarvedStructure]) error
+	mustEmbedUnimplementedSegbServer()
+}
+
+// UnimplementedSegbServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSegbServer struct{}
+
+func (UnimplementedSegbServer) DecodeFile(context.Context, *DecodeFileRequest) (*DecodeFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DecodeFile not implemented")
+}
+func (UnimplementedSegbServer) StreamEntries(*DecodeFileRequest, grpc.ServerStreamingServer[Entry]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEntries not implemented")
+}
+func (UnimplementedSegbServer) Carve(*CarveRequest, grpc.ServerStreamingServer[CarvedStructure]) error {
+	return status.Errorf(codes.Unimplemented, "method Carve not implemented")
+}
+func (UnimplementedSegbServer) mustEmbedUnimplementedSegbServer() {}
+func (UnimplementedSegbServer) testEmbeddedByValue()              {}
+
+// UnsafeSegbServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SegbServer will
+// result in compilation errors.
+type UnsafeSegbServer interface {
+	mustEmbedUnimplementedSegbServer()
+}
+
+func RegisterSegbServer(s grpc.ServiceRegistrar, srv SegbServer) {
+	// If the following call pancis, it indicates UnimplementedSegbServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Segb_ServiceDesc, srv)
+}
+
+func _Segb_DecodeFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecodeFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SegbServer).DecodeFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Segb_DecodeFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SegbServer).DecodeFile(ctx, req.(*DecodeFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Segb_StreamEntries_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DecodeFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SegbServer).StreamEntries(m, &grpc.GenericServerStream[DecodeFileRequest, Entry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Segb_StreamEntriesServer = grpc.ServerStreamingServer[Entry]
+
+func _Segb_Carve_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CarveRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SegbServer).Carve(m, &grpc.GenericServerStream[CarveRequest, CarvedStructure]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Segb_CarveServer = grpc.ServerStreamingServer[CarvedStructure]
+
+// Segb_ServiceDesc is the grpc.ServiceDesc for Segb service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Segb_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "segb.v1.Segb",
+	HandlerType: (*SegbServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DecodeFile",
+			Handler:    _Segb_DecodeFile_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEntries",
+			Handler:       _Segb_StreamEntries_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Carve",
+			Handler:       _Segb_Carve_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "segb.proto",
+}
@@ -0,0 +1,144 @@
+// Package rpc implements the gRPC service defined in rpc/segbpb, letting the
+// parser run close to evidence storage while clients stream results.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/rpc/segbpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Server serves the files below a root directory.
+type Server struct {
+	segbpb.UnimplementedSegbServer
+
+	root string
+}
+
+// NewServer returns a server resolving request paths relative to root.
+func NewServer(root string) *Server {
+	return &Server{root: root}
+}
+
+// open resolves a request path and opens the file, refusing paths that
+// escape the root.
+func (s *Server) open(path string) (*os.File, error) {
+	path = filepath.FromSlash(path)
+	if !filepath.IsLocal(path) {
+		return nil, status.Errorf(codes.InvalidArgument, "path %q is not relative to the server root", path)
+	}
+	file, err := os.Open(filepath.Join(s.root, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, status.Errorf(codes.NotFound, "%s: not found", path)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return file, nil
+}
+
+func (s *Server) decode(path string) (segb.Segb, error) {
+	file, err := s.open(path)
+	if err != nil {
+		return segb.Segb{}, err
+	}
+	defer file.Close()
+
+	decoded, err := segb.Decode(file)
+	if err != nil {
+		return segb.Segb{}, status.Errorf(codes.DataLoss, "%s: %v", path, err)
+	}
+	return decoded, nil
+}
+
+// DecodeFile implements segbpb.SegbServer.
+func (s *Server) DecodeFile(ctx context.Context, req *segbpb.DecodeFileRequest) (*segbpb.DecodeFileResponse, error) {
+	decoded, err := s.decode(req.GetPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return &segbpb.DecodeFileResponse{
+		Version: decoded.Version.String(),
+		Created: timestamppb.New(decoded.Created),
+		Entries: toEntries(decoded.Entries, req.GetIncludeData()),
+	}, nil
+}
+
+// StreamEntries implements segbpb.SegbServer.
+func (s *Server) StreamEntries(req *segbpb.DecodeFileRequest, stream segbpb.Segb_StreamEntriesServer) error {
+	decoded, err := s.decode(req.GetPath())
+	if err != nil {
+		return err
+	}
+
+	// Send blocks once the client's flow control window is full
+	for i, entry := range decoded.Entries {
+		if err := stream.Send(toEntry(i, entry, req.GetIncludeData())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Carve implements segbpb.SegbServer.
+func (s *Server) Carve(req *segbpb.CarveRequest, stream segbpb.Segb_CarveServer) error {
+	file, err := s.open(req.GetPath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return segb.CarveFunc(file, info.Size(), func(c segb.Carved) error {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		structure := &segbpb.CarvedStructure{
+			Offset:     c.Offset,
+			Length:     c.Length,
+			Version:    c.Version.String(),
+			Created:    timestamppb.New(c.Segb.Created),
+			EntryCount: int32(len(c.Segb.Entries)),
+		}
+		if req.GetIncludeEntries() {
+			structure.Entries = toEntries(c.Segb.Entries, req.GetIncludeData())
+		}
+		return stream.Send(structure)
+	})
+}
+
+func toEntries(entries []segb.Entry, withData bool) []*segbpb.Entry {
+	result := make([]*segbpb.Entry, len(entries))
+	for i, entry := range entries {
+		result[i] = toEntry(i, entry, withData)
+	}
+	return result
+}
+
+func toEntry(index int, entry segb.Entry, withData bool) *segbpb.Entry {
+	e := &segbpb.Entry{
+		Index:    int32(index),
+		Id:       int32(entry.ID),
+		State:    segbpb.EntryState(entry.State),
+		Created:  timestamppb.New(entry.Created),
+		Checksum: entry.Checksum,
+		Size:     int64(len(entry.Data)),
+		Offset:   entry.Offset,
+	}
+	if withData {
+		e.Data = entry.Data
+	}
+	return e
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"context"
+	"github.com/bluefalconhd/segb/rpc/segbpb"
+	"github.com/bluefalconhd/segb/segbtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"testing"
+)
+
+func setupClient(t *testing.T) segbpb.SegbClient {
+	root := t.TempDir()
+
+	if err := segbtest.WriteFixtures(root); err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	segbpb.RegisterSegbServer(server, NewServer(root))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return segbpb.NewSegbClient(conn)
+}
+
+func TestDecodeFile(t *testing.T) {
+	client := setupClient(t)
+
+	res, err := client.DecodeFile(context.Background(), &segbpb.DecodeFileRequest{Path: "segb_version1.bin", IncludeData: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetVersion() != "v1" || len(res.GetEntries()) != 3 {
+		t.Fatalf("DecodeFile() = %s with %d entries; want v1 with 3", res.GetVersion(), len(res.GetEntries()))
+	}
+	if got := string(res.GetEntries()[2].GetData()); got != "The rebels." {
+		t.Errorf("entries[2].Data = %q; want %q", got, "The rebels.")
+	}
+
+	_, err = client.DecodeFile(context.Background(), &segbpb.DecodeFileRequest{Path: "../etc/passwd"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("DecodeFile() outside root: %v; want InvalidArgument", err)
+	}
+}
+
+func TestStreamEntries(t *testing.T) {
+	client := setupClient(t)
+
+	stream, err := client.StreamEntries(context.Background(), &segbpb.DecodeFileRequest{Path: "segb_version2.bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*segbpb.Entry
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("len(entries) = %d; want 3", len(entries))
+	}
+	if entries[1].GetSize() != 12 || entries[1].GetData() != nil || entries[1].GetState() != segbpb.EntryState_ENTRY_STATE_WRITTEN {
+		t.Errorf("entries[1] = %v", entries[1])
+	}
+}
+
+func TestCarve(t *testing.T) {
+	client := setupClient(t)
+
+	stream, err := client.Carve(context.Background(), &segbpb.CarveRequest{Path: "segb_version2.bin", IncludeEntries: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	structure, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if structure.GetOffset() != 0 || structure.GetVersion() != "v2" || structure.GetEntryCount() != 3 {
+		t.Errorf("Carve() = %v", structure)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("second Recv() = %v; want EOF", err)
+	}
+}
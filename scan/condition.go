@@ -0,0 +1,388 @@
+package scan
+
+// value is the result of evaluating a condition expression: a boolean or an
+// integer. Undefined values, such as the offset of a string that did not
+// match, are false and compare false.
+type value struct {
+	isInt     bool
+	i         int64
+	b         bool
+	undefined bool
+}
+
+func boolValue(b bool) value { return value{b: b} }
+func intValue(i int64) value { return value{isInt: true, i: i} }
+
+func (v value) truth() bool {
+	if v.undefined {
+		return false
+	}
+	if v.isInt {
+		return v.i != 0
+	}
+	return v.b
+}
+
+type evalContext struct {
+	data    []byte
+	matches [][]StringMatch // Matches of each string of the current rule
+	results map[string]bool // Results of the rules evaluated so far
+}
+
+type expr interface {
+	eval(ctx *evalContext) value
+}
+
+type (
+	literal   value
+	filesize  struct{}
+	ruleRef   string
+	notExpr   struct{ x expr }
+	andExpr   struct{ x, y expr }
+	orExpr    struct{ x, y expr }
+	stringRef struct{ index int }
+	countRef  struct{ index int }
+	offsetRef struct{ index int }
+	stringAt  struct {
+		index int
+		at    expr
+	}
+	stringIn struct {
+		index  int
+		lo, hi expr
+	}
+	compare struct {
+		op   string
+		x, y expr
+	}
+	ofExpr struct {
+		quantifier string // "any", "all", "none", or "" for count
+		count      expr
+		set        []int
+	}
+)
+
+func (e literal) eval(*evalContext) value { return value(e) }
+
+func (filesize) eval(ctx *evalContext) value { return intValue(int64(len(ctx.data))) }
+
+func (e ruleRef) eval(ctx *evalContext) value { return boolValue(ctx.results[string(e)]) }
+
+func (e notExpr) eval(ctx *evalContext) value { return boolValue(!e.x.eval(ctx).truth()) }
+
+func (e andExpr) eval(ctx *evalContext) value {
+	return boolValue(e.x.eval(ctx).truth() && e.y.eval(ctx).truth())
+}
+
+func (e orExpr) eval(ctx *evalContext) value {
+	return boolValue(e.x.eval(ctx).truth() || e.y.eval(ctx).truth())
+}
+
+func (e stringRef) eval(ctx *evalContext) value { return boolValue(len(ctx.matches[e.index]) > 0) }
+
+func (e countRef) eval(ctx *evalContext) value { return intValue(int64(len(ctx.matches[e.index]))) }
+
+func (e offsetRef) eval(ctx *evalContext) value {
+	if len(ctx.matches[e.index]) == 0 {
+		return value{isInt: true, undefined: true}
+	}
+	return intValue(int64(ctx.matches[e.index][0].Offset))
+}
+
+func (e stringAt) eval(ctx *evalContext) value {
+	at := e.at.eval(ctx)
+	if at.undefined {
+		return boolValue(false)
+	}
+	for _, m := range ctx.matches[e.index] {
+		if int64(m.Offset) == at.i {
+			return boolValue(true)
+		}
+	}
+	return boolValue(false)
+}
+
+func (e stringIn) eval(ctx *evalContext) value {
+	lo, hi := e.lo.eval(ctx), e.hi.eval(ctx)
+	if lo.undefined || hi.undefined {
+		return boolValue(false)
+	}
+	for _, m := range ctx.matches[e.index] {
+		if int64(m.Offset) >= lo.i && int64(m.Offset) <= hi.i {
+			return boolValue(true)
+		}
+	}
+	return boolValue(false)
+}
+
+func (e compare) eval(ctx *evalContext) value {
+	x, y := e.x.eval(ctx), e.y.eval(ctx)
+	if x.undefined || y.undefined {
+		return boolValue(false)
+	}
+	a, b := x.i, y.i
+	if !x.isInt || !y.isInt {
+		a, b = boolInt(x.truth()), boolInt(y.truth())
+	}
+	switch e.op {
+	case "<":
+		return boolValue(a < b)
+	case "<=":
+		return boolValue(a <= b)
+	case ">":
+		return boolValue(a > b)
+	case ">=":
+		return boolValue(a >= b)
+	case "==":
+		return boolValue(a == b)
+	default:
+		return boolValue(a != b)
+	}
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (e ofExpr) eval(ctx *evalContext) value {
+	n := int64(0)
+	for _, index := range e.set {
+		if len(ctx.matches[index]) > 0 {
+			n++
+		}
+	}
+	switch e.quantifier {
+	case "any":
+		return boolValue(n > 0)
+	case "all":
+		return boolValue(n == int64(len(e.set)))
+	case "none":
+		return boolValue(n == 0)
+	default:
+		return boolValue(n >= e.count.eval(ctx).i)
+	}
+}
+
+// condParser parses the condition of a rule by recursive descent, from the
+// lowest precedence (or) to the highest (primary expressions).
+type condParser struct {
+	l     *lexer
+	rule  *rule
+	rules map[string]bool // Rules defined before this one
+}
+
+func (p *condParser) peekWord(word string) bool {
+	t, err := p.l.peek()
+	return err == nil && t.kind == tIdent && t.text == word
+}
+
+func (p *condParser) parseOr() (expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("or") {
+		p.l.next()
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = orExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *condParser) parseAnd() (expr, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("and") {
+		p.l.next()
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = andExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *condParser) parseNot() (expr, error) {
+	if p.peekWord("not") {
+		p.l.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *condParser) parseCompare() (expr, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.l.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch t.text {
+	case "<", "<=", ">", ">=", "==", "!=":
+		if t.kind != tPunct {
+			return x, nil
+		}
+		p.l.next()
+		y, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return compare{t.text, x, y}, nil
+	}
+	return x, nil
+}
+
+// stringIndex resolves a single string identifier of the rule.
+func (p *condParser) stringIndex(id string) (int, error) {
+	set := patternSet(p.rule.patterns, id)
+	if len(set) != 1 || id == "$" {
+		return 0, p.l.errorf("undefined string %s", id)
+	}
+	return set[0], nil
+}
+
+func (p *condParser) parsePrimary() (expr, error) {
+	t, err := p.l.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case tPunct:
+		if t.text != "(" {
+			break
+		}
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.l.expect(")")
+
+	case tNumber:
+		if p.peekWord("of") {
+			return p.parseOf("", literal(intValue(t.num)))
+		}
+		return literal(intValue(t.num)), nil
+
+	case tStringID:
+		index, err := p.stringIndex(t.text)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.peekWord("at"):
+			p.l.next()
+			at, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return stringAt{index, at}, nil
+		case p.peekWord("in"):
+			p.l.next()
+			if err := p.l.expect("("); err != nil {
+				return nil, err
+			}
+			lo, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.l.expect(".."); err != nil {
+				return nil, err
+			}
+			hi, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return stringIn{index, lo, hi}, p.l.expect(")")
+		}
+		return stringRef{index}, nil
+
+	case tCount, tOffset:
+		index, err := p.stringIndex("$" + t.text[1:])
+		if err != nil {
+			return nil, err
+		}
+		if t.kind == tCount {
+			return countRef{index}, nil
+		}
+		return offsetRef{index}, nil
+
+	case tIdent:
+		switch t.text {
+		case "true", "false":
+			return literal(boolValue(t.text == "true")), nil
+		case "filesize":
+			return filesize{}, nil
+		case "any", "all", "none":
+			return p.parseOf(t.text, nil)
+		}
+		if p.rules[t.text] {
+			return ruleRef(t.text), nil
+		}
+		return nil, p.l.errorf("undefined identifier %s", t.text)
+	}
+	return nil, p.l.errorf("unexpected %q in condition", t.text)
+}
+
+// parseOf parses the "of" part of a quantified expression.
+func (p *condParser) parseOf(quantifier string, count expr) (expr, error) {
+	if err := p.l.expect("of"); err != nil {
+		return nil, err
+	}
+	e := ofExpr{quantifier: quantifier, count: count}
+
+	if p.peekWord("them") {
+		p.l.next()
+		for i := range p.rule.patterns {
+			e.set = append(e.set, i)
+		}
+	} else {
+		if err := p.l.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			t, err := p.l.next()
+			if err != nil {
+				return nil, err
+			}
+			if t.kind != tStringID {
+				return nil, p.l.errorf("expected string identifier, got %q", t.text)
+			}
+			set := patternSet(p.rule.patterns, t.text)
+			if len(set) == 0 {
+				return nil, p.l.errorf("undefined string %s", t.text)
+			}
+			e.set = append(e.set, set...)
+
+			t, err = p.l.next()
+			if err != nil {
+				return nil, err
+			}
+			if t.text == ")" {
+				break
+			}
+			if t.text != "," {
+				return nil, p.l.errorf("expected , or ), got %q", t.text)
+			}
+		}
+	}
+
+	if len(e.set) == 0 {
+		return nil, p.l.errorf("empty string set")
+	}
+	return e, nil
+}
@@ -0,0 +1,304 @@
+package scan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kinds of lexical tokens
+const (
+	tEOF = iota
+	tIdent
+	tStringID // $a, $a*, $
+	tCount    // #a
+	tOffset   // @a
+	tText     // "..."
+	tNumber
+	tPunct
+)
+
+type token struct {
+	kind int
+	text string
+	num  int64
+	line int
+}
+
+// lexer splits rule source into tokens. Hex strings and regular expressions
+// are only valid as string values, so the parser reads them with readHex and
+// readRegex instead of next.
+type lexer struct {
+	src    string
+	pos    int
+	line   int
+	peeked *token
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1}
+}
+
+func (l *lexer) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments.
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return l.errorf("unterminated comment")
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		t, err := l.scan()
+		if err != nil {
+			return t, err
+		}
+		l.peeked = &t
+	}
+	return *l.peeked, nil
+}
+
+func (l *lexer) next() (token, error) {
+	t, err := l.peek()
+	l.peeked = nil
+	return t, err
+}
+
+// expect consumes the next token, failing unless it has the given text.
+func (l *lexer) expect(text string) error {
+	t, err := l.next()
+	if err != nil {
+		return err
+	}
+	if t.text != text || t.kind == tText {
+		return l.errorf("expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (l *lexer) scan() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tEOF, line: l.line}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case c == '"':
+		return l.scanText()
+
+	case c == '$' || c == '#' || c == '@':
+		l.pos++
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		kind := map[byte]int{'$': tStringID, '#': tCount, '@': tOffset}[c]
+		if c == '$' && l.pos < len(l.src) && l.src[l.pos] == '*' {
+			l.pos++
+		}
+		return token{kind: kind, text: l.src[start:l.pos], line: l.line}, nil
+
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && (isIdentChar(l.src[l.pos])) {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		n, err := parseNumber(text)
+		if err != nil {
+			return token{}, l.errorf("invalid number %q", text)
+		}
+		return token{kind: tNumber, text: text, num: n, line: l.line}, nil
+
+	case isIdentChar(c):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tIdent, text: l.src[start:l.pos], line: l.line}, nil
+	}
+
+	for _, p := range []string{"..", "<=", ">=", "==", "!="} {
+		if strings.HasPrefix(l.src[l.pos:], p) {
+			l.pos += len(p)
+			return token{kind: tPunct, text: p, line: l.line}, nil
+		}
+	}
+	if strings.ContainsRune("{}()[]:=,<>", rune(c)) {
+		l.pos++
+		return token{kind: tPunct, text: string(c), line: l.line}, nil
+	}
+	return token{}, l.errorf("unexpected character %q", c)
+}
+
+// scanText reads a double-quoted string, decoding its escape sequences.
+func (l *lexer) scanText() (token, error) {
+	l.pos++
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return token{}, l.errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		l.pos++
+		if c == '"' {
+			return token{kind: tText, text: b.String(), line: l.line}, nil
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if l.pos >= len(l.src) {
+			return token{}, l.errorf("unterminated string")
+		}
+		c = l.src[l.pos]
+		l.pos++
+		switch c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\', '"':
+			b.WriteByte(c)
+		case 'x':
+			if l.pos+2 > len(l.src) {
+				return token{}, l.errorf("invalid escape")
+			}
+			v, err := strconv.ParseUint(l.src[l.pos:l.pos+2], 16, 8)
+			if err != nil {
+				return token{}, l.errorf("invalid escape \\x%s", l.src[l.pos:l.pos+2])
+			}
+			b.WriteByte(byte(v))
+			l.pos += 2
+		default:
+			return token{}, l.errorf("invalid escape \\%c", c)
+		}
+	}
+}
+
+// readHex reads a hex string, returning the text between its braces.
+func (l *lexer) readHex() (string, error) {
+	start := l.pos + 1
+	end := strings.IndexByte(l.src[start:], '}')
+	if end < 0 {
+		return "", l.errorf("unterminated hex string")
+	}
+	body := l.src[start : start+end]
+	l.line += strings.Count(body, "\n")
+	l.pos = start + end + 1
+
+	// Hex strings may hold comments
+	var b strings.Builder
+	for len(body) > 0 {
+		switch {
+		case strings.HasPrefix(body, "//"):
+			end := strings.IndexByte(body, '\n')
+			if end < 0 {
+				end = len(body)
+			}
+			body = body[end:]
+		case strings.HasPrefix(body, "/*"):
+			end := strings.Index(body, "*/")
+			if end < 0 {
+				return "", l.errorf("unterminated comment")
+			}
+			body = body[end+2:]
+		default:
+			b.WriteByte(body[0])
+			body = body[1:]
+		}
+	}
+	return b.String(), nil
+}
+
+// readRegex reads a regular expression, returning it and its flags.
+func (l *lexer) readRegex() (string, string, error) {
+	var b strings.Builder
+	for l.pos++; ; l.pos++ {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return "", "", l.errorf("unterminated regular expression")
+		}
+		c := l.src[l.pos]
+		if c == '/' {
+			break
+		}
+		if c == '\\' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '/' {
+			c = '/'
+			l.pos++
+		} else if c == '\\' && l.pos+1 < len(l.src) {
+			b.WriteByte(c)
+			l.pos++
+			c = l.src[l.pos]
+		}
+		b.WriteByte(c)
+	}
+	l.pos++
+	start := l.pos
+	for l.pos < len(l.src) && (l.src[l.pos] == 'i' || l.src[l.pos] == 's') {
+		l.pos++
+	}
+	return b.String(), l.src[start:l.pos], nil
+}
+
+// peekRaw returns the next non-space character without consuming it.
+func (l *lexer) peekRaw() (byte, error) {
+	if l.peeked != nil {
+		return 0, l.errorf("internal error: raw read after peek")
+	}
+	if err := l.skipSpace(); err != nil {
+		return 0, err
+	}
+	if l.pos >= len(l.src) {
+		return 0, nil
+	}
+	return l.src[l.pos], nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isAlnum(c)
+}
+
+// parseNumber parses decimal and hex integers with an optional KB or MB
+// suffix.
+func parseNumber(s string) (int64, error) {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "KB"):
+		multiplier, s = 1024, strings.TrimSuffix(s, "KB")
+	case strings.HasSuffix(s, "MB"):
+		multiplier, s = 1024*1024, strings.TrimSuffix(s, "MB")
+	}
+	base := 10
+	if strings.HasPrefix(s, "0x") {
+		base, s = 16, s[2:]
+	}
+	n, err := strconv.ParseInt(s, base, 64)
+	return n * multiplier, err
+}
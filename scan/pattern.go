@@ -0,0 +1,462 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxMatchesPerString caps how many matches of one string are recorded per
+// payload.
+const maxMatchesPerString = 1000
+
+// maxJump is the largest bound of a jump in a hex string. Matching keeps a
+// window of the payload as long as the longest minimum of a jump.
+const maxJump = 1<<16 - 1
+
+// Kinds of pattern tokens
+const (
+	tokByte = iota // A byte, compared under a mask
+	tokJump        // Any number of bytes between min and max (-1: unbounded)
+	tokAlt         // One of several token sequences
+	tokGoto        // Continue at another node, in compiled programs only
+)
+
+type patternToken struct {
+	kind     int
+	value    byte
+	mask     byte
+	fold     bool // Compare ASCII letters case-insensitively
+	min, max int
+	alts     [][]patternToken
+}
+
+// pattern is a compiled rule string.
+type pattern struct {
+	id       string
+	tokens   []patternToken // Text and hex strings of bytes only
+	prog     []node         // Text and hex strings with jumps or alternatives
+	re       *regexp.Regexp // Regular expressions
+	fullword bool
+}
+
+// tokenPattern returns the pattern of a text or hex string, compiling
+// tokens to a program if they are not all bytes.
+func tokenPattern(id string, tokens []patternToken, fullword bool) *pattern {
+	p := &pattern{id: id, fullword: fullword}
+	for _, t := range tokens {
+		if t.kind != tokByte {
+			p.prog = compileTokens(tokens)
+			return p
+		}
+	}
+	p.tokens = tokens
+	return p
+}
+
+// textPattern compiles a text string with its modifiers.
+func textPattern(id string, text []byte, mods modifiers) (*pattern, error) {
+	if mods.has("xor") || mods.has("base64") || mods.has("base64wide") {
+		return nil, fmt.Errorf("%s: xor and base64 modifiers are not supported", id)
+	}
+	if len(text) == 0 {
+		return nil, fmt.Errorf("%s: empty string", id)
+	}
+	fold := mods.has("nocase")
+	seq := func(data []byte) []patternToken {
+		tokens := make([]patternToken, len(data))
+		for i, b := range data {
+			if fold {
+				b = lower(b)
+			}
+			tokens[i] = patternToken{kind: tokByte, value: b, mask: 0xff, fold: fold}
+		}
+		return tokens
+	}
+
+	ascii := seq(text)
+	wide := seq(widen(text))
+	var tokens []patternToken
+	switch {
+	case mods.has("wide") && mods.has("ascii"):
+		tokens = []patternToken{{kind: tokAlt, alts: [][]patternToken{ascii, wide}}}
+	case mods.has("wide"):
+		tokens = wide
+	default:
+		tokens = ascii
+	}
+	return tokenPattern(id, tokens, mods.has("fullword")), nil
+}
+
+// highByteEscape matches an escape of a byte above 0x7f, preceded by an even
+// number of backslashes.
+var highByteEscape = regexp.MustCompile(`(^|[^\\])(\\\\)*\\x[89a-fA-F]`)
+
+// regexPattern compiles a regular expression string. Expressions are run by
+// Go's regexp package, so bytes above 0x7f only match as part of valid UTF-8,
+// and escapes of such bytes, which YARA matches as raw bytes, are rejected.
+func regexPattern(id, expr, flags string, mods modifiers) (*pattern, error) {
+	if mods.has("wide") || mods.has("xor") || mods.has("base64") || mods.has("base64wide") {
+		return nil, fmt.Errorf("%s: wide, xor and base64 modifiers are not supported on regular expressions", id)
+	}
+	if highByteEscape.MatchString(expr) {
+		return nil, fmt.Errorf("%s: byte escapes above \\x7f are not supported in regular expressions", id)
+	}
+	prefix := ""
+	if strings.Contains(flags, "i") || mods.has("nocase") {
+		prefix += "i"
+	}
+	if strings.Contains(flags, "s") {
+		prefix += "s"
+	}
+	if prefix != "" {
+		expr = "(?" + prefix + ")" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	return &pattern{id: id, re: re, fullword: mods.has("fullword")}, nil
+}
+
+// hexPattern compiles the body of a hex string, e.g. "4D 5A ?? [2-4] (00|FF)".
+func hexPattern(id, body string) (*pattern, error) {
+	// Pad delimiters so the body splits into fields
+	for _, d := range []string{"(", ")", "|", "[", "]"} {
+		body = strings.ReplaceAll(body, d, " "+d+" ")
+	}
+	fields := strings.Fields(body)
+
+	tokens, rest, err := parseHexSequence(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%s: unexpected %q in hex string", id, rest[0])
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s: empty hex string", id)
+	}
+	return tokenPattern(id, tokens, false), nil
+}
+
+// parseHexSequence parses hex tokens until the end of the fields or a "|"
+// or ")" closing an alternative, which are left in the returned fields.
+func parseHexSequence(fields []string) ([]patternToken, []string, error) {
+	var tokens []patternToken
+	for len(fields) > 0 {
+		f := fields[0]
+		switch f {
+		case "|", ")":
+			return tokens, fields, nil
+
+		case "(":
+			var alt patternToken
+			alt.kind = tokAlt
+			fields = fields[1:]
+			for {
+				seq, rest, err := parseHexSequence(fields)
+				if err != nil {
+					return nil, nil, err
+				}
+				alt.alts = append(alt.alts, seq)
+				if len(rest) == 0 {
+					return nil, nil, fmt.Errorf("unterminated alternative")
+				}
+				fields = rest[1:]
+				if rest[0] == ")" {
+					break
+				}
+			}
+			tokens = append(tokens, alt)
+			continue
+
+		case "[":
+			if len(fields) < 3 || fields[2] != "]" {
+				return nil, nil, fmt.Errorf("malformed jump")
+			}
+			jump, err := parseJump(fields[1])
+			if err != nil {
+				return nil, nil, err
+			}
+			tokens = append(tokens, jump)
+			fields = fields[3:]
+			continue
+		}
+
+		// A run of hex digits and wildcards, two characters per byte
+		if len(f)%2 != 0 {
+			return nil, nil, fmt.Errorf("odd number of hex digits in %q", f)
+		}
+		for i := 0; i < len(f); i += 2 {
+			t, err := parseHexByte(f[i : i+2])
+			if err != nil {
+				return nil, nil, err
+			}
+			tokens = append(tokens, t)
+		}
+		fields = fields[1:]
+	}
+	return tokens, nil, nil
+}
+
+func parseHexByte(s string) (patternToken, error) {
+	t := patternToken{kind: tokByte}
+	for i, shift := range []uint{4, 0} {
+		c := s[i]
+		if c == '?' {
+			continue
+		}
+		v, err := strconv.ParseUint(string(c), 16, 8)
+		if err != nil {
+			return t, fmt.Errorf("invalid hex byte %q", s)
+		}
+		t.value |= byte(v) << shift
+		t.mask |= 0x0f << shift
+	}
+	return t, nil
+}
+
+// parseJump parses the inside of a jump: "n", "n-m", "n-" or "-".
+func parseJump(s string) (patternToken, error) {
+	t := patternToken{kind: tokJump}
+	lo, hi, ranged := strings.Cut(s, "-")
+	var err error
+	if lo != "" {
+		if t.min, err = strconv.Atoi(lo); err != nil {
+			return t, fmt.Errorf("invalid jump [%s]", s)
+		}
+	}
+	switch {
+	case !ranged:
+		t.max = t.min
+	case hi == "":
+		t.max = -1
+	default:
+		if t.max, err = strconv.Atoi(hi); err != nil || t.max < t.min {
+			return t, fmt.Errorf("invalid jump [%s]", s)
+		}
+	}
+	if t.min < 0 || t.max > maxJump || t.min > maxJump {
+		return t, fmt.Errorf("jump [%s] is out of range: bounds are limited to %d", s, maxJump)
+	}
+	return t, nil
+}
+
+// node is an instruction of a compiled text or hex string: a byte or jump,
+// continuing at next, alternatives starting at alts, or a goto to next.
+type node struct {
+	kind        int
+	value, mask byte
+	fold        bool
+	min, max    int
+	alts        []int
+	next        int // len(prog) is the end of the pattern
+}
+
+// compileTokens lays tokens out as a program in which nodes only refer to
+// nodes after them: each byte and jump continues at the following node, and
+// each alternative ends with a goto past the last alternative.
+func compileTokens(tokens []patternToken) []node {
+	var prog []node
+	var emit func(tokens []patternToken)
+	emit = func(tokens []patternToken) {
+		for _, t := range tokens {
+			i := len(prog)
+			prog = append(prog, node{kind: t.kind, value: t.value, mask: t.mask, fold: t.fold, min: t.min, max: t.max, next: i + 1})
+			if t.kind != tokAlt {
+				continue
+			}
+			var gotos []int
+			for _, alt := range t.alts {
+				prog[i].alts = append(prog[i].alts, len(prog))
+				emit(alt)
+				gotos = append(gotos, len(prog))
+				prog = append(prog, node{kind: tokGoto})
+			}
+			for _, g := range gotos {
+				prog[g].next = len(prog)
+			}
+		}
+	}
+	emit(tokens)
+	return prog
+}
+
+// cell is the result of a node at a position: the end of its match there,
+// and the first position at or after it where the node matches, with the
+// end of that match; -1 when there is none.
+type cell struct {
+	end, first, firstEnd int
+}
+
+var noMatch = cell{-1, -1, -1}
+
+// matchProgram returns the end of the match of the program at each position
+// of data, or -1. The match is the one backtracking would find first, with
+// jumps as short as possible and alternatives tried in order.
+//
+// Positions are evaluated from the end of data backwards and nodes from the
+// last to the first, so every result a node needs is known: a byte needs
+// its successor one position on, a jump the first match of its successor
+// from min positions on. Only a window of positions as deep as the largest
+// jump minimum is kept, and the time is linear in the length of data.
+func matchProgram(prog []node, data []byte) []int {
+	lookahead := 1
+	for _, nd := range prog {
+		if nd.kind == tokJump && nd.min > lookahead {
+			lookahead = nd.min
+		}
+	}
+	n, m := len(data), len(prog)
+	window := min(lookahead+1, n+1)
+	cells := make([]cell, window*m)
+	get := func(k, pos int) cell {
+		switch {
+		case pos > n:
+			return noMatch
+		case k == m:
+			return cell{pos, pos, pos}
+		}
+		return cells[pos%window*m+k]
+	}
+
+	ends := make([]int, n)
+	for pos := n; pos >= 0; pos-- {
+		row := cells[pos%window*m:]
+		for k := m - 1; k >= 0; k-- {
+			nd := &prog[k]
+			end := -1
+			switch nd.kind {
+			case tokByte:
+				if pos < n {
+					b := data[pos]
+					if nd.fold {
+						b = lower(b)
+					}
+					if b&nd.mask == nd.value {
+						end = get(nd.next, pos+1).end
+					}
+				}
+			case tokJump:
+				c := get(nd.next, pos+nd.min)
+				if c.first >= 0 && (nd.max < 0 || c.first <= pos+nd.max) {
+					end = c.firstEnd
+				}
+			case tokAlt:
+				for _, alt := range nd.alts {
+					if end = get(alt, pos).end; end >= 0 {
+						break
+					}
+				}
+			case tokGoto:
+				end = get(nd.next, pos).end
+			}
+			if end >= 0 {
+				row[k] = cell{end, pos, end}
+			} else {
+				row[k] = get(k, pos+1)
+				row[k].end = -1
+			}
+		}
+		if pos < n {
+			ends[pos] = row[0].end
+		}
+	}
+	return ends
+}
+
+// matchBytes reports whether tokens, all bytes, match data at pos.
+func matchBytes(tokens []patternToken, data []byte, pos int) bool {
+	if len(data)-pos < len(tokens) {
+		return false
+	}
+	for i, t := range tokens {
+		b := data[pos+i]
+		if t.fold {
+			b = lower(b)
+		}
+		if b&t.mask != t.value {
+			return false
+		}
+	}
+	return true
+}
+
+// find returns every match of the pattern in data.
+func (p *pattern) find(data []byte) []StringMatch {
+	var matches []StringMatch
+	add := func(start, end int) bool {
+		if p.fullword && !isWord(data, start, end) {
+			return true
+		}
+		matches = append(matches, StringMatch{ID: p.id, Offset: start, Data: data[start:end]})
+		return len(matches) < maxMatchesPerString
+	}
+
+	if p.re != nil {
+		for _, loc := range p.re.FindAllIndex(data, -1) {
+			if !add(loc[0], loc[1]) {
+				break
+			}
+		}
+		return matches
+	}
+
+	if p.prog != nil {
+		for pos, end := range matchProgram(p.prog, data) {
+			if end >= 0 && !add(pos, end) {
+				break
+			}
+		}
+		return matches
+	}
+
+	// Skip ahead to candidate positions when the first byte is fixed
+	first := p.tokens[0]
+	anchored := first.kind == tokByte && first.mask == 0xff && !first.fold
+	for pos := 0; pos < len(data); pos++ {
+		if anchored {
+			n := bytes.IndexByte(data[pos:], first.value)
+			if n < 0 {
+				break
+			}
+			pos += n
+		}
+		if matchBytes(p.tokens, data, pos) && !add(pos, pos+len(p.tokens)) {
+			break
+		}
+	}
+	return matches
+}
+
+// isWord reports whether data[start:end] is delimited by non-alphanumeric
+// characters.
+func isWord(data []byte, start, end int) bool {
+	if start > 0 && isAlnum(data[start-1]) {
+		return false
+	}
+	return end >= len(data) || !isAlnum(data[end])
+}
+
+func isAlnum(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func lower(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// widen interleaves zero bytes, turning ASCII text into UTF-16LE.
+func widen(text []byte) []byte {
+	wide := make([]byte, 0, 2*len(text))
+	for _, b := range text {
+		wide = append(wide, b, 0)
+	}
+	return wide
+}
@@ -0,0 +1,346 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rules is a compiled set of rules run by the built-in engine.
+//
+// The engine supports a practical subset of the YARA language:
+//
+//   - rules with tags, meta and the private and global modifiers
+//   - text strings with the nocase, wide, ascii, fullword and private
+//     modifiers, hex strings with wildcards, jumps of up to 65535 bytes,
+//     alternatives and the private modifier, and regular expressions with
+//     the i and s flags
+//   - conditions built from and, or, not and parentheses over true, false,
+//     $a, $a at N, $a in (N..M), #a, @a, filesize, comparisons, references
+//     to earlier rules, and "any/all/none/N of them" or of a set like
+//     ($a, $b*)
+//
+// Everything else fails to compile rather than being ignored: modules
+// (import), includes, external variables, loops, arithmetic, xor and base64
+// strings, and, since regular expressions run on Go's regexp package and
+// match text as UTF-8, byte escapes above \x7f in them.
+//
+// Text and hex strings match in time linear in the length of the payload,
+// whatever their jumps and alternatives.
+type Rules struct {
+	rules []*rule
+}
+
+type rule struct {
+	name      string
+	tags      []string
+	meta      map[string]string
+	private   bool
+	global    bool
+	patterns  []*pattern
+	condition expr
+}
+
+// Compile parses rule source.
+func Compile(src string) (*Rules, error) {
+	l := newLexer(src)
+	rules := &Rules{}
+	names := map[string]bool{}
+	for {
+		t, err := l.peek()
+		if err != nil {
+			return nil, err
+		}
+		if t.kind == tEOF {
+			return rules, nil
+		}
+		r, err := parseRule(l, names)
+		if err != nil {
+			return nil, err
+		}
+		names[r.name] = true
+		rules.rules = append(rules.rules, r)
+	}
+}
+
+// CompileFile parses rules from a file.
+func CompileFile(path string) (*Rules, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Compile(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func parseRule(l *lexer, names map[string]bool) (*rule, error) {
+	r := &rule{meta: map[string]string{}}
+
+	// Modifiers and name
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.kind == tIdent && t.text == "private":
+			r.private = true
+			continue
+		case t.kind == tIdent && t.text == "global":
+			r.global = true
+			continue
+		case t.kind == tIdent && t.text == "import", t.kind == tIdent && t.text == "include":
+			return nil, l.errorf("%s is not supported", t.text)
+		case t.kind == tIdent && t.text == "rule":
+		default:
+			return nil, l.errorf("expected rule, got %q", t.text)
+		}
+		break
+	}
+	name, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	if name.kind != tIdent {
+		return nil, l.errorf("expected rule name, got %q", name.text)
+	}
+	if names[name.text] {
+		return nil, l.errorf("duplicate rule %s", name.text)
+	}
+	r.name = name.text
+
+	// Tags
+	if t, err := l.peek(); err != nil {
+		return nil, err
+	} else if t.text == ":" {
+		l.next()
+		for {
+			t, err := l.peek()
+			if err != nil {
+				return nil, err
+			}
+			if t.kind != tIdent {
+				break
+			}
+			l.next()
+			r.tags = append(r.tags, t.text)
+		}
+	}
+
+	if err := l.expect("{"); err != nil {
+		return nil, err
+	}
+
+	// Sections
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "}" && t.kind == tPunct {
+			break
+		}
+		if err := l.expect(":"); err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "meta":
+			err = parseMeta(l, r)
+		case "strings":
+			err = parseStrings(l, r)
+		case "condition":
+			p := &condParser{l: l, rule: r, rules: names}
+			r.condition, err = p.parseOr()
+		default:
+			err = l.errorf("unknown section %q", t.text)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.name, err)
+		}
+	}
+	if r.condition == nil {
+		return nil, l.errorf("rule %s has no condition", r.name)
+	}
+	return r, nil
+}
+
+func parseMeta(l *lexer, r *rule) error {
+	for {
+		key, err := l.peek()
+		if err != nil {
+			return err
+		}
+		if key.kind != tIdent || key.text == "strings" || key.text == "condition" {
+			return nil
+		}
+		l.next()
+		if err := l.expect("="); err != nil {
+			return err
+		}
+		value, err := l.next()
+		if err != nil {
+			return err
+		}
+		if value.kind != tText && value.kind != tNumber && value.kind != tIdent {
+			return l.errorf("invalid value for meta %s", key.text)
+		}
+		r.meta[key.text] = value.text
+	}
+}
+
+// modifiers is the set of modifiers following a string.
+type modifiers map[string]bool
+
+func (m modifiers) has(name string) bool { return m[name] }
+
+func parseModifiers(l *lexer) (modifiers, error) {
+	mods := modifiers{}
+	for {
+		t, err := l.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "nocase", "wide", "ascii", "fullword", "private", "xor", "base64", "base64wide":
+			l.next()
+			mods[t.text] = true
+		default:
+			return mods, nil
+		}
+	}
+}
+
+func parseStrings(l *lexer, r *rule) error {
+	for {
+		id, err := l.peek()
+		if err != nil {
+			return err
+		}
+		if id.kind != tStringID {
+			return nil
+		}
+		l.next()
+		if err := l.expect("="); err != nil {
+			return err
+		}
+
+		var p *pattern
+		c, err := l.peekRaw()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case '{':
+			body, err := l.readHex()
+			if err != nil {
+				return err
+			}
+			if p, err = hexPattern(id.text, body); err != nil {
+				return err
+			}
+			mods, err := parseModifiers(l)
+			if err != nil {
+				return err
+			}
+			for mod := range mods {
+				if mod != "private" {
+					return l.errorf("%s: the %s modifier is not supported on hex strings", id.text, mod)
+				}
+			}
+		case '/':
+			expr, flags, err := l.readRegex()
+			if err != nil {
+				return err
+			}
+			mods, err := parseModifiers(l)
+			if err != nil {
+				return err
+			}
+			if p, err = regexPattern(id.text, expr, flags, mods); err != nil {
+				return err
+			}
+		default:
+			text, err := l.next()
+			if err != nil {
+				return err
+			}
+			if text.kind != tText {
+				return l.errorf("invalid value for %s", id.text)
+			}
+			mods, err := parseModifiers(l)
+			if err != nil {
+				return err
+			}
+			if p, err = textPattern(id.text, []byte(text.text), mods); err != nil {
+				return err
+			}
+		}
+
+		if id.text != "$" {
+			for _, existing := range r.patterns {
+				if existing.id == id.text {
+					return l.errorf("duplicate string %s", id.text)
+				}
+			}
+		}
+		r.patterns = append(r.patterns, p)
+	}
+}
+
+// Names returns the names of the compiled rules, in source order.
+func (rs *Rules) Names() []string {
+	names := make([]string, len(rs.rules))
+	for i, r := range rs.rules {
+		names[i] = r.name
+	}
+	return names
+}
+
+// Scan implements Scanner.
+func (rs *Rules) Scan(data []byte) ([]Hit, error) {
+	ctx := &evalContext{data: data, results: map[string]bool{}}
+	var hits []Hit
+	for _, r := range rs.rules {
+		ctx.matches = make([][]StringMatch, len(r.patterns))
+		for i, p := range r.patterns {
+			ctx.matches[i] = p.find(data)
+		}
+
+		matched := r.condition.eval(ctx).truth()
+		ctx.results[r.name] = matched
+
+		// A failing global rule prevents every rule from matching
+		if r.global && !matched {
+			return nil, nil
+		}
+		if !matched || r.private {
+			continue
+		}
+
+		hit := Hit{Rule: r.name, Tags: r.tags, Meta: r.meta}
+		for _, m := range ctx.matches {
+			hit.Strings = append(hit.Strings, m...)
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// patternSet resolves a string reference like "$a" or "$a*" to the indexes
+// of the strings it covers.
+func patternSet(patterns []*pattern, ref string) []int {
+	var set []int
+	for i, p := range patterns {
+		match := p.id == ref
+		if prefix, ok := strings.CutSuffix(ref, "*"); ok {
+			match = strings.HasPrefix(p.id, prefix)
+		}
+		if match {
+			set = append(set, i)
+		}
+	}
+	return set
+}
@@ -0,0 +1,53 @@
+// Package scan matches YARA-style rules against SEGB entry payloads.
+//
+// Matching goes through the Scanner interface. Rules, compiled from YARA
+// source, is the built-in pure Go implementation of a subset of the
+// language, documented on Rules; rules outside it fail to compile. The
+// package does not link libyara: an engine backed by it, such as go-yara,
+// can be used instead by implementing Scanner.
+package scan
+
+import (
+	"github.com/bluefalconhd/segb"
+)
+
+// Scanner matches a set of rules against a payload.
+type Scanner interface {
+	Scan(data []byte) ([]Hit, error)
+}
+
+// StringMatch is one match of a rule string.
+type StringMatch struct {
+	ID     string // String identifier, e.g. "$a"
+	Offset int    // Offset of the match within the payload
+	Data   []byte // Matched bytes
+}
+
+// Hit is a rule that matched a payload.
+type Hit struct {
+	Rule    string
+	Tags    []string
+	Meta    map[string]string
+	Strings []StringMatch
+}
+
+// EntryHit is a rule that matched the payload of an entry.
+type EntryHit struct {
+	Index int // Index of the entry in Segb.Entries
+	Hit
+}
+
+// ScanSegb runs the scanner over every entry payload of a decoded file.
+func ScanSegb(scanner Scanner, s segb.Segb) ([]EntryHit, error) {
+	var hits []EntryHit
+	for i, entry := range s.Entries {
+		found, err := scanner.Scan(entry.Data)
+		if err != nil {
+			return hits, err
+		}
+		for _, hit := range found {
+			hits = append(hits, EntryHit{Index: i, Hit: hit})
+		}
+	}
+	return hits, nil
+}
@@ -0,0 +1,247 @@
+package scan
+
+import (
+	"bytes"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/segbtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testRules = `
+/* Test rules */
+rule Crazy : quote apple {
+	meta:
+		author = "test"
+		score = 10
+	strings:
+		$crazy = "crazy ones" nocase
+		$here = { 48 65 72 65 ?? 73 } // "Here's"
+	condition:
+		$crazy and $here at 0
+}
+
+rule Misfits {
+	strings:
+		$a = /mis+fits?/
+		$b = "rebels"
+	condition:
+		any of them
+}
+
+private rule Short {
+	condition:
+		filesize < 20
+}
+
+rule ShortAndNotCrazy {
+	condition:
+		Short and not Crazy
+}
+`
+
+func hitRules(hits []Hit) []string {
+	var names []string
+	for _, hit := range hits {
+		names = append(names, hit.Rule)
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRules(t *testing.T) {
+	rules, err := Compile(testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		data string
+		want []string
+	}{
+		{"Here's to the CRAZY ONES.", []string{"Crazy"}},
+		{"The misfits.", []string{"Misfits", "ShortAndNotCrazy"}},
+		{"The rebels.", []string{"Misfits", "ShortAndNotCrazy"}},
+		{"Nothing to see in this one.", nil},
+	} {
+		hits, err := rules.Scan([]byte(tc.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hitRules(hits); !equal(got, tc.want) {
+			t.Errorf("Scan(%q) = %v; want %v", tc.data, got, tc.want)
+		}
+	}
+
+	hits, _ := rules.Scan([]byte("Here's to the crazy ones."))
+	hit := hits[0]
+	if hit.Meta["author"] != "test" || !equal(hit.Tags, []string{"quote", "apple"}) {
+		t.Errorf("hit = %+v", hit)
+	}
+	if len(hit.Strings) != 2 || hit.Strings[0].Offset != 14 || hit.Strings[1].Offset != 0 {
+		t.Errorf("hit.Strings = %+v", hit.Strings)
+	}
+}
+
+func TestHexAndModifiers(t *testing.T) {
+	rules, err := Compile(`
+rule Hex {
+	strings:
+		$jump = { 01 02 [1-3] 05 }
+		$alt  = { AA ( BB | CC DD ) EE }
+		$nib  = { F? 0F }
+	condition:
+		#jump == 1 and #alt == 2 and @nib == 10
+}
+
+rule Wide {
+	strings:
+		$w = "key" wide
+		$f = "word" fullword
+	condition:
+		all of ($w, $f)
+}
+
+rule Count {
+	strings:
+		$x1 = "x"
+		$x2 = "y"
+		$z = "z"
+	condition:
+		2 of ($x*) and $z in (0..1)
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0xaa, 0xbb, 0xee, 0x00, 0x00, 0xf1, 0x0f, 0xaa, 0xcc, 0xdd, 0xee}
+	if got := hitRules(mustScan(t, rules, data)); !equal(got, []string{"Hex"}) {
+		t.Errorf("hex: got %v", got)
+	}
+
+	data = []byte("k\x00e\x00y\x00 a word.")
+	if got := hitRules(mustScan(t, rules, data)); !equal(got, []string{"Wide"}) {
+		t.Errorf("wide: got %v", got)
+	}
+	if got := hitRules(mustScan(t, rules, []byte("k\x00e\x00y\x00 passwords"))); got != nil {
+		t.Errorf("fullword: got %v", got)
+	}
+
+	if got := hitRules(mustScan(t, rules, []byte("zxy"))); !equal(got, []string{"Count"}) {
+		t.Errorf("count: got %v", got)
+	}
+	if got := hitRules(mustScan(t, rules, []byte("xyz"))); got != nil {
+		t.Errorf("in range: got %v", got)
+	}
+}
+
+func mustScan(t *testing.T, rules *Rules, data []byte) []Hit {
+	t.Helper()
+	hits, err := rules.Scan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hits
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		`import "pe" rule A { condition: true }`,
+		`rule A { strings: $a = "x" condition: $b }`,
+		`rule A { strings: $a = "x" xor condition: $a }`,
+		`rule A { strings: $a = { 4 } condition: $a }`,
+		`rule A { condition: B }`,
+		`rule A { condition: true } rule A { condition: true }`,
+		`rule A { strings: $a = "x" }`,
+		`rule A { strings: $a = "" condition: $a }`,
+		`rule A { strings: $a = { 41 } wide condition: $a }`,
+		`rule A { strings: $a = { 41 [70000] 42 } condition: $a }`,
+		`rule A { strings: $a = /\xff/ condition: $a }`,
+		`rule A { condition: filesize + 1 > 2 }`,
+		`rule A { condition: for any i in (1..2): (true) }`,
+	} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) succeeded", src)
+		}
+	}
+}
+
+func TestUnboundedJumps(t *testing.T) {
+	rules, err := Compile(`
+rule Jumps {
+	strings:
+		$a = { 41 [-] 42 [-] 43 }
+		$b = { 41 [2-] ( 42 | 43 43 ) }
+	condition:
+		$a or $b
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Backtracking over the jumps from every A would take hours
+	data := bytes.Repeat([]byte("AB"), 32<<10)
+	hits := mustScan(t, rules, data)
+	if len(hits) != 1 || len(hits[0].Strings) != maxMatchesPerString {
+		t.Fatalf("got %v", hitRules(hits))
+	}
+	for _, m := range hits[0].Strings {
+		if m.ID != "$b" || string(m.Data) != "ABAB" {
+			t.Fatalf("got %s %q at %d", m.ID, m.Data, m.Offset)
+		}
+	}
+
+	hits = mustScan(t, rules, []byte("xAxBxxC"))
+	if len(hits) != 1 || len(hits[0].Strings) != 1 || string(hits[0].Strings[0].Data) != "AxBxxC" {
+		t.Errorf("got %+v", hits)
+	}
+}
+
+func TestScanSegb(t *testing.T) {
+	dir := t.TempDir()
+	if err := segbtest.WriteFixtures(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, segbtest.V2Fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	decoded, err := segb.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := Compile(testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err := ScanSegb(rules, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, hit := range hits {
+		got = append(got, hit.Rule+"@"+string(rune('0'+hit.Index)))
+	}
+	want := []string{"Crazy@0", "Misfits@1", "ShortAndNotCrazy@1", "Misfits@2", "ShortAndNotCrazy@2"}
+	if !equal(got, want) {
+		t.Errorf("ScanSegb() = %v; want %v", got, want)
+	}
+}
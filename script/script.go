@@ -0,0 +1,227 @@
+// Package script runs Starlark scripts over the entries of SEGB files, so
+// that entries can be filtered, annotated with derived fields or have the
+// fields of their records rewritten without recompiling the tool.
+//
+// A script defines a function process(entry), called once per entry. entry
+// is a dict of the fields of the entry's export.Record, by their JSON names
+// (decoded payloads included, see segb.RegisterDecoder), with the payload
+// as bytes under "payload". The function may change the dict in place or
+// return a new one: keys that are fields of the record set them, other
+// keys become its derived fields. Returning False drops the entry; any
+// other value, None included, keeps it.
+//
+//	def process(entry):
+//	    if entry["state"] != "written":
+//	        return False
+//	    entry["hour"] = int(entry["created"][11:13])
+//
+// Scripts may use the json module (json.encode, json.decode) and print,
+// which writes to standard error.
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bluefalconhd/segb/export"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Function is the name of the function a script defines.
+const Function = "process"
+
+// payloadKey holds the payload in the dict passed to the function.
+const payloadKey = "payload"
+
+// Script is a loaded script. It is not safe for concurrent use.
+type Script struct {
+	path    string
+	thread  *starlark.Thread
+	process starlark.Callable
+}
+
+// Load reads and runs the script at path, which must define Function.
+func Load(path string) (*Script, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(path, src)
+}
+
+// Compile runs the source of a script, which must define Function. The
+// filename is used in error messages.
+func Compile(filename string, src []byte) (*Script, error) {
+	thread := &starlark.Thread{
+		Name:  filename,
+		Print: func(_ *starlark.Thread, msg string) { fmt.Fprintln(os.Stderr, msg) },
+	}
+	predeclared := starlark.StringDict{"json": starlarkjson.Module}
+	globals, err := starlark.ExecFile(thread, filename, src, predeclared)
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	globals.Freeze()
+	process, ok := globals[Function].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%s: no %s function defined", filename, Function)
+	}
+	return &Script{path: filename, thread: thread, process: process}, nil
+}
+
+// Apply runs the script on the record of an entry and its payload, and
+// reports whether the script keeps the entry. The fields the script
+// changes are set in the record, and those it adds in record.Derived.
+func (s *Script) Apply(record *export.Record, payload []byte) (bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return false, err
+	}
+	entry := toStarlark(fields).(*starlark.Dict)
+	entry.SetKey(starlark.String(payloadKey), starlark.Bytes(payload))
+
+	result, err := starlark.Call(s.thread, s.process, starlark.Tuple{entry}, nil)
+	if err != nil {
+		return false, scriptError(err)
+	}
+	switch result := result.(type) {
+	case starlark.Bool:
+		if !result {
+			return false, nil
+		}
+	case *starlark.Dict:
+		entry = result
+	}
+
+	value, err := fromStarlark(entry)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s: %w", s.path, Function, err)
+	}
+	changed := value.(map[string]any)
+	delete(changed, payloadKey)
+	derived := record.Derived
+	for key, v := range changed {
+		if _, ok := (export.Record{}).Field(key); !ok {
+			if derived == nil {
+				derived = make(map[string]any)
+			}
+			derived[key] = v
+			delete(changed, key)
+		}
+	}
+	if data, err = json.Marshal(changed); err != nil {
+		return false, fmt.Errorf("%s: %s: %w", s.path, Function, err)
+	}
+	var updated export.Record
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return false, fmt.Errorf("%s: %s: %w", s.path, Function, err)
+	}
+	if len(derived) > 0 {
+		updated.Derived = derived
+	}
+	*record = updated
+	return true, nil
+}
+
+// scriptError adds the Starlark backtrace to errors raised by a script.
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(strings.TrimSpace(evalErr.Backtrace()))
+	}
+	return err
+}
+
+// toStarlark converts a value decoded from JSON, with json.Number for
+// numbers, to Starlark.
+func toStarlark(v any) starlark.Value {
+	switch v := v.(type) {
+	case bool:
+		return starlark.Bool(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return starlark.MakeInt64(n)
+		}
+		f, _ := v.Float64()
+		return starlark.Float(f)
+	case string:
+		return starlark.String(v)
+	case []any:
+		list := make([]starlark.Value, len(v))
+		for i, item := range v {
+			list[i] = toStarlark(item)
+		}
+		return starlark.NewList(list)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, key := range keys {
+			dict.SetKey(starlark.String(key), toStarlark(v[key]))
+		}
+		return dict
+	default:
+		return starlark.None
+	}
+}
+
+// fromStarlark converts a Starlark value to one encoding/json serializes.
+func fromStarlark(v starlark.Value) (any, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if n, ok := v.Int64(); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("integer %v out of range", v)
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Bytes:
+		return []byte(v), nil
+	case starlark.Indexable: // Lists and tuples
+		list := make([]any, v.Len())
+		for i := range list {
+			item, err := fromStarlark(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case *starlark.Dict:
+		m := make(map[string]any, v.Len())
+		for _, item := range v.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key %v is not a string", item[0])
+			}
+			value, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[string(key)] = value
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %s", v.Type())
+	}
+}
@@ -0,0 +1,86 @@
+package script
+
+import (
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/export"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testScript = `
+def process(entry):
+    if str(entry["payload"]).startswith("drop"):
+        return False
+    entry["note"] = "seen " + entry["state"]
+    entry["words"] = len(str(entry["payload"]).split(" "))
+    entry["first"] = json.decode(json.encode({"size": entry["size"]}))
+`
+
+func testRecord(payload string) export.Record {
+	entry := segb.Entry{
+		State:   segb.EntryStateWritten,
+		Created: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Data:    []byte(payload),
+	}
+	return export.NewRecord("test.segb", 0, entry, false)
+}
+
+func TestApply(t *testing.T) {
+	s, err := Compile("test.star", []byte(testScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := testRecord("three short words")
+	keep, err := s.Apply(&record, []byte("three short words"))
+	if err != nil || !keep {
+		t.Fatalf("Apply() = %v, %v; want kept", keep, err)
+	}
+	if record.Note != "seen written" {
+		t.Errorf("Note = %q; want the field set by the script", record.Note)
+	}
+	if record.Size != 17 || !record.Created.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("record = %+v; want the other fields unchanged", record)
+	}
+	if record.Derived["words"] != int64(3) {
+		t.Errorf("Derived[words] = %#v; want 3", record.Derived["words"])
+	}
+	if first, ok := record.Derived["first"].(map[string]any); !ok || first["size"] != int64(17) {
+		t.Errorf("Derived[first] = %#v; want the size", record.Derived["first"])
+	}
+	if _, ok := record.Derived["payload"]; ok {
+		t.Error("Derived has the payload")
+	}
+
+	record = testRecord("drop me")
+	if keep, err := s.Apply(&record, []byte("drop me")); err != nil || keep {
+		t.Errorf("Apply(drop) = %v, %v; want dropped", keep, err)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	if _, err := Compile("empty.star", []byte("x = 1\n")); err == nil || !strings.Contains(err.Error(), "no process function") {
+		t.Errorf("Compile(no function) error = %v", err)
+	}
+	if _, err := Compile("syntax.star", []byte("def process(entry)\n")); err == nil {
+		t.Error("Compile(syntax error) did not fail")
+	}
+
+	s, err := Compile("fail.star", []byte("def process(entry):\n    fail(\"bad entry\")\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := testRecord("x")
+	if _, err := s.Apply(&record, []byte("x")); err == nil || !strings.Contains(err.Error(), "bad entry") {
+		t.Errorf("Apply(fail) error = %v; want the script's message", err)
+	}
+
+	s, err = Compile("type.star", []byte("def process(entry):\n    entry[\"size\"] = \"big\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Apply(&record, []byte("x")); err == nil {
+		t.Error("Apply(size of the wrong type) did not fail")
+	}
+}
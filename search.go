@@ -0,0 +1,45 @@
+package segb
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// Match is a search hit inside an entry payload.
+type Match struct {
+	Entry  int // Index of the entry in Segb.Entries
+	Offset int // Offset of the match within the payload
+	Length int
+}
+
+// Search returns the matches of re in every entry payload, in entry order.
+// Matches within one payload do not overlap.
+func (s *Segb) Search(re *regexp.Regexp) []Match {
+	var matches []Match
+	for i, entry := range s.Entries {
+		for _, loc := range re.FindAllIndex(entry.Data, -1) {
+			matches = append(matches, Match{Entry: i, Offset: loc[0], Length: loc[1] - loc[0]})
+		}
+	}
+	return matches
+}
+
+// SearchBytes returns every occurrence of pattern in every entry payload,
+// in entry order, including overlapping ones.
+func (s *Segb) SearchBytes(pattern []byte) []Match {
+	var matches []Match
+	if len(pattern) == 0 {
+		return matches
+	}
+	for i, entry := range s.Entries {
+		for offset := 0; ; offset++ {
+			n := bytes.Index(entry.Data[offset:], pattern)
+			if n < 0 {
+				break
+			}
+			offset += n
+			matches = append(matches, Match{Entry: i, Offset: offset, Length: len(pattern)})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,41 @@
+package segb
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	decoded, err := DecodeBytes(v2File([]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels.")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches := decoded.Search(regexp.MustCompile(`(?i)the \w+`))
+	want := []Match{{0, 10, 9}, {1, 0, 11}, {2, 0, 10}}
+	if len(matches) != len(want) {
+		t.Fatalf("Search() = %+v; want %+v", matches, want)
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("matches[%d] = %+v; want %+v", i, matches[i], want[i])
+		}
+	}
+
+	matches = decoded.SearchBytes([]byte("s"))
+	want = []Match{{0, 5, 1}, {0, 23, 1}, {1, 6, 1}, {1, 10, 1}, {2, 9, 1}}
+	if len(matches) != len(want) {
+		t.Fatalf("SearchBytes() = %+v; want %+v", matches, want)
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("matches[%d] = %+v; want %+v", i, matches[i], want[i])
+		}
+	}
+
+	// Overlapping occurrences are all reported
+	s := Segb{Entries: []Entry{{Data: []byte("aaaa")}}}
+	if n := len(s.SearchBytes([]byte("aa"))); n != 3 {
+		t.Errorf("SearchBytes(aa) found %d matches; want 3", n)
+	}
+}
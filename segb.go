@@ -1,37 +1,25 @@
 package segb
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/bluefalconhd/segb/hexdump"
+	"github.com/bluefalconhd/segb/timeconv"
 	v1 "github.com/bluefalconhd/segb/v1"
 	v2 "github.com/bluefalconhd/segb/v2"
 	"hash/crc32"
 	"io"
+	"os"
+	"slices"
 	"time"
 )
 
+// PrettyHexdump prints a hexdump of data to stdout.
+//
+// Deprecated: use hexdump.Dump, which can write to any io.Writer and is configurable.
 func PrettyHexdump(data []byte) {
-	for i := 0; i < len(data); i += 16 {
-		fmt.Printf("%08x: ", i)
-		for j := 0; j < 16; j++ {
-			if i+j < len(data) {
-				fmt.Printf("%02x ", data[i+j])
-			} else {
-				fmt.Print("   ")
-			}
-		}
-		fmt.Print(" ")
-		for j := 0; j < 16; j++ {
-			if i+j < len(data) {
-				if data[i+j] >= 32 && data[i+j] <= 126 {
-					fmt.Printf("%c", data[i+j])
-				} else {
-					fmt.Print(".")
-				}
-			}
-		}
-		fmt.Println()
-	}
+	_ = hexdump.Dump(os.Stdout, data, hexdump.Options{})
 }
 
 type SegbVersion int
@@ -42,78 +30,168 @@ const (
 	SEGB_VERSION_2
 )
 
+// String returns a short name for the version, e.g. "v2".
+func (v SegbVersion) String() string {
+	switch v {
+	case SEGB_VERSION_1:
+		return "v1"
+	case SEGB_VERSION_2:
+		return "v2"
+	case NONE:
+		return "none"
+	default:
+		// Versions added with Register
+		return fmt.Sprintf("v%d", int(v))
+	}
+}
+
 var ErrUnsupportedVersion = errors.New("unsupported version")
 
-func Decode(stream io.ReadSeeker) (Segb, error) {
+// Decode decodes a SEGB file. Input compressed with gzip, zstd or LZ4 is
+// decompressed transparently, and so are compressed entry payloads unless
+// WithoutPayloadDecompression is passed.
+func Decode(stream io.ReadSeeker, opts ...DecodeOption) (Segb, error) {
+	cfg := decodeConfig{payloads: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 
-	// Detect the version of the SEGB file
-	v, err := DetectVersion(stream)
-	if err != nil {
-		return Segb{}, err
+	if cfg.retry.Attempts > 1 {
+		stream = NewRetryReader(stream, cfg.retry)
 	}
 
-	// Re-seek to the beginning of the file (this took me so long to realize)
-	_, err = stream.Seek(0, io.SeekStart)
+	// Unwrap compressed input
+	stream, _, err := decompress(stream, cfg.decompressLimit())
 	if err != nil {
 		return Segb{}, err
 	}
 
-	switch v {
-	case SEGB_VERSION_1:
-		header, entries, err := v1.ReadSegb(stream)
-		if err != nil {
+	var original []byte
+	if cfg.roundTrip {
+		if _, err := stream.Seek(0, io.SeekStart); err != nil {
 			return Segb{}, err
 		}
-		return V1ToStandardSegb(header, entries), nil
-	case SEGB_VERSION_2:
-		header, _, entries, err := v2.ReadSegb(stream)
-		if err != nil {
+		if original, err = io.ReadAll(stream); err != nil {
 			return Segb{}, err
 		}
+		stream = bytes.NewReader(original)
+	}
 
-		return V2ToStandardSegb(header, entries), nil
-	default:
-		// Return an error if the version is not supported
+	// Detect the version of the SEGB file
+	versions, err := detectVersions(stream)
+	if err != nil {
+		return Segb{}, err
+	}
+	if len(versions) == 0 {
 		return Segb{}, ErrUnsupportedVersion
 	}
-}
-
-func DetectVersion(stream io.ReadSeeker) (SegbVersion, error) {
-	// Buffer to hold the magic string
-	magic := make([]byte, 4)
 
-	// Check for SEGBv2: 'SEGB' @ 0x00
-	_, err := stream.Seek(0x00, io.SeekStart)
-	if err != nil {
-		return NONE, err
+	// A file matching several versions is decoded as the first that
+	// decodes it
+	var decoded Segb
+	var firstErr error
+	for _, v := range versions {
+		// Re-seek to the beginning of the file (this took me so long to realize)
+		if _, err := stream.Seek(0, io.SeekStart); err != nil {
+			return Segb{}, err
+		}
+		h, _ := handlerFor(v)
+		if decoded, err = h.read(stream, cfg); err == nil {
+			break
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
-	_, err = stream.Read(magic)
 	if err != nil {
-		return NONE, err
-	}
-	if string(magic) == "SEGB" {
-		return SEGB_VERSION_2, nil
+		return Segb{}, firstErr
 	}
 
-	// Check for SEGBv1: 'SEGB' @ 0x34
-	_, err = stream.Seek(0x34, io.SeekStart)
-	if err != nil {
-		return NONE, err
+	if cfg.padded {
+		if err := decoded.readPadded(stream); err != nil {
+			return Segb{}, err
+		}
 	}
-	_, err = stream.Read(magic)
-	if err != nil {
+	if err := decoded.applyCRCPolicy(cfg.crcPolicy, cfg.lenient); err != nil {
+		return Segb{}, err
+	}
+	if cfg.payloads {
+		if _, err := decoded.decompressPayloads(cfg.decompressLimit(), cfg.remainingBudget(&decoded)); err != nil {
+			return Segb{}, err
+		}
+	}
+	decoded.classifyPayloads()
+	if cfg.order != SortByOffset {
+		decoded.Sort(cfg.order)
+	}
+	decoded.original = original
+	return decoded, nil
+}
+
+// DecodeBytes decodes a SEGB file held entirely in memory. It performs no
+// file system access, which makes it the entry point for environments such
+// as WebAssembly.
+func DecodeBytes(data []byte, opts ...DecodeOption) (Segb, error) {
+	return Decode(bytes.NewReader(data), opts...)
+}
+
+// DecodeReaderAt decodes the SEGB file in the first size bytes of r. Unlike
+// Decode, it never moves a shared file offset: r is only read with ReadAt,
+// so goroutines can decode from the same *os.File at once, e.g. each
+// extracting different entries with WithEntryFilter.
+func DecodeReaderAt(r io.ReaderAt, size int64, opts ...DecodeOption) (Segb, error) {
+	return Decode(io.NewSectionReader(r, 0, size), opts...)
+}
+
+// DetectVersion tells the version of the SEGB file in stream by trying the
+// sniffer of every registered version, see Register. The built-in ones
+// check the magic and the plausibility of the header: a v2 creation time
+// between 2001 and 2100 and an entry count that fits the file, and a v1
+// end of data past the header. It returns NONE for files that match none,
+// short ones included. A file that matches several versions gets the first
+// one along with an *AmbiguousVersionError listing them all.
+func DetectVersion(stream io.ReadSeeker) (SegbVersion, error) {
+	versions, err := detectVersions(stream)
+	if err != nil || len(versions) == 0 {
 		return NONE, err
 	}
-	if string(magic) == "SEGB" {
-		return SEGB_VERSION_1, nil
+	if len(versions) > 1 {
+		return versions[0], &AmbiguousVersionError{Versions: versions}
 	}
+	return versions[0], nil
+}
 
-	// If neither version is detected, return NONE
-	return NONE, nil
+// detectVersions returns the versions whose sniffers match stream.
+func detectVersions(stream io.ReadSeeker) ([]SegbVersion, error) {
+	handlersMu.RLock()
+	registered := slices.Clone(handlers)
+	handlersMu.RUnlock()
+
+	var versions []SegbVersion
+	for _, h := range registered {
+		if _, err := stream.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		ok, err := h.sniff(stream)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			versions = append(versions, h.version)
+		}
+	}
+	return versions, nil
 }
 
+// CocoaTimestampToTime converts seconds since 2001-01-01 00:00:00 UTC to a
+// time, to the microsecond; see timeconv for other representations.
 func CocoaTimestampToTime(timestamp float64) time.Time {
-	return time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(timestamp) * time.Second)
+	return timeconv.FromCocoa(timestamp)
+}
+
+// TimeToCocoaTimestamp converts t to seconds since 2001-01-01 00:00:00 UTC.
+func TimeToCocoaTimestamp(t time.Time) float64 {
+	return timeconv.ToCocoa(t)
 }
 
 func V2EntryStateToStandardState(e v2.EntryState) EntryState {
@@ -139,49 +217,87 @@ func V1EntryStateToStandardState(e v1.EntryState) EntryState {
 }
 
 func V1ToStandardSegb(header *v1.Header, entries []*v1.Entry) Segb {
-	oldestTime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	return v1ToStandardSegb(entries, v1.DefaultAlignment)
+}
 
+func v1ToStandardSegb(entries []*v1.Entry, alignment int64) Segb {
 	standardEntries := make([]Entry, len(entries))
 	for i, entry := range entries {
+		// The payload follows the 32 byte entry header
+		dataOffset := entry.Offset + v1EntryHeaderSize
+		standardEntries[i] = Entry{
+			ID:           int(entry.ID),
+			State:        V1EntryStateToStandardState(entry.State),
+			Created:      CocoaTimestampToTime(entry.Timestamp1),
+			Data:         entry.Data,
+			Checksum:     entry.CRCChecksum,
+			Offset:       dataOffset,
+			Size:         int64(entry.Length),
+			StoredSize:   v1PayloadEnd(entry, alignment) - dataOffset,
+			Sequence:     int(entry.ID),
+			headerOffset: entry.Offset,
+		}
+	}
+	return Segb{
+		Version:   SEGB_VERSION_1,
+		Created:   v1Created(entries),
+		Entries:   standardEntries,
+		Alignment: int(alignment),
+	}
+}
 
-		// Calculate the creation time
+// v1Created returns the creation time of a v1 file. It is not stored, so we
+// use the oldest entry creation time.
+func v1Created(entries []*v1.Entry) time.Time {
+	oldestTime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, entry := range entries {
 		creationTime := CocoaTimestampToTime(entry.Timestamp1)
 		if creationTime.Before(oldestTime) {
 			oldestTime = creationTime
 		}
-
-		standardEntries[i] = Entry{
-			ID:       int(entry.ID),
-			State:    V1EntryStateToStandardState(entry.State),
-			Created:  CocoaTimestampToTime(entry.Timestamp1),
-			Data:     entry.Data,
-			Checksum: entry.CRCChecksum,
-		}
-	}
-	return Segb{
-		Version: SEGB_VERSION_1,
-		// Creation time is unknown for SEGBv1, so we use the oldest entry creation time
-		Created: oldestTime,
-		Entries: standardEntries,
 	}
+	return oldestTime
+}
+
+// v1PayloadEnd returns where the payload of an entry ends, padding included.
+// Entries are read back to back, so this is where the next entry header
+// starts, if there is one.
+func v1PayloadEnd(entry *v1.Entry, alignment int64) int64 {
+	return alignUp(entry.Offset+v1EntryHeaderSize+int64(entry.Length), alignment)
 }
+
 func V2ToStandardSegb(header *v2.Header, entries []*v2.Entry) Segb {
 
 	standardEntries := make([]Entry, len(entries))
 	for i, entry := range entries {
+		// The payload follows the CRC and unknown fields and runs, padding
+		// included, up to the next entry. v2 has no length field, so the
+		// logical size is the one matching the CRC, see v2.Entry.LengthFromCRC
 		standardEntries[i] = Entry{
-			ID:       int(entry.ID),
-			State:    V2EntryStateToStandardState(entry.State),
-			Created:  CocoaTimestampToTime(entry.CreationTimestamp),
-			Data:     entry.Data,
-			Checksum: entry.CRCChecksum,
+			ID:           int(entry.ID),
+			State:        V2EntryStateToStandardState(entry.State),
+			Created:      CocoaTimestampToTime(entry.CreationTimestamp),
+			Data:         entry.Data,
+			Checksum:     entry.CRCChecksum,
+			Offset:       entry.Offset + v2EntryHeaderSize,
+			Size:         int64(len(entry.Data)),
+			StoredSize:   int64(len(entry.RawData)) - v2EntryHeaderSize,
+			Sequence:     entry.TrailerIndex,
+			LengthSource: LengthTrimmed,
+			headerOffset: entry.Offset,
+		}
+		if entry.LengthFromCRC {
+			standardEntries[i].LengthSource = LengthCRC
 		}
 	}
 
 	return Segb{
-		Version: SEGB_VERSION_2,
-		Created: CocoaTimestampToTime(header.CreationTimestamp),
-		Entries: standardEntries,
+		Version:   SEGB_VERSION_2,
+		Created:   CocoaTimestampToTime(header.CreationTimestamp),
+		Entries:   standardEntries,
+		Alignment: v2.DefaultAlignment,
+
+		HeaderUnknown: append([]byte(nil), header.UnknownPadding[:]...),
 	}
 }
 
@@ -193,21 +309,124 @@ const (
 	EntryStateUnknown EntryState = 0x04
 )
 
+// String returns the lowercase name of the state.
+func (s EntryState) String() string {
+	switch s {
+	case EntryStateWritten:
+		return "written"
+	case EntryStateDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// LengthSource tells how the logical length of a stored payload was
+// determined.
+type LengthSource int
+
+const (
+	// LengthField is the length field of a v1 entry header.
+	LengthField LengthSource = iota
+	// LengthCRC is the length, v2 entries having no length field, whose
+	// CRC32 matches the stored checksum.
+	LengthCRC
+	// LengthTrimmed is the length of a v2 payload with its trailing zero
+	// bytes trimmed, used when no length matches the checksum. Zeros the
+	// payload ends with are lost.
+	LengthTrimmed
+)
+
+// String returns the lowercase name of the source.
+func (l LengthSource) String() string {
+	switch l {
+	case LengthCRC:
+		return "crc"
+	case LengthTrimmed:
+		return "trimmed"
+	default:
+		return "field"
+	}
+}
+
 // Entry
 type Entry struct {
-	ID       int
-	State    EntryState
-	Created  time.Time
-	Data     []byte
-	Checksum uint32
+	ID          int
+	State       EntryState
+	Created     time.Time
+	Data        []byte
+	Checksum    uint32
+	Raw         []byte      // Payload as stored, set only when Data was decompressed
+	Compression Compression // Compression removed from Data, if any
+	ContentType ContentType // Kind of data in Data, see Classify
+
+	// Offset is the absolute position of the stored payload in the decoded
+	// file (the decompressed stream for compressed input, the scanned input
+	// for carved structures). It is zero for entries not read from a file.
+	Offset int64
+
+	CRCValid    bool // Checksum matches the stored payload, as of decoding
+	CRCRepaired bool // A flipped bit was corrected under CRCRepair
+
+	Size         int64        // Logical length of the stored payload
+	StoredSize   int64        // Bytes the payload occupies in the file, alignment padding included
+	LengthSource LengthSource // How Size was determined
+
+	// Sequence is the position of the entry in the trailer for v2, which
+	// need not be file order, and in the file for v1.
+	Sequence int
+
+	// Residual is set for entries recovered from past the end of data of a
+	// v1 file, see WithResidualEntries.
+	Residual bool
+
+	// Padded is set when Data runs past the logical length of the payload
+	// into its alignment padding, see WithRawPayloads.
+	Padded bool
+
+	headerOffset int64 // Start of the entry header, zero when unknown
+}
+
+// Stored returns the payload as stored in the file.
+func (e *Entry) Stored() []byte {
+	if e.Raw != nil {
+		return e.Raw
+	}
+	return e.Data
 }
 
+// checksummed returns the payload as stored, without the padding Data holds
+// under WithRawPayloads: the bytes the checksum covers.
+func (e *Entry) checksummed() []byte {
+	stored := e.Stored()
+	if e.Padded && e.Size < int64(len(stored)) {
+		return stored[:e.Size]
+	}
+	return stored
+}
+
+// CheckCRC verifies the checksum against the payload as stored.
 func (e *Entry) CheckCRC() bool {
-	return e.Checksum == crc32.Checksum(e.Data, crc32.IEEETable)
+	return e.Checksum == crc32.Checksum(e.checksummed(), crc32.IEEETable)
 }
 
 type Segb struct {
-	Version SegbVersion
-	Created time.Time
-	Entries []Entry
+	Version   SegbVersion
+	Created   time.Time
+	Entries   []Entry
+	Alignment int          // Entry alignment in bytes the file was read with
+	Errors    []EntryError // Entries skipped by WithLenientParsing
+
+	// HeaderUnknown holds the 16 header bytes of a v2 file whose purpose is
+	// not known, see v2.InterpretUnknown. It is nil for v1 files.
+	HeaderUnknown []byte
+
+	// Records holds the trailer records of a v2 file as stored, in trailer
+	// order, including those of entries that were not decoded: records in
+	// the unknown state, filtered out or unreadable. Entry.Sequence is the
+	// index of the record of an entry. It is nil for v1 files.
+	Records []v2.Record
+
+	original     []byte // The decoded file, kept by WithRoundTrip
+	originalBase int64  // Offset of original in the input, for parts of a larger one
 }
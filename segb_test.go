@@ -1,9 +1,15 @@
 package segb
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	v2 "github.com/bluefalconhd/segb/v2"
 	"log"
+	"math"
 	"os"
-	"os/exec"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
@@ -14,11 +20,31 @@ var expectedEntryData = []string{
 	"The rebels.",
 }
 
+var expectedEntryCreated = []time.Time{
+	time.Date(2007, 1, 9, 0, 0, 0, 0, time.UTC),
+	time.Date(2007, 6, 29, 0, 0, 0, 0, time.UTC),
+	time.Date(2011, 10, 5, 0, 0, 0, 0, time.UTC),
+}
+
+// SetupTestFiles writes the fixtures segbtest.WriteFixtures does, which
+// cannot be imported from here without an import cycle.
 func SetupTestFiles() {
-	cmd := exec.Command("python3", "generate_test_files.py")
-	err := cmd.Run()
-	if err != nil {
-		log.Fatalf("Failed to generate test files: %v", err)
+	for name, version := range map[string]SegbVersion{
+		"segb_version1.bin": SEGB_VERSION_1,
+		"segb_version2.bin": SEGB_VERSION_2,
+	} {
+		w, err := Create(name, version, WithSyncEvery(0))
+		if err != nil {
+			log.Fatalf("Failed to generate test files: %v", err)
+		}
+		for i, data := range expectedEntryData {
+			if err := w.Append([]byte(data), expectedEntryCreated[i]); err != nil {
+				log.Fatalf("Failed to generate test files: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			log.Fatalf("Failed to generate test files: %v", err)
+		}
 	}
 }
 
@@ -99,6 +125,57 @@ func TestDetectVersion(t *testing.T) {
 	}
 }
 
+func TestDetectVersionImplausible(t *testing.T) {
+	valid := v2File([]byte("payload"))
+
+	// Entry count larger than the trailer the file has room for
+	tooMany := bytes.Clone(valid)
+	binary.LittleEndian.PutUint32(tooMany[4:], 1000)
+
+	// Creation time in 2200
+	future := bytes.Clone(valid)
+	binary.LittleEndian.PutUint64(future[8:], math.Float64bits(6.3e9))
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"magic only", []byte("SEGB")},
+		{"short v1", append(make([]byte, 0x34), "SE"...)},
+		{"too many entries", tooMany},
+		{"creation time", future},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if v, err := DetectVersion(bytes.NewReader(tc.data)); err != nil || v != NONE {
+				t.Errorf("DetectVersion() = %v, %v; want none, nil", v, err)
+			}
+		})
+	}
+}
+
+func TestDetectVersionAmbiguous(t *testing.T) {
+	// The payload puts "SEGB" at 0x34, where v1 has its magic
+	file := v2File([]byte("0123456789abSEGB"))
+
+	v, err := DetectVersion(bytes.NewReader(file))
+	var ambiguous *AmbiguousVersionError
+	if !errors.As(err, &ambiguous) || !errors.Is(err, ErrAmbiguousVersion) {
+		t.Fatalf("DetectVersion() error = %v; want an AmbiguousVersionError", err)
+	}
+	if v != SEGB_VERSION_2 || !slices.Equal(ambiguous.Versions, []SegbVersion{SEGB_VERSION_2, SEGB_VERSION_1}) {
+		t.Errorf("DetectVersion() = %v, %v; want v2, [v2 v1]", v, ambiguous.Versions)
+	}
+
+	decoded, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != SEGB_VERSION_2 || len(decoded.Entries) != 1 {
+		t.Errorf("Decode() = %v with %d entries; want v2 with 1", decoded.Version, len(decoded.Entries))
+	}
+}
+
 func TestDecode(t *testing.T) {
 
 	SetupTestFiles()
@@ -146,3 +223,157 @@ func TestDecode(t *testing.T) {
 	// Check the entries
 	CheckForEntries(t, decoded.Entries)
 }
+
+func TestDecodeBytes(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	data, err := os.ReadFile("segb_version2.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	CheckForEntries(t, decoded.Entries)
+
+	if _, err := DecodeBytes(data[:8]); err == nil {
+		t.Error("DecodeBytes() on a truncated file succeeded")
+	}
+}
+
+func TestHeaderUnknown(t *testing.T) {
+	file := v2File([]byte("The rebels."))
+	binary.LittleEndian.PutUint64(file[16:], math.Float64bits(1.5))
+	binary.LittleEndian.PutUint32(file[24:], 7)
+	binary.LittleEndian.PutUint32(file[28:], 0xffffffff)
+
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := v2.InterpretUnknown([16]byte(decoded.HeaderUnknown))
+	if fields.Float64[0] != 1.5 || fields.Int32[2] != 7 || fields.Int32[3] != -1 {
+		t.Errorf("InterpretUnknown() = %+v", fields)
+	}
+	if fields.Hex != "000000000000f83f07000000ffffffff" {
+		t.Errorf("Hex = %s", fields.Hex)
+	}
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+	data, err := os.ReadFile("segb_version1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, err := DecodeBytes(data); err != nil || decoded.HeaderUnknown != nil {
+		t.Errorf("v1 HeaderUnknown = %v, %v; want nil", decoded.HeaderUnknown, err)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	file := v2File([]byte("first"), []byte("second"), []byte("third"))
+	trailer := file[len(file)-3*v2.TrailerRecordSize:]
+	// Swap the first and last records, and mark the middle one unknown
+	first := slices.Clone(trailer[:v2.TrailerRecordSize])
+	copy(trailer, trailer[2*v2.TrailerRecordSize:])
+	copy(trailer[2*v2.TrailerRecordSize:], first)
+	binary.LittleEndian.PutUint32(trailer[v2.TrailerRecordSize+4:], uint32(v2.EntryStateUnknown))
+
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 2 || len(decoded.Records) != 3 {
+		t.Fatalf("decoded %d entries, %d records; want 2, 3", len(decoded.Entries), len(decoded.Records))
+	}
+	if decoded.Records[0].Offset <= decoded.Records[2].Offset || decoded.Records[1].State != v2.EntryStateUnknown {
+		t.Errorf("Records = %+v; want them in trailer order", decoded.Records)
+	}
+	for _, entry := range decoded.Entries {
+		record := decoded.Records[entry.Sequence]
+		if int64(record.Offset)+32+v2EntryHeaderSize != entry.Offset {
+			t.Errorf("entry %d at 0x%x has record %+v", entry.ID, entry.Offset, record)
+		}
+	}
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+	data, err := os.ReadFile("segb_version1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, err := DecodeBytes(data); err != nil || decoded.Records != nil {
+		t.Errorf("v1 Records = %v, %v; want nil", decoded.Records, err)
+	}
+}
+
+func TestDecodeReaderAt(t *testing.T) {
+
+	SetupTestFiles()
+	defer RemoveTestFiles()
+
+	file, err := os.Open("segb_version2.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each goroutine extracts one entry from the shared file
+	var wg sync.WaitGroup
+	results := make([]string, len(expectedEntryData))
+	errs := make([]error, len(expectedEntryData))
+	for i := range expectedEntryData {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			decoded, err := DecodeReaderAt(file, info.Size(), WithEntryFilter(func(m EntryMeta) bool {
+				return m.ID == i
+			}))
+			if err == nil && len(decoded.Entries) == 1 {
+				results[i] = string(decoded.Entries[0].Data)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, want := range expectedEntryData {
+		if errs[i] != nil || results[i] != want {
+			t.Errorf("entry %d = %q, %v; want %q", i, results[i], errs[i], want)
+		}
+	}
+}
+
+func TestPayloadLength(t *testing.T) {
+	// A protobuf payload whose last field is zero ends in a zero byte
+	payloads := [][]byte{{0x08, 0x01, 0x10, 0x00}, []byte("The misfits.\x00\x00"), []byte("The rebels.")}
+	file := v2File(payloads...)
+
+	decoded, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range decoded.Entries {
+		if !bytes.Equal(entry.Data, payloads[i]) || entry.LengthSource != LengthCRC || !entry.CRCValid {
+			t.Errorf("entry %d = %q (%v, CRC valid %v); want %q from the CRC", i, entry.Data, entry.LengthSource, entry.CRCValid, payloads[i])
+		}
+	}
+
+	// Without a matching CRC the zeros are trimmed
+	file[32+8] ^= 0x04
+	decoded, err = DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := decoded.Entries[0]; !bytes.Equal(entry.Data, []byte{0x0c, 0x01, 0x10}) || entry.LengthSource != LengthTrimmed {
+		t.Errorf("corrupt entry = %q (%v); want trimmed", entry.Data, entry.LengthSource)
+	}
+}
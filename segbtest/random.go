@@ -0,0 +1,125 @@
+package segbtest
+
+import (
+	"encoding/binary"
+	"github.com/bluefalconhd/segb"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// Bounds of the files generated by Random
+const (
+	maxRandomEntries = 32
+	maxRandomPayload = 512
+)
+
+// File is a generated file along with what it holds.
+type File struct {
+	Version   segb.SegbVersion
+	Alignment int // Boundary the entries are padded to
+	Entries   []Entry
+	Data      []byte
+
+	headers []int64 // Offset of each entry header
+}
+
+// Random returns a structurally valid file drawn from rng: its version,
+// alignment, preallocation, number of entries and their sizes, contents,
+// states (written or deleted) and creation times are random. Creation times
+// are whole seconds and v2 payloads do not end in a zero byte, as neither
+// would survive decoding.
+func Random(rng *rand.Rand) File {
+	f := File{
+		Version:   segb.SEGB_VERSION_1,
+		Alignment: []int{1, 4, 8, 16}[rng.Intn(4)],
+	}
+	if rng.Intn(2) == 0 {
+		f.Version = segb.SEGB_VERSION_2
+	}
+	opts := []segb.WriterOption{segb.WithEntryAlignment(f.Alignment)}
+	if rng.Intn(2) == 0 {
+		opts = append(opts, segb.WithPreallocation(int64(1+rng.Intn(4096))))
+	}
+
+	start, headerSize := int64(56), int64(32)
+	if f.Version == segb.SEGB_VERSION_2 {
+		start, headerSize = 32, 8
+	}
+	epoch := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.Entries = make([]Entry, rng.Intn(maxRandomEntries+1))
+	for i := range f.Entries {
+		payload := make([]byte, rng.Intn(maxRandomPayload+1))
+		rng.Read(payload)
+		if f.Version == segb.SEGB_VERSION_2 && len(payload) > 0 && payload[len(payload)-1] == 0 {
+			payload[len(payload)-1] = 1
+		}
+		state := segb.EntryStateWritten
+		if rng.Intn(4) == 0 {
+			state = segb.EntryStateDeleted
+		}
+		f.Entries[i] = Entry{
+			Data:    payload,
+			Created: epoch.Add(time.Duration(rng.Int63n(30*365*24*60*60)) * time.Second),
+			State:   state,
+		}
+
+		f.headers = append(f.headers, start)
+		start = alignUp(start+headerSize+int64(len(payload)), int64(f.Alignment))
+	}
+
+	data, err := Bytes(f.Version, f.Entries, opts...)
+	if err != nil {
+		// The bounds above keep files far from any limit of the format
+		panic(err)
+	}
+	f.Data = data
+	return f
+}
+
+// SetState returns a copy of f with the state of entry i changed to state.
+func (f File) SetState(i int, state segb.EntryState) File {
+	f = f.clone()
+	f.Entries[i].State = state
+	offset := f.headers[i] + 4
+	if f.Version == segb.SEGB_VERSION_2 {
+		offset = f.trailer() + int64(i)*16 + 4
+	}
+	binary.LittleEndian.PutUint32(f.Data[offset:], uint32(state))
+	return f
+}
+
+// CorruptCRC returns a copy of f in which the checksum of entry i no longer
+// matches its payload.
+func (f File) CorruptCRC(i int) File {
+	f = f.clone()
+	offset := f.headers[i] + 24
+	if f.Version == segb.SEGB_VERSION_2 {
+		offset = f.headers[i]
+	}
+	f.Data[offset] ^= 0xff
+	return f
+}
+
+// Truncate returns a copy of f cut to its first n bytes. Entries still
+// describes the whole file.
+func (f File) Truncate(n int) File {
+	f = f.clone()
+	f.Data = f.Data[:n]
+	return f
+}
+
+func (f File) clone() File {
+	f.Entries = slices.Clone(f.Entries)
+	f.Data = slices.Clone(f.Data)
+	return f
+}
+
+// trailer returns the offset of the trailer of a v2 file.
+func (f File) trailer() int64 {
+	return int64(len(f.Data)) - int64(len(f.Entries))*16
+}
+
+func alignUp(n, alignment int64) int64 {
+	return (n + alignment - 1) / alignment * alignment
+}
@@ -0,0 +1,73 @@
+package segbtest
+
+import (
+	"bytes"
+	"github.com/bluefalconhd/segb"
+	"math/rand"
+	"testing"
+)
+
+func decodeFile(t *testing.T, f File, opts ...segb.DecodeOption) segb.Segb {
+	t.Helper()
+	opts = append(opts, segb.WithoutPayloadDecompression(), segb.WithAlignment(f.Alignment))
+	decoded, err := segb.DecodeBytes(f.Data, opts...)
+	if err != nil {
+		t.Fatalf("%v file with %d entries aligned to %d: %v", f.Version, len(f.Entries), f.Alignment, err)
+	}
+	return decoded
+}
+
+func TestRandom(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		f := Random(rand.New(rand.NewSource(seed)))
+		decoded := decodeFile(t, f, segb.WithRoundTrip())
+
+		if decoded.Version != f.Version || len(decoded.Entries) != len(f.Entries) {
+			t.Fatalf("seed %d: decoded %v with %d entries; want %v with %d", seed, decoded.Version, len(decoded.Entries), f.Version, len(f.Entries))
+		}
+		for i, entry := range decoded.Entries {
+			want := f.Entries[i]
+			if !bytes.Equal(entry.Data, want.Data) || entry.State != want.State || !entry.Created.Equal(want.Created) || !entry.CRCValid {
+				t.Errorf("seed %d: entry %d = %d bytes, %v, %v, CRC valid %v; want %d bytes, %v, %v",
+					seed, i, len(entry.Data), entry.State, entry.Created, entry.CRCValid, len(want.Data), want.State, want.Created)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := decoded.Encode(&buf); err != nil || !bytes.Equal(buf.Bytes(), f.Data) {
+			t.Errorf("seed %d: Encode() does not reproduce the file (%v)", seed, err)
+		}
+	}
+}
+
+func TestMutations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 50; n++ {
+		f := Random(rng)
+		if len(f.Entries) == 0 {
+			continue
+		}
+		i := rng.Intn(len(f.Entries))
+
+		state := segb.EntryStateDeleted
+		if f.Entries[i].State == segb.EntryStateDeleted {
+			state = segb.EntryStateWritten
+		}
+		if got := decodeFile(t, f.SetState(i, state)).Entries[i].State; got != state {
+			t.Errorf("SetState(%d, %v): state %v", i, state, got)
+		}
+
+		for j, entry := range decodeFile(t, f.CorruptCRC(i)).Entries {
+			if entry.CRCValid != (j != i) {
+				t.Errorf("CorruptCRC(%d): entry %d CRC valid %v", i, j, entry.CRCValid)
+			}
+		}
+
+		// Truncated files fail to decode or decode to fewer entries, but never
+		// panic
+		truncated := f.Truncate(rng.Intn(len(f.Data)))
+		if decoded, err := segb.DecodeBytes(truncated.Data, segb.WithLenientParsing()); err == nil && len(decoded.Entries) > len(f.Entries) {
+			t.Errorf("Truncate(): %d entries from a file of %d", len(decoded.Entries), len(f.Entries))
+		}
+	}
+}
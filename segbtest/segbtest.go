@@ -0,0 +1,97 @@
+// Package segbtest builds SEGB files for tests, in pure Go, with the
+// writer of the segb package.
+package segbtest
+
+import (
+	"github.com/bluefalconhd/segb"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is the content of an entry in a generated file.
+type Entry struct {
+	Data    []byte
+	Created time.Time
+	State   segb.EntryState // 0 for segb.EntryStateWritten
+}
+
+// Entries are the entries of the fixtures written by WriteFixtures: the
+// opening lines of Apple's "Think different" ad, created on dates from the
+// history of the iPhone.
+var Entries = []Entry{
+	{Data: []byte("Here's to the crazy ones."), Created: time.Date(2007, 1, 9, 0, 0, 0, 0, time.UTC)},
+	{Data: []byte("The misfits."), Created: time.Date(2007, 6, 29, 0, 0, 0, 0, time.UTC)},
+	{Data: []byte("The rebels."), Created: time.Date(2011, 10, 5, 0, 0, 0, 0, time.UTC)},
+}
+
+// Fixture file names used by WriteFixtures.
+const (
+	V1Fixture = "segb_version1.bin"
+	V2Fixture = "segb_version2.bin"
+)
+
+// WriteFixtures writes a v1 and a v2 file holding Entries to dir, named
+// V1Fixture and V2Fixture.
+func WriteFixtures(dir string) error {
+	if err := WriteFile(filepath.Join(dir, V1Fixture), segb.SEGB_VERSION_1, Entries); err != nil {
+		return err
+	}
+	return WriteFile(filepath.Join(dir, V2Fixture), segb.SEGB_VERSION_2, Entries)
+}
+
+// WriteFile writes a file of the given version holding entries to path.
+func WriteFile(path string, version segb.SegbVersion, entries []Entry, opts ...segb.WriterOption) error {
+	data, err := Bytes(version, entries, opts...)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Bytes returns a file of the given version holding entries.
+func Bytes(version segb.SegbVersion, entries []Entry, opts ...segb.WriterOption) ([]byte, error) {
+	var file memFile
+	w, err := segb.NewWriter(&file, version, append(opts, segb.WithSyncEvery(0))...)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		state := entry.State
+		if state == 0 {
+			state = segb.EntryStateWritten
+		}
+		if err := w.AppendWithState(entry.Data, entry.Created, state); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return file.data, nil
+}
+
+// memFile is an in-memory segb.WriterFile.
+type memFile struct {
+	data []byte
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if end := off + int64(len(p)); end > int64(len(f.data)) {
+		f.Truncate(end)
+	}
+	return copy(f.data[off:], p), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	if size <= int64(len(f.data)) {
+		f.data = f.data[:size]
+	} else {
+		f.data = append(f.data, make([]byte, size-int64(len(f.data)))...)
+	}
+	return nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
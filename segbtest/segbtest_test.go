@@ -0,0 +1,35 @@
+package segbtest
+
+import (
+	"github.com/bluefalconhd/segb"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFixtures(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteFixtures(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, version := range map[string]segb.SegbVersion{V1Fixture: segb.SEGB_VERSION_1, V2Fixture: segb.SEGB_VERSION_2} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := segb.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if decoded.Version != version || len(decoded.Entries) != len(Entries) {
+			t.Fatalf("%s: decoded %v with %d entries", name, decoded.Version, len(decoded.Entries))
+		}
+		for i, entry := range decoded.Entries {
+			if string(entry.Data) != string(Entries[i].Data) || !entry.Created.Equal(Entries[i].Created) || !entry.CRCValid {
+				t.Errorf("%s: entry %d = %q, created %v", name, i, entry.Data, entry.Created)
+			}
+		}
+	}
+}
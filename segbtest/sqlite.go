@@ -0,0 +1,243 @@
+package segbtest
+
+import (
+	"encoding/binary"
+	"os"
+)
+
+// ManifestFile is a row of the Files table of the Manifest.db of an iTunes
+// backup.
+type ManifestFile struct {
+	FileID       string
+	Domain       string
+	RelativePath string
+	Flags        int64
+	File         []byte // Archived metadata, nil for NULL
+}
+
+// manifestSchema is the Files table as iOS creates it, less the primary key,
+// whose index backup readers do not use.
+const manifestSchema = "CREATE TABLE Files (fileID TEXT, domain TEXT, relativePath TEXT, flags INTEGER, file BLOB)"
+
+// sqlitePageSize is the page size of the databases written here, small
+// enough that a few thousand rows span interior pages.
+const sqlitePageSize = 4096
+
+// WriteManifest writes a Manifest.db holding files to path, in pure Go. Rows
+// that do not fit a page go on in overflow pages and tables that do not fit
+// a page get interior pages, as SQLite would write them.
+func WriteManifest(path string, files []ManifestFile) error {
+	var db sqliteWriter
+	db.pages = [][]byte{nil} // Page 1, the schema, is written last
+
+	var cells [][]byte
+	for i, f := range files {
+		var file any
+		if f.File != nil {
+			file = f.File
+		}
+		cells = append(cells, db.leafCell(int64(i+1), []any{f.FileID, f.Domain, f.RelativePath, f.Flags, file}))
+	}
+	root := db.table(cells)
+
+	schema := db.leafCell(1, []any{"table", "Files", "Files", int64(root), manifestSchema})
+	db.pages[0] = leafPage([][]byte{schema}, 100)
+	copy(db.pages[0], db.header())
+
+	out := make([]byte, 0, len(db.pages)*sqlitePageSize)
+	for _, page := range db.pages {
+		out = append(out, page...)
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+// sqliteWriter lays out the pages of a SQLite database.
+type sqliteWriter struct {
+	pages [][]byte
+}
+
+// add appends a page, returning its 1-based number.
+func (db *sqliteWriter) add(page []byte) uint32 {
+	db.pages = append(db.pages, page)
+	return uint32(len(db.pages))
+}
+
+// header returns the 100-byte database header.
+func (db *sqliteWriter) header() []byte {
+	be := binary.BigEndian
+	h := make([]byte, 100)
+	copy(h, "SQLite format 3\x00")
+	be.PutUint16(h[16:], sqlitePageSize)
+	h[18], h[19] = 1, 1              // Legacy journal
+	h[21], h[22], h[23] = 64, 32, 32 // Payload fractions
+	be.PutUint32(h[24:], 1)          // Change counter
+	be.PutUint32(h[28:], uint32(len(db.pages)))
+	be.PutUint32(h[40:], 1) // Schema cookie
+	be.PutUint32(h[44:], 4) // Schema format
+	be.PutUint32(h[56:], 1) // UTF-8
+	be.PutUint32(h[92:], 1) // Change counter the size is valid for
+	be.PutUint32(h[96:], 3045000)
+	return h
+}
+
+// leafCell returns the table leaf cell of a row, spilling what does not fit
+// the page into overflow pages.
+func (db *sqliteWriter) leafCell(rowid int64, row []any) []byte {
+	payload := record(row)
+	cell := appendVarint(nil, uint64(len(payload)))
+	cell = appendVarint(cell, uint64(rowid))
+
+	const usable = sqlitePageSize
+	maxLocal := usable - 35
+	if len(payload) <= maxLocal {
+		return append(cell, payload...)
+	}
+	minLocal := (usable-12)*32/255 - 23
+	local := minLocal + (len(payload)-minLocal)%(usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	cell = append(cell, payload[:local]...)
+
+	// The overflow chain is written back to front, so each page knows the
+	// next one
+	var chunks [][]byte
+	for rest := payload[local:]; len(rest) > 0; {
+		n := min(len(rest), usable-4)
+		chunks = append(chunks, rest[:n])
+		rest = rest[n:]
+	}
+	var next uint32
+	for i := len(chunks) - 1; i >= 0; i-- {
+		page := make([]byte, sqlitePageSize)
+		binary.BigEndian.PutUint32(page, next)
+		copy(page[4:], chunks[i])
+		next = db.add(page)
+	}
+	return binary.BigEndian.AppendUint32(cell, next)
+}
+
+// table writes the b-tree of a table holding cells, leaf cells of rowids 1
+// on, and returns its root page.
+func (db *sqliteWriter) table(cells [][]byte) uint32 {
+	type child struct {
+		page   uint32
+		lastID uint64
+	}
+	var children []child
+	for rows := 0; rows < len(cells) || children == nil; {
+		n := fitting(cells[rows:], 8)
+		children = append(children, child{db.add(leafPage(cells[rows:rows+n], 0)), uint64(rows + n)})
+		rows += n
+	}
+
+	// An interior page holds a key for each child but the right-most one
+	perPage := (sqlitePageSize-12)/(2+4+9) + 1
+	for len(children) > 1 {
+		var parents []child
+		count := (len(children) + perPage - 1) / perPage
+		for i := range count {
+			group := children[i*len(children)/count : (i+1)*len(children)/count]
+			var keys [][]byte
+			for _, c := range group[:len(group)-1] {
+				keys = append(keys, appendVarint(binary.BigEndian.AppendUint32(nil, c.page), c.lastID))
+			}
+			right := group[len(group)-1]
+			parents = append(parents, child{db.add(interiorPage(keys, right.page)), right.lastID})
+		}
+		children = parents
+	}
+	return children[0].page
+}
+
+// fitting returns how many of cells fit a page with a header of the given
+// size.
+func fitting(cells [][]byte, header int) int {
+	used := header
+	for i, cell := range cells {
+		if used += 2 + len(cell); used > sqlitePageSize {
+			return i
+		}
+	}
+	return len(cells)
+}
+
+// leafPage lays out a table leaf page holding cells, after offset bytes
+// kept for the database header.
+func leafPage(cells [][]byte, offset int) []byte {
+	page := make([]byte, sqlitePageSize)
+	page[offset] = 0x0d
+	placeCells(page, page[offset:offset+8], page[offset+8:], cells)
+	return page
+}
+
+// interiorPage lays out a table interior page holding cells, with right as
+// its right-most pointer.
+func interiorPage(cells [][]byte, right uint32) []byte {
+	page := make([]byte, sqlitePageSize)
+	page[0] = 0x05
+	binary.BigEndian.PutUint32(page[8:], right)
+	placeCells(page, page[:8], page[12:], cells)
+	return page
+}
+
+// placeCells writes cells to the end of page, their pointers to pointers and
+// their count and start to header.
+func placeCells(page, header, pointers []byte, cells [][]byte) {
+	be := binary.BigEndian
+	end := len(page)
+	for i, cell := range cells {
+		end -= len(cell)
+		copy(page[end:], cell)
+		be.PutUint16(pointers[2*i:], uint16(end))
+	}
+	be.PutUint16(header[3:], uint16(len(cells)))
+	be.PutUint16(header[5:], uint16(end))
+}
+
+// record encodes a row of nil, int64, string and []byte values.
+func record(row []any) []byte {
+	var types, body []byte
+	for _, value := range row {
+		switch v := value.(type) {
+		case nil:
+			types = appendVarint(types, 0)
+		case int64:
+			types = appendVarint(types, 6)
+			body = binary.BigEndian.AppendUint64(body, uint64(v))
+		case string:
+			types = appendVarint(types, uint64(2*len(v)+13))
+			body = append(body, v...)
+		case []byte:
+			types = appendVarint(types, uint64(2*len(v)+12))
+			body = append(body, v...)
+		}
+	}
+	// The header size counts itself, and takes a byte for rows this short
+	header := []byte{byte(len(types) + 1)}
+	return append(append(header, types...), body...)
+}
+
+// appendVarint appends a SQLite varint, big-endian 7 bits a byte, to b.
+func appendVarint(b []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		panic("varint too large")
+	}
+	var buf [8]byte
+	n := 0
+	for {
+		buf[n] = byte(v & 0x7f)
+		n++
+		if v >>= 7; v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := buf[i]
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
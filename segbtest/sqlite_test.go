@@ -0,0 +1,40 @@
+package segbtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	files := []ManifestFile{{FileID: "aa11", Domain: "HomeDomain", RelativePath: "Library/Biome", Flags: 2, File: bytes.Repeat([]byte("x"), 10000)}}
+	for i := range 1000 {
+		files = append(files, ManifestFile{FileID: fmt.Sprintf("f%05d", i), Domain: "MediaDomain", RelativePath: fmt.Sprintf("Media/%05d.JPG", i), Flags: 1})
+	}
+	path := filepath.Join(t.TempDir(), "Manifest.db")
+	if err := WriteManifest(path, files); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("SQLite format 3\x00")) || len(data)%sqlitePageSize != 0 {
+		t.Fatalf("%d bytes starting %q", len(data), data[:16])
+	}
+	pages := binary.BigEndian.Uint32(data[28:])
+	if int(pages) != len(data)/sqlitePageSize {
+		t.Errorf("header says %d pages, file holds %d", pages, len(data)/sqlitePageSize)
+	}
+	// The schema names the root of Files, an interior page
+	if !bytes.Contains(data[:sqlitePageSize], []byte(manifestSchema)) {
+		t.Error("no schema on page 1")
+	}
+	if root := data[(pages-1)*sqlitePageSize]; root != 0x05 {
+		t.Errorf("root page type 0x%02x, want an interior page", root)
+	}
+}
@@ -0,0 +1,255 @@
+// Package server exposes a directory of SEGB files over HTTP: a small JSON
+// API plus an embedded HTML viewer.
+//
+// Endpoints:
+//
+//	GET /                                   HTML viewer
+//	GET /api/files                          list decoded files
+//	GET /api/entries?file=PATH              list the entries of a file
+//	GET /api/payload?file=PATH&entry=N      raw payload of an entry
+//	GET /api/search?q=TEXT[&hex=HEX][&file=PATH]
+//	                                        find payload matches
+//
+// File paths are relative to the served root and use forward slashes.
+package server
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/biome"
+	"github.com/bluefalconhd/segb/export"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// maxSearchResults caps the number of matches returned by one search.
+const maxSearchResults = 1000
+
+// Server serves the SEGB files found below a root directory.
+type Server struct {
+	// Location is the time zone timestamps are served in, UTC if nil
+	Location *time.Location
+
+	corpus *segb.Corpus
+	files  map[string]*segb.FileResult // Keyed by slash-separated path relative to the root
+	mux    *http.ServeMux
+
+	// loadAnnotations reads the sidecar of a file of the corpus, nil when
+	// the files are not on a file system sidecars can be read from
+	loadAnnotations func(path string) (segb.Annotations, error)
+}
+
+// New loads every SEGB file below root and returns a server for them.
+func New(root string, opts ...segb.LoadOption) (*Server, error) {
+	corpus, err := segb.LoadDir(root, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := NewFromCorpus(corpus)
+	s.loadAnnotations = segb.LoadAnnotations
+	return s, nil
+}
+
+// NewFS loads every SEGB file below the directory root of fsys, as
+// segb.LoadFS does, and returns a server for them.
+func NewFS(fsys fs.FS, root string, opts ...segb.LoadOption) (*Server, error) {
+	corpus, err := segb.LoadFS(fsys, root, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := NewFromCorpus(corpus)
+	s.loadAnnotations = func(path string) (segb.Annotations, error) {
+		return segb.LoadAnnotationsFS(fsys, path)
+	}
+	return s, nil
+}
+
+// NewFromCorpus returns a server for an already loaded corpus. Its files may
+// come from anywhere, e.g. an archive, so their annotations are not served.
+func NewFromCorpus(corpus *segb.Corpus) *Server {
+	s := &Server{
+		corpus: corpus,
+		files:  make(map[string]*segb.FileResult, len(corpus.Files)),
+		mux:    http.NewServeMux(),
+	}
+	for path, result := range corpus.Files {
+		s.files[s.relPath(path)] = result
+	}
+
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /api/files", s.handleFiles)
+	s.mux.HandleFunc("GET /api/entries", s.handleEntries)
+	s.mux.HandleFunc("GET /api/payload", s.handlePayload)
+	s.mux.HandleFunc("GET /api/search", s.handleSearch)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) relPath(path string) string {
+	rel, err := filepath.Rel(s.corpus.Root, path)
+	if err != nil {
+		rel = path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// FileInfo describes one file in the /api/files listing.
+type FileInfo struct {
+	Path    string    `json:"path"`
+	Stream  string    `json:"stream,omitempty"` // Biome stream, inferred from the path
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+	Entries int       `json:"entries"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Match is a single search hit.
+type Match struct {
+	File   string `json:"file"`
+	Entry  int    `json:"entry"`
+	Offset int    `json:"offset"` // Offset of the match within the payload
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	files := make([]FileInfo, 0, len(s.files))
+	for _, path := range s.corpus.Paths() {
+		result := s.corpus.Files[path]
+		info := FileInfo{
+			Path:    s.relPath(path),
+			Stream:  biome.StreamName(path),
+			Version: result.Version.String(),
+			Created: s.inLocation(result.Segb.Created),
+			Size:    result.Size,
+			Entries: len(result.Segb.Entries),
+		}
+		if result.Err != nil {
+			info.Error = result.Err.Error()
+		}
+		files = append(files, info)
+	}
+	writeJSON(w, files)
+}
+
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	path, result, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	records := export.Records(path, result.Segb, false)
+	// Annotations are best effort, a bad sidecar should not hide the entries
+	if s.loadAnnotations != nil {
+		if annotations, err := s.loadAnnotations(result.Path); err == nil {
+			export.Annotate(records, annotations)
+		}
+	}
+	// The stream shows in the full path even when the root is inside the
+	// Biome directory
+	stream := biome.StreamName(result.Path)
+	for i := range records {
+		if stream != "" {
+			records[i].Stream = stream
+		}
+		records[i].Created = s.inLocation(records[i].Created)
+	}
+	writeJSON(w, records)
+}
+
+// inLocation returns t in the time zone of the server, leaving the zero
+// time as is.
+func (s *Server) inLocation(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	if s.Location == nil {
+		return t.UTC()
+	}
+	return t.In(s.Location)
+}
+
+func (s *Server) handlePayload(w http.ResponseWriter, r *http.Request) {
+	_, result, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("entry"))
+	if err != nil || index < 0 || index >= len(result.Segb.Entries) {
+		http.Error(w, "invalid entry index", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(result.Segb.Entries[index].Data)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var needle []byte
+	switch {
+	case query.Get("hex") != "":
+		var err error
+		needle, err = hex.DecodeString(query.Get("hex"))
+		if err != nil {
+			http.Error(w, "invalid hex pattern", http.StatusBadRequest)
+			return
+		}
+	case query.Get("q") != "":
+		needle = []byte(query.Get("q"))
+	default:
+		http.Error(w, "missing q or hex parameter", http.StatusBadRequest)
+		return
+	}
+
+	matches := []Match{}
+	for _, path := range s.corpus.Paths() {
+		rel := s.relPath(path)
+		if file := query.Get("file"); file != "" && file != rel {
+			continue
+		}
+		for _, m := range s.corpus.Files[path].Segb.SearchBytes(needle) {
+			if len(matches) == maxSearchResults {
+				break
+			}
+			matches = append(matches, Match{File: rel, Entry: m.Entry, Offset: m.Offset})
+		}
+	}
+	writeJSON(w, matches)
+}
+
+// lookup resolves the file query parameter, writing an error response if it
+// does not name a decoded file.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (string, *segb.FileResult, bool) {
+	path := r.URL.Query().Get("file")
+	result, ok := s.files[path]
+	if !ok {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return "", nil, false
+	}
+	return path, result, true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/export"
+	"github.com/bluefalconhd/segb/segbtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func setupServer(t *testing.T) *httptest.Server {
+	root := t.TempDir()
+
+	if err := segbtest.WriteFixtures(root); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func get(t *testing.T, url string, v any) []byte {
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s: %s", url, res.Status, body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(body, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return body
+}
+
+func TestServer(t *testing.T) {
+	ts := setupServer(t)
+
+	var files []FileInfo
+	get(t, ts.URL+"/api/files", &files)
+	if len(files) != 2 {
+		t.Fatalf("len(files) = %d; want 2", len(files))
+	}
+	if files[0].Path != "segb_version1.bin" || files[0].Version != "v1" || files[0].Entries != 3 {
+		t.Errorf("files[0] = %+v", files[0])
+	}
+
+	var entries []export.Record
+	get(t, ts.URL+"/api/entries?file=segb_version2.bin", &entries)
+	if len(entries) != 3 || entries[1].Size != 12 || entries[1].State != "written" {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	payload := get(t, ts.URL+"/api/payload?file=segb_version2.bin&entry=2", nil)
+	if string(payload) != "The rebels." {
+		t.Errorf("payload = %q; want %q", payload, "The rebels.")
+	}
+
+	var matches []Match
+	get(t, ts.URL+"/api/search?q=the", &matches)
+	if len(matches) != 2 {
+		t.Errorf("len(matches) = %d; want 2 (one per file)", len(matches))
+	}
+	get(t, ts.URL+"/api/search?hex=2e&file=segb_version1.bin", &matches)
+	if len(matches) != 3 || matches[0].Offset != 24 {
+		t.Errorf("matches = %+v; want one '.' at the end of each payload", matches)
+	}
+
+	if res, err := http.Get(ts.URL + "/api/entries?file=missing"); err != nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET missing file: %v, %v; want 404", res.Status, err)
+	}
+}
+
+func TestServerLocation(t *testing.T) {
+	root := t.TempDir()
+	if err := segbtest.WriteFixtures(root); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Location = time.FixedZone("UTC-5", -5*60*60)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var entries []struct {
+		Created string `json:"created"`
+	}
+	get(t, ts.URL+"/api/entries?file=segb_version2.bin", &entries)
+	if len(entries) != 3 || entries[0].Created != "2007-01-08T19:00:00-05:00" {
+		t.Errorf("entries = %+v; want timestamps at -05:00", entries)
+	}
+}
+
+func TestServerAnnotations(t *testing.T) {
+	root := t.TempDir()
+	if err := segbtest.WriteFixtures(root); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "segb_version2.bin")
+	annotations := segb.Annotations{}
+	annotations.Set(segb.Entry{Data: []byte("The misfits.")}, segb.Annotation{Tags: []string{"quote"}, Note: "second line"})
+	if err := annotations.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same files in a file system of their own, as in a bucket
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"segb_version2.bin", "segb_version2.bin" + segb.AnnotationsSuffix} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys["bucket/"+name] = &fstest.MapFile{Data: data}
+	}
+
+	local, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := NewFS(fsys, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	corpus, err := segb.LoadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		srv    *Server
+		tagged bool
+	}{
+		{"directory", local, true},
+		{"file system", remote, true},
+		// A corpus of unknown origin gets no annotations
+		{"corpus", NewFromCorpus(corpus), false},
+	} {
+		ts := httptest.NewServer(tc.srv)
+		var entries []export.Record
+		get(t, ts.URL+"/api/entries?file=segb_version2.bin", &entries)
+		ts.Close()
+		tagged := len(entries) == 3 && slices.Equal(entries[1].Tags, []string{"quote"}) && entries[1].Note == "second line"
+		if len(entries) != 3 || tagged != tc.tagged || entries[0].Tags != nil {
+			t.Errorf("%s: entries = %+v; want entry 1 tagged: %v", tc.name, entries, tc.tagged)
+		}
+	}
+}
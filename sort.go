@@ -0,0 +1,58 @@
+package segb
+
+import (
+	"sort"
+)
+
+// SortOrder is an order of Segb.Entries.
+type SortOrder int
+
+const (
+	// SortByOffset orders entries by position in the file, as Decode returns
+	// them.
+	SortByOffset SortOrder = iota
+	// SortByCreated orders entries by creation time.
+	SortByCreated
+	// SortBySequence orders entries by Entry.Sequence: as listed in the
+	// trailer for v2, in file order for v1.
+	SortBySequence
+)
+
+// String returns the lowercase name of the order.
+func (o SortOrder) String() string {
+	switch o {
+	case SortByCreated:
+		return "created"
+	case SortBySequence:
+		return "sequence"
+	default:
+		return "offset"
+	}
+}
+
+// WithSortOrder makes Decode return entries in the given order.
+func WithSortOrder(order SortOrder) DecodeOption {
+	return func(c *decodeConfig) {
+		c.order = order
+	}
+}
+
+// Sort orders the entries. Ties keep their file order. Entry indexes held
+// elsewhere, e.g. in Match or EntryHit, refer to the order at the time they
+// were produced.
+func (s *Segb) Sort(order SortOrder) {
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		a, b := &s.Entries[i], &s.Entries[j]
+		switch order {
+		case SortByCreated:
+			if !a.Created.Equal(b.Created) {
+				return a.Created.Before(b.Created)
+			}
+		case SortBySequence:
+			if a.Sequence != b.Sequence {
+				return a.Sequence < b.Sequence
+			}
+		}
+		return a.Offset < b.Offset
+	})
+}
@@ -0,0 +1,50 @@
+package segb
+
+import (
+	"encoding/binary"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"math"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	file := v2File([]byte("Here's to the crazy ones."), []byte("The misfits."), []byte("The rebels."))
+
+	// List the records in reverse in the trailer, and date entry 1 first
+	trailer := file[len(file)-3*v2.TrailerRecordSize:]
+	records := make([]byte, len(trailer))
+	for i := 0; i < 3; i++ {
+		copy(records[(2-i)*v2.TrailerRecordSize:], trailer[i*v2.TrailerRecordSize:(i+1)*v2.TrailerRecordSize])
+	}
+	copy(trailer, records)
+	for i, created := range []float64{300, 100, 200} {
+		binary.LittleEndian.PutUint64(trailer[(2-i)*v2.TrailerRecordSize+8:], math.Float64bits(created))
+	}
+
+	for _, tc := range []struct {
+		order SortOrder
+		want  []int // Indexes into expectedEntryData
+	}{
+		{SortByOffset, []int{0, 1, 2}},
+		{SortByCreated, []int{1, 2, 0}},
+		{SortBySequence, []int{2, 1, 0}},
+	} {
+		decoded, err := DecodeBytes(file, WithSortOrder(tc.order))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tc.want {
+			if got := string(decoded.Entries[i].Data); got != expectedEntryData[want] {
+				t.Errorf("%v: entry %d = %q; want %q", tc.order, i, got, expectedEntryData[want])
+			}
+		}
+
+		// Sorting back restores file order
+		decoded.Sort(SortByOffset)
+		for i := range decoded.Entries {
+			if got := string(decoded.Entries[i].Data); got != expectedEntryData[i] {
+				t.Errorf("%v then offset: entry %d = %q", tc.order, i, got)
+			}
+		}
+	}
+}
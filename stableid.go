@@ -0,0 +1,28 @@
+package segb
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// StableID returns an identifier for the entry that, unlike ID and its index
+// in Segb.Entries, does not change when filtering or recovery alters the set
+// of entries decoded: the offset of its payload in the file and the first 8
+// bytes of the SHA-256 of the payload as stored, e.g. "0x60:3e25b049b7bac558".
+// It is meant for references in reports that must still hold when the file
+// is parsed again with other options.
+func (e *Entry) StableID() string {
+	hash := HashPayload(e.checksummed())
+	return fmt.Sprintf("0x%x:%s", e.Offset, hex.EncodeToString(hash[:8]))
+}
+
+// FindStableID returns the index in Entries of the entry with the given
+// stable ID, see Entry.StableID.
+func (s *Segb) FindStableID(id string) (int, bool) {
+	for i := range s.Entries {
+		if s.Entries[i].StableID() == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
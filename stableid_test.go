@@ -0,0 +1,40 @@
+package segb
+
+import "testing"
+
+func TestStableID(t *testing.T) {
+	file := v2File([]byte("first"), zlibPayload("second, compressed"), []byte("third"))
+	all, err := DecodeBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]int)
+	for i := range all.Entries {
+		ids[all.Entries[i].StableID()] = i
+	}
+	if len(ids) != 3 {
+		t.Fatalf("stable IDs = %v; want 3 distinct", ids)
+	}
+
+	// Skipping an entry or decompression changes indexes and payloads, not
+	// stable IDs
+	filtered, err := DecodeBytes(file, WithoutPayloadDecompression(), WithEntryFilter(func(m EntryMeta) bool { return m.ID != 0 }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Entries) != 2 {
+		t.Fatalf("filtered decode has %d entries; want 2", len(filtered.Entries))
+	}
+	for i := range filtered.Entries {
+		id := filtered.Entries[i].StableID()
+		if want, ok := ids[id]; !ok || want != i+1 {
+			t.Errorf("filtered entry %d has stable ID %s; want that of entry %d", i, id, i+1)
+		}
+		if index, ok := all.FindStableID(id); !ok || index != i+1 {
+			t.Errorf("FindStableID(%s) = %d, %v; want %d", id, index, ok, i+1)
+		}
+	}
+	if _, ok := all.FindStableID("0x0:0000000000000000"); ok {
+		t.Error("FindStableID(unknown) found an entry")
+	}
+}
@@ -0,0 +1,76 @@
+// Package sysdiagnose locates and decodes the Biome data inside Apple
+// sysdiagnose archives, the tarballs iOS and macOS write for diagnostics,
+// so that incident responders can go from a collected sysdiagnose to
+// decoded entries without unpacking it. Members are found by path, wherever
+// the Biome directory sits in the archive, and mapped to their stream with
+// biome.ParsePath.
+package sysdiagnose
+
+import (
+	"errors"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/archive"
+	"github.com/bluefalconhd/segb/biome"
+	"strings"
+)
+
+// BiomeDir is the name of the directory holding Biome data, compared
+// without regard to case.
+const BiomeDir = "Biome"
+
+// Result is the outcome of decoding a single SEGB file of an archive.
+type Result struct {
+	Member  string        // Path of the file in the archive
+	Segment biome.Segment // Stream, location and creation time of the file, zero if its path is not a segment path
+	Segb    segb.Segb
+	Err     error
+}
+
+// IsBiomePath reports whether the path of a member is below a Biome
+// directory, or a Biome segment path.
+func IsBiomePath(name string) bool {
+	if _, ok := biome.ParsePath(name); ok {
+		return true
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.EqualFold(part, BiomeDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode decodes every SEGB file with a Biome path in the sysdiagnose
+// archive at path, in archive order. Other files with a Biome path, such
+// as databases, are skipped, and so are the other members of the archive,
+// without being read.
+func Decode(path string, opts ...segb.DecodeOption) ([]Result, error) {
+	var results []Result
+	err := archive.Walk(path, func(m archive.Member) error {
+		if !IsBiomePath(m.Name) {
+			return nil
+		}
+		r, err := m.Open()
+		if err != nil {
+			return err
+		}
+		stream, _, err := segb.Decompress(r)
+		if err != nil {
+			return nil
+		}
+		version, err := segb.DetectVersion(stream)
+		if (err != nil && !errors.Is(err, segb.ErrAmbiguousVersion)) || version == segb.NONE {
+			return nil
+		}
+
+		result := Result{Member: m.Name}
+		result.Segment, _ = biome.ParsePath(m.Name)
+		result.Segb, result.Err = segb.Decode(stream, opts...)
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,68 @@
+package sysdiagnose
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/bluefalconhd/segb"
+	"github.com/bluefalconhd/segb/biome"
+	"github.com/bluefalconhd/segb/segbtest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const root = "sysdiagnose_2024.01.15_10-30-00-0800_iPhone-OS_iPhone_21C66/"
+
+func TestDecode(t *testing.T) {
+	segment, err := segbtest.Bytes(segb.SEGB_VERSION_2, []segbtest.Entry{
+		{Data: []byte("com.apple.mobilesafari"), Created: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := []struct {
+		name string
+		data []byte
+	}{
+		{root + "logs/Biome/streams/restricted/App.InFocus/local/726748800000000", segment},
+		{root + "logs/Biome/sync/sync.db", []byte("SQLite format 3\x00")},
+		{root + "logs/biome/App.MediaUsage/1", segment},
+		// Not Biome data, left unread
+		{root + "logs/other/segment", segment},
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, member := range members {
+		tw.WriteHeader(&tar.Header{Name: member.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(member.data))})
+		tw.Write(member.data)
+	}
+	tw.Close()
+	gz.Close()
+	path := filepath.Join(t.TempDir(), "sysdiagnose.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Decode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("decoded %d files; want 2", len(results))
+	}
+	first := results[0]
+	if first.Member != members[0].name || first.Err != nil || len(first.Segb.Entries) != 1 {
+		t.Errorf("results[0] = %s, %v, %d entries", first.Member, first.Err, len(first.Segb.Entries))
+	}
+	if first.Segment.Stream != "App.InFocus" || first.Segment.Location != biome.LocationLocal || first.Segment.Created.IsZero() {
+		t.Errorf("results[0].Segment = %+v", first.Segment)
+	}
+	// Below a Biome directory, outside the streams layout
+	if results[1].Member != members[2].name || results[1].Segment.Stream != "" {
+		t.Errorf("results[1] = %s, stream %q", results[1].Member, results[1].Segment.Stream)
+	}
+}
@@ -0,0 +1,67 @@
+package timeconv
+
+import (
+	"time"
+)
+
+// Times Guess accepts, from 2002 up to 2031. The window is narrow enough that
+// a value never reads as a time within it in two representations: Cocoa
+// seconds up to 2031 stay below Unix seconds of 2002.
+var (
+	Earliest = time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)
+	Latest   = time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// GuessFloat tells whether a double is a timestamp, Cocoa or Unix seconds,
+// of a time between Earliest and Latest, and returns the time. It returns
+// None if the double is not one.
+func GuessFloat(v float64) (Kind, time.Time) {
+	switch {
+	case v >= ToCocoa(Earliest) && v < ToCocoa(Latest):
+		return Cocoa, FromCocoa(v)
+	case v >= ToUnix(Earliest) && v < ToUnix(Latest):
+		return Unix, FromUnix(v)
+	}
+	return None, time.Time{}
+}
+
+// GuessInt tells whether an integer is a timestamp, Cocoa or Unix seconds,
+// Unix milliseconds or microseconds or WebKit microseconds, of a time
+// between Earliest and Latest, and returns the time. It returns None if the
+// integer is not one. Mach absolute times count from boot and cannot be
+// told apart from other integers.
+func GuessInt(v int64) (Kind, time.Time) {
+	for _, guess := range []struct {
+		kind Kind
+		time func(int64) time.Time
+	}{
+		{Cocoa, func(v int64) time.Time { return CocoaEpoch.Add(time.Duration(v) * time.Second) }},
+		{Unix, func(v int64) time.Time { return time.Unix(v, 0).UTC() }},
+		{UnixMillis, func(v int64) time.Time { return time.UnixMilli(v).UTC() }},
+		{UnixMicros, func(v int64) time.Time { return time.UnixMicro(v).UTC() }},
+		{WebKit, FromWebKit},
+	} {
+		if lo, hi := bounds(guess.kind); v >= lo && v < hi {
+			return guess.kind, guess.time(v)
+		}
+	}
+	return None, time.Time{}
+}
+
+// bounds returns the range of integer values of a representation for times
+// between Earliest and Latest.
+func bounds(kind Kind) (int64, int64) {
+	switch kind {
+	case Cocoa:
+		return Earliest.Unix() - CocoaEpoch.Unix(), Latest.Unix() - CocoaEpoch.Unix()
+	case Unix:
+		return Earliest.Unix(), Latest.Unix()
+	case UnixMillis:
+		return Earliest.UnixMilli(), Latest.UnixMilli()
+	case UnixMicros:
+		return Earliest.UnixMicro(), Latest.UnixMicro()
+	case WebKit:
+		return ToWebKit(Earliest), ToWebKit(Latest)
+	}
+	return 0, 0
+}
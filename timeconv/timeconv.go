@@ -0,0 +1,148 @@
+// Package timeconv converts between time.Time and the timestamp
+// representations found in SEGB payloads: Cocoa (seconds since 2001), Unix
+// (seconds, milliseconds or microseconds since 1970), WebKit (microseconds
+// since 1601) and Mach absolute time (ticks since boot).
+package timeconv
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Epochs of the representations.
+var (
+	CocoaEpoch  = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	UnixEpoch   = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	WebKitEpoch = time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Kind is a timestamp representation.
+type Kind int
+
+const (
+	None       Kind = iota
+	Cocoa           // Seconds since 2001-01-01, usually a double
+	Unix            // Seconds since 1970-01-01
+	UnixMillis      // Milliseconds since 1970-01-01
+	UnixMicros      // Microseconds since 1970-01-01
+	WebKit          // Microseconds since 1601-01-01, as in WebKit and Chrome
+	Mach            // Mach absolute time: ticks since boot
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Cocoa:
+		return "Cocoa"
+	case Unix:
+		return "Unix"
+	case UnixMillis:
+		return "Unix millisecond"
+	case UnixMicros:
+		return "Unix microsecond"
+	case WebKit:
+		return "WebKit"
+	case Mach:
+		return "Mach absolute"
+	default:
+		return "none"
+	}
+}
+
+// FromCocoa converts seconds since 2001-01-01 00:00:00 UTC to a time,
+// keeping the fraction of a second to the microsecond, the precision of
+// recent Cocoa timestamps.
+func FromCocoa(seconds float64) time.Time {
+	return fromSeconds(CocoaEpoch, seconds)
+}
+
+// PlausibleCocoa reports whether a Cocoa timestamp falls between 2001 and
+// 2100, as those of SEGB headers and entries do.
+func PlausibleCocoa(seconds float64) bool {
+	return seconds >= 0 && seconds < 99*365.25*24*60*60
+}
+
+// ToCocoa converts t to seconds since 2001-01-01 00:00:00 UTC.
+func ToCocoa(t time.Time) float64 {
+	return t.Sub(CocoaEpoch).Seconds()
+}
+
+// FromUnix converts seconds since 1970-01-01 00:00:00 UTC to a time, to the
+// microsecond.
+func FromUnix(seconds float64) time.Time {
+	return fromSeconds(UnixEpoch, seconds)
+}
+
+// ToUnix converts t to seconds since 1970-01-01 00:00:00 UTC.
+func ToUnix(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// FromWebKit converts microseconds since 1601-01-01 00:00:00 UTC to a time.
+func FromWebKit(micros int64) time.Time {
+	// The span from 1601 overflows a time.Duration, so go through Unix time
+	return time.UnixMicro(micros + WebKitEpoch.UnixMicro()).UTC()
+}
+
+// ToWebKit converts t to microseconds since 1601-01-01 00:00:00 UTC.
+func ToWebKit(t time.Time) int64 {
+	return t.UnixMicro() - WebKitEpoch.UnixMicro()
+}
+
+// fromSeconds adds fractional seconds to an epoch, to the microsecond.
+func fromSeconds(epoch time.Time, seconds float64) time.Time {
+	whole := math.Floor(seconds)
+	micros := math.Round((seconds - whole) * 1e6)
+	return epoch.Add(time.Duration(whole)*time.Second + time.Duration(micros)*time.Microsecond)
+}
+
+// Timebase is the ratio of Mach absolute time ticks to nanoseconds, as
+// reported by mach_timebase_info on the device.
+type Timebase struct {
+	Numer, Denom uint32
+}
+
+// Timebases of the devices SEGB files come from.
+var (
+	TimebaseIntel        = Timebase{1, 1}   // Intel Macs: nanoseconds
+	TimebaseAppleSilicon = Timebase{125, 3} // iOS devices and Apple silicon Macs: 24 MHz
+)
+
+// Duration converts a number of ticks to a duration.
+func (tb Timebase) Duration(ticks uint64) time.Duration {
+	if tb.Denom == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(ticks, uint64(tb.Numer))
+	if hi >= uint64(tb.Denom) {
+		return math.MaxInt64
+	}
+	nanos, _ := bits.Div64(hi, lo, uint64(tb.Denom))
+	return time.Duration(min(nanos, math.MaxInt64))
+}
+
+// Ticks converts a duration to a number of ticks, the inverse of Duration.
+func (tb Timebase) Ticks(d time.Duration) uint64 {
+	if tb.Numer == 0 || d < 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(uint64(d), uint64(tb.Denom))
+	if hi >= uint64(tb.Numer) {
+		return math.MaxUint64
+	}
+	ticks, _ := bits.Div64(hi, lo, uint64(tb.Numer))
+	return ticks
+}
+
+// FromMach converts a Mach absolute time to a time, given when the device
+// booted and its timebase. Mach absolute time does not advance while the
+// device sleeps, so times long after boot come out early.
+func FromMach(ticks uint64, boot time.Time, tb Timebase) time.Time {
+	return boot.Add(tb.Duration(ticks))
+}
+
+// ToMach converts t to a Mach absolute time, given when the device booted
+// and its timebase. Times before boot give 0.
+func ToMach(t time.Time, boot time.Time, tb Timebase) uint64 {
+	return tb.Ticks(t.Sub(boot))
+}
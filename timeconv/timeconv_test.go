@@ -0,0 +1,105 @@
+package timeconv
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConversions(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 0, 0, 250000000, time.UTC)
+
+	if got := FromCocoa(730987200.25); !got.Equal(want) {
+		t.Errorf("FromCocoa() = %v; want %v", got, want)
+	}
+	if got := ToCocoa(want); got != 730987200.25 {
+		t.Errorf("ToCocoa() = %v; want 730987200.25", got)
+	}
+	if got := FromUnix(1709294400.25); !got.Equal(want) {
+		t.Errorf("FromUnix() = %v; want %v", got, want)
+	}
+	if got := ToUnix(want); got != 1709294400.25 {
+		t.Errorf("ToUnix() = %v; want 1709294400.25", got)
+	}
+	if got := FromWebKit(13353768000250000); !got.Equal(want) {
+		t.Errorf("FromWebKit() = %v; want %v", got, want)
+	}
+	if got := ToWebKit(want); got != 13353768000250000 {
+		t.Errorf("ToWebKit() = %v; want 13353768000250000", got)
+	}
+}
+
+func TestPlausibleCocoa(t *testing.T) {
+	for _, tc := range []struct {
+		seconds float64
+		want    bool
+	}{
+		{0, true},
+		{730987200.25, true},
+		{ToCocoa(time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)), true},
+		{ToCocoa(time.Date(2100, 1, 2, 0, 0, 0, 0, time.UTC)), false},
+		{-1, false},
+		{math.NaN(), false},
+		{math.Inf(1), false},
+	} {
+		if got := PlausibleCocoa(tc.seconds); got != tc.want {
+			t.Errorf("PlausibleCocoa(%v) = %v; want %v", tc.seconds, got, tc.want)
+		}
+	}
+}
+
+func TestMach(t *testing.T) {
+	boot := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	want := boot.Add(4 * time.Hour)
+
+	// 24 MHz: 24 million ticks a second
+	ticks := uint64(4 * 60 * 60 * 24000000)
+	if got := FromMach(ticks, boot, TimebaseAppleSilicon); !got.Equal(want) {
+		t.Errorf("FromMach() = %v; want %v", got, want)
+	}
+	if got := ToMach(want, boot, TimebaseAppleSilicon); got != ticks {
+		t.Errorf("ToMach() = %d; want %d", got, ticks)
+	}
+	if got := FromMach(uint64(4*time.Hour), boot, TimebaseIntel); !got.Equal(want) {
+		t.Errorf("FromMach(Intel) = %v; want %v", got, want)
+	}
+	if got := ToMach(boot.Add(-time.Second), boot, TimebaseIntel); got != 0 {
+		t.Errorf("ToMach(before boot) = %d; want 0", got)
+	}
+}
+
+func TestGuess(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		value float64
+		kind  Kind
+	}{
+		{730987200, Cocoa},
+		{1709294400, Unix},
+		{42, None},
+		{1e12, None},
+	} {
+		kind, got := GuessFloat(tc.value)
+		if kind != tc.kind || (kind != None && !got.Equal(want)) {
+			t.Errorf("GuessFloat(%v) = %v, %v; want %v", tc.value, kind, got, tc.kind)
+		}
+	}
+
+	for _, tc := range []struct {
+		value int64
+		kind  Kind
+	}{
+		{730987200, Cocoa},
+		{1709294400, Unix},
+		{1709294400000, UnixMillis},
+		{1709294400000000, UnixMicros},
+		{13353768000000000, WebKit},
+		{5, None},
+		{-1709294400, None},
+	} {
+		kind, got := GuessInt(tc.value)
+		if kind != tc.kind || (kind != None && !got.Equal(want)) {
+			t.Errorf("GuessInt(%d) = %v, %v; want %v", tc.value, kind, got, tc.kind)
+		}
+	}
+}
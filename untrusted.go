@@ -0,0 +1,40 @@
+package segb
+
+// Limits DecodeUntrusted applies unless its options set others
+const (
+	UntrustedMaxDecompressedSize = 256 << 20
+	UntrustedMaxTotalBytes       = 256 << 20
+	UntrustedMaxEntrySize        = 16 << 20
+)
+
+// WithMaxDecompressedSize lowers how many bytes compressed input, and each
+// compressed entry payload, may expand to from MaxDecompressedSize.
+func WithMaxDecompressedSize(n int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxDecompressed = n
+	}
+}
+
+// DecodeUntrusted is DecodeBytes for input from untrusted sources, e.g. a
+// service receiving SEGB blobs, with memory use bounded by default: it
+// applies WithMaxDecompressedSize(UntrustedMaxDecompressedSize),
+// WithMaxTotalBytes(UntrustedMaxTotalBytes) and
+// WithMaxEntrySize(UntrustedMaxEntrySize) before opts, which can change
+// them.
+func DecodeUntrusted(data []byte, opts ...DecodeOption) (Segb, error) {
+	opts = append([]DecodeOption{
+		WithMaxDecompressedSize(UntrustedMaxDecompressedSize),
+		WithMaxTotalBytes(UntrustedMaxTotalBytes),
+		WithMaxEntrySize(UntrustedMaxEntrySize),
+	}, opts...)
+	return DecodeBytes(data, opts...)
+}
+
+// decompressLimit returns how many bytes a compressed input or payload may
+// expand to.
+func (c decodeConfig) decompressLimit() int64 {
+	if c.maxDecompressed > 0 {
+		return min(c.maxDecompressed, MaxDecompressedSize)
+	}
+	return MaxDecompressedSize
+}
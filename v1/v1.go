@@ -3,16 +3,24 @@ package v1
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"github.com/bluefalconhd/segb/timeconv"
 	"hash/crc32"
 	"io"
+	"math"
 )
 
 const (
 	// FileMagic is the expected magic number at the end of the header.
 	FileMagic = "SEGB"
+	// DefaultAlignment is the boundary entries are padded to.
+	DefaultAlignment = 8
 )
 
+// ErrInvalidMagic is returned when the header does not hold the SEGB magic.
+var ErrInvalidMagic = errors.New("invalid magic number")
+
 // EntryState represents the state of an entry.
 type EntryState int32
 
@@ -69,6 +77,25 @@ func ReadHeader(stream io.ReadSeeker) (*Header, error) {
 
 // ReadEntry reads an entry from the provided stream.
 func ReadEntry(stream io.ReadSeeker, idx int32) (*Entry, error) {
+	entry, err := ReadEntryHeader(stream, idx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the variable-length data section
+	entry.Data = make([]byte, entry.Length)
+	_, err = io.ReadFull(stream, entry.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// ReadEntryHeader reads the fixed-size part of an entry, leaving Data nil and
+// the stream at the start of the data section. It fails if the data would run
+// past the end of the stream.
+func ReadEntryHeader(stream io.ReadSeeker, idx int32) (*Entry, error) {
 	entry := &Entry{}
 	// Record the current offset
 	offset, err := stream.Seek(0, io.SeekCurrent)
@@ -106,33 +133,110 @@ func ReadEntry(stream io.ReadSeeker, idx int32) (*Entry, error) {
 	// set ID
 	entry.ID = idx
 
-	// Read the variable-length data section
-	entry.Data = make([]byte, entry.Length)
-	_, err = io.ReadFull(stream, entry.Data)
+	// Make sure the claimed length fits in what is left of the stream before
+	// allocating for it
+	if entry.Length < 0 {
+		return nil, fmt.Errorf("invalid entry length: %d", entry.Length)
+	}
+	dataStart, err := stream.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	streamEnd, err := stream.Seek(0, io.SeekEnd)
 	if err != nil {
 		return nil, err
 	}
+	if _, err = stream.Seek(dataStart, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if int64(entry.Length) > streamEnd-dataStart {
+		return nil, io.ErrUnexpectedEOF
+	}
 
 	return entry, nil
 }
 
+// Options configures ReadSegbWithOptions.
+type Options struct {
+	// Alignment is the boundary each entry is padded to, relative to the
+	// start of the file. 0 means DefaultAlignment, 1 means no padding.
+	Alignment int
+
+	// SkipData reads only the entry headers, seeking past the data sections
+	// and leaving Entry.Data nil.
+	SkipData bool
+
+	// Filter, if set, is called with each entry as read by ReadEntryHeader.
+	// Entries it rejects are left out without reading their data.
+	Filter func(*Entry) bool
+
+	// Visit, if set, is passed each entry kept once its data is read, in
+	// file order, instead of returning the entries, so that they need not
+	// be held in memory. An error from it stops reading and is returned.
+	Visit func(*Entry) error
+}
+
+func (o Options) alignment() int64 {
+	if o.Alignment <= 0 {
+		return DefaultAlignment
+	}
+	return int64(o.Alignment)
+}
+
 // ReadSegb reads and parses a SEGB version 1 file from the provided stream.
 // It returns the header, a slice of entries, and an error if any.
 func ReadSegb(stream io.ReadSeeker) (*Header, []*Entry, error) {
+	return ReadSegbWithOptions(stream, Options{})
+}
+
+// ReadSegbWithOptions is ReadSegb with non-default options, e.g. for samples
+// padded to a different alignment. A malformed entry fails the read with an
+// EntryError.
+func ReadSegbWithOptions(stream io.ReadSeeker, opts Options) (*Header, []*Entry, error) {
+	header, entries, _, err := readSegb(stream, opts, false)
+	return header, entries, err
+}
+
+// EntryError is an entry that could not be read.
+type EntryError struct {
+	Index  int   // Position of the entry in the file
+	Offset int64 // Offset of the entry header in the file
+	Err    error
+}
+
+func (e EntryError) Error() string {
+	return fmt.Sprintf("entry %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e EntryError) Unwrap() error {
+	return e.Err
+}
+
+// ReadSegbLenient is ReadSegbWithOptions that does not give up on a malformed
+// entry: the error is recorded and reading resumes at the next plausible
+// entry header. Only an unreadable file header is fatal.
+func ReadSegbLenient(stream io.ReadSeeker, opts Options) (*Header, []*Entry, []EntryError, error) {
+	return readSegb(stream, opts, true)
+}
+
+func readSegb(stream io.ReadSeeker, opts Options, lenient bool) (*Header, []*Entry, []EntryError, error) {
+	alignment := opts.alignment()
+
 	// Read the header
 	header, err := ReadHeader(stream)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Verify the magic number
 	if !header.IsValidMagic() {
 
-		return nil, nil, fmt.Errorf("invalid magic number: %s", string(header.Magic[:]))
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrInvalidMagic, string(header.Magic[:]))
 	}
 
 	// Initialize an empty slice to hold entries
 	entries := []*Entry{}
+	var entryErrors []EntryError
 
 	idx := int32(0)
 
@@ -141,7 +245,7 @@ func ReadSegb(stream io.ReadSeeker) (*Header, []*Entry, error) {
 		// Get the current position
 		currentPosition, err := stream.Seek(0, io.SeekCurrent)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		// Check if we've reached the end of data
@@ -149,28 +253,101 @@ func ReadSegb(stream io.ReadSeeker) (*Header, []*Entry, error) {
 			break
 		}
 
-		// Read the next entry
-		entry, err := ReadEntry(stream, idx)
+		// Read the next entry, and its data unless skipped or filtered out
+		entry, err := ReadEntryHeader(stream, idx)
+		keep := err == nil && (opts.Filter == nil || opts.Filter(entry))
+		if err == nil {
+			if opts.SkipData || !keep {
+				_, err = stream.Seek(int64(entry.Length), io.SeekCurrent)
+			} else {
+				entry.Data = make([]byte, entry.Length)
+				_, err = io.ReadFull(stream, entry.Data)
+			}
+		}
 		if err != nil {
-			return nil, nil, err
+			entryErr := EntryError{Index: int(idx), Offset: currentPosition, Err: err}
+			if !lenient {
+				return nil, nil, nil, entryErr
+			}
+			entryErrors = append(entryErrors, entryErr)
+			idx++
+
+			// Resume at the next plausible entry header, if any
+			next, ok := findEntryHeader(stream, currentPosition+alignment, int64(header.EndOfDataOffset), alignment)
+			if !ok {
+				break
+			}
+			if _, err := stream.Seek(next, io.SeekStart); err != nil {
+				return nil, nil, nil, err
+			}
+			continue
+		}
+		if keep && opts.Visit != nil {
+			if err := opts.Visit(entry); err != nil {
+				return nil, nil, nil, err
+			}
+		} else if keep {
+			entries = append(entries, entry)
 		}
-		entries = append(entries, entry)
 
-		// Align to 8-byte boundary
+		// Align to the next entry boundary
 		positionAfterEntry, err := stream.Seek(0, io.SeekCurrent)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
-		padding := (8 - (positionAfterEntry % 8)) % 8
+		padding := (alignment - (positionAfterEntry % alignment)) % alignment
 		if padding > 0 {
 			_, err = stream.Seek(padding, io.SeekCurrent)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		}
 
 		idx++
 	}
 
-	return header, entries, nil
+	return header, entries, entryErrors, nil
+}
+
+// entryHeaderSize is the size of the fixed part of an entry.
+const entryHeaderSize = 32
+
+// findEntryHeader returns the first offset from start, stepping by
+// alignment, that holds a plausible entry header: a known state, Cocoa
+// timestamps between 2001 and 2100, and a length that fits before end.
+func findEntryHeader(stream io.ReadSeeker, start, end, alignment int64) (int64, bool) {
+	streamEnd, err := stream.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	end = min(end, streamEnd)
+
+	// Headers may start at any multiple of alignment from the file start
+	start += (alignment - start%alignment) % alignment
+
+	buf := make([]byte, entryHeaderSize)
+	for offset := start; offset+entryHeaderSize <= end; offset += alignment {
+		if _, err := stream.Seek(offset, io.SeekStart); err != nil {
+			return 0, false
+		}
+		if _, err := io.ReadFull(stream, buf); err != nil {
+			return 0, false
+		}
+
+		length := int64(int32(binary.LittleEndian.Uint32(buf)))
+		state := EntryState(binary.LittleEndian.Uint32(buf[4:]))
+		ts1 := math.Float64frombits(binary.LittleEndian.Uint64(buf[8:]))
+		ts2 := math.Float64frombits(binary.LittleEndian.Uint64(buf[16:]))
+		if length < 0 || offset+entryHeaderSize+length > end {
+			continue
+		}
+		if state != EntryStateWritten && state != EntryStateDeleted && state != EntryStateUnknown {
+			continue
+		}
+		if !timeconv.PlausibleCocoa(ts1) || !timeconv.PlausibleCocoa(ts2) {
+			continue
+		}
+		return offset, true
+	}
+	return 0, false
 }
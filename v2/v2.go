@@ -4,34 +4,22 @@ package v2
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/bluefalconhd/segb/hexdump"
 	"hash/crc32"
 	"io"
+	"math"
+	"os"
 	"sort"
 )
 
+// PrettyHexdump prints a hexdump of data to stdout.
+//
+// Deprecated: use hexdump.Dump, which can write to any io.Writer and is configurable.
 func PrettyHexdump(data []byte) {
-	for i := 0; i < len(data); i += 16 {
-		fmt.Printf("%08x: ", i)
-		for j := 0; j < 16; j++ {
-			if i+j < len(data) {
-				fmt.Printf("%02x ", data[i+j])
-			} else {
-				fmt.Print("   ")
-			}
-		}
-		fmt.Print(" ")
-		for j := 0; j < 16; j++ {
-			if i+j < len(data) {
-				if data[i+j] >= 32 && data[i+j] <= 126 {
-					fmt.Printf("%c", data[i+j])
-				} else {
-					fmt.Print(".")
-				}
-			}
-		}
-		fmt.Println()
-	}
+	_ = hexdump.Dump(os.Stdout, data, hexdump.Options{})
 }
 
 const (
@@ -39,8 +27,13 @@ const (
 	FileMagic = "SEGB"
 	// TrailerRecordSize is the size in bytes of each trailer record.
 	TrailerRecordSize = 16
+	// DefaultAlignment is the boundary entries are padded to.
+	DefaultAlignment = 4
 )
 
+// ErrInvalidMagic is returned when the header does not hold the SEGB magic.
+var ErrInvalidMagic = errors.New("invalid magic number")
+
 // EntryState represents the state of an entry.
 type EntryState int32
 
@@ -68,6 +61,33 @@ func (h *Header) IsValidMagic() bool {
 	return h.MagicString() == FileMagic
 }
 
+// UnknownFields are candidate interpretations of the unknown header bytes,
+// for comparing them across files. Which one, if any, is right is not known.
+type UnknownFields struct {
+	Float64 [2]float64 // Two little-endian float64s, e.g. Cocoa timestamps
+	Int32   [4]int32   // Four little-endian int32s, e.g. counters or flags
+	Hex     string     // The raw bytes
+}
+
+// InterpretUnknown decodes the unknown header bytes into each candidate
+// layout.
+func InterpretUnknown(padding [16]byte) UnknownFields {
+	var fields UnknownFields
+	for i := range fields.Float64 {
+		fields.Float64[i] = math.Float64frombits(binary.LittleEndian.Uint64(padding[i*8:]))
+	}
+	for i := range fields.Int32 {
+		fields.Int32[i] = int32(binary.LittleEndian.Uint32(padding[i*4:]))
+	}
+	fields.Hex = hex.EncodeToString(padding[:])
+	return fields
+}
+
+// InterpretUnknown decodes UnknownPadding, see InterpretUnknown.
+func (h *Header) InterpretUnknown() UnknownFields {
+	return InterpretUnknown(h.UnknownPadding)
+}
+
 // Record represents a trailer record in a SEGB file.
 type Record struct {
 	Offset            int32      // Offset of the entry data from the start of entries
@@ -83,16 +103,26 @@ type Entry struct {
 
 	CRCChecksum uint32  // CRC32 checksum of the entry data
 	Unknown     [4]byte // Unknown 4 bytes
-	Data        []byte  // Entry data (NB: due to some kinks with alignment, this might contain extra zero bytes. Trim as needed)
+	Data        []byte  // Entry data, alignment padding excluded, see LengthFromCRC
+
+	// LengthFromCRC is set when the length of Data is the one whose CRC32
+	// matches CRCChecksum. Entries have no length field, so when no length
+	// does, Data is the payload with its trailing zero bytes trimmed, which
+	// also drops any zeros the payload itself ends with.
+	LengthFromCRC bool
 
 	RawData []byte // Raw data including CRCChecksum and Unknown fields
+
+	// Additional fields for convenience.
+	Offset int64 // Offset of the entry in the file.
+	Span   int64 // Bytes the entry spans, up to the next entry or the trailer.
+
+	TrailerIndex int // Position of the entry's record in the trailer.
 }
 
 // VerifyCRC calculates the CRC32 checksum of the entry data and compares it with the stored checksum.
 func (e *Entry) VerifyCRC() bool {
-	// Exclude the CRCChecksum and Unknown fields (first 8 bytes)
-	dataToCheck := e.RawData[8:]
-	calculatedCRC := crc32.Checksum(dataToCheck, crc32.IEEETable)
+	calculatedCRC := crc32.Checksum(e.Data, crc32.IEEETable)
 	return e.CRCChecksum == calculatedCRC
 }
 
@@ -117,25 +147,23 @@ func ReadRecord(stream io.ReadSeeker) (*Record, error) {
 	return record, nil
 }
 
-// ReadSegb reads and parses a SEGB version 2 file from the provided stream.
-// It returns the header, a slice of records, a slice of entries, and an error if any.
-func ReadSegb(stream io.ReadSeeker) (*Header, []*Record, []*Entry, error) {
-	// Read the header
-	header, err := ReadHeader(stream)
+// ReadTrailer reads the trailer records, in file order, of the file whose
+// header is given, and returns them with the offset of the trailer.
+func ReadTrailer(stream io.ReadSeeker, header *Header) ([]*Record, int64, error) {
+	// Make sure the trailer fits after the header
+	trailerSize := TrailerRecordSize * int64(header.EntryCount)
+	fileSize, err := stream.Seek(0, io.SeekEnd)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, 0, err
 	}
-
-	// Verify the magic number
-	if !header.IsValidMagic() {
-		return nil, nil, nil, fmt.Errorf("invalid magic number: %s", header.MagicString())
+	if header.EntryCount < 0 || trailerSize > fileSize-int64(binary.Size(Header{})) {
+		return nil, 0, fmt.Errorf("trailer of %d records does not fit in the file: %w", header.EntryCount, io.ErrUnexpectedEOF)
 	}
 
 	// Seek to the start of the trailer (list of records)
-	trailerSize := TrailerRecordSize * int64(header.EntryCount)
 	trailerOffset, err := stream.Seek(-trailerSize, io.SeekEnd)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, 0, err
 	}
 
 	// Read the trailer records
@@ -143,18 +171,104 @@ func ReadSegb(stream io.ReadSeeker) (*Header, []*Record, []*Entry, error) {
 	for i := 0; i < int(header.EntryCount); i++ {
 		record, err := ReadRecord(stream)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, 0, err
 		}
 		records[i] = record
 	}
+	return records, trailerOffset, nil
+}
+
+// Options configures ReadSegbWithOptions.
+type Options struct {
+	// Alignment is the boundary each entry is padded to, relative to the
+	// start of the file. 0 means DefaultAlignment, 1 means no padding.
+	// Entries are located through the trailer, so this only matters for
+	// samples whose trailer offsets are themselves suspect.
+	Alignment int
+
+	// Filter, if set, is called with each entry before its data is read,
+	// with RawData and Data nil. Entries it rejects are left out.
+	Filter func(*Entry) bool
+
+	// Visit, if set, is passed each entry kept once its data is read, in
+	// file order, instead of returning the entries, so that they need not
+	// be held in memory. An error from it stops reading and is returned.
+	Visit func(*Entry) error
+}
+
+func (o Options) alignment() int64 {
+	if o.Alignment <= 0 {
+		return DefaultAlignment
+	}
+	return int64(o.Alignment)
+}
+
+// ReadSegb reads and parses a SEGB version 2 file from the provided stream.
+// It returns the header, a slice of records, a slice of entries, and an error if any.
+func ReadSegb(stream io.ReadSeeker) (*Header, []*Record, []*Entry, error) {
+	return ReadSegbWithOptions(stream, Options{})
+}
+
+// ReadSegbWithOptions is ReadSegb with non-default options. A malformed entry
+// fails the read with an EntryError.
+func ReadSegbWithOptions(stream io.ReadSeeker, opts Options) (*Header, []*Record, []*Entry, error) {
+	header, records, entries, _, err := readSegb(stream, opts, false)
+	return header, records, entries, err
+}
+
+// EntryError is an entry that could not be read.
+type EntryError struct {
+	Index  int   // Index of the entry's trailer record, sorted by offset
+	Offset int64 // Offset of the entry in the file
+	Err    error
+}
+
+func (e EntryError) Error() string {
+	return fmt.Sprintf("entry %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e EntryError) Unwrap() error {
+	return e.Err
+}
 
-	// Sort records by Offset
+// ReadSegbLenient is ReadSegbWithOptions that does not give up on a malformed
+// entry: the error is recorded and reading resumes with the next trailer
+// record. Only an unreadable file header or trailer is fatal.
+func ReadSegbLenient(stream io.ReadSeeker, opts Options) (*Header, []*Record, []*Entry, []EntryError, error) {
+	return readSegb(stream, opts, true)
+}
+
+func readSegb(stream io.ReadSeeker, opts Options, lenient bool) (*Header, []*Record, []*Entry, []EntryError, error) {
+	entryAlignment := opts.alignment()
+
+	// Read the header
+	header, err := ReadHeader(stream)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	// Verify the magic number
+	if !header.IsValidMagic() {
+		return nil, nil, nil, nil, fmt.Errorf("%w: %s", ErrInvalidMagic, header.MagicString())
+	}
+
+	records, trailerOffset, err := ReadTrailer(stream, header)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	// Sort records by Offset, remembering their position in the trailer
+	trailerIndex := make(map[*Record]int, len(records))
+	for i, record := range records {
+		trailerIndex[record] = i
+	}
 	sort.Slice(records, func(i, j int) bool {
 		return records[i].Offset < records[j].Offset
 	})
 
 	// Read entries
 	entries := make([]*Entry, 0, len(records))
+	var entryErrors []EntryError
 	for idx, record := range records {
 		if record.State == EntryStateUnknown {
 			continue
@@ -174,57 +288,117 @@ func ReadSegb(stream io.ReadSeeker) (*Header, []*Record, []*Entry, error) {
 			entryLength = trailerOffset - entryStart
 		}
 
-		if entryLength <= 0 {
-			return nil, nil, nil, fmt.Errorf("invalid entry length")
-		}
-
-		// Seek to the entry start position
-		_, err = stream.Seek(entryStart, io.SeekStart)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-
-		// Read the entry data
-		entryData := make([]byte, entryLength)
-		_, err = io.ReadFull(stream, entryData)
-		if err != nil {
-			return nil, nil, nil, err
+		if opts.Filter != nil && !opts.Filter(&Entry{
+			ID:                uint32(idx),
+			State:             record.State,
+			CreationTimestamp: record.CreationTimestamp,
+			Offset:            entryStart,
+			Span:              entryLength,
+		}) {
+			continue
 		}
 
-		// Parse the entry
-		entry := &Entry{}
-		if len(entryData) < 8 {
-			return nil, nil, nil, fmt.Errorf("entry data too short")
-		}
-		buf := bytes.NewReader(entryData[:8])
-		// Read CRCChecksum and Unknown fields
-		err = binary.Read(buf, binary.LittleEndian, &entry.CRCChecksum)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		err = binary.Read(buf, binary.LittleEndian, &entry.Unknown)
+		entry, err := readEntry(stream, entryStart, entryLength, trailerOffset)
 		if err != nil {
-			return nil, nil, nil, err
+			entryErr := EntryError{Index: idx, Offset: entryStart, Err: err}
+			if !lenient {
+				return nil, nil, nil, nil, entryErr
+			}
+			entryErrors = append(entryErrors, entryErr)
+			continue
 		}
 
 		entry.ID = uint32(idx)
 		entry.State = record.State
 		entry.CreationTimestamp = record.CreationTimestamp
-		entry.Data = bytes.TrimRight(entryData[8:], "\x00") // Data after CRCChecksum and Unknown fields, trim padding
-		entry.RawData = entryData
+		entry.TrailerIndex = trailerIndex[record]
 
-		entries = append(entries, entry)
+		if opts.Visit != nil {
+			if err := opts.Visit(entry); err != nil {
+				return nil, nil, nil, nil, err
+			}
+		} else {
+			entries = append(entries, entry)
+		}
 
-		// Handle alignment padding by seeking to the next 4-byte boundary
+		// Handle alignment padding by seeking to the next entry boundary
 		currentPosition := entryStart + entryLength
-		alignment := (4 - (currentPosition % 4)) % 4
+		alignment := (entryAlignment - (currentPosition % entryAlignment)) % entryAlignment
 		if alignment > 0 {
 			_, err = stream.Seek(alignment, io.SeekCurrent)
 			if err != nil {
-				return nil, nil, nil, err
+				return nil, nil, nil, nil, err
 			}
 		}
 	}
 
-	return header, records, entries, nil
+	return header, records, entries, entryErrors, nil
+}
+
+// readEntry reads the entry of entryLength bytes, padding included, at
+// entryStart. Entries must end before the trailer at trailerOffset.
+func readEntry(stream io.ReadSeeker, entryStart, entryLength, trailerOffset int64) (*Entry, error) {
+	if entryLength <= 0 {
+		return nil, fmt.Errorf("invalid entry length")
+	}
+	if entryStart+entryLength > trailerOffset {
+		return nil, fmt.Errorf("entry runs into the trailer: %w", io.ErrUnexpectedEOF)
+	}
+
+	// Seek to the entry start position
+	_, err := stream.Seek(entryStart, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the entry data
+	entryData := make([]byte, entryLength)
+	_, err = io.ReadFull(stream, entryData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the entry
+	entry := &Entry{}
+	if len(entryData) < 8 {
+		return nil, fmt.Errorf("entry data too short")
+	}
+	buf := bytes.NewReader(entryData[:8])
+	// Read CRCChecksum and Unknown fields
+	err = binary.Read(buf, binary.LittleEndian, &entry.CRCChecksum)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(buf, binary.LittleEndian, &entry.Unknown)
+	if err != nil {
+		return nil, err
+	}
+
+	// Data after CRCChecksum and Unknown fields, without padding
+	length, fromCRC := payloadLength(entryData[8:], entry.CRCChecksum)
+	entry.Data = entryData[8 : 8+length]
+	entry.LengthFromCRC = fromCRC
+	entry.RawData = entryData
+	entry.Offset = entryStart
+	entry.Span = entryLength
+	return entry, nil
+}
+
+// payloadLength returns the length of payload without its alignment
+// padding. Padding is zero bytes, so the length lies between that of the
+// payload with its trailing zeros trimmed and the full one; the length whose
+// CRC32 matches checksum is returned with true. If none does, e.g. for a
+// corrupt entry, the trimmed length is returned with false.
+func payloadLength(payload []byte, checksum uint32) (int, bool) {
+	trimmed := len(bytes.TrimRight(payload, "\x00"))
+	crc := crc32.ChecksumIEEE(payload[:trimmed])
+	for n := trimmed; n <= len(payload); n++ {
+		if n > trimmed {
+			crc = crc32.Update(crc, crc32.IEEETable, payload[n-1:n])
+		}
+		if crc == checksum {
+			return n, true
+		}
+	}
+	return trimmed, false
 }
@@ -0,0 +1,181 @@
+package segb
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrStopWalk is returned by the function passed to Walk or WalkStream to
+// stop at the current entry without error, e.g. once a match is found.
+var ErrStopWalk = errors.New("stop walking entries")
+
+// errProbed stops the walk that detects the alignment of a v1 file.
+var errProbed = errors.New("alignment probed")
+
+// Walk calls fn with each entry in order. It stops at the first error fn
+// returns and returns it, or nil for ErrStopWalk.
+func (s *Segb) Walk(fn func(Entry) error) error {
+	for _, entry := range s.Entries {
+		if err := fn(entry); err != nil {
+			if errors.Is(err, ErrStopWalk) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkStream decodes a SEGB file entry by entry, calling fn with each one as
+// soon as it is read, in file order. Once fn returns an error the rest of
+// the file is not read: WalkStream returns the error, or nil for
+// ErrStopWalk. This makes "find the first matching entry" queries cheap on
+// large files, and entries are not held in memory past fn.
+//
+// Options apply as they do to Decode, except those that need the whole
+// file: WithSortOrder, WithRoundTrip and WithResidualEntries are ignored,
+// and WithRawPayloads leaves payloads compressed but not padded.
+// Entries skipped under WithLenientParsing are not visited. An ambiguous
+// file is walked as the first version it matches, and versions added with
+// Register are decoded whole, then walked.
+func WalkStream(stream io.ReadSeeker, fn func(Entry) error, opts ...DecodeOption) error {
+	cfg := decodeConfig{payloads: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.retry.Attempts > 1 {
+		stream = NewRetryReader(stream, cfg.retry)
+	}
+	stream, _, err := decompress(stream, cfg.decompressLimit())
+	if err != nil {
+		return err
+	}
+	versions, err := detectVersions(stream)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return ErrUnsupportedVersion
+	}
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	switch versions[0] {
+	case SEGB_VERSION_1:
+		alignment := cfg.alignment
+		if alignment <= 0 {
+			if alignment, err = probeV1Alignment(stream, cfg); err != nil {
+				return err
+			}
+			if _, err := stream.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		cfg.visit = fn
+		_, err = readV1(stream, alignment, cfg)
+	case SEGB_VERSION_2:
+		cfg.visit = fn
+		_, err = decodeV2(stream, cfg)
+	default:
+		var decoded Segb
+		if decoded, err = Decode(stream, opts...); err == nil {
+			err = decoded.Walk(fn)
+		}
+	}
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
+
+// DecodeToChannel decodes a SEGB file in the background, as WalkStream
+// does, sending each entry on the returned entry channel as soon as it is
+// read, so that a pipeline can fan entries out to workers while the file is
+// still being read. Both channels are closed once decoding ends; the error
+// channel first receives the error decoding stopped with, if any. Canceling
+// ctx stops decoding with ctx.Err(). The stream must not be used until the
+// entry channel is closed.
+func DecodeToChannel(ctx context.Context, stream io.ReadSeeker, opts ...DecodeOption) (<-chan Entry, <-chan error) {
+	entries := make(chan Entry)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(entries)
+		err := WalkStream(stream, func(entry Entry) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			select {
+			case entries <- entry:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts...)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return entries, errs
+}
+
+// visitEntry finishes decoding the single entry of s as Decode does, and
+// passes it to the visit function.
+func (c decodeConfig) visitEntry(s Segb) error {
+	if err := s.applyCRCPolicy(c.crcPolicy, c.lenient); err != nil {
+		return err
+	}
+	if c.payloads {
+		if _, err := s.decompressPayloads(c.decompressLimit(), c.remainingBudget(&s)); err != nil {
+			return err
+		}
+	}
+	s.classifyPayloads()
+	for _, entry := range s.Entries {
+		if err := c.visit(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// probeV1Alignment picks the alignment of a v1 file as decodeV1 does, but
+// reads only the leading entries for each candidate.
+func probeV1Alignment(stream io.ReadSeeker, cfg decodeConfig) (int, error) {
+	best, bestScore := 0, -1
+	var firstErr error
+	for _, candidate := range v1AlignmentCandidates {
+		if _, err := stream.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		score, probed := 0, 0
+		probe := decodeConfig{lenient: cfg.lenient, visit: func(entry Entry) error {
+			if entry.CheckCRC() {
+				score++
+			}
+			if probed++; probed == alignmentProbeEntries {
+				return errProbed
+			}
+			return nil
+		}}
+		if _, err := readV1(stream, candidate, probe); err != nil && err != errProbed {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if score == probed {
+			return candidate, nil
+		}
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	if bestScore < 0 {
+		return 0, firstErr
+	}
+	return best, nil
+}
@@ -0,0 +1,116 @@
+package segb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestWalkStream(t *testing.T) {
+	payloads := [][]byte{[]byte("first"), zlibPayload("second, compressed"), []byte("third")}
+	for _, tc := range []struct {
+		name string
+		file []byte
+	}{
+		{"v1", v1File(4, payloads...)},
+		{"v2", v2File(payloads...)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := DecodeBytes(tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var walked []Entry
+			err = WalkStream(bytes.NewReader(tc.file), func(entry Entry) error {
+				walked = append(walked, entry)
+				return nil
+			})
+			if err != nil || len(walked) != len(decoded.Entries) {
+				t.Fatalf("WalkStream() = %v after %d entries; want %d", err, len(walked), len(decoded.Entries))
+			}
+			for i, entry := range walked {
+				want := decoded.Entries[i]
+				if !bytes.Equal(entry.Data, want.Data) || entry.Offset != want.Offset || entry.Compression != want.Compression ||
+					entry.ContentType != want.ContentType || !entry.CRCValid {
+					t.Errorf("entry %d = %+v; want %+v", i, entry, want)
+				}
+			}
+
+			// The same, walking the decoded file
+			walked = walked[:0]
+			decoded.Walk(func(entry Entry) error {
+				walked = append(walked, entry)
+				return nil
+			})
+			if len(walked) != len(decoded.Entries) {
+				t.Errorf("Walk() visited %d entries; want %d", len(walked), len(decoded.Entries))
+			}
+		})
+	}
+}
+
+func TestWalkStreamStop(t *testing.T) {
+	file := v2File([]byte("first"), []byte("match"), []byte("third"))
+	// Corrupt the last payload, which fails a strict decode
+	file[bytes.Index(file, []byte("third"))] = 'T'
+	if _, err := DecodeBytes(file, WithCRCPolicy(CRCStrict)); !errors.Is(err, ErrCRCMismatch) {
+		t.Fatalf("DecodeBytes(corrupt last entry) = %v; want ErrCRCMismatch", err)
+	}
+
+	var visited []string
+	err := WalkStream(bytes.NewReader(file), func(entry Entry) error {
+		visited = append(visited, string(entry.Data))
+		if string(entry.Data) == "match" {
+			return ErrStopWalk
+		}
+		return nil
+	}, WithCRCPolicy(CRCStrict))
+	if err != nil || !slices.Equal(visited, []string{"first", "match"}) {
+		t.Errorf("WalkStream() = %v, visited %q; want nil after first, match", err, visited)
+	}
+
+	errFound := errors.New("found")
+	err = WalkStream(bytes.NewReader(file), func(entry Entry) error { return errFound })
+	if err != errFound {
+		t.Errorf("WalkStream() = %v; want the error of fn", err)
+	}
+	decoded, _ := DecodeBytes(v2File([]byte("first"), []byte("second")))
+	visited = visited[:0]
+	err = decoded.Walk(func(entry Entry) error {
+		visited = append(visited, string(entry.Data))
+		return ErrStopWalk
+	})
+	if err != nil || len(visited) != 1 {
+		t.Errorf("Walk() = %v after %q; want nil after one entry", err, visited)
+	}
+}
+
+func TestDecodeToChannel(t *testing.T) {
+	file := v2File([]byte("first"), []byte("second"), []byte("third"))
+	entries, errs := DecodeToChannel(context.Background(), bytes.NewReader(file))
+	var received []string
+	for entry := range entries {
+		received = append(received, string(entry.Data))
+	}
+	if err := <-errs; err != nil || !slices.Equal(received, []string{"first", "second", "third"}) {
+		t.Errorf("DecodeToChannel() = %q, %v", received, err)
+	}
+
+	// A consumer that gives up cancels the rest
+	ctx, cancel := context.WithCancel(context.Background())
+	entries, errs = DecodeToChannel(ctx, bytes.NewReader(file))
+	<-entries
+	cancel()
+	for range entries {
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("DecodeToChannel(canceled) error = %v; want context.Canceled", err)
+	}
+
+	_, errs = DecodeToChannel(context.Background(), bytes.NewReader([]byte("not a SEGB file")))
+	if err := <-errs; !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("DecodeToChannel(garbage) error = %v; want ErrUnsupportedVersion", err)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build js && wasm
+
+// Command wasm exposes the SEGB decoder to JavaScript.
+//
+// Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o segb.wasm ./wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+// Once running it defines a global function:
+//
+//	segbDecode(bytes: Uint8Array) => {
+//	  version: "v1" | "v2",
+//	  created: string,              // RFC 3339
+//	  entries: [{ index, id, state, created, size, checksum, data: Uint8Array }],
+//	} | { error: string }
+package main
+
+import (
+	"github.com/bluefalconhd/segb"
+	"syscall/js"
+	"time"
+)
+
+func decode(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeObject {
+		return map[string]any{"error": "segbDecode expects a Uint8Array"}
+	}
+
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+
+	decoded, err := segb.DecodeBytes(data)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	entries := make([]any, len(decoded.Entries))
+	for i, entry := range decoded.Entries {
+		payload := js.Global().Get("Uint8Array").New(len(entry.Data))
+		js.CopyBytesToJS(payload, entry.Data)
+
+		entries[i] = map[string]any{
+			"index":    i,
+			"id":       entry.ID,
+			"state":    entry.State.String(),
+			"created":  entry.Created.Format(time.RFC3339Nano),
+			"size":     len(entry.Data),
+			"checksum": int(entry.Checksum),
+			"data":     payload,
+		}
+	}
+
+	return map[string]any{
+		"version": decoded.Version.String(),
+		"created": decoded.Created.Format(time.RFC3339Nano),
+		"entries": entries,
+	}
+}
+
+func main() {
+	js.Global().Set("segbDecode", js.FuncOf(decode))
+
+	// Keep the program alive so the exported function stays callable
+	select {}
+}
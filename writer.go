@@ -0,0 +1,367 @@
+package segb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	v1 "github.com/bluefalconhd/segb/v1"
+	v2 "github.com/bluefalconhd/segb/v2"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Sizes of the file headers
+const (
+	v1HeaderSize = 56
+	v2HeaderSize = 32
+)
+
+// ErrWriterClosed is returned when appending to a closed Writer.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// WriterFile is the file a Writer appends to. *os.File implements it.
+type WriterFile interface {
+	io.WriterAt
+	Truncate(size int64) error
+	Sync() error
+}
+
+// WriterOption configures a Writer.
+type WriterOption func(*writerConfig)
+
+type writerConfig struct {
+	syncEvery int   // Entries per sync, 0 to sync only on Sync and Close
+	prealloc  int64 // Bytes the file grows by at a time, 0 to grow as needed
+	alignment int   // 0 for the version's default
+	atomic    bool
+}
+
+// WithSyncEvery makes the Writer commit and fsync the file every n entries
+// instead of after each one, trading the entries appended since the last
+// sync in a crash for fewer fsyncs. With 0, the file is only synced by
+// Writer.Sync and Writer.Close.
+func WithSyncEvery(n int) WriterOption {
+	return func(c *writerConfig) {
+		c.syncEvery = n
+	}
+}
+
+// WithPreallocation makes the Writer grow the file n bytes at a time, like
+// Apple's writer reserves room for a segment up front, rather than by each
+// entry. The reserved space is zero-filled and stays in the file: after the
+// entries of a v1 file, and between the entries and the trailer of a v2 one.
+func WithPreallocation(n int64) WriterOption {
+	return func(c *writerConfig) {
+		c.prealloc = n
+	}
+}
+
+// WithEntryAlignment sets the boundary entries are padded to, by default
+// v1.DefaultAlignment or v2.DefaultAlignment. 1 means no padding.
+func WithEntryAlignment(n int) WriterOption {
+	return func(c *writerConfig) {
+		c.alignment = n
+	}
+}
+
+// WithAtomicWrite makes Create write to a temporary file in the same
+// directory, which Close renames over the path once it is synced. Other
+// processes see either the previous file or the complete new one, never a
+// partial one, and nothing written is visible before Close. Use Writer.Abort
+// to give up on the file.
+func WithAtomicWrite() WriterOption {
+	return func(c *writerConfig) {
+		c.atomic = true
+	}
+}
+
+// Writer appends entries to a SEGB file.
+//
+// Appended entries become part of the file when it is committed, by writing
+// them along with the v1 end of data offset or the v2 entry count and
+// trailer, right before each sync. A crash loses the entries appended since
+// the last sync, but one during a commit can leave the file unreadable.
+type Writer struct {
+	file      WriterFile
+	version   SegbVersion
+	cfg       writerConfig
+	created   float64
+	end       int64       // End of the committed entries
+	size      int64       // Size of the file
+	records   []v2.Record // Trailer records of a v2 file
+	pending   []byte      // Entries appended since the last commit
+	unsynced  int         // Number of entries in pending
+	committed int         // Number of entries in the file
+	closed    bool
+
+	// Set for Writers from Create
+	osFile *os.File
+	path   string // Where an atomic Writer renames its file to
+}
+
+// NewWriter starts a new SEGB file of the given version in file, replacing
+// its content. Close the Writer, which does not close file, when done.
+func NewWriter(file WriterFile, version SegbVersion, opts ...WriterOption) (*Writer, error) {
+	cfg := writerConfig{syncEvery: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.atomic {
+		return nil, errors.New("atomic writes need a path, use Create")
+	}
+	return newWriter(file, version, cfg)
+}
+
+// Create creates or truncates the SEGB file at path, like os.Create, and
+// returns a Writer for it. Closing the Writer closes the file.
+func Create(path string, version SegbVersion, opts ...WriterOption) (*Writer, error) {
+	cfg := writerConfig{syncEvery: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var file *os.File
+	var err error
+	if cfg.atomic {
+		file, err = os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+		if err == nil {
+			err = file.Chmod(0o644)
+		}
+	} else {
+		file, err = os.Create(path)
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+		return nil, err
+	}
+
+	w, err := newWriter(file, version, cfg)
+	if err != nil {
+		file.Close()
+		if cfg.atomic {
+			os.Remove(file.Name())
+		}
+		return nil, err
+	}
+	w.osFile = file
+	if cfg.atomic {
+		w.path = path
+	}
+	return w, nil
+}
+
+func newWriter(file WriterFile, version SegbVersion, cfg writerConfig) (*Writer, error) {
+	w := &Writer{
+		file:    file,
+		version: version,
+		cfg:     cfg,
+		created: TimeToCocoaTimestamp(time.Now()),
+	}
+
+	switch version {
+	case SEGB_VERSION_1:
+		w.end = v1HeaderSize
+		if w.cfg.alignment <= 0 {
+			w.cfg.alignment = v1.DefaultAlignment
+		}
+	case SEGB_VERSION_2:
+		w.end = v2HeaderSize
+		if w.cfg.alignment <= 0 {
+			w.cfg.alignment = v2.DefaultAlignment
+		}
+	default:
+		return nil, ErrUnsupportedVersion
+	}
+
+	if err := file.Truncate(0); err != nil {
+		return nil, err
+	}
+	if err := w.Sync(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Append adds an entry in the written state holding payload, created at
+// timestamp.
+func (w *Writer) Append(payload []byte, timestamp time.Time) error {
+	return w.AppendWithState(payload, timestamp, EntryStateWritten)
+}
+
+// AppendWithState is Append for an entry in the given state, e.g. a deleted
+// one.
+func (w *Writer) AppendWithState(payload []byte, timestamp time.Time, state EntryState) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	start := w.end + int64(len(w.pending))
+	headerSize := int64(v1EntryHeaderSize)
+	if w.version == SEGB_VERSION_2 {
+		headerSize = v2EntryHeaderSize
+	}
+	end := alignUp(start+headerSize+int64(len(payload)), int64(w.cfg.alignment))
+	if end+int64(len(w.records)+1)*v2.TrailerRecordSize > math.MaxInt32 {
+		return fmt.Errorf("entry of %d bytes would grow the file past %d bytes", len(payload), math.MaxInt32)
+	}
+
+	created := TimeToCocoaTimestamp(timestamp)
+	checksum := crc32.ChecksumIEEE(payload)
+	switch w.version {
+	case SEGB_VERSION_1:
+		w.pending = binary.LittleEndian.AppendUint32(w.pending, uint32(len(payload)))
+		w.pending = binary.LittleEndian.AppendUint32(w.pending, uint32(state))
+		w.pending = binary.LittleEndian.AppendUint64(w.pending, math.Float64bits(created))
+		w.pending = binary.LittleEndian.AppendUint64(w.pending, math.Float64bits(created))
+		w.pending = binary.LittleEndian.AppendUint32(w.pending, checksum)
+		w.pending = append(w.pending, make([]byte, 4)...)
+	case SEGB_VERSION_2:
+		w.records = append(w.records, v2.Record{
+			Offset:            int32(start - v2HeaderSize),
+			State:             v2.EntryState(state),
+			CreationTimestamp: created,
+		})
+		w.pending = binary.LittleEndian.AppendUint32(w.pending, checksum)
+		w.pending = append(w.pending, make([]byte, 4)...)
+	}
+	w.pending = append(w.pending, payload...)
+	w.pending = append(w.pending, make([]byte, end-w.end-int64(len(w.pending)))...)
+
+	w.unsynced++
+	if w.cfg.syncEvery > 0 && w.unsynced >= w.cfg.syncEvery {
+		return w.Sync()
+	}
+	return nil
+}
+
+// Sync commits the entries appended so far and fsyncs the file.
+func (w *Writer) Sync() error {
+	if err := w.commit(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Close syncs the file. It closes the underlying file only for Writers from
+// Create, renaming it into place if they write atomically.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.Sync()
+	if w.osFile == nil {
+		return err
+	}
+	if closeErr := w.osFile.Close(); err == nil {
+		err = closeErr
+	}
+	if w.path == "" {
+		return err
+	}
+	if err != nil {
+		os.Remove(w.osFile.Name())
+		return err
+	}
+	if err := os.Rename(w.osFile.Name(), w.path); err != nil {
+		os.Remove(w.osFile.Name())
+		return err
+	}
+
+	// Make the rename itself durable
+	if dir, err := os.Open(filepath.Dir(w.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// Abort stops writing without committing the entries appended since the
+// last sync. An atomic Writer removes its temporary file, leaving the path
+// as it was.
+func (w *Writer) Abort() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.osFile == nil {
+		return nil
+	}
+	err := w.osFile.Close()
+	if w.path != "" {
+		if removeErr := os.Remove(w.osFile.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
+
+// commit writes the pending entries, then the headers and trailer that make
+// them part of the file.
+func (w *Writer) commit() error {
+	end := w.end + int64(len(w.pending))
+	trailerSize := int64(len(w.records)) * v2.TrailerRecordSize
+	size := w.size
+	if end+trailerSize > size {
+		size = end + trailerSize
+		if w.cfg.prealloc > 0 {
+			size = alignUp(size, w.cfg.prealloc)
+		}
+	}
+
+	// A v2 trailer sits at the end of the file, so growing it leaves the old
+	// one in what is now free space
+	if w.version == SEGB_VERSION_2 && size != w.size {
+		oldTrailer := w.size - int64(w.committed)*v2.TrailerRecordSize
+		if stale := w.size - max(oldTrailer, end); stale > 0 {
+			if _, err := w.file.WriteAt(make([]byte, stale), w.size-stale); err != nil {
+				return err
+			}
+		}
+	}
+	if size != w.size {
+		if err := w.file.Truncate(size); err != nil {
+			return err
+		}
+	}
+	if _, err := w.file.WriteAt(w.pending, w.end); err != nil {
+		return err
+	}
+
+	var header []byte
+	switch w.version {
+	case SEGB_VERSION_1:
+		header = binary.LittleEndian.AppendUint32(nil, uint32(end))
+		header = append(header, make([]byte, 48)...)
+		header = append(header, v1.FileMagic...)
+	case SEGB_VERSION_2:
+		var trailer []byte
+		for _, record := range w.records {
+			trailer = binary.LittleEndian.AppendUint32(trailer, uint32(record.Offset))
+			trailer = binary.LittleEndian.AppendUint32(trailer, uint32(record.State))
+			trailer = binary.LittleEndian.AppendUint64(trailer, math.Float64bits(record.CreationTimestamp))
+		}
+		if _, err := w.file.WriteAt(trailer, size-trailerSize); err != nil {
+			return err
+		}
+		header = append(header, v2.FileMagic...)
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(w.records)))
+		header = binary.LittleEndian.AppendUint64(header, math.Float64bits(w.created))
+		header = append(header, make([]byte, 16)...)
+	}
+	if _, err := w.file.WriteAt(header, 0); err != nil {
+		return err
+	}
+
+	w.end, w.size = end, size
+	w.committed = len(w.records)
+	w.pending, w.unsynced = w.pending[:0], 0
+	return nil
+}
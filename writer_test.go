@@ -0,0 +1,185 @@
+package segb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	payloads := []string{"Here's to the crazy ones.", "The misfits.", "The rebels."}
+	created := time.Date(2024, 6, 10, 17, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name    string
+		version SegbVersion
+		opts    []WriterOption
+	}{
+		{"v1", SEGB_VERSION_1, nil},
+		{"v2", SEGB_VERSION_2, nil},
+		{"v1 batched", SEGB_VERSION_1, []WriterOption{WithSyncEvery(2)}},
+		{"v2 batched", SEGB_VERSION_2, []WriterOption{WithSyncEvery(2)}},
+		{"v1 preallocated", SEGB_VERSION_1, []WriterOption{WithPreallocation(64)}},
+		{"v2 preallocated", SEGB_VERSION_2, []WriterOption{WithPreallocation(64)}},
+		{"v2 unpadded", SEGB_VERSION_2, []WriterOption{WithEntryAlignment(1)}},
+	} {
+		path := filepath.Join(t.TempDir(), "segment")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWriter(file, tc.version, tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, payload := range payloads {
+			if err := w.Append([]byte(payload), created.Add(time.Duration(i)*time.Minute)); err != nil {
+				t.Fatalf("%s: Append(): %v", tc.name, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close(): %v", tc.name, err)
+		}
+		if err := w.Append(nil, created); err != ErrWriterClosed {
+			t.Errorf("%s: Append() after Close() = %v; want ErrWriterClosed", tc.name, err)
+		}
+		file.Close()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBytes(data)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if decoded.Version != tc.version || len(decoded.Entries) != len(payloads) {
+			t.Fatalf("%s: decoded %v with %d entries", tc.name, decoded.Version, len(decoded.Entries))
+		}
+		for i, entry := range decoded.Entries {
+			if string(entry.Data) != payloads[i] || !entry.CRCValid || entry.State != EntryStateWritten {
+				t.Errorf("%s: entry %d = %q (CRC valid %v, %v)", tc.name, i, entry.Data, entry.CRCValid, entry.State)
+			}
+			if want := created.Add(time.Duration(i) * time.Minute); !entry.Created.Equal(want) {
+				t.Errorf("%s: entry %d created %v; want %v", tc.name, i, entry.Created, want)
+			}
+		}
+	}
+}
+
+func TestWriterSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segment")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	w, err := NewWriter(file, SEGB_VERSION_2, WithSyncEvery(0), WithPreallocation(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := func() int {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data)%4096 != 0 {
+			t.Errorf("file is %d bytes; want a multiple of 4096", len(data))
+		}
+		return len(decoded.Entries)
+	}
+
+	// Entries only show up once synced
+	for i := 0; i < 2; i++ {
+		if err := w.Append([]byte("The round pegs in the square holes."), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := entries(); n != 0 {
+		t.Errorf("before Sync(): %d entries; want 0", n)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := entries(); n != 2 {
+		t.Errorf("after Sync(): %d entries; want 2", n)
+	}
+
+	// Outgrowing the preallocated space moves the trailer
+	for i := 0; i < 100; i++ {
+		if err := w.Append([]byte("The ones who see things differently."), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := entries(); n != 102 {
+		t.Errorf("after Close(): %d entries; want 102", n)
+	}
+}
+
+func TestWriterAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "segment")
+	previous := v2File([]byte("The misfits."))
+	if err := os.WriteFile(path, previous, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unchanged := func(when string) {
+		if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, previous) {
+			t.Errorf("%s: file changed (%v)", when, err)
+		}
+	}
+
+	// An aborted write leaves the previous file and no temporary one
+	w, err := Create(path, SEGB_VERSION_2, WithAtomicWrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Append([]byte("The rebels."), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	unchanged("before Abort()")
+	if err := w.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	unchanged("after Abort()")
+	if names, _ := os.ReadDir(dir); len(names) != 1 {
+		t.Errorf("after Abort(): %d files in directory; want 1", len(names))
+	}
+
+	w, err = Create(path, SEGB_VERSION_2, WithAtomicWrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Append([]byte("The troublemakers."), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	unchanged("before Close()")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := os.ReadDir(dir); len(names) != 1 {
+		t.Errorf("after Close(): %d files in directory; want 1", len(names))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeBytes(data)
+	if err != nil || len(decoded.Entries) != 1 || string(decoded.Entries[0].Data) != "The troublemakers." {
+		t.Errorf("after Close(): %+v, %v", decoded.Entries, err)
+	}
+
+	if _, err := NewWriter(nil, SEGB_VERSION_2, WithAtomicWrite()); err == nil {
+		t.Error("NewWriter() with WithAtomicWrite() succeeded")
+	}
+}